@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeysHandler struct {
+	apiKeyRepo storage.APIKeyRepository
+}
+
+func NewAPIKeysHandler(apiKeyRepo storage.APIKeyRepository) *APIKeysHandler {
+	return &APIKeysHandler{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// @Summary Create API key
+// @Description Create a personal API key for the current user. The key is only returned once
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key body models.APIKeyRequest true "API key name and optional lifetime"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys [post]
+func (keys *APIKeysHandler) CreateHandler(ctx *gin.Context) {
+	var request models.APIKeyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	rawKey, prefix, err := middleware.GenerateAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error generating API key",
+		})
+		return
+	}
+
+	key := &models.APIKey{
+		UserID:  ctx.GetUint("user_id"),
+		Name:    request.Name,
+		Prefix:  prefix,
+		KeyHash: middleware.HashAPIKey(rawKey),
+	}
+	if request.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, request.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := keys.apiKeyRepo.CreateAPIKey(ctx.Request.Context(), key); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating API key: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"id":         key.ID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"key":        rawKey,
+		"expires_at": key.ExpiresAt,
+	})
+}
+
+// @Summary List API keys
+// @Description List the API keys of the current user without their secrets
+// @Tags api-keys
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys [get]
+func (keys *APIKeysHandler) ListHandler(ctx *gin.Context) {
+	userKeys, err := keys.apiKeyRepo.ListUserAPIKeys(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving API keys: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"api_keys": userKeys,
+	})
+}
+
+// @Summary Revoke API key
+// @Description Revoke one of the current user's API keys
+// @Tags api-keys
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys/{id} [delete]
+func (keys *APIKeysHandler) DeleteHandler(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 0)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": storage.ErrAPIKeyNotFound.Error(),
+		})
+		return
+	}
+
+	if err := keys.apiKeyRepo.DeleteAPIKey(ctx.Request.Context(), ctx.GetUint("user_id"), uint(id)); err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking API key: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked",
+	})
+}
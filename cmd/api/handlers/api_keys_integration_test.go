@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+
+	user := &models.User{
+		Username: "apikeyuser",
+		Email:    "apikeyuser@example.com",
+		Password: "hashedpassword",
+	}
+	require.NoError(t, tx.Create(user).Error)
+
+	apiKeyRepo := storage.NewGormAPIKeyRepository(tx)
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	handler := NewAPIKeysHandler(apiKeyRepo)
+	auth := middleware.NewAuthMiddleware(sessRepo, apiKeyRepo)
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", user.ID)
+	testutils.SetJSONBody(ctx, `{"name":"ci"}`)
+	handler.CreateHandler(ctx)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	rawKey := created["key"].(string)
+
+	authenticate := func() (*gin.Context, int) {
+		ctx, recorder := testutils.NewTestContext()
+		ctx.Request.Header.Set(middleware.APIKeyHeader, rawKey)
+		auth.Middleware()(ctx)
+		return ctx, recorder.Code
+	}
+
+	authCtx, status := authenticate()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, user.ID, authCtx.GetUint("user_id"))
+
+	stored, err := apiKeyRepo.GetAPIKeyByPrefix(authCtx, created["prefix"].(string))
+	require.NoError(t, err)
+	assert.NotNil(t, stored.LastUsedAt)
+	assert.NotEqual(t, rawKey, stored.KeyHash)
+
+	ctx, recorder = testutils.NewTestContext()
+	ctx.Set("user_id", user.ID)
+	ctx.Params = gin.Params{{Key: "id", Value: fmt.Sprint(stored.ID)}}
+	handler.DeleteHandler(ctx)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	_, status = authenticate()
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeysCreateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(*mocks.MockAPIKeyRepository)
+		expectedStatus int
+		expectExpiry   bool
+	}{
+		{
+			name:           "Success",
+			body:           `{"name":"ci"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Success With Expiry",
+			body:           `{"name":"ci","expires_in_days":30}`,
+			expectedStatus: http.StatusCreated,
+			expectExpiry:   true,
+		},
+		{
+			name:           "Missing Name",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Negative Expiry",
+			body:           `{"name":"ci","expires_in_days":-1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Database Error",
+			body: `{"name":"ci"}`,
+			mockSetup: func(mkr *mocks.MockAPIKeyRepository) {
+				mkr.CreateAPIKeyFunc = func(ctx context.Context, key *models.APIKey) error {
+					return errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *models.APIKey
+			mockKeyRepo := mocks.NewDefaultAPIKeyMock()
+			mockKeyRepo.CreateAPIKeyFunc = func(ctx context.Context, key *models.APIKey) error {
+				stored = key
+				key.ID = 4
+				return nil
+			}
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockKeyRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewAPIKeysHandler(mockKeyRepo)
+			handler.CreateHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			rawKey := response["key"].(string)
+			assert.True(t, strings.HasPrefix(rawKey, "mt_"+stored.Prefix+"_"))
+			assert.Equal(t, middleware.HashAPIKey(rawKey), stored.KeyHash)
+			assert.NotContains(t, stored.KeyHash, rawKey)
+			assert.Equal(t, uint(1), stored.UserID)
+			assert.Equal(t, "ci", stored.Name)
+			assert.Equal(t, tt.expectExpiry, stored.ExpiresAt != nil)
+			if tt.expectExpiry {
+				assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *stored.ExpiresAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestAPIKeysListHandler(t *testing.T) {
+	mockKeyRepo := mocks.NewDefaultAPIKeyMock()
+	mockKeyRepo.ListUserAPIKeysFunc = func(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+		assert.Equal(t, uint(1), userID)
+		return []*models.APIKey{
+			{ID: 4, UserID: 1, Name: "ci", Prefix: "0123456789ab", KeyHash: "secret-hash"},
+		}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(1))
+
+	handler := NewAPIKeysHandler(mockKeyRepo)
+	handler.ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "secret-hash")
+
+	var response struct {
+		APIKeys []map[string]interface{} `json:"api_keys"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.APIKeys, 1)
+	assert.Equal(t, "0123456789ab", response.APIKeys[0]["prefix"])
+	assert.Equal(t, "ci", response.APIKeys[0]["name"])
+}
+
+func TestAPIKeysDeleteHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mockSetup      func(*mocks.MockAPIKeyRepository)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			id:   "4",
+			mockSetup: func(mkr *mocks.MockAPIKeyRepository) {
+				mkr.DeleteAPIKeyFunc = func(ctx context.Context, userID uint, id uint) error {
+					assert.Equal(t, uint(1), userID)
+					assert.Equal(t, uint(4), id)
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Foreign Or Missing Key",
+			id:   "5",
+			mockSetup: func(mkr *mocks.MockAPIKeyRepository) {
+				mkr.DeleteAPIKeyFunc = func(ctx context.Context, userID uint, id uint) error {
+					return storage.ErrAPIKeyNotFound
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "abc",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "Database Error",
+			id:   "4",
+			mockSetup: func(mkr *mocks.MockAPIKeyRepository) {
+				mkr.DeleteAPIKeyFunc = func(ctx context.Context, userID uint, id uint) error {
+					return errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeyRepo := mocks.NewDefaultAPIKeyMock()
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockKeyRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			ctx.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			handler := NewAPIKeysHandler(mockKeyRepo)
+			handler.DeleteHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+		})
+	}
+}
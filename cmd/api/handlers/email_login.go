@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/mailer"
+	"multitech/pkg/storage"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	emailLoginTTL = 10 * time.Minute
+	// emailLoginInterval is the minimum time between two login mails to the
+	// same address.
+	emailLoginInterval    = time.Minute
+	emailLoginCodeDigits  = 6
+	maxEmailLoginAttempts = 5
+	emailLoginMailTimeout = 30 * time.Second
+)
+
+type EmailLoginHandler struct {
+	userRepo       storage.UserRepository
+	emailLoginRepo storage.EmailLoginRepository
+	sessRepo       storage.SessionsRepository
+	refreshRepo    storage.RefreshTokenRepository
+	challengeRepo  storage.MFAChallengeRepository
+	mailer         mailer.Mailer
+}
+
+func NewEmailLoginHandler(userRepo storage.UserRepository, emailLoginRepo storage.EmailLoginRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, challengeRepo storage.MFAChallengeRepository, mail mailer.Mailer) *EmailLoginHandler {
+	return &EmailLoginHandler{
+		userRepo:       userRepo,
+		emailLoginRepo: emailLoginRepo,
+		sessRepo:       sessRepo,
+		refreshRepo:    refreshRepo,
+		challengeRepo:  challengeRepo,
+		mailer:         mail,
+	}
+}
+
+// @Summary Start passwordless login
+// @Description Mail a magic link (method "link", the default) or a 6-digit code (method "code") to the address.
+// @Description Code logins return a login_token to submit together with the code. The response does not reveal whether the address belongs to an account
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.EmailLoginRequest true "Email address and login method"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/email [post]
+func (emailLogin *EmailLoginHandler) RequestHandler(ctx *gin.Context) {
+	var request models.EmailLoginRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if request.Method == "" {
+		request.Method = models.EmailLoginMethodLink
+	}
+
+	// Throttling comes before the lookup so that unknown addresses behave the
+	// same. Addresses are looked up regardless of case, so they are throttled
+	// that way too.
+	request.Email = strings.ToLower(request.Email)
+	wait, err := emailLogin.emailLoginRepo.ThrottleEmailLogin(ctx.Request.Context(), request.Email, emailLoginInterval)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error throttling login mails: " + err.Error(),
+		})
+		return
+	}
+	if wait > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many login mails, try again later",
+		})
+		return
+	}
+
+	// Unknown addresses get a token too, it just never becomes valid.
+	loginToken, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating login token",
+		})
+		return
+	}
+
+	user, err := emailLogin.userRepo.GetUserByEmail(ctx.Request.Context(), request.Email)
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+	case err != nil:
+		log.Printf("Error retrieving user for email login: %v", err)
+	default:
+		// Delivery runs in the background so that the response time does not
+		// depend on whether a mail is sent.
+		go emailLogin.sendLoginMail(context.WithoutCancel(ctx.Request.Context()), user, loginToken, request.Method)
+	}
+
+	if request.Method == models.EmailLoginMethodCode {
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"message":     "If the address belongs to an account, a login code has been sent",
+			"login_token": loginToken,
+			"expires_in":  int(emailLoginTTL.Seconds()),
+		})
+		return
+	}
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address belongs to an account, a login link has been sent",
+	})
+}
+
+// @Summary Complete passwordless login
+// @Description Exchange the token from a magic link, or a login_token together with the mailed code, for an access token and a refresh token.
+// @Description Users with TOTP enabled receive an mfa_token instead, to be exchanged at /login/mfa
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.EmailLoginVerification true "Login token and, for code logins, the code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/email/verify [post]
+func (emailLogin *EmailLoginHandler) VerifyHandler(ctx *gin.Context) {
+	var verification models.EmailLoginVerification
+	if err := ctx.ShouldBindJSON(&verification); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	challenge, err := emailLogin.emailLoginRepo.GetEmailLoginChallenge(ctx.Request.Context(), verification.LoginToken)
+	if err != nil {
+		emailLogin.challengeError(ctx, err)
+		return
+	}
+
+	// Only link challenges are completed by the token alone. For codes the
+	// attempt is reserved before the code is checked, so concurrent requests
+	// cannot try more than maxEmailLoginAttempts codes between them.
+	if challenge.Method != models.EmailLoginMethodLink {
+		attempts, err := emailLogin.emailLoginRepo.RecordEmailLoginAttempt(ctx.Request.Context(), verification.LoginToken)
+		if err != nil {
+			emailLogin.challengeError(ctx, err)
+			return
+		}
+		if attempts > maxEmailLoginAttempts {
+			emailLogin.discardChallenge(ctx, verification.LoginToken)
+			return
+		}
+		if !checkEmailLoginCode(verification.Code, challenge.CodeHash) {
+			emailLogin.rejectCode(ctx, verification.LoginToken, attempts)
+			return
+		}
+	}
+
+	// Deleting the challenge is the commit point: of two concurrent requests
+	// only one gets a session.
+	if err := emailLogin.emailLoginRepo.DeleteEmailLoginChallenge(ctx.Request.Context(), verification.LoginToken); err != nil {
+		emailLogin.challengeError(ctx, err)
+		return
+	}
+
+	user, err := emailLogin.userRepo.GetUserByID(ctx.Request.Context(), challenge.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": storage.ErrEmailLoginNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+
+	// Receiving the mail proves control of the address.
+	if user.EmailVerifiedAt == nil {
+		if err := emailLogin.userRepo.MarkEmailVerified(ctx.Request.Context(), user.ID, time.Now()); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error updating user: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if user.TOTPEnabled {
+		startMFAChallenge(ctx, emailLogin.challengeRepo, user)
+		return
+	}
+
+	respondWithTokens(ctx, emailLogin.sessRepo, emailLogin.refreshRepo, user)
+}
+
+func (emailLogin *EmailLoginHandler) sendLoginMail(ctx context.Context, user *models.User, loginToken string, method string) {
+	ctx, cancel := context.WithTimeout(ctx, emailLoginMailTimeout)
+	defer cancel()
+
+	challenge := &models.EmailLoginChallenge{
+		UserID: user.ID,
+		Method: method,
+	}
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hello %s,\n\nuse this link within %d minutes to log in:\n\n%s?token=%s\n\nIf you did not try to log in, you can ignore this mail.\n",
+			user.Username, int(emailLoginTTL.Minutes()), emailLoginURL(), loginToken),
+	}
+
+	if method == models.EmailLoginMethodCode {
+		code, err := generateEmailLoginCode()
+		if err != nil {
+			log.Printf("Error generating email login code: %v", err)
+			return
+		}
+		challenge.CodeHash = hashEmailLoginCode(code)
+		msg.Subject = "Your login code"
+		msg.Body = fmt.Sprintf("Hello %s,\n\nyour login code is %s. It is valid for %d minutes.\n\nIf you did not try to log in, you can ignore this mail.\n",
+			user.Username, code, int(emailLoginTTL.Minutes()))
+	}
+
+	if err := emailLogin.emailLoginRepo.CreateEmailLoginChallenge(ctx, loginToken, challenge, emailLoginTTL); err != nil {
+		log.Printf("Error storing email login challenge for user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := emailLogin.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Error sending login mail to user %d: %v", user.ID, err)
+	}
+}
+
+// rejectCode answers an invalid code, the attempts-th tried against the
+// challenge, and discards the challenge once no attempt is left.
+func (emailLogin *EmailLoginHandler) rejectCode(ctx *gin.Context, loginToken string, attempts int) {
+	if attempts >= maxEmailLoginAttempts {
+		emailLogin.discardChallenge(ctx, loginToken)
+		return
+	}
+
+	ctx.JSON(http.StatusUnauthorized, gin.H{
+		"error": "Invalid code",
+	})
+}
+
+func (emailLogin *EmailLoginHandler) discardChallenge(ctx *gin.Context, loginToken string) {
+	if err := emailLogin.emailLoginRepo.DeleteEmailLoginChallenge(ctx.Request.Context(), loginToken); err != nil && !errors.Is(err, storage.ErrEmailLoginNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting login challenge: " + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, gin.H{
+		"error": "Too many invalid codes, request a new one",
+	})
+}
+
+func (emailLogin *EmailLoginHandler) challengeError(ctx *gin.Context, err error) {
+	if errors.Is(err, storage.ErrEmailLoginNotFound) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Error retrieving login challenge: " + err.Error(),
+	})
+}
+
+func generateEmailLoginCode() (string, error) {
+	limit := big.NewInt(int64(math.Pow10(emailLoginCodeDigits)))
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", emailLoginCodeDigits, n.Int64()), nil
+}
+
+func hashEmailLoginCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func checkEmailLoginCode(code string, codeHash string) bool {
+	if code == "" || codeHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashEmailLoginCode(code)), []byte(codeHash)) == 1
+}
+
+// CheckEmailLoginURL fails when mail is delivered to real inboxes but
+// EMAIL_LOGIN_URL is unset. The default points at POST /login/email/verify,
+// which a mail client cannot open, so only the file and memory mailers of
+// development setups may rely on it.
+func CheckEmailLoginURL(mail mailer.Mailer) error {
+	return requireLinkURL(mail, "EMAIL_LOGIN_URL")
+}
+
+// requireLinkURL fails when the SMTP mailer is used without the link target
+// in the environment variable key.
+func requireLinkURL(mail mailer.Mailer, key string) error {
+	if _, ok := mail.(*mailer.SMTPMailer); ok && os.Getenv(key) == "" {
+		return fmt.Errorf("%s is required with the smtp mailer", key)
+	}
+	return nil
+}
+
+func emailLoginURL() string {
+	if url := os.Getenv("EMAIL_LOGIN_URL"); url != "" {
+		return url
+	}
+	return oidcIssuer() + "/login/email/verify"
+}
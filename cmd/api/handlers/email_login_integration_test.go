@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailLoginCodeLifecycle(t *testing.T) {
+	bg := context.Background()
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+		return &models.User{ID: 81, Username: "emaillogin", Email: email}, nil
+	}
+	mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+		return &models.User{ID: id, Username: "emaillogin", Email: "emaillogin@example.com"}, nil
+	}
+
+	emailLoginRepo := storage.NewRedisEmailLoginRepository(testutils.TestRedis)
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, 81)
+	defer sessRepo.DeleteUserSessions(bg, 81)
+	mail := mailer.NewMemoryMailer()
+	handler := NewEmailLoginHandler(mockUserRepo, emailLoginRepo, sessRepo, refreshRepo, storage.NewRedisMFAChallengeRepository(testutils.TestRedis), mail)
+
+	requestCode := func() string {
+		defer testutils.TestRedis.Del(bg, "email_login_throttle:emaillogin@example.com")
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"email":"emaillogin@example.com","method":"code"}`)
+		handler.RequestHandler(ctx)
+		require.Equal(t, http.StatusAccepted, recorder.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return response["login_token"].(string)
+	}
+	submit := func(loginToken string, code string) int {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"login_token":"`+loginToken+`","code":"`+code+`"}`)
+		handler.VerifyHandler(ctx)
+		return recorder.Code
+	}
+	mailedCode := func(count int) string {
+		require.Eventually(t, func() bool { return len(mail.Messages()) == count }, time.Second, 5*time.Millisecond)
+		sent, _ := mail.Last()
+		return regexp.MustCompile(`\b\d{6}\b`).FindString(sent.Body)
+	}
+
+	lockedToken := requestCode()
+	lockedCode := mailedCode(1)
+	wrongCode := "000000"
+	if lockedCode == wrongCode {
+		wrongCode = "111111"
+	}
+	for attempt := 0; attempt < maxEmailLoginAttempts; attempt++ {
+		assert.Equal(t, http.StatusUnauthorized, submit(lockedToken, wrongCode))
+	}
+	// The challenge is gone after too many attempts, even for the right code.
+	assert.Equal(t, http.StatusUnauthorized, submit(lockedToken, lockedCode))
+
+	loginToken := requestCode()
+	code := mailedCode(2)
+	assert.Equal(t, http.StatusOK, submit(loginToken, code))
+	assert.Equal(t, http.StatusUnauthorized, submit(loginToken, code))
+
+	sessions, err := sessRepo.ListUserSessions(bg, 81)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
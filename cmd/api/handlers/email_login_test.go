@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailLoginRequestHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		mockUserSetup   func(*mocks.MockUserRepository)
+		mockLoginSetup  func(*mocks.MockEmailLoginRepository)
+		expectedStatus  int
+		expectToken     bool
+		expectMail      bool
+		expectedMessage string
+	}{
+		{
+			name:            "Link",
+			body:            `{"email":"test@example.com"}`,
+			expectedStatus:  http.StatusAccepted,
+			expectMail:      true,
+			expectedMessage: "If the address belongs to an account, a login link has been sent",
+		},
+		{
+			name:            "Code",
+			body:            `{"email":"test@example.com","method":"code"}`,
+			expectedStatus:  http.StatusAccepted,
+			expectToken:     true,
+			expectMail:      true,
+			expectedMessage: "If the address belongs to an account, a login code has been sent",
+		},
+		{
+			name: "Unknown Email Code",
+			body: `{"email":"nobody@example.com","method":"code"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+					return nil, storage.ErrUserNotFound
+				}
+			},
+			expectedStatus:  http.StatusAccepted,
+			expectToken:     true,
+			expectedMessage: "If the address belongs to an account, a login code has been sent",
+		},
+		{
+			name: "Throttled",
+			body: `{"email":"test@example.com"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.ThrottleEmailLoginFunc = func(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+					return 30 * time.Second, nil
+				}
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "Throttled In Another Case",
+			body: `{"email":"Test@Example.com"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.ThrottleEmailLoginFunc = func(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+					if email == "test@example.com" {
+						return 30 * time.Second, nil
+					}
+					return 0, nil
+				}
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "Unknown Method",
+			body:           `{"email":"test@example.com","method":"pigeon"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockLoginRepo := mocks.NewDefaultEmailLoginMock()
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+			if tt.mockLoginSetup != nil {
+				tt.mockLoginSetup(mockLoginRepo)
+			}
+			mail := mailer.NewMemoryMailer()
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewEmailLoginHandler(mockUserRepo, mockLoginRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mail)
+			handler.RequestHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusAccepted {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedMessage, response["message"])
+				_, hasToken := response["login_token"]
+				assert.Equal(t, tt.expectToken, hasToken)
+			}
+			if tt.expectMail {
+				assert.Eventually(t, func() bool { return len(mail.Messages()) == 1 }, time.Second, 5*time.Millisecond)
+			} else {
+				assert.Empty(t, mail.Messages())
+			}
+		})
+	}
+}
+
+func TestEmailLoginSendLoginMail(t *testing.T) {
+	user := &models.User{ID: 4, Username: "alice", Email: "alice@example.com"}
+
+	t.Run("Link", func(t *testing.T) {
+		var stored *models.EmailLoginChallenge
+		mockLoginRepo := mocks.NewDefaultEmailLoginMock()
+		mockLoginRepo.CreateEmailLoginChallengeFunc = func(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error {
+			assert.Equal(t, "link-token", token)
+			stored = challenge
+			return nil
+		}
+		mail := mailer.NewMemoryMailer()
+		handler := NewEmailLoginHandler(mocks.NewDefaultUserMock(), mockLoginRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mail)
+
+		handler.sendLoginMail(context.Background(), user, "link-token", models.EmailLoginMethodLink)
+
+		require.NotNil(t, stored)
+		assert.Equal(t, models.EmailLoginChallenge{UserID: 4, Method: models.EmailLoginMethodLink}, *stored)
+		sent, ok := mail.Last()
+		require.True(t, ok)
+		assert.Contains(t, sent.Body, "?token=link-token")
+	})
+
+	t.Run("Code", func(t *testing.T) {
+		var stored *models.EmailLoginChallenge
+		mockLoginRepo := mocks.NewDefaultEmailLoginMock()
+		mockLoginRepo.CreateEmailLoginChallengeFunc = func(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error {
+			stored = challenge
+			return nil
+		}
+		mail := mailer.NewMemoryMailer()
+		handler := NewEmailLoginHandler(mocks.NewDefaultUserMock(), mockLoginRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mail)
+
+		handler.sendLoginMail(context.Background(), user, "code-token", models.EmailLoginMethodCode)
+
+		sent, ok := mail.Last()
+		require.True(t, ok)
+		assert.NotContains(t, sent.Body, "code-token", "the login token must not be mailed for code logins")
+		code := regexp.MustCompile(`\b\d{6}\b`).FindString(sent.Body)
+		require.NotEmpty(t, code)
+		require.NotNil(t, stored)
+		assert.True(t, checkEmailLoginCode(code, stored.CodeHash))
+		assert.NotContains(t, stored.CodeHash, code)
+	})
+}
+
+func TestCheckEmailLoginURL(t *testing.T) {
+	smtpMailer := mailer.NewSMTPMailer("smtp.example.com", "587", "", "", "noreply@example.com")
+
+	t.Setenv("EMAIL_LOGIN_URL", "")
+	assert.NoError(t, CheckEmailLoginURL(mailer.NewMemoryMailer()))
+	assert.ErrorContains(t, CheckEmailLoginURL(smtpMailer), "EMAIL_LOGIN_URL")
+
+	t.Setenv("EMAIL_LOGIN_URL", "https://app.example.com/login/email")
+	assert.NoError(t, CheckEmailLoginURL(smtpMailer))
+}
+
+func TestEmailLoginVerifyHandler(t *testing.T) {
+	codeChallenge := func(ctx context.Context, token string) (*models.EmailLoginChallenge, error) {
+		return &models.EmailLoginChallenge{UserID: 1, Method: models.EmailLoginMethodCode, CodeHash: hashEmailLoginCode("123456")}, nil
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockLoginSetup func(*mocks.MockEmailLoginRepository)
+		mockUserSetup  func(*mocks.MockUserRepository)
+		envSetup       func(*mocks.EnvMock)
+		expectedStatus int
+		expectedBody   string
+		expectSession  bool
+	}{
+		{
+			name:           "Link",
+			body:           `{"login_token":"link-token"}`,
+			expectedStatus: http.StatusOK,
+			expectSession:  true,
+		},
+		{
+			name: "Code",
+			body: `{"login_token":"code-token","code":"123456"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.GetEmailLoginChallengeFunc = codeChallenge
+			},
+			expectedStatus: http.StatusOK,
+			expectSession:  true,
+		},
+		{
+			name: "Code Missing",
+			body: `{"login_token":"code-token"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.GetEmailLoginChallengeFunc = codeChallenge
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid code"}`,
+		},
+		{
+			name: "Last Attempt",
+			body: `{"login_token":"code-token","code":"000000"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.GetEmailLoginChallengeFunc = codeChallenge
+				mlr.RecordEmailLoginAttemptFunc = func(ctx context.Context, token string) (int, error) {
+					return maxEmailLoginAttempts, nil
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Too many invalid codes, request a new one"}`,
+		},
+		{
+			name: "Attempts Used Up Concurrently",
+			body: `{"login_token":"code-token","code":"123456"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.GetEmailLoginChallengeFunc = codeChallenge
+				mlr.RecordEmailLoginAttemptFunc = func(ctx context.Context, token string) (int, error) {
+					return maxEmailLoginAttempts + 1, nil
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Too many invalid codes, request a new one"}`,
+		},
+		{
+			name: "Link Without Attempts",
+			body: `{"login_token":"link-token"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.RecordEmailLoginAttemptFunc = func(ctx context.Context, token string) (int, error) {
+					return maxEmailLoginAttempts + 1, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectSession:  true,
+		},
+		{
+			name: "Unknown Token",
+			body: `{"login_token":"nope"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.GetEmailLoginChallengeFunc = func(ctx context.Context, token string) (*models.EmailLoginChallenge, error) {
+					return nil, storage.ErrEmailLoginNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"` + storage.ErrEmailLoginNotFound.Error() + `"}`,
+		},
+		{
+			name: "Used Concurrently",
+			body: `{"login_token":"link-token"}`,
+			mockLoginSetup: func(mlr *mocks.MockEmailLoginRepository) {
+				mlr.DeleteEmailLoginChallengeFunc = func(ctx context.Context, token string) error {
+					return storage.ErrEmailLoginNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "TOTP Enabled",
+			body: `{"login_token":"link-token"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+					return &models.User{ID: id, Username: "testuser", TOTPSecret: testTOTPSecret, TOTPEnabled: true}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"mfa_required":true,"mfa_token":"*","expires_in":300}`,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sessionCreated bool
+			var verifiedAt *time.Time
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.MarkEmailVerifiedFunc = func(ctx context.Context, id uint, at time.Time) error {
+				verifiedAt = &at
+				return nil
+			}
+			mockLoginRepo := mocks.NewDefaultEmailLoginMock()
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.CreateSessionFunc = func(ctx context.Context, session *models.Session) error {
+				sessionCreated = true
+				return nil
+			}
+			if tt.mockLoginSetup != nil {
+				tt.mockLoginSetup(mockLoginRepo)
+			}
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewEmailLoginHandler(mockUserRepo, mockLoginRepo, mockSessRepo, mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mailer.NewMemoryMailer())
+			handler.VerifyHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				var expected, actual map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(tt.expectedBody), &expected))
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &actual))
+				for key, value := range expected {
+					if value == "*" {
+						assert.NotEmpty(t, actual[key])
+						delete(expected, key)
+						delete(actual, key)
+					}
+				}
+				assert.Equal(t, expected, actual)
+			}
+			assert.Equal(t, tt.expectSession, sessionCreated)
+			if tt.expectSession {
+				assert.NotNil(t, verifiedAt, "a completed email login verifies the address")
+			}
+		})
+	}
+}
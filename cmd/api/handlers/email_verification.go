@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/mailer"
+	"multitech/pkg/storage"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	emailVerificationTTL     = 24 * time.Hour
+	emailVerificationPurpose = "email_verification"
+	// emailVerificationInterval is the minimum time between two verification
+	// mails to the same address.
+	emailVerificationInterval    = time.Minute
+	emailVerificationMailTimeout = 30 * time.Second
+)
+
+// emailVerificationClaims are signed like access tokens but carry a purpose,
+// so neither kind of token is accepted in place of the other. The email is
+// included so that a link stops working once the address changes.
+type emailVerificationClaims struct {
+	Purpose string `json:"purpose"`
+	Email   string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+type EmailVerificationHandler struct {
+	userRepo         storage.UserRepository
+	verificationRepo storage.EmailVerificationRepository
+	mailer           mailer.Mailer
+}
+
+func NewEmailVerificationHandler(userRepo storage.UserRepository, verificationRepo storage.EmailVerificationRepository, mail mailer.Mailer) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		mailer:           mail,
+	}
+}
+
+// @Summary Verify email address
+// @Description Confirm the email address of an account with the token from the verification mail
+// @Tags auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /verify-email [get]
+func (verification *EmailVerificationHandler) VerifyHandler(ctx *gin.Context) {
+	claims := &emailVerificationClaims{}
+	if _, err := middleware.ParseTokenClaims(ctx.Query("token"), claims); err != nil || claims.Purpose != emailVerificationPurpose || claims.ID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": storage.ErrEmailVerificationNotFound.Error(),
+		})
+		return
+	}
+
+	userID, err := verification.verificationRepo.ConsumeEmailVerification(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrEmailVerificationNotFound) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving verification token: " + err.Error(),
+		})
+		return
+	}
+	if strconv.FormatUint(uint64(userID), 10) != claims.Subject {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": storage.ErrEmailVerificationNotFound.Error(),
+		})
+		return
+	}
+
+	user, err := verification.userRepo.GetUserByID(ctx.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": storage.ErrEmailVerificationNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+	if user.Email != claims.Email {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "Verification link does not match the current email address",
+		})
+		return
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := verification.userRepo.MarkEmailVerified(ctx.Request.Context(), user.ID, time.Now()); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error updating user: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Email address verified",
+	})
+}
+
+// @Summary Resend verification email
+// @Description Send a new verification mail. The response does not reveal whether the address belongs to an account
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body models.EmailVerificationRequest true "Email address of the account"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /verify-email/resend [post]
+func (verification *EmailVerificationHandler) ResendHandler(ctx *gin.Context) {
+	var request models.EmailVerificationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Throttling comes before the lookup so that unknown addresses behave the
+	// same. Addresses are looked up regardless of case, so they are throttled
+	// that way too.
+	request.Email = strings.ToLower(request.Email)
+	wait, err := verification.verificationRepo.ThrottleEmailVerification(ctx.Request.Context(), request.Email, emailVerificationInterval)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error throttling verification mails: " + err.Error(),
+		})
+		return
+	}
+	if wait > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many verification mails, try again later",
+		})
+		return
+	}
+
+	// Failures past this point are only logged: any other answer would tell
+	// unverified accounts apart from other addresses.
+	user, err := verification.userRepo.GetUserByEmail(ctx.Request.Context(), request.Email)
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+	case err != nil:
+		log.Printf("Error retrieving user for email verification: %v", err)
+	case user.EmailVerifiedAt == nil:
+		// Delivery runs in the background so that the response time does not
+		// depend on whether a mail is sent.
+		go verification.resendEmailVerification(context.WithoutCancel(ctx.Request.Context()), user)
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address belongs to an unverified account, a verification mail has been sent",
+	})
+}
+
+func (verification *EmailVerificationHandler) resendEmailVerification(ctx context.Context, user *models.User) {
+	ctx, cancel := context.WithTimeout(ctx, emailVerificationMailTimeout)
+	defer cancel()
+
+	if err := sendEmailVerification(ctx, verification.verificationRepo, verification.mailer, user); err != nil {
+		log.Printf("Error sending verification mail to user %d: %v", user.ID, err)
+	}
+}
+
+// sendEmailVerification mails the user a single-use link that verifies their
+// current email address.
+func sendEmailVerification(ctx context.Context, verificationRepo storage.EmailVerificationRepository, mail mailer.Mailer, user *models.User) error {
+	tokenID, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	token, err := middleware.SignToken(&emailVerificationClaims{
+		Purpose: emailVerificationPurpose,
+		Email:   user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(emailVerificationTTL)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := verificationRepo.StoreEmailVerification(ctx, tokenID, user.ID, emailVerificationTTL); err != nil {
+		return err
+	}
+
+	return mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nplease confirm your email address by opening this link within 24 hours:\n\n%s?token=%s\n\nIf you did not create an account, you can ignore this mail.\n",
+			user.Username, emailVerificationURL(), token),
+	})
+}
+
+func emailVerificationURL() string {
+	if url := os.Getenv("EMAIL_VERIFICATION_URL"); url != "" {
+		return url
+	}
+	return oidcIssuer() + "/verify-email"
+}
+
+// emailVerificationRequired reports whether unverified users are refused at login.
+func emailVerificationRequired() bool {
+	required, _ := strconv.ParseBool(os.Getenv("EMAIL_VERIFICATION_REQUIRED"))
+	return required
+}
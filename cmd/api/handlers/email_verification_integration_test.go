@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationLifecycle(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	user := &models.User{ID: 71, Username: "verifyuser", Email: "verifyuser@example.com"}
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+		return user, nil
+	}
+	mockUserRepo.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+		return user, nil
+	}
+	mockUserRepo.MarkEmailVerifiedFunc = func(ctx context.Context, id uint, at time.Time) error {
+		verified := *user
+		verified.EmailVerifiedAt = &at
+		user = &verified
+		return nil
+	}
+
+	verificationRepo := storage.NewRedisEmailVerificationRepository(testutils.TestRedis)
+	defer testutils.TestRedis.Del(context.Background(), "email_verification_throttle:verifyuser@example.com")
+	mail := mailer.NewMemoryMailer()
+	handler := NewEmailVerificationHandler(mockUserRepo, verificationRepo, mail)
+
+	resend := func() int {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"email":"VerifyUser@example.com"}`)
+		handler.ResendHandler(ctx)
+		return recorder.Code
+	}
+	verify := func(token string) int {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetQuery(ctx, url.Values{"token": {token}})
+		handler.VerifyHandler(ctx)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusAccepted, resend())
+	assert.Equal(t, http.StatusTooManyRequests, resend())
+	require.Len(t, mail.Messages(), 1)
+
+	token := mailedVerificationToken(t, mail)
+	assert.Equal(t, http.StatusOK, verify(token))
+	assert.NotNil(t, user.EmailVerifiedAt)
+	assert.Equal(t, http.StatusBadRequest, verify(token))
+
+	wait, err := verificationRepo.ThrottleEmailVerification(context.Background(), "verifyuser@example.com", time.Minute)
+	require.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/mailer"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mailedVerificationToken extracts the token from the last verification mail.
+func mailedVerificationToken(t *testing.T, mail *mailer.MemoryMailer) string {
+	sent, ok := mail.Last()
+	require.True(t, ok, "no mail was sent")
+	match := regexp.MustCompile(`\?token=(\S+)`).FindStringSubmatch(sent.Body)
+	require.Len(t, match, 2, "mail does not contain a verification link")
+	return match[1]
+}
+
+func TestSendEmailVerification(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Set("EMAIL_VERIFICATION_URL", "https://app.example.com/verify")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	var storedID string
+	var storedTTL time.Duration
+	mockVerificationRepo := mocks.NewDefaultEmailVerificationMock()
+	mockVerificationRepo.StoreEmailVerificationFunc = func(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error {
+		assert.Equal(t, uint(3), userID)
+		storedID = tokenID
+		storedTTL = ttl
+		return nil
+	}
+	mail := mailer.NewMemoryMailer()
+
+	user := &models.User{ID: 3, Username: "alice", Email: "alice@example.com"}
+	require.NoError(t, sendEmailVerification(context.Background(), mockVerificationRepo, mail, user))
+
+	sent, _ := mail.Last()
+	assert.Equal(t, "alice@example.com", sent.To)
+	assert.Contains(t, sent.Body, "https://app.example.com/verify?token=")
+
+	claims := &emailVerificationClaims{}
+	_, err := middleware.ParseTokenClaims(mailedVerificationToken(t, mail), claims)
+	require.NoError(t, err)
+	assert.Equal(t, emailVerificationPurpose, claims.Purpose)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.Equal(t, "3", claims.Subject)
+	assert.Equal(t, storedID, claims.ID)
+	assert.Equal(t, emailVerificationTTL, storedTTL)
+}
+
+func TestEmailVerificationVerifyHandler(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	mail := mailer.NewMemoryMailer()
+	require.NoError(t, sendEmailVerification(context.Background(), mocks.NewDefaultEmailVerificationMock(), mail,
+		&models.User{ID: 1, Username: "testuser", Email: "test@example.com"}))
+	validToken := mailedVerificationToken(t, mail)
+
+	accessToken, err := middleware.GenerateToken(1, "session", nil)
+	require.NoError(t, err)
+
+	expiredToken, err := middleware.SignToken(&emailVerificationClaims{
+		Purpose: emailVerificationPurpose,
+		Email:   "test@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "expired",
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		token             string
+		mockUserSetup     func(*mocks.MockUserRepository)
+		mockVerifySetup   func(*mocks.MockEmailVerificationRepository)
+		expectedStatus    int
+		expectedBody      string
+		expectVerifiedNow bool
+	}{
+		{
+			name:              "Success",
+			token:             validToken,
+			expectedStatus:    http.StatusOK,
+			expectedBody:      `{"message":"Email address verified"}`,
+			expectVerifiedNow: true,
+		},
+		{
+			name:  "Already Verified",
+			token: validToken,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+					verifiedAt := time.Now().Add(-time.Hour)
+					return &models.User{ID: id, Email: "test@example.com", EmailVerifiedAt: &verifiedAt}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "Token Already Used",
+			token: validToken,
+			mockVerifySetup: func(mvr *mocks.MockEmailVerificationRepository) {
+				mvr.ConsumeEmailVerificationFunc = func(ctx context.Context, tokenID string) (uint, error) {
+					return 0, storage.ErrEmailVerificationNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"` + storage.ErrEmailVerificationNotFound.Error() + `"}`,
+		},
+		{
+			name:  "Email Changed",
+			token: validToken,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+					return &models.User{ID: id, Email: "new@example.com"}, nil
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Verification link does not match the current email address"}`,
+		},
+		{
+			name:           "Access Token",
+			token:          accessToken,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"` + storage.ErrEmailVerificationNotFound.Error() + `"}`,
+		},
+		{
+			name:           "Expired Token",
+			token:          expiredToken,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing Token",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "Storage Error",
+			token: validToken,
+			mockVerifySetup: func(mvr *mocks.MockEmailVerificationRepository) {
+				mvr.ConsumeEmailVerificationFunc = func(ctx context.Context, tokenID string) (uint, error) {
+					return 0, errors.New("redis error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verifiedAt *time.Time
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.MarkEmailVerifiedFunc = func(ctx context.Context, id uint, at time.Time) error {
+				verifiedAt = &at
+				return nil
+			}
+			mockVerificationRepo := mocks.NewDefaultEmailVerificationMock()
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+			if tt.mockVerifySetup != nil {
+				tt.mockVerifySetup(mockVerificationRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetQuery(ctx, url.Values{"token": {tt.token}})
+
+			handler := NewEmailVerificationHandler(mockUserRepo, mockVerificationRepo, mailer.NewMemoryMailer())
+			handler.VerifyHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+			if tt.expectVerifiedNow {
+				assert.NotNil(t, verifiedAt)
+			} else {
+				assert.Nil(t, verifiedAt)
+			}
+		})
+	}
+}
+
+func TestEmailVerificationResendHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		mockUserSetup   func(*mocks.MockUserRepository)
+		mockVerifySetup func(*mocks.MockEmailVerificationRepository)
+		expectedStatus  int
+		expectMail      bool
+		retryAfter      string
+	}{
+		{
+			name:           "Unverified User",
+			body:           `{"email":"test@example.com"}`,
+			expectedStatus: http.StatusAccepted,
+			expectMail:     true,
+		},
+		{
+			name: "Already Verified",
+			body: `{"email":"test@example.com"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+					verifiedAt := time.Now()
+					return &models.User{ID: 1, Email: email, EmailVerifiedAt: &verifiedAt}, nil
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "Unknown Email",
+			body: `{"email":"nobody@example.com"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+					return nil, storage.ErrUserNotFound
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "Lookup Error",
+			body: `{"email":"test@example.com"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+					return nil, errors.New("connection refused")
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "Mail Not Stored",
+			body: `{"email":"test@example.com"}`,
+			mockVerifySetup: func(mvr *mocks.MockEmailVerificationRepository) {
+				mvr.StoreEmailVerificationFunc = func(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error {
+					return errors.New("connection refused")
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "Throttled",
+			body: `{"email":"test@example.com"}`,
+			mockVerifySetup: func(mvr *mocks.MockEmailVerificationRepository) {
+				mvr.ThrottleEmailVerificationFunc = func(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+					return 41500 * time.Millisecond, nil
+				}
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			retryAfter:     "42",
+		},
+		{
+			name: "Throttled In Another Case",
+			body: `{"email":"Test@Example.com"}`,
+			mockVerifySetup: func(mvr *mocks.MockEmailVerificationRepository) {
+				mvr.ThrottleEmailVerificationFunc = func(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+					if email == "test@example.com" {
+						return 41500 * time.Millisecond, nil
+					}
+					return 0, nil
+				}
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			retryAfter:     "42",
+		},
+		{
+			name:           "Missing Email",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockVerificationRepo := mocks.NewDefaultEmailVerificationMock()
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+			if tt.mockVerifySetup != nil {
+				tt.mockVerifySetup(mockVerificationRepo)
+			}
+			mail := mailer.NewMemoryMailer()
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewEmailVerificationHandler(mockUserRepo, mockVerificationRepo, mail)
+			handler.ResendHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.retryAfter, recorder.Header().Get("Retry-After"))
+			if tt.expectMail {
+				assert.Eventually(t, func() bool { return len(mail.Messages()) == 1 }, time.Second, 5*time.Millisecond)
+			} else {
+				assert.Empty(t, mail.Messages())
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// invitationTTL is how long an invitation stays valid unless the request
+// asks for another number of days.
+const invitationTTL = 7 * 24 * time.Hour
+
+type InvitationsHandler struct {
+	invitationRepo storage.InvitationRepository
+	rbac           *middleware.RBAC
+}
+
+func NewInvitationsHandler(invitationRepo storage.InvitationRepository, rbac *middleware.RBAC) *InvitationsHandler {
+	return &InvitationsHandler{
+		invitationRepo: invitationRepo,
+		rbac:           rbac,
+	}
+}
+
+// @Summary Invite a user
+// @Description Create a single-use invitation to register with the email address, optionally granting a role whose permissions the caller's own roles cover. The invitation token is only returned once. Requires the invitations:write permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param invitation body models.InvitationRequest true "Email address, optional role and lifetime"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/invitations [post]
+func (invitations *InvitationsHandler) CreateHandler(ctx *gin.Context) {
+	var request models.InvitationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Otherwise invitations:write would let its holders hand out any role,
+	// including one they do not hold themselves, and register with it.
+	if request.Role != "" {
+		covered, err := invitations.rbac.CallerCoversRole(ctx, request.Role)
+		if err != nil {
+			if errors.Is(err, storage.ErrRoleNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error checking role: " + err.Error(),
+			})
+			return
+		}
+		if !covered {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error": "Role " + request.Role + " grants more than your own roles",
+			})
+			return
+		}
+	}
+
+	token, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error generating token",
+		})
+		return
+	}
+
+	ttl := invitationTTL
+	if request.ExpiresInDays > 0 {
+		ttl = time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	}
+	invitation := &models.Invitation{
+		Email:     request.Email,
+		TokenHash: hashInvitationToken(token),
+		Role:      request.Role,
+		InvitedBy: ctx.GetUint("user_id"),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := invitations.invitationRepo.CreateInvitation(ctx.Request.Context(), invitation); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating invitation: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"id":         invitation.ID,
+		"email":      invitation.Email,
+		"role":       invitation.Role,
+		"expires_at": invitation.ExpiresAt,
+		"token":      token,
+	})
+}
+
+// @Summary List invitations
+// @Description List every invitation, newest first, with whether and by whom it was used. Requires the invitations:read permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/invitations [get]
+func (invitations *InvitationsHandler) ListHandler(ctx *gin.Context) {
+	list, err := invitations.invitationRepo.ListInvitations(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing invitations: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"invitations": list,
+	})
+}
+
+// @Summary Revoke an invitation
+// @Description Delete an invitation, so that its token can no longer be used to register. Requires the invitations:write permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/invitations/{id} [delete]
+func (invitations *InvitationsHandler) RevokeHandler(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": storage.ErrInvitationNotFound.Error(),
+		})
+		return
+	}
+
+	if err := invitations.invitationRepo.DeleteInvitation(ctx.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, storage.ErrInvitationNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking invitation: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Invitation revoked",
+	})
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationRepository(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+	bg := context.Background()
+
+	require.NoError(t, tx.Create(&models.Role{Name: "invitee"}).Error)
+	inviter := &models.User{Username: "inviter", Email: "inviter@example.com", Password: "x"}
+	require.NoError(t, tx.Create(inviter).Error)
+
+	inviteRepo := storage.NewGormInvitationRepository(tx)
+	roleRepo := storage.NewGormRoleRepository(tx)
+
+	invite := func(email, token, role string, expiresAt time.Time) *models.Invitation {
+		invitation := &models.Invitation{
+			Email:     email,
+			TokenHash: hashInvitationToken(token),
+			Role:      role,
+			InvitedBy: inviter.ID,
+			ExpiresAt: expiresAt,
+		}
+		require.NoError(t, inviteRepo.CreateInvitation(bg, invitation))
+		return invitation
+	}
+
+	assert.ErrorIs(t, inviteRepo.CreateInvitation(bg, &models.Invitation{
+		Email:     "nobody@example.com",
+		TokenHash: hashInvitationToken("unknown-role"),
+		Role:      "overlord",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}), storage.ErrRoleNotFound)
+
+	invited := invite("Invited@Example.com", "valid-token", "invitee", time.Now().Add(time.Hour))
+	invite("expired@example.com", "expired-token", "", time.Now().Add(-time.Hour))
+	revoked := invite("revoked@example.com", "revoked-token", "", time.Now().Add(time.Hour))
+
+	_, err := inviteRepo.AcceptInvitation(bg, hashInvitationToken("unknown-token"), &models.User{Username: "unknown", Email: "unknown@example.com", Password: "x"})
+	assert.ErrorIs(t, err, storage.ErrInvitationInvalid)
+	_, err = inviteRepo.AcceptInvitation(bg, hashInvitationToken("expired-token"), &models.User{Username: "expired", Email: "expired@example.com", Password: "x"})
+	assert.ErrorIs(t, err, storage.ErrInvitationInvalid)
+	_, err = inviteRepo.AcceptInvitation(bg, hashInvitationToken("valid-token"), &models.User{Username: "intruder", Email: "intruder@example.com", Password: "x"})
+	assert.ErrorIs(t, err, storage.ErrInvitationEmailMismatch)
+
+	// A failed registration leaves the invitation unused.
+	_, err = inviteRepo.AcceptInvitation(bg, hashInvitationToken("valid-token"), &models.User{Username: "inviter", Email: "invited@example.com", Password: "x"})
+	assert.ErrorIs(t, err, storage.ErrUserExists)
+
+	user := &models.User{Username: "invited", Email: "invited@example.com", Password: "x"}
+	accepted, err := inviteRepo.AcceptInvitation(bg, hashInvitationToken("valid-token"), user)
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+	assert.Equal(t, invited.ID, accepted.ID)
+	require.NotNil(t, accepted.UserID)
+	assert.Equal(t, user.ID, *accepted.UserID)
+
+	roles, err := roleRepo.GetUserRoles(bg, user.ID)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, "invitee", roles[0].Name)
+
+	// Invitations are single use.
+	_, err = inviteRepo.AcceptInvitation(bg, hashInvitationToken("valid-token"), &models.User{Username: "invited2", Email: "invited@example.com", Password: "x"})
+	assert.ErrorIs(t, err, storage.ErrInvitationInvalid)
+
+	require.NoError(t, inviteRepo.DeleteInvitation(bg, revoked.ID))
+	assert.ErrorIs(t, inviteRepo.DeleteInvitation(bg, revoked.ID), storage.ErrInvitationNotFound)
+	_, err = inviteRepo.AcceptInvitation(bg, hashInvitationToken("revoked-token"), &models.User{Username: "revoked", Email: "revoked@example.com", Password: "x"})
+	assert.ErrorIs(t, err, storage.ErrInvitationInvalid)
+
+	invitations, err := inviteRepo.ListInvitations(bg)
+	require.NoError(t, err)
+	require.Len(t, invitations, 2)
+	for _, invitation := range invitations {
+		if invitation.ID == invited.ID {
+			assert.NotNil(t, invitation.UsedAt)
+		} else {
+			assert.Nil(t, invitation.UsedAt)
+		}
+	}
+}
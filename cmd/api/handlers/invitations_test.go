@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInvitationsHandler(invitationRepo storage.InvitationRepository) *InvitationsHandler {
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return []models.Role{
+			{Name: "admin", Permissions: []models.Permission{{Name: "*"}}},
+			{Name: "inviter", Permissions: []models.Permission{{Name: "invitations:*"}}},
+			{Name: "support", Permissions: []models.Permission{{Name: "users:read"}}},
+		}, nil
+	}
+	return NewInvitationsHandler(invitationRepo, middleware.NewRBAC(mockRoleRepo))
+}
+
+func TestInvitationsCreateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		callerRoles    []string
+		createErr      error
+		expectedStatus int
+		expectedTTL    time.Duration
+	}{
+		{name: "Success", body: `{"email":"invitee@mail.com","role":"admin"}`, callerRoles: []string{"admin"}, expectedStatus: http.StatusCreated, expectedTTL: invitationTTL},
+		{name: "Custom Lifetime", body: `{"email":"invitee@mail.com","expires_in_days":2}`, callerRoles: []string{"inviter"}, expectedStatus: http.StatusCreated, expectedTTL: 48 * time.Hour},
+		{name: "Caller's Own Role", body: `{"email":"invitee@mail.com","role":"inviter"}`, callerRoles: []string{"inviter"}, expectedStatus: http.StatusCreated, expectedTTL: invitationTTL},
+		{name: "Role Beyond Caller", body: `{"email":"invitee@mail.com","role":"admin"}`, callerRoles: []string{"inviter", "support"}, expectedStatus: http.StatusForbidden},
+		{name: "Invalid Email", body: `{"email":"invitee"}`, callerRoles: []string{"admin"}, expectedStatus: http.StatusBadRequest},
+		{name: "Lifetime Too Long", body: `{"email":"invitee@mail.com","expires_in_days":365}`, callerRoles: []string{"admin"}, expectedStatus: http.StatusBadRequest},
+		{name: "Unknown Role", body: `{"email":"invitee@mail.com","role":"nobody"}`, callerRoles: []string{"admin"}, expectedStatus: http.StatusNotFound},
+		{name: "Role Deleted Meanwhile", body: `{"email":"invitee@mail.com","role":"support"}`, callerRoles: []string{"admin"}, createErr: storage.ErrRoleNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *models.Invitation
+			mockInvitationRepo := mocks.NewDefaultInvitationMock()
+			mockInvitationRepo.CreateInvitationFunc = func(ctx context.Context, invitation *models.Invitation) error {
+				stored = invitation
+				invitation.ID = 3
+				return tt.createErr
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("subject_type", middleware.SubjectTypeUser)
+			ctx.Set("user_id", uint(7))
+			ctx.Set("roles", tt.callerRoles)
+			testutils.SetJSONBody(ctx, tt.body)
+
+			newTestInvitationsHandler(mockInvitationRepo).CreateHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			token, _ := response["token"].(string)
+			require.NotEmpty(t, token)
+			assert.Equal(t, hashInvitationToken(token), stored.TokenHash, "only the token hash is stored")
+			assert.Equal(t, uint(7), stored.InvitedBy)
+			assert.Equal(t, "invitee@mail.com", response["email"])
+			assert.WithinDuration(t, time.Now().Add(tt.expectedTTL), stored.ExpiresAt, time.Minute)
+		})
+	}
+}
+
+func TestInvitationsListHandler(t *testing.T) {
+	mockInvitationRepo := mocks.NewDefaultInvitationMock()
+	mockInvitationRepo.ListInvitationsFunc = func(ctx context.Context) ([]models.Invitation, error) {
+		return []models.Invitation{{ID: 3, Email: "invitee@mail.com", TokenHash: "secret-hash"}}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+
+	newTestInvitationsHandler(mockInvitationRepo).ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"email":"invitee@mail.com"`)
+	assert.NotContains(t, recorder.Body.String(), "secret-hash")
+}
+
+func TestInvitationsRevokeHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		deleteErr      error
+		expectedStatus int
+	}{
+		{name: "Success", id: "3", expectedStatus: http.StatusOK},
+		{name: "Not Found", id: "3", deleteErr: storage.ErrInvitationNotFound, expectedStatus: http.StatusNotFound},
+		{name: "Invalid ID", id: "abc", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted uint
+			mockInvitationRepo := mocks.NewDefaultInvitationMock()
+			mockInvitationRepo.DeleteInvitationFunc = func(ctx context.Context, id uint) error {
+				deleted = id
+				return tt.deleteErr
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			newTestInvitationsHandler(mockInvitationRepo).RevokeHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.id == "3" {
+				assert.Equal(t, uint(3), deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"multitech/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+}
+
+func NewJWKSHandler() *JWKSHandler {
+	return &JWKSHandler{}
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, including verification-only keys kept during rotation. Shared secrets are never published
+// @Tags system
+// @Produce json
+// @Success 200 {object} middleware.JWKSet
+// @Failure 500 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func (*JWKSHandler) Handler(ctx *gin.Context) {
+	ring, err := middleware.LoadKeyring()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error loading signing keys",
+		})
+		return
+	}
+
+	set, err := ring.JWKSet()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error loading signing keys",
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, set)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "signing.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	tests := []struct {
+		name           string
+		envSetup       func(*mocks.EnvMock)
+		expectedStatus int
+		expectedKeys   int
+	}{
+		{
+			name: "Shared Secret",
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_SECRET", "testsecret")
+			},
+			expectedStatus: http.StatusOK,
+			expectedKeys:   0,
+		},
+		{
+			name: "Ed25519 Key",
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_PRIVATE_KEY_FILE", keyFile)
+				em.Set("JWT_KEY_ID", "ed-key")
+			},
+			expectedStatus: http.StatusOK,
+			expectedKeys:   1,
+		},
+		{
+			name: "Missing Key File",
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_PRIVATE_KEY_FILE", filepath.Join(t.TempDir(), "missing.pem"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			tt.envSetup(mockEnv)
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			ctx, recorder := testutils.NewTestContext()
+
+			handler := NewJWKSHandler()
+			handler.Handler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Keys []map[string]string `json:"keys"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Len(t, response.Keys, tt.expectedKeys)
+			if tt.expectedKeys > 0 {
+				assert.Equal(t, "ed-key", response.Keys[0]["kid"])
+				assert.Equal(t, "OKP", response.Keys[0]["kty"])
+				assert.Equal(t, "EdDSA", response.Keys[0]["alg"])
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLockoutThreshold   = 5
+	defaultIPLockoutThreshold = 50
+	defaultLoginFailureWindow = 15 * time.Minute
+	defaultLockoutDuration    = 15 * time.Minute
+	// freeLoginFailures are answered without delay, typos happen. Every
+	// further failure doubles the wait, starting at one second.
+	freeLoginFailures = 2
+	maxLoginDelay     = 30 * time.Second
+)
+
+// lockoutPolicy decides when failed logins slow down or lock a username and
+// when a client IP is blocked.
+type lockoutPolicy struct {
+	threshold   int
+	ipThreshold int
+	window      time.Duration
+	duration    time.Duration
+}
+
+func loadLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		threshold:   envInt("LOGIN_LOCKOUT_THRESHOLD", defaultLockoutThreshold),
+		ipThreshold: envInt("LOGIN_IP_LOCKOUT_THRESHOLD", defaultIPLockoutThreshold),
+		window:      envDuration("LOGIN_FAILURE_WINDOW", defaultLoginFailureWindow),
+		duration:    envDuration("LOGIN_LOCKOUT_DURATION", defaultLockoutDuration),
+	}
+}
+
+// delay is how long a username has to wait after its nth failure.
+func (policy lockoutPolicy) delay(failures int) time.Duration {
+	if failures < freeLoginFailures {
+		return 0
+	}
+	shift := failures - freeLoginFailures
+	if shift >= 5 {
+		return maxLoginDelay
+	}
+	return min(time.Second<<shift, maxLoginDelay)
+}
+
+type LockoutHandler struct {
+	attemptRepo storage.LoginAttemptRepository
+}
+
+func NewLockoutHandler(attemptRepo storage.LoginAttemptRepository) *LockoutHandler {
+	return &LockoutHandler{
+		attemptRepo: attemptRepo,
+	}
+}
+
+// @Summary Unlock login
+// @Description Lift the lockout and reset the failed login counter of a username, a client IP or both
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param unlock body models.UnlockRequest true "Username and/or client IP"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/unlock [post]
+func (lockout *LockoutHandler) UnlockHandler(ctx *gin.Context) {
+	var request models.UnlockRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if request.Username == "" && request.IP == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "Username or IP is required",
+		})
+		return
+	}
+
+	var keys []string
+	if request.Username != "" {
+		keys = append(keys, userLoginKey(request.Username))
+	}
+	if request.IP != "" {
+		keys = append(keys, ipLoginKey(request.IP))
+	}
+
+	for _, key := range keys {
+		if err := lockout.attemptRepo.UnblockLogin(ctx.Request.Context(), key); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error unlocking login: " + err.Error(),
+			})
+			return
+		}
+		if err := lockout.attemptRepo.ResetLoginFailures(ctx.Request.Context(), key); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error resetting failed logins: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Login unlocked",
+	})
+}
+
+// checkLoginBlocked answers the request if one of the keys is blocked.
+func checkLoginBlocked(ctx *gin.Context, attemptRepo storage.LoginAttemptRepository, keys ...string) bool {
+	for _, key := range keys {
+		reason, remaining, err := attemptRepo.GetLoginBlock(ctx.Request.Context(), key)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error checking failed logins: " + err.Error(),
+			})
+			return true
+		}
+		if remaining > 0 {
+			respondLoginBlocked(ctx, reason, remaining)
+			return true
+		}
+	}
+	return false
+}
+
+// recordLoginFailure counts a failed login for the username and the client
+// IP and blocks them as the policy demands. It reports whether the response
+// was written, which is the case once the username gets locked.
+func recordLoginFailure(ctx *gin.Context, attemptRepo storage.LoginAttemptRepository, username string) bool {
+	policy := loadLockoutPolicy()
+	userKey := userLoginKey(username)
+
+	ipFailures, err := attemptRepo.RecordLoginFailure(ctx.Request.Context(), ipLoginKey(ctx.ClientIP()), policy.window)
+	if err != nil {
+		log.Printf("Error recording failed login: %v", err)
+	} else if ipFailures >= policy.ipThreshold {
+		if err := attemptRepo.BlockLogin(ctx.Request.Context(), ipLoginKey(ctx.ClientIP()), storage.LoginBlockThrottled, policy.duration); err != nil {
+			log.Printf("Error blocking client IP: %v", err)
+		}
+	}
+
+	failures, err := attemptRepo.RecordLoginFailure(ctx.Request.Context(), userKey, policy.window)
+	if err != nil {
+		log.Printf("Error recording failed login: %v", err)
+		return false
+	}
+
+	if failures >= policy.threshold {
+		if err := attemptRepo.BlockLogin(ctx.Request.Context(), userKey, storage.LoginBlockLocked, policy.duration); err != nil {
+			log.Printf("Error locking username: %v", err)
+			return false
+		}
+		// The lockout replaces the counter, so that every lockout is followed
+		// by a full set of attempts.
+		if err := attemptRepo.ResetLoginFailures(ctx.Request.Context(), userKey); err != nil {
+			log.Printf("Error resetting failed logins: %v", err)
+		}
+		respondLoginBlocked(ctx, storage.LoginBlockLocked, policy.duration)
+		return true
+	}
+
+	if delay := policy.delay(failures); delay > 0 {
+		if err := attemptRepo.BlockLogin(ctx.Request.Context(), userKey, storage.LoginBlockThrottled, delay); err != nil {
+			log.Printf("Error delaying username: %v", err)
+		}
+	}
+	return false
+}
+
+// resetLoginFailures clears the counter of the username once a login is
+// complete, including its second factor. The client IP keeps counting, so
+// that one valid account does not renew the budget for guessing others.
+func resetLoginFailures(ctx *gin.Context, attemptRepo storage.LoginAttemptRepository, username string) {
+	if err := attemptRepo.ResetLoginFailures(ctx.Request.Context(), userLoginKey(username)); err != nil {
+		log.Printf("Error resetting failed logins: %v", err)
+	}
+}
+
+func respondLoginBlocked(ctx *gin.Context, reason string, remaining time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	if reason == storage.LoginBlockLocked {
+		ctx.JSON(http.StatusLocked, gin.H{
+			"error": "Account temporarily locked after too many failed logins",
+		})
+		return
+	}
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error": "Too many failed logins, try again later",
+	})
+}
+
+func userLoginKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
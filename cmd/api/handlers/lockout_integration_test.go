@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLockoutLifecycle(t *testing.T) {
+	bg := context.Background()
+	os.Setenv("JWT_SECRET", "testsecret")
+	os.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("LOGIN_LOCKOUT_THRESHOLD")
+
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) {
+		return &models.User{ID: 91, Username: "lockme", Password: testPasswordHash}, nil
+	}
+
+	attemptRepo := storage.NewRedisLoginAttemptRepository(testutils.TestRedis)
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, 91)
+	defer sessRepo.DeleteUserSessions(bg, 91)
+	for _, key := range []string{"user:lockme", "ip:198.51.100.4"} {
+		defer attemptRepo.ResetLoginFailures(bg, key)
+		defer attemptRepo.UnblockLogin(bg, key)
+	}
+	handler := NewLoginHandler(mockUserRepo, sessRepo, refreshRepo, storage.NewRedisMFAChallengeRepository(testutils.TestRedis), attemptRepo)
+
+	login := func(password string) (int, string) {
+		ctx, recorder := testutils.NewTestContext()
+		ctx.Request.RemoteAddr = "198.51.100.4:50000"
+		testutils.SetJSONBody(ctx, `{"username":"lockme","password":"`+password+`"}`)
+		handler.Handler(ctx)
+		return recorder.Code, recorder.Header().Get("Retry-After")
+	}
+
+	status, _ := login("wrongpass")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// The second failure earns a delay, which is enforced even for the right password.
+	status, _ = login("wrongpass")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, retryAfter := login("testpass")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, "1", retryAfter)
+
+	// Skip the delay and reach the threshold.
+	require.NoError(t, attemptRepo.UnblockLogin(bg, "user:lockme"))
+	status, retryAfter = login("wrongpass")
+	assert.Equal(t, http.StatusLocked, status)
+	assert.Equal(t, "900", retryAfter)
+
+	status, _ = login("testpass")
+	assert.Equal(t, http.StatusLocked, status)
+
+	// An administrator lifts the lockout, after which the right password works
+	// and the counters start from zero.
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"username":"LockMe"}`)
+	NewLockoutHandler(attemptRepo).UnlockHandler(ctx)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	status, _ = login("testpass")
+	assert.Equal(t, http.StatusOK, status)
+
+	failures, err := attemptRepo.RecordLoginFailure(bg, "user:lockme", defaultLoginFailureWindow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+
+	// The successful login leaves the failures of the client IP in place.
+	failures, err = attemptRepo.RecordLoginFailure(bg, "ip:198.51.100.4", defaultLoginFailureWindow)
+	require.NoError(t, err)
+	assert.Equal(t, 4, failures)
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPasswordHash = "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm"
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	policy := loadLockoutPolicy()
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{failures: 1, delay: 0},
+		{failures: 2, delay: time.Second},
+		{failures: 3, delay: 2 * time.Second},
+		{failures: 5, delay: 8 * time.Second},
+		{failures: 6, delay: 16 * time.Second},
+		{failures: 7, delay: maxLoginDelay},
+		{failures: 90, delay: maxLoginDelay},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, policy.delay(tt.failures), "failures: %d", tt.failures)
+	}
+}
+
+func TestLoginHandlerLockout(t *testing.T) {
+	tests := []struct {
+		name             string
+		password         string
+		mockAttemptSetup func(*mocks.MockLoginAttemptRepository)
+		expectedStatus   int
+		expectedBody     string
+		expectRetryAfter string
+		expectBlock      string
+	}{
+		{
+			name:     "Username Locked",
+			password: "testpass",
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.GetLoginBlockFunc = func(ctx context.Context, key string) (string, time.Duration, error) {
+					if key == "user:testuser" {
+						return storage.LoginBlockLocked, 90 * time.Second, nil
+					}
+					return "", 0, nil
+				}
+			},
+			expectedStatus:   http.StatusLocked,
+			expectedBody:     `{"error":"Account temporarily locked after too many failed logins"}`,
+			expectRetryAfter: "90",
+		},
+		{
+			name:     "Client IP Throttled",
+			password: "testpass",
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.GetLoginBlockFunc = func(ctx context.Context, key string) (string, time.Duration, error) {
+					if key == "ip:203.0.113.7" {
+						return storage.LoginBlockThrottled, 1500 * time.Millisecond, nil
+					}
+					return "", 0, nil
+				}
+			},
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedBody:     `{"error":"Too many failed logins, try again later"}`,
+			expectRetryAfter: "2",
+		},
+		{
+			name:     "Failure Below Threshold",
+			password: "wrongpass",
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+					return 3, nil
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid credentials"}`,
+			expectBlock:    storage.LoginBlockThrottled,
+		},
+		{
+			name:     "Failure Reaching Threshold",
+			password: "wrongpass",
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+					return defaultLockoutThreshold, nil
+				}
+			},
+			expectedStatus:   http.StatusLocked,
+			expectedBody:     `{"error":"Account temporarily locked after too many failed logins"}`,
+			expectRetryAfter: "900",
+			expectBlock:      storage.LoginBlockLocked,
+		},
+		{
+			name:     "Counter Unavailable",
+			password: "wrongpass",
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+					return 0, errors.New("redis error")
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid credentials"}`,
+		},
+		{
+			name:     "Block Check Error",
+			password: "testpass",
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.GetLoginBlockFunc = func(ctx context.Context, key string) (string, time.Duration, error) {
+					return "", 0, errors.New("redis error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+				return &models.User{ID: 1, Username: identifier, Password: testPasswordHash}, nil
+			}
+			var blockedUser string
+			mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+			mockAttemptRepo.BlockLoginFunc = func(ctx context.Context, key string, reason string, duration time.Duration) error {
+				if key == "user:testuser" {
+					blockedUser = reason
+				}
+				return nil
+			}
+			tt.mockAttemptSetup(mockAttemptRepo)
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request.RemoteAddr = "203.0.113.7:40000"
+			testutils.SetJSONBody(ctx, `{"username":"TestUser","password":"`+tt.password+`"}`)
+
+			handler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mockAttemptRepo)
+			handler.Handler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+			assert.Equal(t, tt.expectRetryAfter, recorder.Header().Get("Retry-After"))
+			assert.Equal(t, tt.expectBlock, blockedUser)
+		})
+	}
+}
+
+func TestLoginHandlerIgnoresSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name         string
+		proxies      string
+		expectedKeys []string
+	}{
+		{
+			name:         "No Trusted Proxies",
+			expectedKeys: []string{"ip:203.0.113.7", "user:testuser"},
+		},
+		{
+			name:         "Trusted Proxy",
+			proxies:      "203.0.113.7",
+			expectedKeys: []string{"ip:198.51.100.4", "user:testuser"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+				return &models.User{ID: 1, Username: identifier, Password: testPasswordHash}, nil
+			}
+			var checked, recorded []string
+			mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+			mockAttemptRepo.GetLoginBlockFunc = func(ctx context.Context, key string) (string, time.Duration, error) {
+				checked = append(checked, key)
+				return "", 0, nil
+			}
+			mockAttemptRepo.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+				recorded = append(recorded, key)
+				return 1, nil
+			}
+
+			recorder := httptest.NewRecorder()
+			ctx, engine := gin.CreateTestContext(recorder)
+			assert.NoError(t, engine.SetTrustedProxies(middleware.TrustedProxiesFromEnv()))
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+			ctx.Request.RemoteAddr = "203.0.113.7:40000"
+			ctx.Request.Header.Set("X-Forwarded-For", "198.51.100.4")
+			testutils.SetJSONBody(ctx, `{"username":"testuser","password":"wrongpass"}`)
+
+			handler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mockAttemptRepo)
+			handler.Handler(ctx)
+
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.Contains(t, checked, tt.expectedKeys[0])
+			assert.Equal(t, tt.expectedKeys, recorded)
+		})
+	}
+}
+
+func TestLoginHandlerResetsFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		totpEnabled   bool
+		expectedReset []string
+	}{
+		{
+			name:          "Password Only",
+			expectedReset: []string{"user:testuser"},
+		},
+		{
+			name:        "Second Factor Pending",
+			totpEnabled: true,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+				return &models.User{ID: 1, Username: identifier, Password: testPasswordHash, TOTPEnabled: tt.totpEnabled}, nil
+			}
+			var reset []string
+			mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+			mockAttemptRepo.ResetLoginFailuresFunc = func(ctx context.Context, key string) error {
+				reset = append(reset, key)
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request.RemoteAddr = "203.0.113.7:40000"
+			testutils.SetJSONBody(ctx, `{"username":"testuser","password":"testpass"}`)
+
+			handler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mockAttemptRepo)
+			handler.Handler(ctx)
+
+			// The client IP keeps its failures, so one valid account does not
+			// renew the budget for guessing others.
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tt.expectedReset, reset)
+		})
+	}
+}
+
+func TestUnlockHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		unblockErr     error
+		expectedStatus int
+		expectedKeys   []string
+	}{
+		{
+			name:           "Username",
+			body:           `{"username":"TestUser"}`,
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"user:testuser"},
+		},
+		{
+			name:           "Username And IP",
+			body:           `{"username":"testuser","ip":"203.0.113.7"}`,
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"user:testuser", "ip:203.0.113.7"},
+		},
+		{
+			name:           "Nothing To Unlock",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid IP",
+			body:           `{"ip":"not-an-ip"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Storage Error",
+			body:           `{"username":"testuser"}`,
+			unblockErr:     errors.New("redis error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unblocked, reset []string
+			mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+			mockAttemptRepo.UnblockLoginFunc = func(ctx context.Context, key string) error {
+				if tt.unblockErr != nil {
+					return tt.unblockErr
+				}
+				unblocked = append(unblocked, key)
+				return nil
+			}
+			mockAttemptRepo.ResetLoginFailuresFunc = func(ctx context.Context, key string) error {
+				reset = append(reset, key)
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewLockoutHandler(mockAttemptRepo)
+			handler.UnlockHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedKeys, unblocked)
+			assert.Equal(t, tt.expectedKeys, reset)
+		})
+	}
+}
@@ -1,30 +1,45 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"log"
 	"multitech/internal/models"
 	"multitech/middleware"
+	"multitech/pkg/hasher"
 	"multitech/pkg/storage"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const mfaChallengeTTL = 5 * time.Minute
+
 type LoginHandler struct {
-	userRepo storage.UserRepository
-	sessRepo storage.SessionsRepository
+	userRepo      storage.UserRepository
+	sessRepo      storage.SessionsRepository
+	refreshRepo   storage.RefreshTokenRepository
+	challengeRepo storage.MFAChallengeRepository
+	attemptRepo   storage.LoginAttemptRepository
 }
 
-func NewLoginHandler(userRepo storage.UserRepository, sessRepo storage.SessionsRepository) *LoginHandler {
+func NewLoginHandler(userRepo storage.UserRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, challengeRepo storage.MFAChallengeRepository, attemptRepo storage.LoginAttemptRepository) *LoginHandler {
 	return &LoginHandler{
-		userRepo: userRepo,
-		sessRepo: sessRepo,
+		userRepo:      userRepo,
+		sessRepo:      sessRepo,
+		refreshRepo:   refreshRepo,
+		challengeRepo: challengeRepo,
+		attemptRepo:   attemptRepo,
 	}
 }
 
 // @Summary User login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return a short-lived JWT access token with a refresh token.
+// @Description The identifier is the username or the email address, in any case; username is still accepted in its place.
+// @Description Users with TOTP enabled receive an mfa_token instead, to be exchanged at /login/mfa.
+// @Description Repeated failures slow down (429) and then temporarily lock (423) the username; both carry Retry-After
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -32,6 +47,9 @@ func NewLoginHandler(userRepo storage.UserRepository, sessRepo storage.SessionsR
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /login [post]
 func (login *LoginHandler) Handler(ctx *gin.Context) {
@@ -43,9 +61,30 @@ func (login *LoginHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
-	user, err := login.userRepo.GetUserByUsername(ctx.Request.Context(), creds.Username)
+	identifier := strings.TrimSpace(creds.LoginIdentifier())
+	if identifier == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "Identifier is required",
+		})
+		return
+	}
+
+	// Blocks are checked before the lookup, so unknown identifiers lock the same way.
+	if checkLoginBlocked(ctx, login.attemptRepo, ipLoginKey(ctx.ClientIP()), userLoginKey(identifier)) {
+		return
+	}
+
+	user, err := login.userRepo.GetUserByIdentifier(ctx.Request.Context(), identifier)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
+			// Spend the time of a password check, so that response times do
+			// not tell which identifiers exist.
+			if _, err := hasher.Default().Hash(creds.Password); err != nil {
+				log.Printf("Error hashing password of unknown user: %v", err)
+			}
+			if recordLoginFailure(ctx, login.attemptRepo, identifier) {
+				return
+			}
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"error": storage.ErrUserNotFound.Error(),
 			})
@@ -57,22 +96,75 @@ func (login *LoginHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
+	// Failures are counted against the username however the user logged in,
+	// so that email and username logins share one budget. The key is checked
+	// for every login, not only email logins, to keep the timing the same.
+	if checkLoginBlocked(ctx, login.attemptRepo, userLoginKey(user.Username)) {
+		return
+	}
+
 	if err := user.CheckPassword(creds.Password); err != nil {
+		if recordLoginFailure(ctx, login.attemptRepo, user.Username) {
+			return
+		}
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Invalid credentials",
 		})
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID)
+	if user.PasswordNeedsRehash() {
+		upgradePasswordHash(ctx.Request.Context(), login.userRepo, user, creds.Password)
+	}
+
+	if emailVerificationRequired() && user.EmailVerifiedAt == nil {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": "Email address not verified",
+		})
+		return
+	}
+
+	// The failures of TOTP users are reset by /login/mfa, once the second
+	// factor is verified too.
+	if user.TOTPEnabled {
+		startMFAChallenge(ctx, login.challengeRepo, user)
+		return
+	}
+
+	resetLoginFailures(ctx, login.attemptRepo, user.Username)
+	respondWithTokens(ctx, login.sessRepo, login.refreshRepo, user)
+}
+
+// startMFAChallenge answers a verified first factor of a TOTP user with a
+// short-lived challenge token instead of a session.
+func startMFAChallenge(ctx *gin.Context, challengeRepo storage.MFAChallengeRepository, user *models.User) {
+	mfaToken, err := middleware.GenerateOpaqueToken()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error generating token",
+			"error": "Error creating MFA challenge",
 		})
 		return
 	}
 
-	if err := login.sessRepo.StoreSession(ctx.Request.Context(), token, user.ID, 24*time.Hour); err != nil {
+	if err := challengeRepo.CreateMFAChallenge(ctx.Request.Context(), mfaToken, &models.MFAChallenge{UserID: user.ID}, mfaChallengeTTL); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating MFA challenge: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+		"expires_in":   int(mfaChallengeTTL.Seconds()),
+	})
+}
+
+// respondWithTokens opens a session for a fully authenticated user and
+// writes the login response.
+func respondWithTokens(ctx *gin.Context, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, user *models.User) {
+	tokens, err := issueTokens(ctx, sessRepo, refreshRepo, user)
+	if err != nil {
 		if errors.Is(err, storage.ErrSessionExists) {
 			ctx.JSON(http.StatusConflict, gin.H{
 				"error": storage.ErrSessionExists.Error(),
@@ -86,7 +178,9 @@ func (login *LoginHandler) Handler(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"token": token,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    int(middleware.AccessTokenTTL.Seconds()),
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
@@ -94,3 +188,19 @@ func (login *LoginHandler) Handler(ctx *gin.Context) {
 		},
 	})
 }
+
+// upgradePasswordHash stores a hash of the current algorithm and parameters
+// while the plain text password is at hand. Failures only postpone the
+// upgrade to the next login.
+func upgradePasswordHash(ctx context.Context, userRepo storage.UserRepository, user *models.User, password string) {
+	hashedPassword, err := hasher.Default().Hash(password)
+	if err != nil {
+		log.Printf("Error rehashing password of user %d: %v", user.ID, err)
+		return
+	}
+	if err := userRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		log.Printf("Error storing rehashed password of user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"multitech/internal/models"
 	"multitech/pkg/storage"
@@ -25,6 +26,7 @@ func TestLoginHandlerSuccess(t *testing.T) {
 
 	userRepo := storage.NewGormUserRepository(tx)
 	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
 
 	ctx, recorder := testutils.NewTestContext()
 	testutils.SetJSONBody(ctx, `{"username":"testuser","password":"testpass"}`)
@@ -32,7 +34,7 @@ func TestLoginHandlerSuccess(t *testing.T) {
 	os.Setenv("JWT_SECRET", "testsecret")
 	defer os.Unsetenv("JWT_SECRET")
 
-	handler := NewLoginHandler(userRepo, sessRepo)
+	handler := NewLoginHandler(userRepo, sessRepo, refreshRepo, storage.NewRedisMFAChallengeRepository(testutils.TestRedis), storage.NewRedisLoginAttemptRepository(testutils.TestRedis))
 	handler.Handler(ctx)
 
 	assert.Equal(t, http.StatusOK, recorder.Code)
@@ -40,12 +42,13 @@ func TestLoginHandlerSuccess(t *testing.T) {
 	var response map[string]interface{}
 	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
 	assert.NotEmpty(t, response["token"])
+	assert.NotEmpty(t, response["refresh_token"])
 	assert.NotEmpty(t, response["user"])
 
 	token := response["token"].(string)
-	userID, err := sessRepo.GetSession(ctx, token)
+	session, err := sessRepo.GetSession(ctx, token)
 	assert.NoError(t, err)
-	assert.Equal(t, user.ID, userID)
+	assert.Equal(t, user.ID, session.UserID)
 }
 
 func TestLoginHandlerInvalidPassword(t *testing.T) {
@@ -61,6 +64,7 @@ func TestLoginHandlerInvalidPassword(t *testing.T) {
 
 	userRepo := storage.NewGormUserRepository(tx)
 	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
 
 	ctx, recoder := testutils.NewTestContext()
 	testutils.SetJSONBody(ctx, `{"username":"testuser","password":"wrongpass"}`)
@@ -68,7 +72,7 @@ func TestLoginHandlerInvalidPassword(t *testing.T) {
 	os.Setenv("JWT_SECRET", "testsecret")
 	defer os.Unsetenv("JWT_SECRET")
 
-	handler := NewLoginHandler(userRepo, sessRepo)
+	handler := NewLoginHandler(userRepo, sessRepo, refreshRepo, storage.NewRedisMFAChallengeRepository(testutils.TestRedis), storage.NewRedisLoginAttemptRepository(testutils.TestRedis))
 	handler.Handler(ctx)
 
 	assert.Equal(t, http.StatusUnauthorized, recoder.Code)
@@ -81,13 +85,62 @@ func TestLoginHandlerUserNotFound(t *testing.T) {
 
 	userRepo := storage.NewGormUserRepository(tx)
 	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
 
 	ctx, recorder := testutils.NewTestContext()
 	testutils.SetJSONBody(ctx, `{"username":"nonexistent","password":"testpass"}`)
 
-	handler := NewLoginHandler(userRepo, sessRepo)
+	handler := NewLoginHandler(userRepo, sessRepo, refreshRepo, storage.NewRedisMFAChallengeRepository(testutils.TestRedis), storage.NewRedisLoginAttemptRepository(testutils.TestRedis))
 	handler.Handler(ctx)
 
 	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
 	assert.JSONEq(t, `{"error":"User not found"}`, recorder.Body.String())
 }
+
+func TestLoginHandlerIdentifierLookup(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+
+	user := &models.User{
+		Username: "IdentifierUser",
+		Email:    "Identifier@Example.com",
+		Password: "testpass",
+	}
+	user.HashPassword()
+	assert.NoError(t, tx.Create(user).Error)
+
+	userRepo := storage.NewGormUserRepository(tx)
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	for _, identifier := range []string{"identifieruser", "IDENTIFIERUSER", "identifier@example.com", "Identifier@Example.com"} {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"identifier":"`+identifier+`","password":"testpass"}`)
+
+		handler := NewLoginHandler(userRepo, sessRepo, refreshRepo, storage.NewRedisMFAChallengeRepository(testutils.TestRedis), storage.NewRedisLoginAttemptRepository(testutils.TestRedis))
+		handler.Handler(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, identifier)
+	}
+
+	found, err := userRepo.GetUserByIdentifier(context.Background(), "nobody@example.com")
+	assert.Nil(t, found)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+}
+
+func TestGetUserByIdentifierPrefersUsername(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+
+	byEmail := &models.User{Username: "emailowner", Email: "shared@example.com", Password: "x"}
+	byUsername := &models.User{Username: "Shared@Example.com", Email: "other@example.com", Password: "x"}
+	assert.NoError(t, tx.Create(byEmail).Error)
+	assert.NoError(t, tx.Create(byUsername).Error)
+
+	found, err := storage.NewGormUserRepository(tx).GetUserByIdentifier(context.Background(), "shared@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, byUsername.ID, found.ID)
+}
@@ -5,14 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"multitech/internal/models"
+	"multitech/pkg/hasher"
 	"multitech/pkg/storage"
 	"multitech/pkg/testutils"
 	"multitech/pkg/testutils/mocks"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoginHandler(t *testing.T) {
@@ -27,12 +30,12 @@ func TestLoginHandler(t *testing.T) {
 	}{
 		{
 			name:        "Success",
-			requestBody: `{"username": "testuser", "password": "testpass"}`,
+			requestBody: `{"identifier": "testuser", "password": "testpass"}`,
 			mockUserSetup: func(mur *mocks.MockUserRepository) {
-				mur.GetUserByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) {
+				mur.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
 					return &models.User{
 						ID:       1,
-						Username: username,
+						Username: identifier,
 						Password: "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm",
 					}, nil
 				}
@@ -41,16 +44,58 @@ func TestLoginHandler(t *testing.T) {
 				em.Set("JWT_SECRET", "testsecret")
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"token":"*", "user":{"id":1,"username":"testuser","email":""}}`,
+			expectedBody:   `{"token":"*", "refresh_token":"*", "expires_in":900, "user":{"id":1,"username":"testuser","email":""}}`,
+		},
+		{
+			name:        "TOTP Enabled",
+			requestBody: `{"identifier": "testuser", "password": "testpass"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+					return &models.User{
+						ID:          1,
+						Username:    identifier,
+						Password:    "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm",
+						TOTPSecret:  "JBSWY3DPEHPK3PXP",
+						TOTPEnabled: true,
+					}, nil
+				}
+			},
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.CreateSessionFunc = func(ctx context.Context, session *models.Session) error {
+					t.Error("session must not be created before the second factor")
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"mfa_required":true, "mfa_token":"*", "expires_in":300}`,
+		},
+		{
+			name:        "Email Not Verified",
+			requestBody: `{"identifier": "testuser", "password": "testpass"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+					return &models.User{
+						ID:       1,
+						Username: identifier,
+						Password: "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm",
+					}, nil
+				}
+			},
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_SECRET", "testsecret")
+				em.Set("EMAIL_VERIFICATION_REQUIRED", "true")
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error": "Email address not verified"}`,
 		},
 		{
 			name:        "Invalid Password",
-			requestBody: `{"username": "testuser", "password": "wrongpass"}`,
+			requestBody: `{"identifier": "testuser", "password": "wrongpass"}`,
 			mockUserSetup: func(mur *mocks.MockUserRepository) {
-				mur.GetUserByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) {
+				mur.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
 					return &models.User{
 						ID:       1,
-						Username: username,
+						Username: identifier,
 						Password: "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm",
 					}, nil
 				}
@@ -63,9 +108,9 @@ func TestLoginHandler(t *testing.T) {
 		},
 		{
 			name:        "User Not Found",
-			requestBody: `{"username": "nonexistent", "password": "testpass"}`,
+			requestBody: `{"identifier": "nonexistent", "password": "testpass"}`,
 			mockUserSetup: func(mur *mocks.MockUserRepository) {
-				mur.GetUserByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) {
+				mur.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
 					return nil, storage.ErrUserNotFound
 				}
 			},
@@ -80,6 +125,7 @@ func TestLoginHandler(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockUserRepo := mocks.NewDefaultUserMock()
 			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
 			mockEnv := mocks.NewEnvMock()
 
 			if tt.mockUserSetup != nil {
@@ -97,7 +143,7 @@ func TestLoginHandler(t *testing.T) {
 			ctx, recorder := testutils.NewTestContext()
 			testutils.SetJSONBody(ctx, tt.requestBody)
 
-			loginHandler := NewLoginHandler(mockUserRepo, mockSessRepo)
+			loginHandler := NewLoginHandler(mockUserRepo, mockSessRepo, mockRefreshRepo, mocks.NewDefaultMFAChallengeMock(), mocks.NewDefaultLoginAttemptMock())
 			loginHandler.Handler(ctx)
 
 			assert.Equal(t, tt.expectedStatus, recorder.Code)
@@ -124,3 +170,214 @@ func TestLoginHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestLoginHandlerRehash(t *testing.T) {
+	currentHash, err := hasher.Default().Hash("testpass")
+	require.NoError(t, err)
+	pbkdf2Hash, err := hasher.NewPBKDF2("sha256", 10).Hash("testpass")
+	require.NoError(t, err)
+	saltedSHA256Hash, err := hasher.NewSaltedSHA256(false).Hash("testpass")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		storedHash   string
+		expectRehash bool
+	}{
+		{name: "Legacy Bcrypt Hash", storedHash: "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm", expectRehash: true},
+		{name: "Imported PBKDF2 Hash", storedHash: pbkdf2Hash, expectRehash: true},
+		{name: "Imported Salted SHA-256 Hash", storedHash: saltedSHA256Hash, expectRehash: true},
+		{name: "Current Hash", storedHash: currentHash},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rehashed string
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+				return &models.User{ID: 1, Username: identifier, Password: tt.storedHash}, nil
+			}
+			mockUserRepo.UpdatePasswordFunc = func(ctx context.Context, id uint, passwordHash string) error {
+				rehashed = passwordHash
+				return nil
+			}
+			mockEnv := mocks.NewEnvMock()
+			mockEnv.Set("JWT_SECRET", "testsecret")
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, `{"identifier":"testuser","password":"testpass"}`)
+
+			loginHandler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mocks.NewDefaultLoginAttemptMock())
+			loginHandler.Handler(ctx)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			if !tt.expectRehash {
+				assert.Empty(t, rehashed)
+				return
+			}
+			assert.True(t, strings.HasPrefix(rehashed, "$argon2id$"))
+			assert.False(t, hasher.Default().NeedsRehash(rehashed))
+			assert.NoError(t, hasher.Default().Verify(rehashed, "testpass"))
+		})
+	}
+}
+
+func TestLoginHandlerIdentifier(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedLookup   string
+		expectedResetKey string
+	}{
+		{
+			name:             "Email Address",
+			body:             `{"identifier":" Test@Example.com ","password":"testpass"}`,
+			expectedStatus:   http.StatusOK,
+			expectedLookup:   "Test@Example.com",
+			expectedResetKey: "user:testuser",
+		},
+		{
+			name:             "Username",
+			body:             `{"identifier":"TestUser","password":"testpass"}`,
+			expectedStatus:   http.StatusOK,
+			expectedLookup:   "TestUser",
+			expectedResetKey: "user:testuser",
+		},
+		{
+			name:             "Deprecated Username Field",
+			body:             `{"username":"testuser","password":"testpass"}`,
+			expectedStatus:   http.StatusOK,
+			expectedLookup:   "testuser",
+			expectedResetKey: "user:testuser",
+		},
+		{
+			name:           "Missing Identifier",
+			body:           `{"identifier":"  ","password":"testpass"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookup string
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+				lookup = identifier
+				return &models.User{ID: 1, Username: "testuser", Email: "test@example.com", Password: testPasswordHash}, nil
+			}
+			var reset []string
+			mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+			mockAttemptRepo.ResetLoginFailuresFunc = func(ctx context.Context, key string) error {
+				reset = append(reset, key)
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request.RemoteAddr = "203.0.113.7:40000"
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mockAttemptRepo)
+			handler.Handler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedLookup, lookup)
+			if tt.expectedResetKey != "" {
+				assert.Contains(t, reset, tt.expectedResetKey)
+			}
+		})
+	}
+}
+
+func TestLoginHandlerEmailSharesUsernameLockout(t *testing.T) {
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+		return &models.User{ID: 1, Username: "testuser", Email: "test@example.com", Password: testPasswordHash}, nil
+	}
+	var checked, recorded []string
+	mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+	mockAttemptRepo.GetLoginBlockFunc = func(ctx context.Context, key string) (string, time.Duration, error) {
+		checked = append(checked, key)
+		return "", 0, nil
+	}
+	mockAttemptRepo.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+		recorded = append(recorded, key)
+		return 1, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Request.RemoteAddr = "203.0.113.7:40000"
+	testutils.SetJSONBody(ctx, `{"identifier":"test@example.com","password":"wrongpass"}`)
+
+	handler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mockAttemptRepo)
+	handler.Handler(ctx)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, []string{"ip:203.0.113.7", "user:test@example.com", "user:testuser"}, checked)
+	assert.ElementsMatch(t, []string{"ip:203.0.113.7", "user:testuser"}, recorded)
+}
+
+// countingAlgorithm stands in for a real algorithm and counts how often it
+// hashes and verifies.
+type countingAlgorithm struct {
+	hashes   int
+	verifies int
+}
+
+func (algorithm *countingAlgorithm) Hash(password string) (string, error) {
+	algorithm.hashes++
+	return "$counting$" + password, nil
+}
+
+func (algorithm *countingAlgorithm) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$counting$")
+}
+
+func (algorithm *countingAlgorithm) Verify(encoded string, password string) error {
+	algorithm.verifies++
+	if encoded != "$counting$"+password {
+		return hasher.ErrMismatchedPassword
+	}
+	return nil
+}
+
+func (algorithm *countingAlgorithm) Outdated(encoded string) bool {
+	return false
+}
+
+func TestLoginHandlerUnknownIdentifierTiming(t *testing.T) {
+	algorithm := &countingAlgorithm{}
+	previous := hasher.Default()
+	hasher.SetDefault(hasher.New(algorithm))
+	defer hasher.SetDefault(previous)
+
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIdentifierFunc = func(ctx context.Context, identifier string) (*models.User, error) {
+		if identifier == "testuser" {
+			return &models.User{ID: 1, Username: "testuser", Password: "$counting$testpass"}, nil
+		}
+		return nil, storage.ErrUserNotFound
+	}
+
+	for _, identifier := range []string{"testuser", "nobody@example.com"} {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"identifier":"`+identifier+`","password":"wrongpass"}`)
+
+		handler := NewLoginHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mocks.NewDefaultLoginAttemptMock())
+		handler.Handler(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	}
+	assert.Equal(t, 1, algorithm.verifies, "the known user's password is checked")
+	assert.Equal(t, 1, algorithm.hashes, "the unknown user's password costs as much")
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"multitech/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LogoutHandler struct {
+	sessRepo    storage.SessionsRepository
+	refreshRepo storage.RefreshTokenRepository
+}
+
+func NewLogoutHandler(sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository) *LogoutHandler {
+	return &LogoutHandler{
+		sessRepo:    sessRepo,
+		refreshRepo: refreshRepo,
+	}
+}
+
+// @Summary Logout
+// @Description Revoke the current session together with its refresh tokens
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /logout [post]
+func (logout *LogoutHandler) Handler(ctx *gin.Context) {
+	sessionID := ctx.GetString("session_id")
+	if err := logout.sessRepo.DeleteSession(ctx.Request.Context(), sessionID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting session: " + err.Error(),
+		})
+		return
+	}
+
+	if err := logout.refreshRepo.RevokeFamily(ctx.Request.Context(), sessionID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking refresh tokens: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// @Summary Logout everywhere
+// @Description Revoke every session and refresh token of the current user. Organization tokens only revoke the sessions in their organization
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /logout/all [post]
+func (logout *LogoutHandler) AllHandler(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+	if _, ok := storage.OrganizationFromContext(ctx.Request.Context()); ok {
+		logout.organizationSessionsHandler(ctx, userID)
+		return
+	}
+
+	if err := logout.sessRepo.DeleteUserSessions(ctx.Request.Context(), userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting sessions: " + err.Error(),
+		})
+		return
+	}
+
+	if err := logout.refreshRepo.RevokeUserRefreshTokens(ctx.Request.Context(), userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking refresh tokens: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out from all sessions",
+	})
+}
+
+// organizationSessionsHandler ends the sessions of the organization the
+// token is scoped to. The refresh families are revoked one by one, since
+// the user's other sessions keep theirs.
+func (logout *LogoutHandler) organizationSessionsHandler(ctx *gin.Context, userID uint) {
+	userSessions, err := logout.sessRepo.ListUserSessions(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving sessions: " + err.Error(),
+		})
+		return
+	}
+
+	for _, session := range userSessions {
+		if err := logout.sessRepo.DeleteSession(ctx.Request.Context(), session.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error deleting sessions: " + err.Error(),
+			})
+			return
+		}
+		if err := logout.refreshRepo.RevokeFamily(ctx.Request.Context(), session.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error revoking refresh tokens: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out from all sessions",
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestSession(t *testing.T, sessRepo storage.SessionsRepository, sessionID string, userID uint, token string) {
+	now := time.Now()
+	assert.NoError(t, sessRepo.CreateSession(context.Background(), &models.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(time.Minute),
+	}))
+	assert.NoError(t, sessRepo.StoreSession(context.Background(), token, sessionID, time.Minute))
+}
+
+func TestLogoutHandlerDeletesCurrentSession(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+
+	createTestSession(t, sessRepo, "logout-current", 42, "logout-current-token")
+	createTestSession(t, sessRepo, "logout-other", 42, "logout-other-token")
+	defer sessRepo.DeleteUserSessions(bg, 42)
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(42))
+	ctx.Set("token", "logout-current-token")
+	ctx.Set("session_id", "logout-current")
+
+	handler := NewLogoutHandler(sessRepo, storage.NewRedisRefreshTokenRepository(testutils.TestRedis))
+	handler.Handler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	_, err := sessRepo.GetSession(bg, "logout-current-token")
+	assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+
+	session, err := sessRepo.GetSession(bg, "logout-other-token")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), session.UserID)
+}
+
+func TestLogoutAllHandlerDeletesEverySession(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+
+	createTestSession(t, sessRepo, "logout-all-1", 43, "logout-all-1-token")
+	createTestSession(t, sessRepo, "logout-all-2", 43, "logout-all-2-token")
+	createTestSession(t, sessRepo, "logout-all-foreign", 44, "logout-all-foreign-token")
+	defer sessRepo.DeleteUserSessions(bg, 44)
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(43))
+	ctx.Set("token", "logout-all-1-token")
+	ctx.Set("session_id", "logout-all-1")
+
+	handler := NewLogoutHandler(sessRepo, storage.NewRedisRefreshTokenRepository(testutils.TestRedis))
+	handler.AllHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	for _, token := range []string{"logout-all-1-token", "logout-all-2-token"} {
+		_, err := sessRepo.GetSession(bg, token)
+		assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+	}
+
+	session, err := sessRepo.GetSession(bg, "logout-all-foreign-token")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(44), session.UserID)
+}
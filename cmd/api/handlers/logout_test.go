@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		mockSessSetup    func(*mocks.MockSessionsRepository)
+		mockRefreshSetup func(*mocks.MockRefreshTokenRepository)
+		expectedStatus   int
+		expectedBody     string
+	}{
+		{
+			name: "Success",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+					if sessionID != "current-session" {
+						return errors.New("unexpected session")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Logged out successfully"}`,
+		},
+		{
+			name: "Storage Error",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+					return errors.New("connection refused")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Error deleting session: connection refused"}`,
+		},
+		{
+			name: "Refresh Revoke Error",
+			mockRefreshSetup: func(mrr *mocks.MockRefreshTokenRepository) {
+				mrr.RevokeFamilyFunc = func(ctx context.Context, familyID string) error {
+					if familyID != "current-session" {
+						return errors.New("unexpected family")
+					}
+					return errors.New("connection refused")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Error revoking refresh tokens: connection refused"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+			if tt.mockSessSetup != nil {
+				tt.mockSessSetup(mockSessRepo)
+			}
+			if tt.mockRefreshSetup != nil {
+				tt.mockRefreshSetup(mockRefreshRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			ctx.Set("token", "current-token")
+			ctx.Set("session_id", "current-session")
+
+			logoutHandler := NewLogoutHandler(mockSessRepo, mockRefreshRepo)
+			logoutHandler.Handler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestLogoutAllHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockSessSetup  func(*mocks.MockSessionsRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.DeleteUserSessionsFunc = func(ctx context.Context, userID uint) error {
+					if userID != 1 {
+						return errors.New("unexpected user")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Logged out from all sessions"}`,
+		},
+		{
+			name: "Storage Error",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.DeleteUserSessionsFunc = func(ctx context.Context, userID uint) error {
+					return errors.New("connection refused")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Error deleting sessions: connection refused"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			if tt.mockSessSetup != nil {
+				tt.mockSessSetup(mockSessRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			ctx.Set("token", "current-token")
+
+			logoutHandler := NewLogoutHandler(mockSessRepo, mocks.NewDefaultRefreshTokenMock())
+			logoutHandler.AllHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestLogoutAllHandlerOrganizationToken(t *testing.T) {
+	tests := []struct {
+		name             string
+		listErr          error
+		expectedStatus   int
+		expectedBody     string
+		expectedRevoked  []string
+		expectedSessions []string
+	}{
+		{
+			name:             "Success",
+			expectedStatus:   http.StatusOK,
+			expectedBody:     `{"message":"Logged out from all sessions"}`,
+			expectedRevoked:  []string{"session-1", "session-2"},
+			expectedSessions: []string{"session-1", "session-2"},
+		},
+		{
+			name:           "Storage Error",
+			listErr:        errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Error retrieving sessions: connection refused"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted, revoked []string
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.ListUserSessionsFunc = func(ctx context.Context, userID uint) ([]*models.Session, error) {
+				if organizationID, ok := storage.OrganizationFromContext(ctx); !ok || organizationID != 7 {
+					return nil, errors.New("unexpected scope")
+				}
+				if tt.listErr != nil {
+					return nil, tt.listErr
+				}
+				return []*models.Session{
+					{ID: "session-1", UserID: userID, OrganizationID: 7},
+					{ID: "session-2", UserID: userID, OrganizationID: 7},
+				}, nil
+			}
+			mockSessRepo.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+				deleted = append(deleted, sessionID)
+				return nil
+			}
+			mockSessRepo.DeleteUserSessionsFunc = func(ctx context.Context, userID uint) error {
+				return errors.New("unexpected call")
+			}
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+			mockRefreshRepo.RevokeFamilyFunc = func(ctx context.Context, familyID string) error {
+				revoked = append(revoked, familyID)
+				return nil
+			}
+			mockRefreshRepo.RevokeUserRefreshTokensFunc = func(ctx context.Context, userID uint) error {
+				return errors.New("unexpected call")
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request = ctx.Request.WithContext(storage.WithOrganization(context.Background(), 7))
+			ctx.Set("user_id", uint(1))
+			ctx.Set("organization_id", uint(7))
+
+			logoutHandler := NewLogoutHandler(mockSessRepo, mockRefreshRepo)
+			logoutHandler.AllHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			assert.Equal(t, tt.expectedSessions, deleted)
+			assert.Equal(t, tt.expectedRevoked, revoked)
+		})
+	}
+}
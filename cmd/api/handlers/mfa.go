@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/totp"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTOTPIssuer = "Multitech"
+	// maxMFAAttempts bounds the codes tried against one challenge; with six
+	// digits that leaves a guessing chance of 5 in a million per password entry.
+	maxMFAAttempts = 5
+)
+
+type MFAHandler struct {
+	userRepo      storage.UserRepository
+	sessRepo      storage.SessionsRepository
+	refreshRepo   storage.RefreshTokenRepository
+	challengeRepo storage.MFAChallengeRepository
+	attemptRepo   storage.LoginAttemptRepository
+}
+
+func NewMFAHandler(userRepo storage.UserRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, challengeRepo storage.MFAChallengeRepository, attemptRepo storage.LoginAttemptRepository) *MFAHandler {
+	return &MFAHandler{
+		userRepo:      userRepo,
+		sessRepo:      sessRepo,
+		refreshRepo:   refreshRepo,
+		challengeRepo: challengeRepo,
+		attemptRepo:   attemptRepo,
+	}
+}
+
+// @Summary Enroll TOTP
+// @Description Generate a new TOTP secret for the current user. It is enforced once confirmed with a first code
+// @Tags mfa
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /mfa/totp/enroll [post]
+func (mfa *MFAHandler) EnrollHandler(ctx *gin.Context) {
+	user, ok := mfa.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error": "TOTP is already enabled",
+		})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error generating TOTP secret",
+		})
+		return
+	}
+
+	if err := mfa.userRepo.SetTOTP(ctx.Request.Context(), user.ID, secret, false, 0); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error updating user: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer(), user.Username, secret),
+	})
+}
+
+// @Summary Confirm TOTP
+// @Description Enable TOTP for the current user with a first code from the enrolled authenticator
+// @Tags mfa
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param code body models.TOTPConfirmation true "Current TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /mfa/totp/confirm [post]
+func (mfa *MFAHandler) ConfirmHandler(ctx *gin.Context) {
+	var confirmation models.TOTPConfirmation
+	if err := ctx.ShouldBindJSON(&confirmation); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	user, ok := mfa.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error": "TOTP is already enabled",
+		})
+		return
+	}
+	if user.TOTPSecret == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "TOTP enrollment has not been started",
+		})
+		return
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, confirmation.Code, time.Now(), user.TOTPLastStep)
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid code",
+		})
+		return
+	}
+
+	if err := mfa.userRepo.SetTOTP(ctx.Request.Context(), user.ID, user.TOTPSecret, true, step); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error updating user: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "TOTP enabled",
+	})
+}
+
+// @Summary Complete MFA login
+// @Description Exchange the mfa_token from /login and a valid TOTP code for an access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.MFALoginCredentials true "MFA challenge token and TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/mfa [post]
+func (mfa *MFAHandler) LoginHandler(ctx *gin.Context) {
+	var creds models.MFALoginCredentials
+	if err := ctx.ShouldBindJSON(&creds); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	challenge, err := mfa.challengeRepo.GetMFAChallenge(ctx.Request.Context(), creds.MFAToken)
+	if err != nil {
+		mfa.challengeError(ctx, err)
+		return
+	}
+
+	user, err := mfa.userRepo.GetUserByID(ctx.Request.Context(), challenge.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": storage.ErrMFAChallengeNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+
+	// Wrong codes count as failed logins, so a locked or delayed username
+	// cannot try codes either.
+	if checkLoginBlocked(ctx, mfa.attemptRepo, userLoginKey(user.Username)) {
+		return
+	}
+
+	// The attempt is reserved before the code is checked, so concurrent
+	// requests cannot try more than maxMFAAttempts codes between them.
+	attempts, err := mfa.challengeRepo.RecordAttempt(ctx.Request.Context(), creds.MFAToken)
+	if err != nil {
+		mfa.challengeError(ctx, err)
+		return
+	}
+	if attempts > maxMFAAttempts {
+		mfa.discardChallenge(ctx, creds.MFAToken)
+		return
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, creds.Code, time.Now(), user.TOTPLastStep)
+	if !user.TOTPEnabled || !valid {
+		if recordLoginFailure(ctx, mfa.attemptRepo, user.Username) {
+			return
+		}
+		mfa.rejectCode(ctx, creds.MFAToken, attempts)
+		return
+	}
+
+	// The step is only stored if it is later than the last accepted one, so
+	// a code replayed by a concurrent request is rejected.
+	if err := mfa.userRepo.RecordTOTPStep(ctx.Request.Context(), user.ID, step); err != nil {
+		if errors.Is(err, storage.ErrTOTPStepUsed) {
+			mfa.rejectCode(ctx, creds.MFAToken, attempts)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error updating user: " + err.Error(),
+		})
+		return
+	}
+
+	// Deleting the challenge is the commit point: of two concurrent requests
+	// with a valid code only one gets a session.
+	if err := mfa.challengeRepo.DeleteMFAChallenge(ctx.Request.Context(), creds.MFAToken); err != nil {
+		mfa.challengeError(ctx, err)
+		return
+	}
+
+	resetLoginFailures(ctx, mfa.attemptRepo, user.Username)
+	respondWithTokens(ctx, mfa.sessRepo, mfa.refreshRepo, user)
+}
+
+// rejectCode answers an invalid code, the attempts-th tried against the
+// challenge, and discards the challenge once no attempt is left.
+func (mfa *MFAHandler) rejectCode(ctx *gin.Context, mfaToken string, attempts int) {
+	if attempts >= maxMFAAttempts {
+		mfa.discardChallenge(ctx, mfaToken)
+		return
+	}
+
+	ctx.JSON(http.StatusUnauthorized, gin.H{
+		"error": "Invalid code",
+	})
+}
+
+func (mfa *MFAHandler) discardChallenge(ctx *gin.Context, mfaToken string) {
+	if err := mfa.challengeRepo.DeleteMFAChallenge(ctx.Request.Context(), mfaToken); err != nil && !errors.Is(err, storage.ErrMFAChallengeNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting MFA challenge: " + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, gin.H{
+		"error": "Too many invalid codes, log in again",
+	})
+}
+
+func (mfa *MFAHandler) challengeError(ctx *gin.Context, err error) {
+	if errors.Is(err, storage.ErrMFAChallengeNotFound) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Error retrieving MFA challenge: " + err.Error(),
+	})
+}
+
+func (mfa *MFAHandler) currentUser(ctx *gin.Context) (*models.User, bool) {
+	user, err := mfa.userRepo.GetUserByID(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultTOTPIssuer
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFALoginChallengeLifecycle(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	challengeRepo := storage.NewRedisMFAChallengeRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, 61)
+	defer sessRepo.DeleteUserSessions(bg, 61)
+
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+		return &models.User{ID: 61, Username: "mfauser", TOTPSecret: testTOTPSecret, TOTPEnabled: true}, nil
+	}
+	handler := NewMFAHandler(mockUserRepo, sessRepo, refreshRepo, challengeRepo, mocks.NewDefaultLoginAttemptMock())
+
+	submit := func(mfaToken string, code string) int {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"mfa_token":"`+mfaToken+`","code":"`+code+`"}`)
+		handler.LoginHandler(ctx)
+		return recorder.Code
+	}
+
+	require.NoError(t, challengeRepo.CreateMFAChallenge(bg, "mfa-lockout", &models.MFAChallenge{UserID: 61}, time.Minute))
+	for attempt := 1; attempt < maxMFAAttempts; attempt++ {
+		assert.Equal(t, http.StatusUnauthorized, submit("mfa-lockout", "000000"))
+	}
+	challenge, err := challengeRepo.GetMFAChallenge(bg, "mfa-lockout")
+	require.NoError(t, err)
+	assert.Equal(t, maxMFAAttempts-1, challenge.Attempts)
+
+	assert.Equal(t, http.StatusUnauthorized, submit("mfa-lockout", "000000"))
+	_, err = challengeRepo.GetMFAChallenge(bg, "mfa-lockout")
+	assert.ErrorIs(t, err, storage.ErrMFAChallengeNotFound)
+	// Even a valid code is useless once the challenge is gone.
+	assert.Equal(t, http.StatusUnauthorized, submit("mfa-lockout", currentTOTPCode(t)))
+
+	require.NoError(t, challengeRepo.CreateMFAChallenge(bg, "mfa-success", &models.MFAChallenge{UserID: 61}, time.Minute))
+	assert.Equal(t, http.StatusOK, submit("mfa-success", currentTOTPCode(t)))
+	assert.Equal(t, http.StatusUnauthorized, submit("mfa-success", currentTOTPCode(t)))
+
+	sessions, err := sessRepo.ListUserSessions(bg, 61)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestTOTPUpdatesKeepOtherColumns(t *testing.T) {
+	bg := context.Background()
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+
+	user := &models.User{Username: "totpcolumns", Email: "totpcolumns@example.com", Password: "oldhash"}
+	require.NoError(t, tx.Create(user).Error)
+	userRepo := storage.NewGormUserRepository(tx)
+
+	// A password change between loading the user and storing the TOTP
+	// settings must survive.
+	require.NoError(t, userRepo.UpdatePassword(bg, user.ID, "newhash"))
+	require.NoError(t, userRepo.SetTOTP(bg, user.ID, testTOTPSecret, true, 10))
+	require.NoError(t, userRepo.MarkEmailVerified(bg, user.ID, time.Now()))
+
+	require.NoError(t, userRepo.RecordTOTPStep(bg, user.ID, 11))
+	assert.ErrorIs(t, userRepo.RecordTOTPStep(bg, user.ID, 11), storage.ErrTOTPStepUsed)
+	assert.ErrorIs(t, userRepo.RecordTOTPStep(bg, user.ID, 9), storage.ErrTOTPStepUsed)
+
+	stored, err := userRepo.GetUserByID(bg, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "newhash", stored.Password)
+	assert.Equal(t, testTOTPSecret, stored.TOTPSecret)
+	assert.True(t, stored.TOTPEnabled)
+	assert.Equal(t, int64(11), stored.TOTPLastStep)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+}
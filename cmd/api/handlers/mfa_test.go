@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"multitech/pkg/totp"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func currentTOTPCode(t *testing.T) string {
+	code, err := totp.Code(testTOTPSecret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestMFAEnrollHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		user           *models.User
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			user:           &models.User{ID: 1, Username: "alice"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Already Enabled",
+			user:           &models.User{ID: 1, Username: "alice", TOTPSecret: testTOTPSecret, TOTPEnabled: true},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *models.User
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+				return tt.user, nil
+			}
+			mockUserRepo.SetTOTPFunc = func(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error {
+				updated = &models.User{ID: id, TOTPSecret: secret, TOTPEnabled: enabled, TOTPLastStep: lastStep}
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+
+			handler := NewMFAHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mocks.NewDefaultLoginAttemptMock())
+			handler.EnrollHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Nil(t, updated)
+				return
+			}
+
+			var response map[string]string
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			require.NotNil(t, updated)
+			assert.Equal(t, updated.TOTPSecret, response["secret"])
+			assert.False(t, updated.TOTPEnabled)
+
+			uri, err := url.Parse(response["otpauth_uri"])
+			require.NoError(t, err)
+			assert.Equal(t, "otpauth", uri.Scheme)
+			assert.Equal(t, response["secret"], uri.Query().Get("secret"))
+			assert.Equal(t, defaultTOTPIssuer, uri.Query().Get("issuer"))
+		})
+	}
+}
+
+func TestMFAConfirmHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		user           *models.User
+		code           func(t *testing.T) string
+		expectedStatus int
+		expectEnabled  bool
+	}{
+		{
+			name:           "Success",
+			user:           &models.User{ID: 1, TOTPSecret: testTOTPSecret},
+			code:           currentTOTPCode,
+			expectedStatus: http.StatusOK,
+			expectEnabled:  true,
+		},
+		{
+			name:           "Wrong Code",
+			user:           &models.User{ID: 1, TOTPSecret: testTOTPSecret},
+			code:           func(t *testing.T) string { return "000000" },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Not Enrolled",
+			user:           &models.User{ID: 1},
+			code:           currentTOTPCode,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Already Enabled",
+			user:           &models.User{ID: 1, TOTPSecret: testTOTPSecret, TOTPEnabled: true},
+			code:           currentTOTPCode,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *models.User
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+				return tt.user, nil
+			}
+			mockUserRepo.SetTOTPFunc = func(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error {
+				updated = &models.User{ID: id, TOTPSecret: secret, TOTPEnabled: enabled, TOTPLastStep: lastStep}
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			testutils.SetJSONBody(ctx, `{"code":"`+tt.code(t)+`"}`)
+
+			handler := NewMFAHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultMFAChallengeMock(), mocks.NewDefaultLoginAttemptMock())
+			handler.ConfirmHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectEnabled, updated != nil && updated.TOTPEnabled)
+			if tt.expectEnabled {
+				assert.Equal(t, totp.Step(time.Now()), updated.TOTPLastStep)
+			}
+		})
+	}
+}
+
+func TestMFALoginHandler(t *testing.T) {
+	enabledUser := func() *models.User {
+		return &models.User{ID: 1, Username: "alice", TOTPSecret: testTOTPSecret, TOTPEnabled: true}
+	}
+
+	tests := []struct {
+		name                string
+		code                func(t *testing.T) string
+		user                func() *models.User
+		mockChallengeSetup  func(*mocks.MockMFAChallengeRepository)
+		mockAttemptSetup    func(*mocks.MockLoginAttemptRepository)
+		stepErr             error
+		expectedStatus      int
+		expectedError       string
+		expectSession       bool
+		expectChallengeGone bool
+		expectFailure       bool
+		expectedReset       []string
+	}{
+		{
+			name:                "Success",
+			code:                currentTOTPCode,
+			user:                enabledUser,
+			expectedStatus:      http.StatusOK,
+			expectSession:       true,
+			expectChallengeGone: true,
+			expectedReset:       []string{"user:alice"},
+		},
+		{
+			name:           "Wrong Code",
+			code:           func(t *testing.T) string { return "000000" },
+			user:           enabledUser,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "Invalid code",
+			expectFailure:  true,
+		},
+		{
+			name: "Wrong Code Locks Username",
+			code: func(t *testing.T) string { return "000000" },
+			user: enabledUser,
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+					return defaultLockoutThreshold, nil
+				}
+			},
+			expectedStatus: http.StatusLocked,
+			expectedError:  "Account temporarily locked after too many failed logins",
+			expectFailure:  true,
+			// The lockout replaces the failure counter.
+			expectedReset: []string{"user:alice"},
+		},
+		{
+			name: "Locked Username",
+			code: currentTOTPCode,
+			user: enabledUser,
+			mockAttemptSetup: func(mar *mocks.MockLoginAttemptRepository) {
+				mar.GetLoginBlockFunc = func(ctx context.Context, key string) (string, time.Duration, error) {
+					return storage.LoginBlockLocked, time.Minute, nil
+				}
+			},
+			expectedStatus: http.StatusLocked,
+			expectedError:  "Account temporarily locked after too many failed logins",
+		},
+		{
+			name: "Too Many Attempts",
+			code: func(t *testing.T) string { return "000000" },
+			user: enabledUser,
+			mockChallengeSetup: func(mcr *mocks.MockMFAChallengeRepository) {
+				mcr.RecordAttemptFunc = func(ctx context.Context, token string) (int, error) {
+					return maxMFAAttempts, nil
+				}
+			},
+			expectedStatus:      http.StatusUnauthorized,
+			expectedError:       "Too many invalid codes, log in again",
+			expectChallengeGone: true,
+			expectFailure:       true,
+		},
+		{
+			name: "Attempts Used Up Concurrently",
+			code: currentTOTPCode,
+			user: enabledUser,
+			mockChallengeSetup: func(mcr *mocks.MockMFAChallengeRepository) {
+				mcr.RecordAttemptFunc = func(ctx context.Context, token string) (int, error) {
+					return maxMFAAttempts + 1, nil
+				}
+			},
+			expectedStatus:      http.StatusUnauthorized,
+			expectedError:       "Too many invalid codes, log in again",
+			expectChallengeGone: true,
+		},
+		{
+			name: "Replayed Code",
+			code: currentTOTPCode,
+			user: func() *models.User {
+				user := enabledUser()
+				user.TOTPLastStep = totp.Step(time.Now()) + totp.Skew
+				return user
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "Invalid code",
+			expectFailure:  true,
+		},
+		{
+			name:           "Code Replayed Concurrently",
+			code:           currentTOTPCode,
+			user:           enabledUser,
+			stepErr:        storage.ErrTOTPStepUsed,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "Invalid code",
+		},
+		{
+			name: "Unknown Challenge",
+			code: currentTOTPCode,
+			user: enabledUser,
+			mockChallengeSetup: func(mcr *mocks.MockMFAChallengeRepository) {
+				mcr.GetMFAChallengeFunc = func(ctx context.Context, token string) (*models.MFAChallenge, error) {
+					return nil, storage.ErrMFAChallengeNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  storage.ErrMFAChallengeNotFound.Error(),
+		},
+		{
+			name: "Challenge Used Concurrently",
+			code: currentTOTPCode,
+			user: enabledUser,
+			mockChallengeSetup: func(mcr *mocks.MockMFAChallengeRepository) {
+				mcr.DeleteMFAChallengeFunc = func(ctx context.Context, token string) error {
+					return storage.ErrMFAChallengeNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  storage.ErrMFAChallengeNotFound.Error(),
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			mockEnv.Set("JWT_SECRET", "testsecret")
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+				return tt.user(), nil
+			}
+			mockUserRepo.RecordTOTPStepFunc = func(ctx context.Context, id uint, step int64) error {
+				return tt.stepErr
+			}
+
+			challengeGone := false
+			mockChallengeRepo := mocks.NewDefaultMFAChallengeMock()
+			mockChallengeRepo.DeleteMFAChallengeFunc = func(ctx context.Context, token string) error {
+				challengeGone = true
+				return nil
+			}
+			if tt.mockChallengeSetup != nil {
+				tt.mockChallengeSetup(mockChallengeRepo)
+			}
+
+			sessionCreated := false
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.CreateSessionFunc = func(ctx context.Context, session *models.Session) error {
+				sessionCreated = true
+				return nil
+			}
+
+			var failed, reset []string
+			mockAttemptRepo := mocks.NewDefaultLoginAttemptMock()
+			if tt.mockAttemptSetup != nil {
+				tt.mockAttemptSetup(mockAttemptRepo)
+			}
+			recordFailure := mockAttemptRepo.RecordLoginFailureFunc
+			mockAttemptRepo.RecordLoginFailureFunc = func(ctx context.Context, key string, window time.Duration) (int, error) {
+				failed = append(failed, key)
+				return recordFailure(ctx, key, window)
+			}
+			mockAttemptRepo.ResetLoginFailuresFunc = func(ctx context.Context, key string) error {
+				reset = append(reset, key)
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, `{"mfa_token":"challenge","code":"`+tt.code(t)+`"}`)
+
+			handler := NewMFAHandler(mockUserRepo, mockSessRepo, mocks.NewDefaultRefreshTokenMock(), mockChallengeRepo, mockAttemptRepo)
+			handler.LoginHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectSession, sessionCreated)
+			assert.Equal(t, tt.expectChallengeGone, challengeGone)
+			assert.Equal(t, tt.expectFailure, slices.Contains(failed, "user:alice"), "wrong codes count as failed logins")
+			assert.Equal(t, tt.expectedReset, reset)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, response["error"])
+				return
+			}
+			assert.NotEmpty(t, response["token"])
+			assert.NotEmpty(t, response["refresh_token"])
+		})
+	}
+}
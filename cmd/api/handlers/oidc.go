@@ -0,0 +1,658 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	authorizationCodeTTL = time.Minute
+	// authorizationRequestTTL is how long the user has to sign in and consent.
+	authorizationRequestTTL = 10 * time.Minute
+	defaultOIDCIssuer       = "http://localhost:8080"
+	minCodeVerifierLen      = 43
+	maxCodeVerifierLen      = 128
+)
+
+var supportedScopes = []string{"openid", "profile", "email"}
+
+type OIDCHandler struct {
+	userRepo    storage.UserRepository
+	clientRepo  storage.OAuthClientRepository
+	codeRepo    storage.AuthorizationCodeRepository
+	sessRepo    storage.SessionsRepository
+	serviceRepo storage.ServiceAccountRepository
+}
+
+func NewOIDCHandler(userRepo storage.UserRepository, clientRepo storage.OAuthClientRepository, codeRepo storage.AuthorizationCodeRepository, sessRepo storage.SessionsRepository, serviceRepo storage.ServiceAccountRepository) *OIDCHandler {
+	return &OIDCHandler{
+		userRepo:    userRepo,
+		clientRepo:  clientRepo,
+		codeRepo:    codeRepo,
+		sessRepo:    sessRepo,
+		serviceRepo: serviceRepo,
+	}
+}
+
+type idTokenClaims struct {
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	EmailVerified     *bool            `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// @Summary OpenID Connect discovery
+// @Description OpenID Provider metadata
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /.well-known/openid-configuration [get]
+func (*OIDCHandler) DiscoveryHandler(ctx *gin.Context) {
+	ring, err := middleware.LoadKeyring()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error loading signing keys",
+		})
+		return
+	}
+
+	set, err := ring.JWKSet()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error loading signing keys",
+		})
+		return
+	}
+
+	algs := []string{}
+	for _, key := range set.Keys {
+		if !containsString(algs, key.Alg) {
+			algs = append(algs, key.Alg)
+		}
+	}
+
+	issuer := oidcIssuer()
+	ctx.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": algs,
+		"scopes_supported":                      supportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "email", "email_verified"},
+	})
+}
+
+// @Summary OAuth 2.0 authorization endpoint
+// @Description Validate an authorization request and redirect the browser to the consent page at OIDC_CONSENT_URL with a consent_challenge. The page signs the user in and
+// @Description answers the challenge at /authorize/consent, which redirects back to the client. PKCE with S256 is required
+// @Tags oidc
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string true "Space separated scopes, must include openid"
+// @Param state query string false "Opaque value returned to the client"
+// @Param nonce query string false "Value copied into the ID token"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 302
+// @Failure 400 {object} map[string]interface{}
+// @Router /authorize [get]
+func (oidc *OIDCHandler) AuthorizeHandler(ctx *gin.Context) {
+	client, err := oidc.clientRepo.GetClientByClientID(ctx.Request.Context(), ctx.Query("client_id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrClientNotFound) {
+			oauthError(ctx, http.StatusBadRequest, "invalid_client", "Unknown client")
+			return
+		}
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error retrieving client")
+		return
+	}
+
+	// Until the redirect URI is known to belong to the client, errors must not be redirected.
+	redirectURI := ctx.Query("redirect_uri")
+	if !client.HasRedirectURI(redirectURI) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "Unregistered redirect_uri")
+		return
+	}
+
+	state := ctx.Query("state")
+	if ctx.Query("response_type") != "code" {
+		redirectWithParams(ctx, redirectURI, url.Values{"error": {"unsupported_response_type"}, "state": {state}})
+		return
+	}
+
+	scope := filterScopes(ctx.Query("scope"))
+	if !hasScope(scope, "openid") {
+		redirectWithParams(ctx, redirectURI, url.Values{"error": {"invalid_scope"}, "error_description": {"The openid scope is required"}, "state": {state}})
+		return
+	}
+
+	challenge := ctx.Query("code_challenge")
+	if challenge == "" || ctx.Query("code_challenge_method") != "S256" {
+		redirectWithParams(ctx, redirectURI, url.Values{"error": {"invalid_request"}, "error_description": {"PKCE with S256 is required"}, "state": {state}})
+		return
+	}
+
+	consentChallenge, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		redirectWithParams(ctx, redirectURI, url.Values{"error": {"server_error"}, "state": {state}})
+		return
+	}
+
+	request := &models.AuthorizationRequest{
+		ClientID:            client.ClientID,
+		ClientName:          client.Name,
+		RedirectURI:         redirectURI,
+		Scope:               scope,
+		State:               state,
+		Nonce:               ctx.Query("nonce"),
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	}
+	if err := oidc.codeRepo.StoreAuthorizationRequest(ctx.Request.Context(), consentChallenge, request, authorizationRequestTTL); err != nil {
+		redirectWithParams(ctx, redirectURI, url.Values{"error": {"server_error"}, "state": {state}})
+		return
+	}
+
+	redirectWithParams(ctx, oidcConsentURL(), url.Values{"consent_challenge": {consentChallenge}})
+}
+
+// @Summary Get a pending authorization request
+// @Description The client and scopes of an authorization request, for the consent page to show to the signed-in user
+// @Tags oidc
+// @Security BearerAuth
+// @Produce json
+// @Param consent_challenge query string true "Consent challenge from the redirect of /authorize"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authorize/consent [get]
+func (oidc *OIDCHandler) ConsentInfoHandler(ctx *gin.Context) {
+	request, err := oidc.codeRepo.GetAuthorizationRequest(ctx.Request.Context(), ctx.Query("consent_challenge"))
+	if err != nil {
+		respondAuthorizationRequestError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"client_id":    request.ClientID,
+		"client_name":  request.ClientName,
+		"redirect_uri": request.RedirectURI,
+		"scope":        strings.Fields(request.Scope),
+	})
+}
+
+// @Summary Answer an authorization request
+// @Description Grant or refuse the client access for the signed-in user. Returns the client URL to send the browser to, carrying an authorization code
+// @Description or an access_denied error. Each consent challenge can be answered once
+// @Tags oidc
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param consent body models.ConsentRequest true "Consent challenge and decision"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authorize/consent [post]
+func (oidc *OIDCHandler) ConsentHandler(ctx *gin.Context) {
+	var consent models.ConsentRequest
+	if err := ctx.ShouldBindJSON(&consent); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	request, err := oidc.codeRepo.ConsumeAuthorizationRequest(ctx.Request.Context(), consent.ConsentChallenge)
+	if err != nil {
+		respondAuthorizationRequestError(ctx, err)
+		return
+	}
+
+	if !consent.Approve {
+		ctx.JSON(http.StatusOK, gin.H{
+			"redirect_to": redirectURL(request.RedirectURI, url.Values{"error": {"access_denied"}, "state": {request.State}}),
+		})
+		return
+	}
+
+	authTime := time.Now()
+	if session, err := oidc.sessRepo.GetSessionByID(ctx.Request.Context(), ctx.GetString("session_id")); err == nil {
+		authTime = session.CreatedAt
+	}
+
+	code, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error generating authorization code",
+		})
+		return
+	}
+
+	authCode := &models.AuthorizationCode{
+		ClientID:            request.ClientID,
+		UserID:              ctx.GetUint("user_id"),
+		RedirectURI:         request.RedirectURI,
+		Scope:               request.Scope,
+		Nonce:               request.Nonce,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		AuthTime:            authTime,
+	}
+	if err := oidc.codeRepo.StoreAuthorizationCode(ctx.Request.Context(), code, authCode, authorizationCodeTTL); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error storing authorization code",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"redirect_to": redirectURL(request.RedirectURI, url.Values{"code": {code}, "state": {request.State}}),
+	})
+}
+
+// @Summary OAuth 2.0 token endpoint
+// @Description Exchange an authorization code and PKCE verifier for an access token and an ID token, or service account credentials for an access token
+// @Description with the client_credentials grant
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used in the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param scope formData string false "Requested scope for client_credentials, limited to the scope of the service account"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic authentication"
+// @Param client_secret formData string false "Client secret of confidential clients and service accounts"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /token [post]
+func (oidc *OIDCHandler) TokenHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	switch ctx.PostForm("grant_type") {
+	case "authorization_code":
+		oidc.exchangeAuthorizationCode(ctx)
+	case "client_credentials":
+		oidc.exchangeClientCredentials(ctx)
+	default:
+		oauthError(ctx, http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+}
+
+func (oidc *OIDCHandler) exchangeAuthorizationCode(ctx *gin.Context) {
+	client, ok := oidc.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	// The code is single-use, so the key is checked before it is consumed.
+	if err := checkIDTokenKey(); err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	authCode, err := oidc.codeRepo.ConsumeAuthorizationCode(ctx.Request.Context(), ctx.PostForm("code"))
+	if err != nil {
+		if errors.Is(err, storage.ErrAuthorizationCodeNotFound) {
+			oauthError(ctx, http.StatusBadRequest, "invalid_grant", err.Error())
+			return
+		}
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error retrieving authorization code")
+		return
+	}
+
+	if authCode.ClientID != client.ClientID || authCode.RedirectURI != ctx.PostForm("redirect_uri") {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "Authorization code was issued to another client or redirect_uri")
+		return
+	}
+
+	if !verifyCodeChallenge(ctx.PostForm("code_verifier"), authCode.CodeChallenge) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "Invalid code_verifier")
+		return
+	}
+
+	user, err := oidc.userRepo.GetUserByID(ctx.Request.Context(), authCode.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			oauthError(ctx, http.StatusBadRequest, "invalid_grant", err.Error())
+			return
+		}
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error retrieving user")
+		return
+	}
+
+	idToken, err := generateIDToken(user, client.ClientID, authCode)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	// No refresh token is issued, so the session ends together with the access token.
+	session, err := openSession(ctx, oidc.sessRepo, user.ID, 0, middleware.AccessTokenTTL)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error creating session")
+		return
+	}
+
+	accessToken, err := issueScopedAccessToken(ctx.Request.Context(), oidc.sessRepo, user.ID, session.ID, authCode.Scope)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error creating session")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(middleware.AccessTokenTTL.Seconds()),
+		"scope":        authCode.Scope,
+		"id_token":     idToken,
+	})
+}
+
+func (oidc *OIDCHandler) exchangeClientCredentials(ctx *gin.Context) {
+	clientID, secret, basic := ctx.Request.BasicAuth()
+	if !basic {
+		clientID = ctx.PostForm("client_id")
+		secret = ctx.PostForm("client_secret")
+	}
+
+	account, err := oidc.serviceRepo.GetServiceAccountByClientID(ctx.Request.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			oauthError(ctx, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+			return
+		}
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error retrieving service account")
+		return
+	}
+
+	if secret == "" || account.CheckSecret(secret) != nil {
+		oauthError(ctx, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	// Without an explicit request the token gets everything the account may use.
+	scope := account.Scope
+	if requested := ctx.PostForm("scope"); requested != "" {
+		for _, value := range strings.Fields(requested) {
+			if !hasScope(account.Scope, value) {
+				oauthError(ctx, http.StatusBadRequest, "invalid_scope", "Scope "+value+" is not allowed for this client")
+				return
+			}
+		}
+		scope = strings.Join(strings.Fields(requested), " ")
+	}
+
+	accessToken, err := issueServiceToken(ctx, oidc.sessRepo, account, scope)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error creating session")
+		return
+	}
+
+	response := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(middleware.AccessTokenTTL.Seconds()),
+	}
+	if scope != "" {
+		response["scope"] = scope
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary OpenID Connect userinfo
+// @Description Claims about the authenticated user, limited by the scope of the access token
+// @Tags oidc
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /userinfo [get]
+func (oidc *OIDCHandler) UserInfoHandler(ctx *gin.Context) {
+	scope := ctx.GetString("scope")
+	if scope != "" && !hasScope(scope, "openid") {
+		oauthError(ctx, http.StatusForbidden, "insufficient_scope", "The openid scope is required")
+		return
+	}
+
+	user, err := oidc.userRepo.GetUserByID(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			oauthError(ctx, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		}
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error retrieving user")
+		return
+	}
+
+	claims := gin.H{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+	}
+	if scope == "" || hasScope(scope, "profile") {
+		claims["preferred_username"] = user.Username
+	}
+	if scope == "" || hasScope(scope, "email") {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+	ctx.JSON(http.StatusOK, claims)
+}
+
+// authenticateClient resolves the client from HTTP Basic credentials or the
+// form body. Confidential clients must present their secret, public clients
+// rely on PKCE alone.
+func (oidc *OIDCHandler) authenticateClient(ctx *gin.Context) (*models.OAuthClient, bool) {
+	clientID, secret, basic := ctx.Request.BasicAuth()
+	if !basic {
+		clientID = ctx.PostForm("client_id")
+		secret = ctx.PostForm("client_secret")
+	}
+
+	client, err := oidc.clientRepo.GetClientByClientID(ctx.Request.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrClientNotFound) {
+			oauthError(ctx, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+			return nil, false
+		}
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "Error retrieving client")
+		return nil, false
+	}
+
+	if client.Public() {
+		if secret != "" {
+			oauthError(ctx, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+			return nil, false
+		}
+		return client, true
+	}
+
+	if secret == "" || client.CheckSecret(secret) != nil {
+		oauthError(ctx, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return nil, false
+	}
+	return client, true
+}
+
+func generateIDToken(user *models.User, audience string, authCode *models.AuthorizationCode) (string, error) {
+	if err := checkIDTokenKey(); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &idTokenClaims{
+		Nonce:    authCode.Nonce,
+		AuthTime: jwt.NewNumericDate(authCode.AuthTime),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    oidcIssuer(),
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(middleware.AccessTokenTTL)),
+		},
+	}
+	if hasScope(authCode.Scope, "profile") {
+		claims.PreferredUsername = user.Username
+	}
+	if hasScope(authCode.Scope, "email") {
+		claims.Email = user.Email
+		verified := user.EmailVerifiedAt != nil
+		claims.EmailVerified = &verified
+	}
+	return middleware.SignToken(claims)
+}
+
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	if len(verifier) < minCodeVerifierLen || len(verifier) > maxCodeVerifierLen {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func oidcIssuer() string {
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		return strings.TrimSuffix(issuer, "/")
+	}
+	return defaultOIDCIssuer
+}
+
+// oidcConsentURL is the first-party page that signs the user in and asks for
+// consent to authorization requests.
+func oidcConsentURL() string {
+	return os.Getenv("OIDC_CONSENT_URL")
+}
+
+// OIDCEnabledFromEnv reports whether the OpenID Provider routes are served,
+// which they are once OIDC_CONSENT_URL names the consent page. It fails when
+// the active signing key cannot sign ID tokens.
+func OIDCEnabledFromEnv() (bool, error) {
+	if oidcConsentURL() == "" {
+		return false, nil
+	}
+	if err := checkIDTokenKey(); err != nil {
+		return false, fmt.Errorf("OIDC_CONSENT_URL is set: %w", err)
+	}
+	return true, nil
+}
+
+// checkIDTokenKey fails unless the active signing key is asymmetric. Relying
+// parties verify ID tokens through the JWKS, which never contains shared
+// secrets.
+func checkIDTokenKey() error {
+	ring, err := middleware.LoadKeyring()
+	if err != nil {
+		return err
+	}
+	if ring.Active().Symmetric() {
+		return errors.New("ID tokens require an asymmetric signing key")
+	}
+	return nil
+}
+
+func filterScopes(scope string) string {
+	var granted []string
+	for _, requested := range strings.Fields(scope) {
+		if containsString(supportedScopes, requested) && !containsString(granted, requested) {
+			granted = append(granted, requested)
+		}
+	}
+	return strings.Join(granted, " ")
+}
+
+func hasScope(scope string, want string) bool {
+	return containsString(strings.Fields(scope), want)
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
+
+func oauthError(ctx *gin.Context, status int, code string, description string) {
+	body := gin.H{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	ctx.JSON(status, body)
+}
+
+func respondAuthorizationRequestError(ctx *gin.Context, err error) {
+	if errors.Is(err, storage.ErrAuthorizationRequestNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Error retrieving authorization request",
+	})
+}
+
+func redirectWithParams(ctx *gin.Context, redirectURI string, params url.Values) {
+	target := redirectURL(redirectURI, params)
+	if target == "" {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "Invalid redirect_uri")
+		return
+	}
+	ctx.Redirect(http.StatusFound, target)
+}
+
+// redirectURL adds the non-empty params to the query of redirectURI. It
+// returns an empty string for an unparsable URI.
+func redirectURL(redirectURI string, params url.Values) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return ""
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		if len(values) == 0 || values[0] == "" {
+			continue
+		}
+		query.Set(key, values[0])
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
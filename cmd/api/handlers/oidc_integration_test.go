@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	codeRepo := storage.NewRedisAuthorizationCodeRepository(testutils.TestRedis)
+	defer sessRepo.DeleteUserSessions(bg, 1)
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_PRIVATE_KEY_FILE", writeEd25519KeyFile(t))
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), codeRepo, sessRepo, mocks.NewDefaultServiceAccountMock())
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetQuery(ctx, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"client"},
+		"redirect_uri":          {"https://app.example.com/callback"},
+		"scope":                 {"openid"},
+		"code_challenge":        {testCodeChallenge()},
+		"code_challenge_method": {"S256"},
+	})
+	handler.AuthorizeHandler(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	require.Equal(t, http.StatusFound, recorder.Code)
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	require.NoError(t, err)
+	challenge := location.Query().Get("consent_challenge")
+	require.NotEmpty(t, challenge)
+
+	consent := func() *httptest.ResponseRecorder {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"consent_challenge":"`+challenge+`","approve":true}`)
+		ctx.Set("user_id", uint(1))
+		handler.ConsentHandler(ctx)
+		return recorder
+	}
+
+	approved := consent()
+	require.Equal(t, http.StatusOK, approved.Code)
+	var redirect map[string]string
+	require.NoError(t, json.Unmarshal(approved.Body.Bytes(), &redirect))
+	location, err = url.Parse(redirect["redirect_to"])
+	require.NoError(t, err)
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	// Consent challenges are answered once.
+	assert.Equal(t, http.StatusNotFound, consent().Code)
+
+	exchange := func() *http.Response {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetFormBody(ctx, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"client_id":     {"client"},
+			"code_verifier": {testCodeVerifier},
+		})
+		handler.TokenHandler(ctx)
+		return recorder.Result()
+	}
+
+	first := exchange()
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+
+	var response map[string]interface{}
+	require.NoError(t, json.NewDecoder(first.Body).Decode(&response))
+	session, err := sessRepo.GetSession(bg, response["access_token"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), session.UserID)
+	assert.WithinDuration(t, time.Now().Add(middleware.AccessTokenTTL), session.ExpiresAt, time.Minute, "code grant sessions end with their access token")
+
+	// Authorization codes are single use.
+	assert.Equal(t, http.StatusBadRequest, exchange().StatusCode)
+}
+
+func TestOIDCClientCredentialsSession(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetFormBody(ctx, url.Values{"grant_type": {"client_credentials"}})
+	ctx.Request.SetBasicAuth("batch", "service-secret")
+
+	handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mocks.NewDefaultAuthorizationCodeMock(), sessRepo, mocks.NewDefaultServiceAccountMock())
+	handler.TokenHandler(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	session, err := sessRepo.GetSession(bg, response["access_token"].(string))
+	require.NoError(t, err)
+	defer sessRepo.DeleteSession(bg, session.ID)
+	assert.Equal(t, uint(7), session.ServiceAccountID)
+
+	// Service account sessions never show up among a user's devices.
+	sessions, err := sessRepo.ListUserSessions(bg, 7)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	sessions, err = sessRepo.ListUserSessions(bg, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,760 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func testCodeChallenge() string {
+	sum := sha256.Sum256([]byte(testCodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeEd25519KeyFile(t *testing.T) string {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "signing.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return keyFile
+}
+
+func TestOIDCDiscoveryHandler(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_PRIVATE_KEY_FILE", writeEd25519KeyFile(t))
+	mockEnv.Set("OIDC_ISSUER", "https://auth.example.com/")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	ctx, recorder := testutils.NewTestContext()
+
+	handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mocks.NewDefaultAuthorizationCodeMock(), mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+	handler.DiscoveryHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "https://auth.example.com", response["issuer"])
+	assert.Equal(t, "https://auth.example.com/token", response["token_endpoint"])
+	assert.Equal(t, "https://auth.example.com/.well-known/jwks.json", response["jwks_uri"])
+	assert.Equal(t, []interface{}{"EdDSA"}, response["id_token_signing_alg_values_supported"])
+	assert.Equal(t, []interface{}{"S256"}, response["code_challenge_methods_supported"])
+}
+
+func TestOIDCEnabledFromEnv(t *testing.T) {
+	keyFile := writeEd25519KeyFile(t)
+
+	tests := []struct {
+		name          string
+		consentURL    string
+		keyFile       string
+		expectEnabled bool
+		expectError   bool
+	}{
+		{name: "No Consent Page", keyFile: keyFile},
+		{name: "Asymmetric Key", consentURL: "https://auth.example.com/consent", keyFile: keyFile, expectEnabled: true},
+		{name: "Symmetric Key", consentURL: "https://auth.example.com/consent", expectError: true},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			mockEnv.Set("OIDC_CONSENT_URL", tt.consentURL)
+			mockEnv.Set("JWT_PRIVATE_KEY_FILE", tt.keyFile)
+			mockEnv.Set("JWT_KEY_ID", "ed-key")
+			mockEnv.Set("JWT_SECRET", "testsecret")
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			enabled, err := OIDCEnabledFromEnv()
+			assert.Equal(t, tt.expectEnabled, enabled)
+			if tt.expectError {
+				assert.ErrorContains(t, err, "OIDC_CONSENT_URL")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthorizeHandler(t *testing.T) {
+	validQuery := func() url.Values {
+		return url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"client"},
+			"redirect_uri":          {"https://app.example.com/callback"},
+			"scope":                 {"openid profile admin"},
+			"state":                 {"xyz"},
+			"nonce":                 {"n-0S6_WzA2Mj"},
+			"code_challenge":        {testCodeChallenge()},
+			"code_challenge_method": {"S256"},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		query            func() url.Values
+		mockClientSetup  func(*mocks.MockOAuthClientRepository)
+		mockCodeSetup    func(*mocks.MockAuthorizationCodeRepository)
+		expectedStatus   int
+		expectedHost     string
+		expectedLocation map[string]string
+		expectedError    string
+	}{
+		{
+			name:  "Success",
+			query: validQuery,
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.StoreAuthorizationRequestFunc = func(ctx context.Context, challenge string, request *models.AuthorizationRequest, ttl time.Duration) error {
+					assert.NotEmpty(t, challenge)
+					assert.Equal(t, "client", request.ClientID)
+					assert.Equal(t, "openid profile", request.Scope)
+					assert.Equal(t, "xyz", request.State)
+					assert.Equal(t, "n-0S6_WzA2Mj", request.Nonce)
+					assert.Equal(t, testCodeChallenge(), request.CodeChallenge)
+					assert.Equal(t, authorizationRequestTTL, ttl)
+					return nil
+				}
+				mcr.StoreAuthorizationCodeFunc = func(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error {
+					t.Error("no code may be issued before consent")
+					return nil
+				}
+			},
+			expectedStatus:   http.StatusFound,
+			expectedHost:     "auth.example.com",
+			expectedLocation: map[string]string{"state": ""},
+		},
+		{
+			name:  "Unknown Client",
+			query: validQuery,
+			mockClientSetup: func(mcr *mocks.MockOAuthClientRepository) {
+				mcr.GetClientByClientIDFunc = func(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+					return nil, storage.ErrClientNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_client",
+		},
+		{
+			name: "Unregistered Redirect URI",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("redirect_uri", "https://evil.example.com/callback")
+				return query
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name: "Missing Openid Scope",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("scope", "profile")
+				return query
+			},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: map[string]string{"error": "invalid_scope", "state": "xyz"},
+		},
+		{
+			name: "Missing PKCE",
+			query: func() url.Values {
+				query := validQuery()
+				query.Del("code_challenge")
+				return query
+			},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: map[string]string{"error": "invalid_request", "state": "xyz"},
+		},
+		{
+			name: "Plain PKCE",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("code_challenge_method", "plain")
+				return query
+			},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: map[string]string{"error": "invalid_request", "state": "xyz"},
+		},
+		{
+			name: "Unsupported Response Type",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("response_type", "token")
+				return query
+			},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: map[string]string{"error": "unsupported_response_type", "state": "xyz"},
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("OIDC_CONSENT_URL", "https://auth.example.com/consent")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClientRepo := mocks.NewDefaultOAuthClientMock()
+			if tt.mockClientSetup != nil {
+				tt.mockClientSetup(mockClientRepo)
+			}
+			mockCodeRepo := mocks.NewDefaultAuthorizationCodeMock()
+			if tt.mockCodeSetup != nil {
+				tt.mockCodeSetup(mockCodeRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetQuery(ctx, tt.query())
+
+			handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mockClientRepo, mockCodeRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+			handler.AuthorizeHandler(ctx)
+			ctx.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedError != "" {
+				var response map[string]string
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedError, response["error"])
+				return
+			}
+
+			expectedHost := tt.expectedHost
+			if expectedHost == "" {
+				expectedHost = "app.example.com"
+			}
+			location, err := url.Parse(recorder.Header().Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, expectedHost, location.Host)
+			for key, value := range tt.expectedLocation {
+				assert.Equal(t, value, location.Query().Get(key))
+			}
+			if tt.expectedLocation["error"] == "" {
+				assert.NotEmpty(t, location.Query().Get("consent_challenge"))
+			}
+		})
+	}
+}
+
+func TestOIDCConsentInfoHandler(t *testing.T) {
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetQuery(ctx, url.Values{"consent_challenge": {"challenge"}})
+
+	handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mocks.NewDefaultAuthorizationCodeMock(), mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+	handler.ConsentInfoHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"client_id":"client","client_name":"Client","redirect_uri":"https://app.example.com/callback","scope":["openid"]}`, recorder.Body.String())
+
+	mockCodeRepo := mocks.NewDefaultAuthorizationCodeMock()
+	mockCodeRepo.GetAuthorizationRequestFunc = func(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+		return nil, storage.ErrAuthorizationRequestNotFound
+	}
+	ctx, recorder = testutils.NewTestContext()
+	testutils.SetQuery(ctx, url.Values{"consent_challenge": {"expired"}})
+
+	handler = NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mockCodeRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+	handler.ConsentInfoHandler(ctx)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestOIDCConsentHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		consumeErr       error
+		expectedStatus   int
+		expectedLocation map[string]string
+		expectCode       bool
+	}{
+		{
+			name:             "Approve",
+			body:             `{"consent_challenge":"challenge","approve":true}`,
+			expectedStatus:   http.StatusOK,
+			expectedLocation: map[string]string{"state": "xyz"},
+			expectCode:       true,
+		},
+		{
+			name:             "Deny",
+			body:             `{"consent_challenge":"challenge","approve":false}`,
+			expectedStatus:   http.StatusOK,
+			expectedLocation: map[string]string{"error": "access_denied", "state": "xyz"},
+		},
+		{
+			name:           "Missing Challenge",
+			body:           `{"approve":true}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Answered Challenge",
+			body:           `{"consent_challenge":"challenge","approve":true}`,
+			consumeErr:     storage.ErrAuthorizationRequestNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *models.AuthorizationCode
+			mockCodeRepo := mocks.NewDefaultAuthorizationCodeMock()
+			if tt.consumeErr != nil {
+				mockCodeRepo.ConsumeAuthorizationRequestFunc = func(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+					return nil, tt.consumeErr
+				}
+			}
+			mockCodeRepo.StoreAuthorizationCodeFunc = func(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error {
+				assert.Equal(t, authorizationCodeTTL, ttl)
+				stored = authCode
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+			ctx.Set("user_id", uint(1))
+			ctx.Set("session_id", "session")
+
+			handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mockCodeRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+			handler.ConsentHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectCode, stored != nil)
+			if tt.expectCode {
+				assert.Equal(t, uint(1), stored.UserID)
+				assert.Equal(t, "client", stored.ClientID)
+				assert.Equal(t, "challenge", stored.CodeChallenge)
+			}
+			if tt.expectedLocation == nil {
+				return
+			}
+
+			var response map[string]string
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			location, err := url.Parse(response["redirect_to"])
+			require.NoError(t, err)
+			assert.Equal(t, "app.example.com", location.Host)
+			for key, value := range tt.expectedLocation {
+				assert.Equal(t, value, location.Query().Get(key))
+			}
+			assert.Equal(t, tt.expectCode, location.Query().Get("code") != "")
+		})
+	}
+}
+
+func TestOIDCTokenHandler(t *testing.T) {
+	keyFile := writeEd25519KeyFile(t)
+
+	validForm := func() url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"code"},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"client_id":     {"client"},
+			"code_verifier": {testCodeVerifier},
+		}
+	}
+	authCode := func() *models.AuthorizationCode {
+		return &models.AuthorizationCode{
+			ClientID:            "client",
+			UserID:              1,
+			RedirectURI:         "https://app.example.com/callback",
+			Scope:               "openid email",
+			Nonce:               "n-0S6_WzA2Mj",
+			CodeChallenge:       testCodeChallenge(),
+			CodeChallengeMethod: "S256",
+			AuthTime:            time.Now(),
+		}
+	}
+	confidentialClient := func(mcr *mocks.MockOAuthClientRepository) {
+		mcr.GetClientByClientIDFunc = func(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+			client := &models.OAuthClient{ClientID: clientID, ClientSecret: "s3cret", RedirectURIs: []string{"https://app.example.com/callback"}}
+			require.NoError(t, client.HashSecret())
+			return client, nil
+		}
+	}
+
+	tests := []struct {
+		name            string
+		form            func() url.Values
+		basicAuth       []string
+		envSetup        func(*mocks.EnvMock)
+		mockClientSetup func(*mocks.MockOAuthClientRepository)
+		mockCodeSetup   func(*mocks.MockAuthorizationCodeRepository)
+		expectedStatus  int
+		expectedError   string
+		expectCodeKept  bool
+	}{
+		{
+			name: "Success",
+			form: validForm,
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+					return authCode(), nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "Confidential Client With Basic Auth",
+			form:            validForm,
+			basicAuth:       []string{"client", "s3cret"},
+			mockClientSetup: confidentialClient,
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+					return authCode(), nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "Confidential Client Wrong Secret",
+			form:            validForm,
+			basicAuth:       []string{"client", "wrong"},
+			mockClientSetup: confidentialClient,
+			expectedStatus:  http.StatusUnauthorized,
+			expectedError:   "invalid_client",
+		},
+		{
+			name: "Unsupported Grant Type",
+			form: func() url.Values {
+				form := validForm()
+				form.Set("grant_type", "password")
+				return form
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unsupported_grant_type",
+		},
+		{
+			name: "Unknown Code",
+			form: validForm,
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+					return nil, storage.ErrAuthorizationCodeNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+		{
+			name: "Wrong Code Verifier",
+			form: func() url.Values {
+				form := validForm()
+				form.Set("code_verifier", strings.Repeat("a", 43))
+				return form
+			},
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+					return authCode(), nil
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+		{
+			name: "Redirect URI Mismatch",
+			form: func() url.Values {
+				form := validForm()
+				form.Set("redirect_uri", "https://app.example.com/other")
+				return form
+			},
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+					return authCode(), nil
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+		{
+			name: "Symmetric Signing Key",
+			form: validForm,
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_PRIVATE_KEY_FILE", "")
+				em.Set("JWT_SECRET", "testsecret")
+			},
+			mockCodeSetup: func(mcr *mocks.MockAuthorizationCodeRepository) {
+				mcr.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+					return authCode(), nil
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "server_error",
+			expectCodeKept: true,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			mockEnv.Set("JWT_PRIVATE_KEY_FILE", keyFile)
+			mockEnv.Set("JWT_KEY_ID", "ed-key")
+			mockEnv.Set("OIDC_ISSUER", "https://auth.example.com")
+			if tt.envSetup != nil {
+				tt.envSetup(mockEnv)
+			}
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			mockClientRepo := mocks.NewDefaultOAuthClientMock()
+			if tt.mockClientSetup != nil {
+				tt.mockClientSetup(mockClientRepo)
+			}
+			mockCodeRepo := mocks.NewDefaultAuthorizationCodeMock()
+			if tt.mockCodeSetup != nil {
+				tt.mockCodeSetup(mockCodeRepo)
+			}
+			consumed := false
+			consume := mockCodeRepo.ConsumeAuthorizationCodeFunc
+			mockCodeRepo.ConsumeAuthorizationCodeFunc = func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+				consumed = true
+				return consume(ctx, code)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			form := tt.form()
+			if tt.basicAuth != nil {
+				form.Del("client_id")
+			}
+			testutils.SetFormBody(ctx, form)
+			if tt.basicAuth != nil {
+				ctx.Request.SetBasicAuth(tt.basicAuth[0], tt.basicAuth[1])
+			}
+
+			handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mockClientRepo, mockCodeRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+			handler.TokenHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+			if tt.expectCodeKept {
+				assert.False(t, consumed, "the code must stay usable")
+			}
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, response["error"])
+				return
+			}
+
+			assert.Equal(t, "Bearer", response["token_type"])
+			assert.Equal(t, "openid email", response["scope"])
+
+			accessToken, err := middleware.ParseToken(response["access_token"].(string))
+			require.NoError(t, err)
+			assert.Equal(t, "openid email", accessToken.Claims.(*middleware.Claims).Scope)
+
+			ring, err := middleware.LoadKeyring()
+			require.NoError(t, err)
+			claims := &idTokenClaims{}
+			_, err = jwt.ParseWithClaims(response["id_token"].(string), claims, func(token *jwt.Token) (interface{}, error) {
+				assert.Equal(t, "ed-key", token.Header["kid"])
+				return ring.Active().Public, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "https://auth.example.com", claims.Issuer)
+			assert.Equal(t, "1", claims.Subject)
+			assert.Equal(t, jwt.ClaimStrings{"client"}, claims.Audience)
+			assert.Equal(t, "n-0S6_WzA2Mj", claims.Nonce)
+			assert.Equal(t, "test@example.com", claims.Email)
+			assert.Empty(t, claims.PreferredUsername)
+		})
+	}
+}
+
+func TestOIDCClientCredentialsGrant(t *testing.T) {
+	scopedAccount := func(msr *mocks.MockServiceAccountRepository) {
+		msr.GetServiceAccountByClientIDFunc = func(ctx context.Context, clientID string) (*models.ServiceAccount, error) {
+			account := &models.ServiceAccount{ID: 7, ClientID: clientID, ClientSecret: "service-secret", Scope: "reports:read reports:write"}
+			require.NoError(t, account.HashSecret())
+			return account, nil
+		}
+	}
+
+	tests := []struct {
+		name             string
+		form             url.Values
+		basicAuth        []string
+		mockServiceSetup func(*mocks.MockServiceAccountRepository)
+		expectedStatus   int
+		expectedError    string
+		expectedScope    string
+	}{
+		{
+			name:             "Success With Basic Auth",
+			form:             url.Values{"grant_type": {"client_credentials"}},
+			basicAuth:        []string{"batch", "service-secret"},
+			mockServiceSetup: scopedAccount,
+			expectedStatus:   http.StatusOK,
+			expectedScope:    "reports:read reports:write",
+		},
+		{
+			name:             "Success With Narrowed Scope",
+			form:             url.Values{"grant_type": {"client_credentials"}, "client_id": {"batch"}, "client_secret": {"service-secret"}, "scope": {"reports:read"}},
+			mockServiceSetup: scopedAccount,
+			expectedStatus:   http.StatusOK,
+			expectedScope:    "reports:read",
+		},
+		{
+			name:             "Scope Not Allowed",
+			form:             url.Values{"grant_type": {"client_credentials"}, "client_id": {"batch"}, "client_secret": {"service-secret"}, "scope": {"admin"}},
+			mockServiceSetup: scopedAccount,
+			expectedStatus:   http.StatusBadRequest,
+			expectedError:    "invalid_scope",
+		},
+		{
+			name:           "Wrong Secret",
+			form:           url.Values{"grant_type": {"client_credentials"}},
+			basicAuth:      []string{"batch", "wrong"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name: "Unknown Service Account",
+			form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"missing"}, "client_secret": {"service-secret"}},
+			mockServiceSetup: func(msr *mocks.MockServiceAccountRepository) {
+				msr.GetServiceAccountByClientIDFunc = func(ctx context.Context, clientID string) (*models.ServiceAccount, error) {
+					return nil, storage.ErrServiceAccountNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			mockEnv.Set("JWT_SECRET", "testsecret")
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			mockServiceRepo := mocks.NewDefaultServiceAccountMock()
+			if tt.mockServiceSetup != nil {
+				tt.mockServiceSetup(mockServiceRepo)
+			}
+			var createdSession *models.Session
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.CreateSessionFunc = func(ctx context.Context, session *models.Session) error {
+				createdSession = session
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetFormBody(ctx, tt.form)
+			if tt.basicAuth != nil {
+				ctx.Request.SetBasicAuth(tt.basicAuth[0], tt.basicAuth[1])
+			}
+
+			handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mocks.NewDefaultAuthorizationCodeMock(), mockSessRepo, mockServiceRepo)
+			handler.TokenHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, response["error"])
+				assert.Nil(t, createdSession)
+				return
+			}
+
+			assert.Equal(t, tt.expectedScope, response["scope"])
+			assert.NotContains(t, response, "refresh_token")
+			assert.NotContains(t, response, "id_token")
+
+			token, err := middleware.ParseToken(response["access_token"].(string))
+			require.NoError(t, err)
+			claims := token.Claims.(*middleware.Claims)
+			assert.Equal(t, middleware.SubjectTypeService, claims.Subject())
+			assert.Equal(t, uint(7), claims.ServiceAccountID)
+			assert.Equal(t, uint(0), claims.UserID)
+
+			require.NotNil(t, createdSession)
+			assert.Equal(t, uint(7), createdSession.ServiceAccountID)
+			assert.Equal(t, uint(0), createdSession.UserID)
+		})
+	}
+}
+
+func TestOIDCUserInfoHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		scope          string
+		expectedStatus int
+		expectedClaims map[string]interface{}
+	}{
+		{
+			name:           "Openid Only",
+			scope:          "openid",
+			expectedStatus: http.StatusOK,
+			expectedClaims: map[string]interface{}{"sub": "1"},
+		},
+		{
+			name:           "Profile And Email",
+			scope:          "openid profile email",
+			expectedStatus: http.StatusOK,
+			expectedClaims: map[string]interface{}{"sub": "1", "preferred_username": "testuser", "email": "test@example.com", "email_verified": false},
+		},
+		{
+			name:           "First Party Token",
+			expectedStatus: http.StatusOK,
+			expectedClaims: map[string]interface{}{"sub": "1", "preferred_username": "testuser", "email": "test@example.com", "email_verified": false},
+		},
+		{
+			name:           "Missing Openid Scope",
+			scope:          "profile",
+			expectedStatus: http.StatusForbidden,
+			expectedClaims: map[string]interface{}{"error": "insufficient_scope", "error_description": "The openid scope is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			ctx.Set("scope", tt.scope)
+
+			handler := NewOIDCHandler(mocks.NewDefaultUserMock(), mocks.NewDefaultOAuthClientMock(), mocks.NewDefaultAuthorizationCodeMock(), mocks.NewDefaultSessionsMock(), mocks.NewDefaultServiceAccountMock())
+			handler.UserInfoHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedClaims, response)
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationsHandler struct {
+	orgRepo     storage.OrganizationRepository
+	userRepo    storage.UserRepository
+	sessRepo    storage.SessionsRepository
+	refreshRepo storage.RefreshTokenRepository
+	rbac        *middleware.RBAC
+}
+
+func NewOrganizationsHandler(orgRepo storage.OrganizationRepository, userRepo storage.UserRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, rbac *middleware.RBAC) *OrganizationsHandler {
+	return &OrganizationsHandler{
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		sessRepo:    sessRepo,
+		refreshRepo: refreshRepo,
+		rbac:        rbac,
+	}
+}
+
+// @Summary Create an organization
+// @Description Create an organization. The caller becomes its owner
+// @Tags organizations
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param organization body models.CreateOrganizationRequest true "Slug and name"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations [post]
+func (orgs *OrganizationsHandler) CreateHandler(ctx *gin.Context) {
+	var request models.CreateOrganizationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := models.ValidateSlug(request.Slug); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	organization := &models.Organization{
+		Slug: request.Slug,
+		Name: request.Name,
+	}
+	if err := orgs.orgRepo.CreateOrganization(ctx.Request.Context(), organization, ctx.GetUint("user_id")); err != nil {
+		if errors.Is(err, storage.ErrOrganizationExists) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating organization: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"organization": organization,
+	})
+}
+
+// @Summary List organizations
+// @Description List the organizations the caller is a member of, with the caller's role in each
+// @Tags organizations
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations [get]
+func (orgs *OrganizationsHandler) ListHandler(ctx *gin.Context) {
+	members, err := orgs.orgRepo.ListUserOrganizations(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing organizations: " + err.Error(),
+		})
+		return
+	}
+
+	response := make([]gin.H, 0, len(members))
+	for _, member := range members {
+		if member.Organization == nil {
+			continue
+		}
+		response = append(response, gin.H{
+			"id":   member.Organization.ID,
+			"slug": member.Organization.Slug,
+			"name": member.Organization.Name,
+			"role": member.Role,
+		})
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"organizations": response,
+	})
+}
+
+// @Summary Get an organization token
+// @Description Open a session within an organization the caller is a member of and return an access token with an org_id claim and the caller's role in it, and a refresh token. Organization tokens only see the members and sessions of their organization
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/{id}/token [post]
+func (orgs *OrganizationsHandler) TokenHandler(ctx *gin.Context) {
+	organizationID, ok := organizationIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Non-members learn nothing about the organization.
+	member, err := orgs.orgRepo.GetMembership(ctx.Request.Context(), organizationID, ctx.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrMembershipNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": storage.ErrOrganizationNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving membership",
+		})
+		return
+	}
+
+	user, err := orgs.userRepo.GetUserByID(storage.WithOrganization(ctx.Request.Context(), organizationID), member.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+
+	tokens, err := issueMemberTokens(ctx, orgs.sessRepo, orgs.refreshRepo, user, member)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating session: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    int(middleware.AccessTokenTTL.Seconds()),
+		"org_id":        organizationID,
+		"role":          member.Role,
+	})
+}
+
+// @Summary List members
+// @Description List the members of an organization with their roles. Requires an organization token for it and the members:read permission in it
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/{id}/members [get]
+func (orgs *OrganizationsHandler) ListMembersHandler(ctx *gin.Context) {
+	organizationID, ok := organizationIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	members, err := orgs.orgRepo.ListMembers(ctx.Request.Context(), organizationID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing members: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"members": members,
+	})
+}
+
+// @Summary Invite a member
+// @Description Invite a user to an organization with an organization role, org_member unless given. The user joins once they accept. The role may not grant more than the caller's own roles in the organization. Requires an organization token for it and the members:write permission in it
+// @Tags organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param invitation body models.InviteMemberRequest true "User ID and optional role"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/{id}/invitations [post]
+func (orgs *OrganizationsHandler) InviteHandler(ctx *gin.Context) {
+	organizationID, ok := organizationIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request models.InviteMemberRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if request.Role == "" {
+		request.Role = models.OrganizationMemberRole
+	}
+	if !orgs.checkGrantableRole(ctx, request.Role) {
+		return
+	}
+
+	invitation := &models.OrganizationInvitation{
+		OrganizationID: organizationID,
+		UserID:         request.UserID,
+		Role:           request.Role,
+		InvitedBy:      ctx.GetUint("user_id"),
+		ExpiresAt:      time.Now().Add(invitationTTL),
+	}
+	if err := orgs.orgRepo.InviteMember(ctx.Request.Context(), invitation); err != nil {
+		respondMemberError(ctx, err, "Error inviting member: ")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+	})
+}
+
+// @Summary List organization invitations
+// @Description List the caller's unexpired invitations to join organizations
+// @Tags organizations
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/invitations [get]
+func (orgs *OrganizationsHandler) ListInvitationsHandler(ctx *gin.Context) {
+	invitations, err := orgs.orgRepo.ListUserInvitations(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing invitations: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+	})
+}
+
+// @Summary Accept an organization invitation
+// @Description Join the organization of one of the caller's invitations with its role
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param invitation_id path int true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/invitations/{invitation_id}/accept [post]
+func (orgs *OrganizationsHandler) AcceptInvitationHandler(ctx *gin.Context) {
+	invitationID, ok := organizationInvitationIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	member, err := orgs.orgRepo.AcceptMemberInvitation(ctx.Request.Context(), invitationID, ctx.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrInvitationInvalid) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		respondMemberError(ctx, err, "Error accepting invitation: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Invitation accepted",
+		"org_id":  member.OrganizationID,
+		"role":    member.Role,
+	})
+}
+
+// @Summary Decline an organization invitation
+// @Description Delete one of the caller's invitations to join an organization
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param invitation_id path int true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/invitations/{invitation_id} [delete]
+func (orgs *OrganizationsHandler) DeclineInvitationHandler(ctx *gin.Context) {
+	invitationID, ok := organizationInvitationIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := orgs.orgRepo.DeclineMemberInvitation(ctx.Request.Context(), invitationID, ctx.GetUint("user_id")); err != nil {
+		if errors.Is(err, storage.ErrInvitationNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error declining invitation: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Invitation declined",
+	})
+}
+
+// @Summary Change the role of a member
+// @Description Change the role of a member to another organization role. The role applies from the member's next organization token or token refresh on. Neither the old nor the new role may grant more than the caller's own roles in the organization. Requires an organization token for it and the members:write permission in it
+// @Tags organizations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Param member body models.SetMemberRequest true "Role name, such as org_member or org_owner"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/{id}/members/{user_id} [put]
+func (orgs *OrganizationsHandler) SetMemberHandler(ctx *gin.Context) {
+	organizationID, ok := organizationIDParam(ctx)
+	if !ok {
+		return
+	}
+	userID, ok := memberIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request models.SetMemberRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !orgs.checkGrantableRole(ctx, request.Role) || !orgs.checkMemberRoleCovered(ctx, organizationID, userID) {
+		return
+	}
+
+	if err := orgs.orgRepo.SetMemberRole(ctx.Request.Context(), organizationID, userID, request.Role); err != nil {
+		respondMemberError(ctx, err, "Error setting member: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Member saved",
+		"role":    request.Role,
+	})
+}
+
+// @Summary Remove a member
+// @Description Remove a user from an organization and sign out their sessions in it. The member's role may not grant more than the caller's own roles in the organization. Requires an organization token for it and the members:write permission in it
+// @Tags organizations
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /organizations/{id}/members/{user_id} [delete]
+func (orgs *OrganizationsHandler) RemoveMemberHandler(ctx *gin.Context) {
+	organizationID, ok := organizationIDParam(ctx)
+	if !ok {
+		return
+	}
+	userID, ok := memberIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if !orgs.checkMemberRoleCovered(ctx, organizationID, userID) {
+		return
+	}
+
+	if err := orgs.orgRepo.RemoveMember(ctx.Request.Context(), organizationID, userID); err != nil {
+		respondMemberError(ctx, err, "Error removing member: ")
+		return
+	}
+
+	// Only the sessions within this organization end; refreshing their
+	// tokens fails once the session is gone.
+	if err := orgs.sessRepo.DeleteUserSessions(storage.WithOrganization(ctx.Request.Context(), organizationID), userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting sessions: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Member removed",
+	})
+}
+
+// checkGrantableRole rejects roles that are no organization roles or that
+// grant more than the caller's own roles in the organization, so that a
+// member cannot hand out more power than they hold.
+func (orgs *OrganizationsHandler) checkGrantableRole(ctx *gin.Context, role string) bool {
+	if err := models.ValidateOrganizationRole(role); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+	return orgs.checkRoleCovered(ctx, role)
+}
+
+// checkMemberRoleCovered rejects changes to members whose current role grants
+// more than the caller's own roles in the organization.
+func (orgs *OrganizationsHandler) checkMemberRoleCovered(ctx *gin.Context, organizationID uint, userID uint) bool {
+	member, err := orgs.orgRepo.GetMembership(ctx.Request.Context(), organizationID, userID)
+	if err != nil {
+		respondMemberError(ctx, err, "Error retrieving membership: ")
+		return false
+	}
+	return orgs.checkRoleCovered(ctx, member.Role)
+}
+
+func (orgs *OrganizationsHandler) checkRoleCovered(ctx *gin.Context, role string) bool {
+	covered, err := orgs.rbac.RolesCoverRole(ctx.Request.Context(), ctx.GetStringSlice("organization_roles"), role)
+	if err != nil {
+		respondMemberError(ctx, err, "Error checking role: ")
+		return false
+	}
+	if !covered {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": "Role " + role + " grants more than your own roles",
+		})
+		return false
+	}
+	return true
+}
+
+func organizationIDParam(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": storage.ErrOrganizationNotFound.Error(),
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func memberIDParam(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("user_id"), 10, 0)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": storage.ErrUserNotFound.Error(),
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func organizationInvitationIDParam(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("invitation_id"), 10, 0)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": storage.ErrInvitationNotFound.Error(),
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondMemberError(ctx *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrOrganizationNotFound), errors.Is(err, storage.ErrUserNotFound),
+		errors.Is(err, storage.ErrRoleNotFound), errors.Is(err, storage.ErrMembershipNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, storage.ErrLastOwner), errors.Is(err, storage.ErrMemberExists):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": message + err.Error(),
+		})
+	}
+}
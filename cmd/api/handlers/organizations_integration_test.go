@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationRepository(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+	bg := context.Background()
+
+	for _, name := range []string{models.OrganizationOwnerRole, models.OrganizationMemberRole} {
+		require.NoError(t, tx.Create(&models.Role{Name: name}).Error)
+	}
+	owner := &models.User{Username: "orgowner", Email: "orgowner@example.com", Password: "x"}
+	require.NoError(t, tx.Create(owner).Error)
+	outsider := &models.User{Username: "orgoutsider", Email: "orgoutsider@example.com", Password: "x"}
+	require.NoError(t, tx.Create(outsider).Error)
+
+	orgRepo := storage.NewGormOrganizationRepository(tx)
+	userRepo := storage.NewGormUserRepository(tx)
+
+	acme := &models.Organization{Slug: "acme", Name: "Acme"}
+	require.NoError(t, orgRepo.CreateOrganization(bg, acme, owner.ID))
+	assert.ErrorIs(t, orgRepo.CreateOrganization(bg, &models.Organization{Slug: "acme", Name: "Other"}, owner.ID), storage.ErrOrganizationExists)
+
+	member, err := orgRepo.GetMembership(bg, acme.ID, owner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrganizationOwnerRole, member.Role)
+	_, err = orgRepo.GetMembership(bg, acme.ID, outsider.ID)
+	assert.ErrorIs(t, err, storage.ErrMembershipNotFound)
+
+	memberships, err := orgRepo.ListUserOrganizations(bg, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	assert.Equal(t, "acme", memberships[0].Organization.Slug)
+
+	assert.ErrorIs(t, orgRepo.SetMemberRole(bg, acme.ID, owner.ID, models.OrganizationMemberRole), storage.ErrLastOwner)
+	assert.ErrorIs(t, orgRepo.RemoveMember(bg, acme.ID, owner.ID), storage.ErrLastOwner)
+	assert.ErrorIs(t, orgRepo.SetMemberRole(bg, acme.ID, outsider.ID, models.OrganizationMemberRole), storage.ErrMembershipNotFound)
+	invite := func(organizationID uint, userID uint, role string) *models.OrganizationInvitation {
+		return &models.OrganizationInvitation{OrganizationID: organizationID, UserID: userID, Role: role, InvitedBy: owner.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	}
+	assert.ErrorIs(t, orgRepo.InviteMember(bg, invite(acme.ID, outsider.ID, "overlord")), storage.ErrRoleNotFound)
+	assert.ErrorIs(t, orgRepo.InviteMember(bg, invite(acme.ID+1000, outsider.ID, models.OrganizationMemberRole)), storage.ErrOrganizationNotFound)
+	assert.ErrorIs(t, orgRepo.InviteMember(bg, invite(acme.ID, owner.ID, models.OrganizationMemberRole)), storage.ErrMemberExists)
+
+	// Users outside of the organization are not found within its scope.
+	scoped := storage.WithOrganization(bg, acme.ID)
+	_, err = userRepo.GetUserByID(scoped, outsider.ID)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+	_, err = userRepo.GetUserByIdentifier(scoped, "orgoutsider")
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+	assert.ErrorIs(t, userRepo.UpdatePassword(scoped, outsider.ID, "hash"), storage.ErrUserNotFound)
+	_, err = userRepo.GetUserByUsername(scoped, "orgowner")
+	assert.NoError(t, err)
+
+	// Invited users only join once they accept.
+	invitation := invite(acme.ID, outsider.ID, models.OrganizationMemberRole)
+	require.NoError(t, orgRepo.InviteMember(bg, invitation))
+	_, err = userRepo.GetUserByID(scoped, outsider.ID)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+	invitations, err := orgRepo.ListUserInvitations(bg, outsider.ID)
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, "acme", invitations[0].Organization.Slug)
+	_, err = orgRepo.AcceptMemberInvitation(bg, invitation.ID, owner.ID)
+	assert.ErrorIs(t, err, storage.ErrInvitationInvalid, "only the invited user accepts")
+
+	member, err = orgRepo.AcceptMemberInvitation(bg, invitation.ID, outsider.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrganizationMemberRole, member.Role)
+	_, err = orgRepo.AcceptMemberInvitation(bg, invitation.ID, outsider.ID)
+	assert.ErrorIs(t, err, storage.ErrInvitationInvalid)
+	_, err = userRepo.GetUserByID(scoped, outsider.ID)
+	assert.NoError(t, err)
+
+	require.NoError(t, orgRepo.SetMemberRole(bg, acme.ID, outsider.ID, models.OrganizationOwnerRole))
+	require.NoError(t, orgRepo.SetMemberRole(bg, acme.ID, owner.ID, models.OrganizationMemberRole), "another owner is left")
+	members, err := orgRepo.ListMembers(bg, acme.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, models.OrganizationMemberRole, members[0].Role)
+	assert.Equal(t, models.OrganizationOwnerRole, members[1].Role)
+
+	require.NoError(t, orgRepo.RemoveMember(bg, acme.ID, owner.ID))
+	assert.ErrorIs(t, orgRepo.RemoveMember(bg, acme.ID, owner.ID), storage.ErrMembershipNotFound)
+
+	// Users created within a scope join the organization.
+	created := &models.User{Username: "orgnewcomer", Email: "orgnewcomer@example.com", Password: "x"}
+	require.NoError(t, userRepo.CreateUser(scoped, created))
+	member, err = orgRepo.GetMembership(bg, acme.ID, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrganizationMemberRole, member.Role)
+}
+
+func TestSessionRepositoryOrganizationScope(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	defer sessRepo.DeleteUserSessions(bg, 85)
+
+	now := time.Now()
+	for _, session := range []*models.Session{
+		{ID: "tenant-global", UserID: 85},
+		{ID: "tenant-acme", UserID: 85, OrganizationID: 4},
+		{ID: "tenant-globex", UserID: 85, OrganizationID: 5},
+	} {
+		session.CreatedAt, session.LastSeen, session.ExpiresAt = now, now, now.Add(time.Minute)
+		require.NoError(t, sessRepo.CreateSession(bg, session))
+	}
+
+	acme := storage.WithOrganization(bg, 4)
+	sessions, err := sessRepo.ListUserSessions(acme, 85)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "tenant-acme", sessions[0].ID)
+
+	_, err = sessRepo.GetSessionByID(acme, "tenant-globex")
+	assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+	require.NoError(t, sessRepo.DeleteSession(acme, "tenant-globex"))
+	_, err = sessRepo.GetSessionByID(bg, "tenant-globex")
+	assert.NoError(t, err, "a session of another organization is not deleted")
+
+	require.NoError(t, sessRepo.DeleteUserSessions(acme, 85))
+	sessions, err = sessRepo.ListUserSessions(bg, 85)
+	require.NoError(t, err)
+	ids := []string{}
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	assert.ElementsMatch(t, []string{"tenant-global", "tenant-globex"}, ids)
+}
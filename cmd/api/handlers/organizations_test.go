@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOrganizationsHandler(orgRepo storage.OrganizationRepository, userRepo storage.UserRepository, sessRepo storage.SessionsRepository) *OrganizationsHandler {
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return []models.Role{
+			{Name: "admin", Permissions: []models.Permission{{Name: "*"}}},
+			{Name: models.OrganizationOwnerRole, Permissions: []models.Permission{{Name: "members:*"}, {Name: "organization:*"}}},
+			{Name: "org_admin", Permissions: []models.Permission{{Name: "members:*"}}},
+			{Name: models.OrganizationMemberRole, Permissions: []models.Permission{{Name: "members:read"}}},
+		}, nil
+	}
+	return NewOrganizationsHandler(orgRepo, userRepo, sessRepo, mocks.NewDefaultRefreshTokenMock(), middleware.NewRBAC(mockRoleRepo))
+}
+
+func TestOrganizationsCreateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		createErr      error
+		expectedStatus int
+	}{
+		{name: "Success", body: `{"slug":"acme","name":"Acme"}`, expectedStatus: http.StatusCreated},
+		{name: "Invalid Slug", body: `{"slug":"Acme Inc","name":"Acme"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Missing Name", body: `{"slug":"acme"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Slug Taken", body: `{"slug":"acme","name":"Acme"}`, createErr: storage.ErrOrganizationExists, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ownerID uint
+			mockOrgRepo := mocks.NewDefaultOrganizationMock()
+			mockOrgRepo.CreateOrganizationFunc = func(ctx context.Context, organization *models.Organization, owner uint) error {
+				ownerID = owner
+				organization.ID = 4
+				return tt.createErr
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(7))
+			testutils.SetJSONBody(ctx, tt.body)
+
+			newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mocks.NewDefaultSessionsMock()).CreateHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				assert.Equal(t, uint(7), ownerID)
+				assert.Contains(t, recorder.Body.String(), `"slug":"acme"`)
+			}
+		})
+	}
+}
+
+func TestOrganizationsListHandler(t *testing.T) {
+	mockOrgRepo := mocks.NewDefaultOrganizationMock()
+	mockOrgRepo.ListUserOrganizationsFunc = func(ctx context.Context, userID uint) ([]models.OrganizationMember, error) {
+		return []models.OrganizationMember{{
+			OrganizationID: 4,
+			UserID:         userID,
+			Role:           models.OrganizationOwnerRole,
+			Organization:   &models.Organization{ID: 4, Slug: "acme", Name: "Acme"},
+		}}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(7))
+
+	newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mocks.NewDefaultSessionsMock()).ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"organizations":[{"id":4,"slug":"acme","name":"Acme","role":"org_owner"}]}`, recorder.Body.String())
+}
+
+func TestOrganizationsTokenHandler(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	var session *models.Session
+	mockSessRepo := mocks.NewDefaultSessionsMock()
+	mockSessRepo.CreateSessionFunc = func(ctx context.Context, s *models.Session) error {
+		session = s
+		return nil
+	}
+	var scopedLookup bool
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+		organizationID, ok := storage.OrganizationFromContext(ctx)
+		scopedLookup = ok && organizationID == 4
+		return &models.User{ID: id, Roles: []models.Role{{Name: "support"}}}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(7))
+	ctx.Params = gin.Params{{Key: "id", Value: "4"}}
+
+	newTestOrganizationsHandler(mocks.NewDefaultOrganizationMock(), mockUserRepo, mockSessRepo).TokenHandler(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, scopedLookup, "the user is looked up within the organization")
+	require.NotNil(t, session)
+	assert.Equal(t, uint(4), session.OrganizationID)
+
+	var response struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		Role         string `json:"role"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Equal(t, models.OrganizationMemberRole, response.Role)
+	token, err := middleware.ParseToken(response.Token)
+	require.NoError(t, err)
+	claims := token.Claims.(*middleware.Claims)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, uint(4), claims.OrganizationID)
+	assert.Equal(t, []string{models.OrganizationMemberRole}, claims.OrganizationRoles)
+	assert.Empty(t, claims.Roles)
+}
+
+func TestOrganizationsTokenHandlerNotMember(t *testing.T) {
+	mockOrgRepo := mocks.NewDefaultOrganizationMock()
+	mockOrgRepo.GetMembershipFunc = func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+		return nil, storage.ErrMembershipNotFound
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(7))
+	ctx.Params = gin.Params{{Key: "id", Value: "4"}}
+
+	newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mocks.NewDefaultSessionsMock()).TokenHandler(ctx)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.JSONEq(t, `{"error":"Organization not found"}`, recorder.Body.String())
+}
+
+func TestOrganizationsInviteHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		callerRoles    []string
+		inviteErr      error
+		expectedStatus int
+		expectedRole   string
+	}{
+		{name: "Success", body: `{"user_id":9,"role":"org_admin"}`, callerRoles: []string{"org_owner"}, expectedStatus: http.StatusCreated, expectedRole: "org_admin"},
+		{name: "Member By Default", body: `{"user_id":9}`, callerRoles: []string{"org_admin"}, expectedStatus: http.StatusCreated, expectedRole: "org_member"},
+		{name: "Missing User", body: `{"role":"org_member"}`, callerRoles: []string{"org_owner"}, expectedStatus: http.StatusBadRequest},
+		{name: "Global Role", body: `{"user_id":9,"role":"admin"}`, callerRoles: []string{"org_owner"}, expectedStatus: http.StatusBadRequest},
+		{name: "Role Beyond Caller", body: `{"user_id":9,"role":"org_owner"}`, callerRoles: []string{"org_admin"}, expectedStatus: http.StatusForbidden},
+		{name: "Unknown Role", body: `{"user_id":9,"role":"org_overlord"}`, callerRoles: []string{"org_owner"}, expectedStatus: http.StatusNotFound},
+		{name: "Already A Member", body: `{"user_id":9}`, callerRoles: []string{"org_owner"}, inviteErr: storage.ErrMemberExists, expectedStatus: http.StatusConflict},
+		{name: "Unknown User", body: `{"user_id":9}`, callerRoles: []string{"org_owner"}, inviteErr: storage.ErrUserNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invited *models.OrganizationInvitation
+			mockOrgRepo := mocks.NewDefaultOrganizationMock()
+			mockOrgRepo.InviteMemberFunc = func(ctx context.Context, invitation *models.OrganizationInvitation) error {
+				if tt.inviteErr != nil {
+					return tt.inviteErr
+				}
+				invited = invitation
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(7))
+			ctx.Set("organization_roles", tt.callerRoles)
+			ctx.Params = gin.Params{{Key: "id", Value: "4"}}
+			testutils.SetJSONBody(ctx, tt.body)
+
+			newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mocks.NewDefaultSessionsMock()).InviteHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedRole == "" {
+				assert.Nil(t, invited)
+				return
+			}
+			require.NotNil(t, invited)
+			assert.Equal(t, uint(4), invited.OrganizationID)
+			assert.Equal(t, uint(9), invited.UserID)
+			assert.Equal(t, uint(7), invited.InvitedBy)
+			assert.Equal(t, tt.expectedRole, invited.Role)
+			assert.True(t, invited.ExpiresAt.After(time.Now()))
+		})
+	}
+}
+
+func TestOrganizationsAcceptInvitationHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		invitationID   string
+		acceptErr      error
+		expectedStatus int
+	}{
+		{name: "Success", invitationID: "3", expectedStatus: http.StatusOK},
+		{name: "Invalid ID", invitationID: "x", expectedStatus: http.StatusNotFound},
+		{name: "Not The Caller's Or Expired", invitationID: "3", acceptErr: storage.ErrInvitationInvalid, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgRepo := mocks.NewDefaultOrganizationMock()
+			mockOrgRepo.AcceptMemberInvitationFunc = func(ctx context.Context, id uint, userID uint) (*models.OrganizationMember, error) {
+				if id != 3 || userID != 7 {
+					t.Errorf("unexpected invitation %d of user %d", id, userID)
+				}
+				if tt.acceptErr != nil {
+					return nil, tt.acceptErr
+				}
+				return &models.OrganizationMember{OrganizationID: 4, UserID: userID, Role: models.OrganizationMemberRole}, nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(7))
+			ctx.Params = gin.Params{{Key: "invitation_id", Value: tt.invitationID}}
+
+			newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mocks.NewDefaultSessionsMock()).AcceptInvitationHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.JSONEq(t, `{"message":"Invitation accepted","org_id":4,"role":"org_member"}`, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestOrganizationsSetMemberHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		callerRoles    []string
+		currentRole    string
+		setErr         error
+		expectedStatus int
+		expectedRole   string
+	}{
+		{name: "Success", body: `{"role":"org_owner"}`, callerRoles: []string{"org_owner"}, currentRole: "org_member", expectedStatus: http.StatusOK, expectedRole: "org_owner"},
+		{name: "Missing Role", body: `{}`, callerRoles: []string{"org_owner"}, currentRole: "org_member", expectedStatus: http.StatusBadRequest},
+		{name: "Global Role", body: `{"role":"admin"}`, callerRoles: []string{"org_owner"}, currentRole: "org_member", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown Role", body: `{"role":"org_overlord"}`, callerRoles: []string{"org_owner"}, currentRole: "org_member", expectedStatus: http.StatusNotFound},
+		{name: "Role Beyond Caller", body: `{"role":"org_owner"}`, callerRoles: []string{"org_admin"}, currentRole: "org_member", expectedStatus: http.StatusForbidden},
+		{name: "Demoting A Stronger Member", body: `{"role":"org_member"}`, callerRoles: []string{"org_admin"}, currentRole: "org_owner", expectedStatus: http.StatusForbidden},
+		{name: "Not A Member", body: `{"role":"org_member"}`, callerRoles: []string{"org_owner"}, expectedStatus: http.StatusNotFound},
+		{name: "Last Owner", body: `{"role":"org_member"}`, callerRoles: []string{"org_owner"}, currentRole: "org_owner", setErr: storage.ErrLastOwner, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var role string
+			mockOrgRepo := mocks.NewDefaultOrganizationMock()
+			mockOrgRepo.GetMembershipFunc = func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+				if tt.currentRole == "" {
+					return nil, storage.ErrMembershipNotFound
+				}
+				return &models.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: tt.currentRole}, nil
+			}
+			mockOrgRepo.SetMemberRoleFunc = func(ctx context.Context, organizationID uint, userID uint, r string) error {
+				if organizationID != 4 || userID != 9 {
+					t.Errorf("unexpected member %d of %d", userID, organizationID)
+				}
+				if tt.setErr != nil {
+					return tt.setErr
+				}
+				role = r
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("organization_roles", tt.callerRoles)
+			ctx.Params = gin.Params{{Key: "id", Value: "4"}, {Key: "user_id", Value: "9"}}
+			testutils.SetJSONBody(ctx, tt.body)
+
+			newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mocks.NewDefaultSessionsMock()).SetMemberHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedRole, role)
+		})
+	}
+}
+
+func TestOrganizationsRemoveMemberHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		callerRoles     []string
+		currentRole     string
+		removeErr       error
+		expectedStatus  int
+		expectSignedOut bool
+	}{
+		{name: "Success", callerRoles: []string{"org_admin"}, currentRole: "org_member", expectedStatus: http.StatusOK, expectSignedOut: true},
+		{name: "Not A Member", callerRoles: []string{"org_owner"}, expectedStatus: http.StatusNotFound},
+		{name: "Stronger Member", callerRoles: []string{"org_admin"}, currentRole: "org_owner", expectedStatus: http.StatusForbidden},
+		{name: "Last Owner", callerRoles: []string{"org_owner"}, currentRole: "org_owner", removeErr: storage.ErrLastOwner, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgRepo := mocks.NewDefaultOrganizationMock()
+			mockOrgRepo.GetMembershipFunc = func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+				if tt.currentRole == "" {
+					return nil, storage.ErrMembershipNotFound
+				}
+				return &models.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: tt.currentRole}, nil
+			}
+			mockOrgRepo.RemoveMemberFunc = func(ctx context.Context, organizationID uint, userID uint) error {
+				return tt.removeErr
+			}
+			var signedOut bool
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.DeleteUserSessionsFunc = func(ctx context.Context, userID uint) error {
+				organizationID, ok := storage.OrganizationFromContext(ctx)
+				signedOut = ok && organizationID == 4 && userID == 9
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("organization_roles", tt.callerRoles)
+			ctx.Params = gin.Params{{Key: "id", Value: "4"}, {Key: "user_id", Value: "9"}}
+
+			newTestOrganizationsHandler(mockOrgRepo, mocks.NewDefaultUserMock(), mockSessRepo).RemoveMemberHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectSignedOut, signedOut, "only the sessions within the organization end")
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	// passwordResetInterval is the minimum time between two reset mails to
+	// the same address. Requests in between are answered but not mailed.
+	passwordResetInterval    = time.Minute
+	passwordResetMailTimeout = 30 * time.Second
+)
+
+type PasswordResetHandler struct {
+	userRepo       storage.UserRepository
+	resetRepo      storage.PasswordResetRepository
+	sessRepo       storage.SessionsRepository
+	refreshRepo    storage.RefreshTokenRepository
+	mailer         mailer.Mailer
+	passwordPolicy *password.Policy
+}
+
+func NewPasswordResetHandler(userRepo storage.UserRepository, resetRepo storage.PasswordResetRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, mail mailer.Mailer, passwordPolicy *password.Policy) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		userRepo:       userRepo,
+		resetRepo:      resetRepo,
+		sessRepo:       sessRepo,
+		refreshRepo:    refreshRepo,
+		mailer:         mail,
+		passwordPolicy: passwordPolicy,
+	}
+}
+
+// @Summary Request password reset
+// @Description Mail a single-use password reset link. The response is the same whether or not the address belongs to an account
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body models.PasswordForgotRequest true "Email address of the account"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /password/forgot [post]
+func (reset *PasswordResetHandler) ForgotHandler(ctx *gin.Context) {
+	var request models.PasswordForgotRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Failures past this point are only logged: any other answer would tell
+	// known addresses apart from unknown ones.
+	user, err := reset.userRepo.GetUserByEmail(ctx.Request.Context(), request.Email)
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+	case err != nil:
+		log.Printf("Error retrieving user for password reset: %v", err)
+	default:
+		// Delivery runs in the background so that the response time does not
+		// depend on whether a mail is sent.
+		go reset.sendPasswordReset(context.WithoutCancel(ctx.Request.Context()), user)
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address belongs to an account, a password reset link has been sent",
+	})
+}
+
+// @Summary Reset password
+// @Description Set a new password with the token from a password reset mail. The token is consumed and all sessions of the user are revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body models.PasswordResetRequest true "Reset token and new password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /password/reset [post]
+func (reset *PasswordResetHandler) ResetHandler(ctx *gin.Context) {
+	var request models.PasswordResetRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	userID, err := reset.resetRepo.GetPasswordReset(ctx.Request.Context(), request.Token)
+	if err != nil {
+		reset.tokenError(ctx, err)
+		return
+	}
+
+	user, err := reset.userRepo.GetUserByID(ctx.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": storage.ErrPasswordResetNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+
+	// Validate before consuming, so that a rejected password does not burn the token.
+	if !checkPasswordPolicy(ctx, reset.passwordPolicy, password.Candidate{
+		Password: request.NewPassword,
+		Username: user.Username,
+		Email:    user.Email,
+	}) {
+		return
+	}
+
+	if _, err := reset.resetRepo.ConsumePasswordReset(ctx.Request.Context(), request.Token); err != nil {
+		reset.tokenError(ctx, err)
+		return
+	}
+
+	user.Password = request.NewPassword
+	if err := user.HashPassword(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error hashing password",
+		})
+		return
+	}
+
+	if err := reset.userRepo.UpdatePassword(ctx.Request.Context(), user.ID, user.Password); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error updating password: " + err.Error(),
+		})
+		return
+	}
+
+	if err := reset.sessRepo.DeleteUserSessions(ctx.Request.Context(), user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting sessions: " + err.Error(),
+		})
+		return
+	}
+
+	if err := reset.refreshRepo.RevokeUserRefreshTokens(ctx.Request.Context(), user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking refresh tokens: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password updated, all sessions have been revoked",
+	})
+}
+
+func (reset *PasswordResetHandler) tokenError(ctx *gin.Context, err error) {
+	if errors.Is(err, storage.ErrPasswordResetNotFound) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Error retrieving reset token: " + err.Error(),
+	})
+}
+
+func (reset *PasswordResetHandler) sendPasswordReset(ctx context.Context, user *models.User) {
+	ctx, cancel := context.WithTimeout(ctx, passwordResetMailTimeout)
+	defer cancel()
+
+	allowed, err := reset.resetRepo.ThrottlePasswordReset(ctx, strings.ToLower(user.Email), passwordResetInterval)
+	if err != nil {
+		log.Printf("Error throttling password reset for user %d: %v", user.ID, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	token, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("Error generating password reset token: %v", err)
+		return
+	}
+
+	if err := reset.resetRepo.StorePasswordReset(ctx, token, user.ID, passwordResetTTL); err != nil {
+		log.Printf("Error storing password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	err = reset.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nuse this link within one hour to choose a new password:\n\n%s?token=%s\n\nIf you did not ask for a password reset, you can ignore this mail.\n",
+			user.Username, passwordResetURL(), token),
+	})
+	if err != nil {
+		log.Printf("Error sending password reset mail to user %d: %v", user.ID, err)
+	}
+}
+
+// CheckPasswordResetURL fails when mail is delivered to real inboxes but
+// PASSWORD_RESET_URL is unset. The default points at POST /password/reset,
+// which a mail client cannot open.
+func CheckPasswordResetURL(mail mailer.Mailer) error {
+	return requireLinkURL(mail, "PASSWORD_RESET_URL")
+}
+
+func passwordResetURL() string {
+	if url := os.Getenv("PASSWORD_RESET_URL"); url != "" {
+		return url
+	}
+	return oidcIssuer() + "/password/reset"
+}
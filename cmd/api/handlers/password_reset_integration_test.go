@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetLifecycle(t *testing.T) {
+	bg := context.Background()
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+
+	user := &models.User{
+		Username: "resetuser",
+		Email:    "resetuser@example.com",
+		Password: "oldpassword",
+	}
+	require.NoError(t, user.HashPassword())
+	require.NoError(t, tx.Create(user).Error)
+
+	userRepo := storage.NewGormUserRepository(tx)
+	resetRepo := storage.NewRedisPasswordResetRepository(testutils.TestRedis)
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer testutils.TestRedis.Del(bg, "password_reset_throttle:resetuser@example.com")
+	defer refreshRepo.RevokeUserRefreshTokens(bg, user.ID)
+	defer sessRepo.DeleteUserSessions(bg, user.ID)
+	mail := mailer.NewMemoryMailer()
+	handler := NewPasswordResetHandler(userRepo, resetRepo, sessRepo, refreshRepo, mail, password.DefaultPolicy())
+
+	require.NoError(t, sessRepo.CreateSession(bg, &models.Session{
+		ID:        "reset-session",
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	ctx, recorder := testutils.NewTestContext()
+	// Addresses are looked up regardless of case.
+	testutils.SetJSONBody(ctx, `{"email":"ResetUser@Example.com"}`)
+	handler.ForgotHandler(ctx)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.Eventually(t, func() bool { return len(mail.Messages()) == 1 }, time.Second, 5*time.Millisecond)
+
+	sent, _ := mail.Last()
+	match := regexp.MustCompile(`\?token=(\S+)`).FindStringSubmatch(sent.Body)
+	require.Len(t, match, 2)
+
+	resetPassword := func(token string) int {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"token":"`+token+`","new_password":"newpassword"}`)
+		handler.ResetHandler(ctx)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, resetPassword(match[1]))
+	assert.Equal(t, http.StatusBadRequest, resetPassword(match[1]))
+
+	stored, err := userRepo.GetUserByID(bg, user.ID)
+	require.NoError(t, err)
+	assert.NoError(t, stored.CheckPassword("newpassword"))
+	assert.Equal(t, "resetuser@example.com", stored.Email)
+
+	sessions, err := sessRepo.ListUserSessions(bg, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetForgotHandler(t *testing.T) {
+	const expectedBody = `{"message":"If the address belongs to an account, a password reset link has been sent"}`
+
+	tests := []struct {
+		name           string
+		body           string
+		mockUserSetup  func(*mocks.MockUserRepository)
+		mockResetSetup func(*mocks.MockPasswordResetRepository)
+		expectedStatus int
+		expectMail     bool
+	}{
+		{
+			name:           "Known Email",
+			body:           `{"email":"test@example.com"}`,
+			expectedStatus: http.StatusAccepted,
+			expectMail:     true,
+		},
+		{
+			name: "Unknown Email",
+			body: `{"email":"nobody@example.com"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+					return nil, storage.ErrUserNotFound
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "Database Error",
+			body: `{"email":"test@example.com"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByEmailFunc = func(ctx context.Context, email string) (*models.User, error) {
+					return nil, errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Missing Email",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := mocks.NewDefaultUserMock()
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+			mail := mailer.NewMemoryMailer()
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewPasswordResetHandler(mockUserRepo, mocks.NewDefaultPasswordResetMock(), mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mail, password.DefaultPolicy())
+			handler.ForgotHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusAccepted {
+				assert.JSONEq(t, expectedBody, recorder.Body.String())
+			}
+			if tt.expectMail {
+				assert.Eventually(t, func() bool { return len(mail.Messages()) == 1 }, time.Second, 5*time.Millisecond)
+			} else {
+				assert.Empty(t, mail.Messages())
+			}
+		})
+	}
+}
+
+func TestPasswordResetSendPasswordReset(t *testing.T) {
+	var storedToken string
+	mockResetRepo := mocks.NewDefaultPasswordResetMock()
+	mockResetRepo.StorePasswordResetFunc = func(ctx context.Context, token string, userID uint, ttl time.Duration) error {
+		assert.Equal(t, uint(5), userID)
+		assert.Equal(t, passwordResetTTL, ttl)
+		storedToken = token
+		return nil
+	}
+	mail := mailer.NewMemoryMailer()
+	handler := NewPasswordResetHandler(mocks.NewDefaultUserMock(), mockResetRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mail, password.DefaultPolicy())
+
+	handler.sendPasswordReset(context.Background(), &models.User{ID: 5, Username: "alice", Email: "alice@example.com"})
+
+	sent, ok := mail.Last()
+	require.True(t, ok)
+	assert.Equal(t, "alice@example.com", sent.To)
+	require.NotEmpty(t, storedToken)
+	assert.True(t, strings.Contains(sent.Body, "?token="+storedToken))
+
+	mockResetRepo.ThrottlePasswordResetFunc = func(ctx context.Context, email string, interval time.Duration) (bool, error) {
+		return email != "alice@example.com", nil
+	}
+	handler.sendPasswordReset(context.Background(), &models.User{ID: 5, Username: "alice", Email: "Alice@Example.com"})
+	assert.Len(t, mail.Messages(), 1, "throttled requests must not send mail")
+}
+
+func TestCheckPasswordResetURL(t *testing.T) {
+	smtpMailer := mailer.NewSMTPMailer("smtp.example.com", "587", "", "", "noreply@example.com")
+
+	t.Setenv("PASSWORD_RESET_URL", "")
+	assert.NoError(t, CheckPasswordResetURL(mailer.NewMemoryMailer()))
+	assert.ErrorContains(t, CheckPasswordResetURL(smtpMailer), "PASSWORD_RESET_URL")
+
+	t.Setenv("PASSWORD_RESET_URL", "https://app.example.com/reset-password")
+	assert.NoError(t, CheckPasswordResetURL(smtpMailer))
+}
+
+func TestPasswordResetResetHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockResetSetup func(*mocks.MockPasswordResetRepository)
+		mockUserSetup  func(*mocks.MockUserRepository)
+		expectedStatus int
+		expectedBody   string
+		expectRevoked  bool
+		expectConsumed bool
+	}{
+		{
+			name:           "Success",
+			body:           `{"token":"reset-token","new_password":"newpassword"}`,
+			expectedStatus: http.StatusOK,
+			expectRevoked:  true,
+			expectConsumed: true,
+		},
+		{
+			name:           "Password Too Short",
+			body:           `{"token":"reset-token","new_password":"short"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Password must be at least 8 characters","violations":[{"code":"min_length","message":"Password must be at least 8 characters"}]}`,
+		},
+		{
+			name:           "Password Contains Username",
+			body:           `{"token":"reset-token","new_password":"TestUser2024"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Password must not contain the username","violations":[{"code":"contains_user_input","message":"Password must not contain the username"}]}`,
+		},
+		{
+			name: "Invalid Token",
+			body: `{"token":"used-token","new_password":"newpassword"}`,
+			mockResetSetup: func(mrr *mocks.MockPasswordResetRepository) {
+				mrr.GetPasswordResetFunc = func(ctx context.Context, token string) (uint, error) {
+					return 0, storage.ErrPasswordResetNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"` + storage.ErrPasswordResetNotFound.Error() + `"}`,
+		},
+		{
+			name: "User Deleted",
+			body: `{"token":"reset-token","new_password":"newpassword"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+					return nil, storage.ErrUserNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"` + storage.ErrPasswordResetNotFound.Error() + `"}`,
+		},
+		{
+			name:           "Missing Token",
+			body:           `{"new_password":"newpassword"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updatedHash string
+			var consumed, sessionsDeleted, refreshRevoked bool
+
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.UpdatePasswordFunc = func(ctx context.Context, id uint, passwordHash string) error {
+				updatedHash = passwordHash
+				return nil
+			}
+			mockResetRepo := mocks.NewDefaultPasswordResetMock()
+			mockResetRepo.ConsumePasswordResetFunc = func(ctx context.Context, token string) (uint, error) {
+				consumed = true
+				return 1, nil
+			}
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.DeleteUserSessionsFunc = func(ctx context.Context, userID uint) error {
+				sessionsDeleted = true
+				return nil
+			}
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+			mockRefreshRepo.RevokeUserRefreshTokensFunc = func(ctx context.Context, userID uint) error {
+				refreshRevoked = true
+				return nil
+			}
+			if tt.mockResetSetup != nil {
+				tt.mockResetSetup(mockResetRepo)
+			}
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewPasswordResetHandler(mockUserRepo, mockResetRepo, mockSessRepo, mockRefreshRepo, mailer.NewMemoryMailer(), password.DefaultPolicy())
+			handler.ResetHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+			assert.Equal(t, tt.expectConsumed, consumed)
+			assert.Equal(t, tt.expectRevoked, sessionsDeleted)
+			assert.Equal(t, tt.expectRevoked, refreshRevoked)
+			if tt.expectRevoked {
+				assert.NoError(t, (&models.User{Password: updatedHash}).CheckPassword("newpassword"))
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PoliciesHandler struct {
+	authorizer *middleware.Authorizer
+}
+
+func NewPoliciesHandler(authorizer *middleware.Authorizer) *PoliciesHandler {
+	return &PoliciesHandler{
+		authorizer: authorizer,
+	}
+}
+
+// @Summary List policies
+// @Description List the authorization policies loaded at startup. Requires the policies:read permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /admin/policies [get]
+func (policies *PoliciesHandler) ListHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"policies": policies.authorizer.Engine().Policies(),
+	})
+}
+
+// @Summary Explain a policy decision
+// @Description Dry-run the authorization policies for an action and show how each of them took part in the decision. The caller's own subject and request are used unless overridden in the body. Requires the policies:read permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.ExplainPolicyRequest true "Action and attributes"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/policies/explain [post]
+func (policies *PoliciesHandler) ExplainHandler(ctx *gin.Context) {
+	var request models.ExplainPolicyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	input, err := policies.authorizer.Input(ctx, request.Action, normalizeAttributes(request.Resource))
+	if err != nil {
+		log.Printf("Error loading roles: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error checking permissions",
+		})
+		return
+	}
+	for key, value := range normalizeAttributes(request.Subject) {
+		input.Subject[key] = value
+	}
+	for key, value := range normalizeAttributes(request.Request) {
+		input.Request[key] = value
+	}
+	if value, ok := request.Request["time"]; ok {
+		text, _ := value.(string)
+		at, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "request.time must be an RFC 3339 timestamp",
+			})
+			return
+		}
+		input.Request["time"] = at
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"input":    input,
+		"decision": policies.authorizer.Engine().Evaluate(input),
+	})
+}
+
+// normalizeAttributes turns whole JSON numbers into integers, which is how
+// the middleware passes IDs to policy conditions.
+func normalizeAttributes(attributes map[string]any) map[string]any {
+	normalized := make(map[string]any, len(attributes))
+	for key, value := range attributes {
+		normalized[key] = normalizeAttribute(value)
+	}
+	return normalized
+}
+
+func normalizeAttribute(value any) any {
+	switch value := value.(type) {
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return int64(value)
+		}
+	case map[string]any:
+		return normalizeAttributes(value)
+	case []any:
+		normalized := make([]any, len(value))
+		for i, item := range value {
+			normalized[i] = normalizeAttribute(item)
+		}
+		return normalized
+	}
+	return value
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"multitech/middleware"
+	"multitech/pkg/authz"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAuthorizer(t *testing.T) *middleware.Authorizer {
+	engine, err := authz.NewEngine([]authz.Policy{
+		{
+			Name:      "owners-during-business-hours",
+			Effect:    authz.EffectAllow,
+			Actions:   []string{"documents:edit"},
+			Condition: `resource.owner_id == subject.id && request.time.getHours("UTC") >= 9 && request.time.getHours("UTC") < 17`,
+		},
+		{
+			Name:      "locked",
+			Effect:    authz.EffectDeny,
+			Actions:   []string{"documents:*"},
+			Condition: `has(resource.locked) && resource.locked`,
+		},
+	})
+	require.NoError(t, err)
+	return middleware.NewAuthorizer(engine, mocks.NewDefaultRoleMock())
+}
+
+func TestPoliciesListHandler(t *testing.T) {
+	ctx, recorder := testutils.NewTestContext()
+
+	NewPoliciesHandler(testAuthorizer(t)).ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Policies []authz.Policy `json:"policies"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Policies, 2)
+	assert.Equal(t, "owners-during-business-hours", response.Policies[0].Name)
+	assert.Equal(t, authz.EffectDeny, response.Policies[1].Effect)
+}
+
+func TestPoliciesExplainHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectAllowed  bool
+		expectReason   string
+		expectMatched  []bool
+	}{
+		{
+			name:           "Caller Owns Resource",
+			body:           `{"action":"documents:edit","resource":{"owner_id":7},"request":{"time":"2025-06-02T10:00:00Z"}}`,
+			expectedStatus: http.StatusOK,
+			expectAllowed:  true,
+			expectReason:   "Allowed by policy owners-during-business-hours",
+			expectMatched:  []bool{true, false},
+		},
+		{
+			name:           "Other Subject",
+			body:           `{"action":"documents:edit","subject":{"id":8},"resource":{"owner_id":7},"request":{"time":"2025-06-02T10:00:00Z"}}`,
+			expectedStatus: http.StatusOK,
+			expectReason:   "No policy allows documents:edit",
+			expectMatched:  []bool{false, false},
+		},
+		{
+			name:           "Outside Business Hours",
+			body:           `{"action":"documents:edit","resource":{"owner_id":7},"request":{"time":"2025-06-02T20:00:00Z"}}`,
+			expectedStatus: http.StatusOK,
+			expectReason:   "No policy allows documents:edit",
+			expectMatched:  []bool{false, false},
+		},
+		{
+			name:           "Denied",
+			body:           `{"action":"documents:edit","resource":{"owner_id":7,"locked":true},"request":{"time":"2025-06-02T10:00:00Z"}}`,
+			expectedStatus: http.StatusOK,
+			expectReason:   "Denied by policy locked",
+			expectMatched:  []bool{true, true},
+		},
+		{
+			name:           "Missing Action",
+			body:           `{"resource":{"owner_id":7}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Time",
+			body:           `{"action":"documents:edit","request":{"time":"tomorrow"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/admin/policies/explain", strings.NewReader(tt.body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			ctx.Set("subject_type", middleware.SubjectTypeUser)
+			ctx.Set("user_id", uint(7))
+			ctx.Set("roles", []string{"admin"})
+
+			NewPoliciesHandler(testAuthorizer(t)).ExplainHandler(ctx)
+
+			require.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Input    authz.Input    `json:"input"`
+				Decision authz.Decision `json:"decision"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectAllowed, response.Decision.Allowed)
+			assert.Equal(t, tt.expectReason, response.Decision.Reason)
+			matched := []bool{}
+			for _, evaluation := range response.Decision.Evaluations {
+				matched = append(matched, evaluation.Matched)
+			}
+			assert.Equal(t, tt.expectMatched, matched)
+			assert.Equal(t, []any{"admin"}, response.Input.Subject["roles"])
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitAcrossReplicas(t *testing.T) {
+	bg := context.Background()
+	defer testutils.TestRedis.Del(bg, "rate_limit:replicas:ip:198.51.100.9")
+
+	policy := middleware.RateLimitPolicy{Name: "replicas", Limit: 3, Period: time.Minute, Key: middleware.RateLimitByIP}
+	newReplica := func() *gin.Engine {
+		router := gin.New()
+		limiter := middleware.NewRateLimiter(storage.NewRedisRateLimitRepository(testutils.TestRedis))
+		router.POST("/login", limiter.Middleware(policy), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+		return router
+	}
+	replicas := []*gin.Engine{newReplica(), newReplica()}
+
+	send := func(replica *gin.Engine) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/login", nil)
+		request.RemoteAddr = "198.51.100.9:40000"
+		replica.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// The burst is shared, whichever replica answers.
+	for i, remaining := range []string{"2", "1", "0"} {
+		recorder := send(replicas[i%2])
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "3", recorder.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, remaining, recorder.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "3;w=60", recorder.Header().Get("RateLimit-Policy"))
+	}
+
+	recorder := send(replicas[1])
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	// One request is earned back every 20 seconds.
+	assert.InDelta(t, 20, retryAfter, 1)
+	reset, err := strconv.Atoi(recorder.Header().Get("RateLimit-Reset"))
+	require.NoError(t, err)
+	assert.InDelta(t, 60, reset, 1)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"multitech/internal/models"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// recoveryCodeAlphabet leaves out characters that are easily confused on paper.
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+type RecoveryHandler struct {
+	userRepo       storage.UserRepository
+	recoveryRepo   storage.RecoveryCodeRepository
+	sessRepo       storage.SessionsRepository
+	refreshRepo    storage.RefreshTokenRepository
+	passwordPolicy *password.Policy
+}
+
+func NewRecoveryHandler(userRepo storage.UserRepository, recoveryRepo storage.RecoveryCodeRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, passwordPolicy *password.Policy) *RecoveryHandler {
+	return &RecoveryHandler{
+		userRepo:       userRepo,
+		recoveryRepo:   recoveryRepo,
+		sessRepo:       sessRepo,
+		refreshRepo:    refreshRepo,
+		passwordPolicy: passwordPolicy,
+	}
+}
+
+// @Summary Generate recovery codes
+// @Description Generate a new set of one-time recovery codes for the current user, replacing any previous set. The codes are only returned once
+// @Tags recovery
+// @Security BearerAuth
+// @Produce json
+// @Success 201 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recovery-codes [post]
+func (recovery *RecoveryHandler) GenerateHandler(ctx *gin.Context) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error generating recovery codes",
+			})
+			return
+		}
+		codes = append(codes, code)
+		records = append(records, &models.RecoveryCode{CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := recovery.recoveryRepo.ReplaceRecoveryCodes(ctx.Request.Context(), ctx.GetUint("user_id"), records); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error storing recovery codes: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// @Summary Recovery code status
+// @Description Number of unused recovery codes of the current user
+// @Tags recovery
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recovery-codes [get]
+func (recovery *RecoveryHandler) StatusHandler(ctx *gin.Context) {
+	remaining, err := recovery.recoveryRepo.CountUnusedRecoveryCodes(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving recovery codes: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"remaining": remaining,
+	})
+}
+
+// @Summary Recover account
+// @Description Set a new password with a one-time recovery code. The code is consumed and all sessions of the user are revoked
+// @Tags recovery
+// @Accept json
+// @Produce json
+// @Param credentials body models.RecoverCredentials true "Username, recovery code and new password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /recover [post]
+func (recovery *RecoveryHandler) RecoverHandler(ctx *gin.Context) {
+	var creds models.RecoverCredentials
+	if err := ctx.ShouldBindJSON(&creds); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	user, err := recovery.userRepo.GetUserByUsername(ctx.Request.Context(), creds.Username)
+	if err != nil {
+		// Unknown users get the same answer as wrong codes so that usernames cannot be probed.
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": storage.ErrRecoveryCodeInvalid.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+
+	// The code is checked before the password policy, whose answers depend on the
+	// username and email and would otherwise tell strangers whether an account exists.
+	// It is only consumed after the policy, so that a rejected password does not burn it.
+	codeHash := hashRecoveryCode(creds.RecoveryCode)
+	if !recovery.respondRecoveryCodeError(ctx, recovery.recoveryRepo.CheckRecoveryCode(ctx.Request.Context(), user.ID, codeHash)) {
+		return
+	}
+
+	if !checkPasswordPolicy(ctx, recovery.passwordPolicy, password.Candidate{
+		Password: creds.NewPassword,
+		Username: user.Username,
+		Email:    user.Email,
+	}) {
+		return
+	}
+
+	if !recovery.respondRecoveryCodeError(ctx, recovery.recoveryRepo.ConsumeRecoveryCode(ctx.Request.Context(), user.ID, codeHash)) {
+		return
+	}
+
+	user.Password = creds.NewPassword
+	if err := user.HashPassword(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error hashing password",
+		})
+		return
+	}
+
+	if err := recovery.userRepo.UpdatePassword(ctx.Request.Context(), user.ID, user.Password); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error updating password: " + err.Error(),
+		})
+		return
+	}
+
+	if err := recovery.sessRepo.DeleteUserSessions(ctx.Request.Context(), user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting sessions: " + err.Error(),
+		})
+		return
+	}
+
+	if err := recovery.refreshRepo.RevokeUserRefreshTokens(ctx.Request.Context(), user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking refresh tokens: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password updated, all sessions have been revoked",
+	})
+}
+
+// respondRecoveryCodeError writes the answer for a failed recovery code lookup
+// and reports whether err was nil.
+func (recovery *RecoveryHandler) respondRecoveryCodeError(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, storage.ErrRecoveryCodeInvalid) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Error checking recovery code: " + err.Error(),
+	})
+	return false
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx.
+func generateRecoveryCode() (string, error) {
+	var code strings.Builder
+	alphabetSize := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < recoveryCodeLength; i++ {
+		if i == recoveryCodeLength/2 {
+			code.WriteByte('-')
+		}
+		index, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(recoveryCodeAlphabet[index.Int64()])
+	}
+	return code.String(), nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so that codes typed from
+// paper still match.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(code)
+	normalized = strings.ReplaceAll(normalized, "-", "")
+	normalized = strings.ReplaceAll(normalized, " ", "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
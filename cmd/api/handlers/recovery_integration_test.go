@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryCodeLifecycle(t *testing.T) {
+	bg := context.Background()
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+
+	user := &models.User{
+		Username: "recoveryuser",
+		Email:    "recoveryuser@example.com",
+		Password: "oldpass",
+	}
+	require.NoError(t, user.HashPassword())
+	require.NoError(t, tx.Create(user).Error)
+
+	userRepo := storage.NewGormUserRepository(tx)
+	recoveryRepo := storage.NewGormRecoveryCodeRepository(tx)
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, user.ID)
+	defer sessRepo.DeleteUserSessions(bg, user.ID)
+	handler := NewRecoveryHandler(userRepo, recoveryRepo, sessRepo, refreshRepo, password.DefaultPolicy())
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", user.ID)
+	handler.GenerateHandler(ctx)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var generated struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &generated))
+	require.Len(t, generated.RecoveryCodes, recoveryCodeCount)
+
+	require.NoError(t, sessRepo.CreateSession(bg, &models.Session{
+		ID:        "recovery-session",
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	recoverAccount := func(code string) int {
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, `{"username":"recoveryuser","recovery_code":"`+code+`","new_password":"newpassword"}`)
+		handler.RecoverHandler(ctx)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, recoverAccount(generated.RecoveryCodes[0]))
+	assert.Equal(t, http.StatusUnauthorized, recoverAccount(generated.RecoveryCodes[0]))
+
+	stored, err := userRepo.GetUserByUsername(bg, "recoveryuser")
+	require.NoError(t, err)
+	assert.NoError(t, stored.CheckPassword("newpassword"))
+
+	sessions, err := sessRepo.ListUserSessions(bg, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	remaining, err := recoveryRepo.CountUnusedRecoveryCodes(bg, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(recoveryCodeCount-1), remaining)
+
+	// Regenerating invalidates the previous set.
+	ctx, recorder = testutils.NewTestContext()
+	ctx.Set("user_id", user.ID)
+	handler.GenerateHandler(ctx)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, http.StatusUnauthorized, recoverAccount(generated.RecoveryCodes[1]))
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryGenerateHandler(t *testing.T) {
+	var stored []*models.RecoveryCode
+	mockRecoveryRepo := mocks.NewDefaultRecoveryCodeMock()
+	mockRecoveryRepo.ReplaceRecoveryCodesFunc = func(ctx context.Context, userID uint, codes []*models.RecoveryCode) error {
+		assert.Equal(t, uint(1), userID)
+		stored = codes
+		return nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(1))
+
+	handler := NewRecoveryHandler(mocks.NewDefaultUserMock(), mockRecoveryRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), password.DefaultPolicy())
+	handler.GenerateHandler(ctx)
+
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.RecoveryCodes, recoveryCodeCount)
+	require.Len(t, stored, recoveryCodeCount)
+
+	format := regexp.MustCompile(`^[a-z2-9]{5}-[a-z2-9]{5}$`)
+	seen := map[string]bool{}
+	for i, code := range response.RecoveryCodes {
+		assert.Regexp(t, format, code)
+		assert.False(t, seen[code], "codes must be unique")
+		seen[code] = true
+		assert.Equal(t, hashRecoveryCode(code), stored[i].CodeHash)
+	}
+}
+
+func TestRecoveryGenerateHandlerStorageError(t *testing.T) {
+	mockRecoveryRepo := mocks.NewDefaultRecoveryCodeMock()
+	mockRecoveryRepo.ReplaceRecoveryCodesFunc = func(ctx context.Context, userID uint, codes []*models.RecoveryCode) error {
+		return errors.New("database error")
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(1))
+
+	handler := NewRecoveryHandler(mocks.NewDefaultUserMock(), mockRecoveryRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), password.DefaultPolicy())
+	handler.GenerateHandler(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "recovery_codes")
+}
+
+func TestRecoveryStatusHandler(t *testing.T) {
+	mockRecoveryRepo := mocks.NewDefaultRecoveryCodeMock()
+	mockRecoveryRepo.CountUnusedRecoveryCodesFunc = func(ctx context.Context, userID uint) (int64, error) {
+		return 7, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(1))
+
+	handler := NewRecoveryHandler(mocks.NewDefaultUserMock(), mockRecoveryRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), password.DefaultPolicy())
+	handler.StatusHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"remaining":7}`, recorder.Body.String())
+}
+
+func TestRecoverHandler(t *testing.T) {
+	const code = "abcde-fgh23"
+
+	tests := []struct {
+		name              string
+		body              string
+		mockUserSetup     func(*mocks.MockUserRepository)
+		mockRecoverySetup func(*mocks.MockRecoveryCodeRepository)
+		expectedStatus    int
+		expectedBody      string
+		expectRevoked     bool
+	}{
+		{
+			name:           "Success",
+			body:           `{"username":"testuser","recovery_code":"` + code + `","new_password":"newpassword"}`,
+			expectedStatus: http.StatusOK,
+			expectRevoked:  true,
+		},
+		{
+			name:           "Code Typed Loosely",
+			body:           `{"username":"testuser","recovery_code":"ABCDE FGH23","new_password":"newpassword"}`,
+			expectedStatus: http.StatusOK,
+			expectRevoked:  true,
+		},
+		{
+			name:           "Missing Fields",
+			body:           `{"username":"testuser"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Password Too Short",
+			body:           `{"username":"testuser","recovery_code":"` + code + `","new_password":"abc"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Password must be at least 8 characters","violations":[{"code":"min_length","message":"Password must be at least 8 characters"}]}`,
+		},
+		{
+			name:           "Wrong Code Before Password Policy",
+			body:           `{"username":"testuser","recovery_code":"zzzzz-zzzzz","new_password":"testuser1"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"` + storage.ErrRecoveryCodeInvalid.Error() + `"}`,
+		},
+		{
+			name: "Unknown User",
+			body: `{"username":"nobody","recovery_code":"` + code + `","new_password":"newpassword"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByUsernameFunc = func(ctx context.Context, username string) (*models.User, error) {
+					return nil, storage.ErrUserNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"` + storage.ErrRecoveryCodeInvalid.Error() + `"}`,
+		},
+		{
+			name: "Invalid Or Used Code",
+			body: `{"username":"testuser","recovery_code":"zzzzz-zzzzz","new_password":"newpassword"}`,
+			mockRecoverySetup: func(mrr *mocks.MockRecoveryCodeRepository) {
+				mrr.CheckRecoveryCodeFunc = func(ctx context.Context, userID uint, codeHash string) error {
+					return storage.ErrRecoveryCodeInvalid
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"` + storage.ErrRecoveryCodeInvalid.Error() + `"}`,
+		},
+		{
+			name: "Update Error",
+			body: `{"username":"testuser","recovery_code":"` + code + `","new_password":"newpassword"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.UpdatePasswordFunc = func(ctx context.Context, id uint, passwordHash string) error {
+					return errors.New("database error")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updatedHash string
+			var codeConsumed, sessionsDeleted, refreshRevoked bool
+
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.UpdatePasswordFunc = func(ctx context.Context, id uint, passwordHash string) error {
+				updatedHash = passwordHash
+				return nil
+			}
+			mockRecoveryRepo := mocks.NewDefaultRecoveryCodeMock()
+			mockRecoveryRepo.CheckRecoveryCodeFunc = func(ctx context.Context, userID uint, codeHash string) error {
+				if codeHash != hashRecoveryCode(code) {
+					return storage.ErrRecoveryCodeInvalid
+				}
+				return nil
+			}
+			mockRecoveryRepo.ConsumeRecoveryCodeFunc = func(ctx context.Context, userID uint, codeHash string) error {
+				if codeHash != hashRecoveryCode(code) {
+					return storage.ErrRecoveryCodeInvalid
+				}
+				codeConsumed = true
+				return nil
+			}
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.DeleteUserSessionsFunc = func(ctx context.Context, userID uint) error {
+				sessionsDeleted = true
+				return nil
+			}
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+			mockRefreshRepo.RevokeUserRefreshTokensFunc = func(ctx context.Context, userID uint) error {
+				refreshRevoked = true
+				return nil
+			}
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+			if tt.mockRecoverySetup != nil {
+				tt.mockRecoverySetup(mockRecoveryRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			handler := NewRecoveryHandler(mockUserRepo, mockRecoveryRepo, mockSessRepo, mockRefreshRepo, password.DefaultPolicy())
+			handler.RecoverHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+			if tt.expectedStatus != http.StatusInternalServerError {
+				assert.Equal(t, tt.expectRevoked, codeConsumed, "only accepted passwords consume the code")
+			}
+			assert.Equal(t, tt.expectRevoked, sessionsDeleted)
+			assert.Equal(t, tt.expectRevoked, refreshRevoked)
+			if tt.expectRevoked {
+				require.NotEmpty(t, updatedHash)
+				assert.NoError(t, (&models.User{Password: updatedHash}).CheckPassword("newpassword"))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefreshHandler struct {
+	userRepo    storage.UserRepository
+	sessRepo    storage.SessionsRepository
+	refreshRepo storage.RefreshTokenRepository
+	orgRepo     storage.OrganizationRepository
+}
+
+func NewRefreshHandler(userRepo storage.UserRepository, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, orgRepo storage.OrganizationRepository) *RefreshHandler {
+	return &RefreshHandler{
+		userRepo:    userRepo,
+		sessRepo:    sessRepo,
+		refreshRepo: refreshRepo,
+		orgRepo:     orgRepo,
+	}
+}
+
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated on every use and replaying a used one revokes the whole token family
+// @Description The new access token carries the user's current roles. Organization tokens carry the current role in the organization and are refused once the user is no longer a member
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.RefreshCredentials true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /token/refresh [post]
+func (refresh *RefreshHandler) Handler(ctx *gin.Context) {
+	var creds models.RefreshCredentials
+	if err := ctx.ShouldBindJSON(&creds); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	newRefreshToken, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error generating token",
+		})
+		return
+	}
+
+	rotated, err := refresh.refreshRepo.RotateRefreshToken(ctx.Request.Context(), creds.RefreshToken, newRefreshToken, middleware.RefreshTokenTTL)
+	if errors.Is(err, storage.ErrRefreshTokenReused) {
+		// Access tokens minted from the compromised family die with its session.
+		if err := refresh.sessRepo.DeleteSession(ctx.Request.Context(), rotated.FamilyID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error deleting session: " + err.Error(),
+			})
+			return
+		}
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) || errors.Is(err, storage.ErrRefreshTokenReused) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error rotating refresh token: " + err.Error(),
+		})
+		return
+	}
+
+	// The refresh family outlives its session once the session is revoked or expired.
+	session, err := refresh.sessRepo.GetSessionByID(ctx.Request.Context(), rotated.FamilyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			refresh.refreshRepo.RevokeFamily(ctx.Request.Context(), rotated.FamilyID)
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": storage.ErrSessionNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving session: " + err.Error(),
+		})
+		return
+	}
+
+	// The membership is loaded again, so that a removed member is signed out
+	// and a changed role applies.
+	userCtx := ctx.Request.Context()
+	var member *models.OrganizationMember
+	if session.OrganizationID != 0 {
+		member, err = refresh.orgRepo.GetMembership(ctx.Request.Context(), session.OrganizationID, rotated.UserID)
+		if err != nil {
+			if errors.Is(err, storage.ErrMembershipNotFound) {
+				refresh.refreshRepo.RevokeFamily(ctx.Request.Context(), rotated.FamilyID)
+				refresh.sessRepo.DeleteSession(ctx.Request.Context(), rotated.FamilyID)
+				ctx.JSON(http.StatusUnauthorized, gin.H{
+					"error": storage.ErrMembershipNotFound.Error(),
+				})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error retrieving membership",
+			})
+			return
+		}
+		userCtx = storage.WithOrganization(userCtx, session.OrganizationID)
+	}
+
+	// The user is loaded again, so that granted and revoked roles apply.
+	user, err := refresh.userRepo.GetUserByID(userCtx, rotated.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			refresh.refreshRepo.RevokeFamily(ctx.Request.Context(), rotated.FamilyID)
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": storage.ErrUserNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving user",
+		})
+		return
+	}
+
+	accessToken, err := issueMemberAccessToken(ctx.Request.Context(), refresh.sessRepo, user, member, rotated.FamilyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error creating session: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":         accessToken,
+		"refresh_token": newRefreshToken,
+		"expires_in":    int(middleware.AccessTokenTTL.Seconds()),
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshHandlerRotation(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, 51)
+	defer sessRepo.DeleteUserSessions(bg, 51)
+
+	createTestSession(t, sessRepo, "rotation-family", 51, "rotation-access-token")
+
+	assert.NoError(t, refreshRepo.StoreRefreshToken(bg, "rotation-initial", &models.RefreshToken{
+		UserID:    51,
+		FamilyID:  "rotation-family",
+		CreatedAt: time.Now(),
+	}, time.Minute))
+
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"refresh_token":"rotation-initial"}`)
+
+	handler := NewRefreshHandler(mocks.NewDefaultUserMock(), sessRepo, refreshRepo, mocks.NewDefaultOrganizationMock())
+	handler.Handler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	rotatedToken := response["refresh_token"].(string)
+	assert.NotEqual(t, "rotation-initial", rotatedToken)
+
+	session, err := sessRepo.GetSession(bg, response["token"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(51), session.UserID)
+	assert.Equal(t, "rotation-family", session.ID)
+
+	rotated, err := refreshRepo.GetRefreshToken(bg, rotatedToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "rotation-family", rotated.FamilyID)
+	assert.False(t, rotated.Used)
+}
+
+func TestRefreshHandlerReuseRevokesFamily(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, 52)
+	defer sessRepo.DeleteUserSessions(bg, 52)
+
+	createTestSession(t, sessRepo, "reuse-family", 52, "reuse-access-token")
+	assert.NoError(t, refreshRepo.StoreRefreshToken(bg, "reuse-initial", &models.RefreshToken{
+		UserID:    52,
+		FamilyID:  "reuse-family",
+		CreatedAt: time.Now(),
+	}, time.Minute))
+
+	rotated, err := refreshRepo.RotateRefreshToken(bg, "reuse-initial", "reuse-second", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(52), rotated.UserID)
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"refresh_token":"reuse-initial"}`)
+
+	handler := NewRefreshHandler(mocks.NewDefaultUserMock(), sessRepo, refreshRepo, mocks.NewDefaultOrganizationMock())
+	handler.Handler(ctx)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.JSONEq(t, `{"error":"Refresh token reuse detected"}`, recorder.Body.String())
+
+	_, err = refreshRepo.GetRefreshToken(bg, "reuse-second")
+	assert.ErrorIs(t, err, storage.ErrRefreshTokenNotFound)
+	_, err = sessRepo.GetSession(bg, "reuse-access-token")
+	assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		requestBody      string
+		mockRefreshSetup func(*mocks.MockRefreshTokenRepository)
+		mockSessSetup    func(*mocks.MockSessionsRepository)
+		mockUserSetup    func(*mocks.MockUserRepository)
+		envSetup         func(*mocks.EnvMock)
+		expectedStatus   int
+		expectedBody     string
+	}{
+		{
+			name:        "Success",
+			requestBody: `{"refresh_token": "old-token"}`,
+			mockRefreshSetup: func(mrr *mocks.MockRefreshTokenRepository) {
+				mrr.RotateRefreshTokenFunc = func(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+					if oldToken != "old-token" || newToken == "" || newToken == oldToken {
+						return nil, errors.New("unexpected rotation")
+					}
+					return &models.RefreshToken{UserID: 7, FamilyID: "family"}, nil
+				}
+			},
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_SECRET", "testsecret")
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"token":"*","refresh_token":"*","expires_in":900}`,
+		},
+		{
+			name:           "Missing Token",
+			requestBody:    `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Unknown Token",
+			requestBody: `{"refresh_token": "unknown"}`,
+			mockRefreshSetup: func(mrr *mocks.MockRefreshTokenRepository) {
+				mrr.RotateRefreshTokenFunc = func(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+					return nil, storage.ErrRefreshTokenNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid or expired refresh token"}`,
+		},
+		{
+			name:        "Reused Token",
+			requestBody: `{"refresh_token": "used"}`,
+			mockRefreshSetup: func(mrr *mocks.MockRefreshTokenRepository) {
+				mrr.RotateRefreshTokenFunc = func(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+					return &models.RefreshToken{UserID: 7, FamilyID: "family", Used: true}, storage.ErrRefreshTokenReused
+				}
+			},
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+					if sessionID != "family" {
+						return errors.New("unexpected session")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Refresh token reuse detected"}`,
+		},
+		{
+			name:        "Revoked Session",
+			requestBody: `{"refresh_token": "old-token"}`,
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.GetSessionByIDFunc = func(ctx context.Context, sessionID string) (*models.Session, error) {
+					return nil, storage.ErrSessionNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid or expired session"}`,
+		},
+		{
+			name:        "Deleted User",
+			requestBody: `{"refresh_token": "old-token"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+					return nil, storage.ErrUserNotFound
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"User not found"}`,
+		},
+		{
+			name:        "Session Store Error",
+			requestBody: `{"refresh_token": "old-token"}`,
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.StoreSessionFunc = func(ctx context.Context, token string, sessionID string, duration time.Duration) error {
+					return errors.New("connection refused")
+				}
+			},
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_SECRET", "testsecret")
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Error creating session: connection refused"}`,
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockEnv := mocks.NewEnvMock()
+
+			if tt.mockRefreshSetup != nil {
+				tt.mockRefreshSetup(mockRefreshRepo)
+			}
+			if tt.mockSessSetup != nil {
+				tt.mockSessSetup(mockSessRepo)
+			}
+			if tt.mockUserSetup != nil {
+				tt.mockUserSetup(mockUserRepo)
+			}
+			if tt.envSetup != nil {
+				tt.envSetup(mockEnv)
+				mockEnv.Apply()
+				defer mockEnv.Restore(originEnv)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.requestBody)
+
+			refreshHandler := NewRefreshHandler(mockUserRepo, mockSessRepo, mockRefreshRepo, mocks.NewDefaultOrganizationMock())
+			refreshHandler.Handler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				if strings.Contains(tt.expectedBody, "*") {
+					var expected, actual map[string]interface{}
+					if err := json.Unmarshal([]byte(tt.expectedBody), &expected); err != nil {
+						t.Fatalf("Failed to parse expected JSON: %v", err)
+					}
+					if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+						t.Fatalf("Failed to parse expected JSON: %v", err)
+					}
+					for key, value := range expected {
+						if strVal, ok := value.(string); ok && strVal == "*" {
+							delete(expected, key)
+							delete(actual, key)
+						}
+					}
+					assert.Equal(t, expected, actual)
+				} else {
+					assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshHandlerCarriesCurrentRoles(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+		return &models.User{ID: id, Username: "testuser", Roles: []models.Role{{Name: "support"}}}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"refresh_token": "old-token"}`)
+
+	NewRefreshHandler(mockUserRepo, mocks.NewDefaultSessionsMock(), mocks.NewDefaultRefreshTokenMock(), mocks.NewDefaultOrganizationMock()).Handler(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	token, err := middleware.ParseToken(response.Token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support"}, token.Claims.(*middleware.Claims).Roles)
+}
+
+func TestRefreshHandlerOrganizationSession(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "testsecret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	mockSessRepo := mocks.NewDefaultSessionsMock()
+	mockSessRepo.GetSessionByIDFunc = func(ctx context.Context, sessionID string) (*models.Session, error) {
+		return &models.Session{ID: sessionID, UserID: 1, OrganizationID: 4}, nil
+	}
+	var scopedLookup bool
+	mockUserRepo := mocks.NewDefaultUserMock()
+	mockUserRepo.GetUserByIDFunc = func(ctx context.Context, id uint) (*models.User, error) {
+		organizationID, ok := storage.OrganizationFromContext(ctx)
+		scopedLookup = ok && organizationID == 4
+		return &models.User{ID: id, Username: "testuser"}, nil
+	}
+	mockOrgRepo := mocks.NewDefaultOrganizationMock()
+	mockOrgRepo.GetMembershipFunc = func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+		return &models.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: models.OrganizationOwnerRole}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"refresh_token": "old-token"}`)
+
+	NewRefreshHandler(mockUserRepo, mockSessRepo, mocks.NewDefaultRefreshTokenMock(), mockOrgRepo).Handler(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, scopedLookup, "the user is looked up within the organization")
+	var response struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	token, err := middleware.ParseToken(response.Token)
+	require.NoError(t, err)
+	claims := token.Claims.(*middleware.Claims)
+	assert.Equal(t, uint(4), claims.OrganizationID)
+	assert.Equal(t, []string{models.OrganizationOwnerRole}, claims.OrganizationRoles)
+}
+
+func TestRefreshHandlerRemovedMember(t *testing.T) {
+	var deletedSession string
+	var revokedFamily string
+	mockSessRepo := mocks.NewDefaultSessionsMock()
+	mockSessRepo.GetSessionByIDFunc = func(ctx context.Context, sessionID string) (*models.Session, error) {
+		return &models.Session{ID: sessionID, UserID: 1, OrganizationID: 4}, nil
+	}
+	mockSessRepo.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+		deletedSession = sessionID
+		return nil
+	}
+	mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+	mockRefreshRepo.RotateRefreshTokenFunc = func(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+		return &models.RefreshToken{UserID: 1, FamilyID: "family"}, nil
+	}
+	mockRefreshRepo.RevokeFamilyFunc = func(ctx context.Context, familyID string) error {
+		revokedFamily = familyID
+		return nil
+	}
+	mockOrgRepo := mocks.NewDefaultOrganizationMock()
+	mockOrgRepo.GetMembershipFunc = func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+		return nil, storage.ErrMembershipNotFound
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"refresh_token": "old-token"}`)
+
+	NewRefreshHandler(mocks.NewDefaultUserMock(), mockSessRepo, mockRefreshRepo, mockOrgRepo).Handler(ctx)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.JSONEq(t, `{"error":"Membership not found"}`, recorder.Body.String())
+	assert.Equal(t, "family", deletedSession)
+	assert.Equal(t, "family", revokedFamily)
+}
@@ -3,18 +3,21 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"log"
 	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
 	"multitech/pkg/storage"
 	"net/http"
+	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	minPasswordLength = 8
-	maxPasswordLength = 254
 	minUsernameLength = 3
 	maxUsernameLength = 254
 	minEmailLength    = 3
@@ -22,24 +25,58 @@ const (
 	emailRegexPattern = `^[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`
 )
 
+// RegistrationMode decides who may register with POST /register.
+type RegistrationMode string
+
+const (
+	RegistrationOpen       RegistrationMode = "open"
+	RegistrationInviteOnly RegistrationMode = "invite-only"
+	RegistrationClosed     RegistrationMode = "closed"
+)
+
+// RegistrationModeFromEnv reads REGISTRATION_MODE, open by default.
+func RegistrationModeFromEnv() (RegistrationMode, error) {
+	switch mode := RegistrationMode(os.Getenv("REGISTRATION_MODE")); mode {
+	case "":
+		return RegistrationOpen, nil
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("Invalid REGISTRATION_MODE %q, expected open, invite-only or closed", mode)
+	}
+}
+
 type RegisterHandler struct {
-	userRepo storage.UserRepository
+	userRepo         storage.UserRepository
+	invitationRepo   storage.InvitationRepository
+	verificationRepo storage.EmailVerificationRepository
+	mailer           mailer.Mailer
+	passwordPolicy   *password.Policy
+	mode             RegistrationMode
 }
 
-func NewRegisterHandler(userRepo storage.UserRepository) *RegisterHandler {
+func NewRegisterHandler(userRepo storage.UserRepository, invitationRepo storage.InvitationRepository, verificationRepo storage.EmailVerificationRepository, mail mailer.Mailer, passwordPolicy *password.Policy, mode RegistrationMode) *RegisterHandler {
 	return &RegisterHandler{
-		userRepo: userRepo,
+		userRepo:         userRepo,
+		invitationRepo:   invitationRepo,
+		verificationRepo: verificationRepo,
+		mailer:           mail,
+		passwordPolicy:   passwordPolicy,
+		mode:             mode,
 	}
 }
 
 // @Summary Register new user
-// @Description Create a new user account
+// @Description Create a new user account and send a verification link to its email address.
+// @Description A password that breaks the password policy is rejected with every violation at once
+// @Description While REGISTRATION_MODE is invite-only an invitation token for the email address is required and used up; while it is closed nobody may register
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body models.RegisterCredentials true "User registration data"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /register [post]
@@ -52,9 +89,15 @@ func (register *RegisterHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
-	if len(regCreds.Password) < minPasswordLength {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Password must be at least %d characters", minPasswordLength),
+	switch {
+	case register.mode == RegistrationClosed:
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": "Registration is closed",
+		})
+		return
+	case register.mode == RegistrationInviteOnly && regCreds.Invitation == "":
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": "Registration requires an invitation",
 		})
 		return
 	}
@@ -66,6 +109,15 @@ func (register *RegisterHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
+	// Logins accept the username or the email address in one field, so a
+	// username must not look like an address.
+	if strings.Contains(regCreds.Username, "@") {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "Username must not contain @",
+		})
+		return
+	}
+
 	if err := register.validateEmail(regCreds.Email); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -73,6 +125,14 @@ func (register *RegisterHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
+	if !checkPasswordPolicy(ctx, register.passwordPolicy, password.Candidate{
+		Password: regCreds.Password,
+		Username: regCreds.Username,
+		Email:    regCreds.Email,
+	}) {
+		return
+	}
+
 	user := models.User{
 		Username:  regCreds.Username,
 		Email:     regCreds.Email,
@@ -87,7 +147,13 @@ func (register *RegisterHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
-	if err := register.userRepo.CreateUser(ctx.Request.Context(), &user); err != nil {
+	if err := register.createUser(ctx, &user, regCreds.Invitation); err != nil {
+		if errors.Is(err, storage.ErrInvitationInvalid) || errors.Is(err, storage.ErrInvitationEmailMismatch) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		if errors.Is(err, storage.ErrUserExists) {
 			ctx.JSON(http.StatusConflict, gin.H{
 				"error": "User already exists",
@@ -100,12 +166,49 @@ func (register *RegisterHandler) Handler(ctx *gin.Context) {
 		return
 	}
 
+	// The account exists at this point; a failed mail can be retried through
+	// /verify-email/resend.
+	if err := sendEmailVerification(ctx.Request.Context(), register.verificationRepo, register.mailer, &user); err != nil {
+		log.Printf("Error sending verification mail to user %d: %v", user.ID, err)
+	}
+
 	ctx.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user_id": user.ID,
 	})
 }
 
+// createUser stores the user, using up the invitation if there is one.
+func (register *RegisterHandler) createUser(ctx *gin.Context, user *models.User, invitation string) error {
+	if invitation == "" {
+		return register.userRepo.CreateUser(ctx.Request.Context(), user)
+	}
+	_, err := register.invitationRepo.AcceptInvitation(ctx.Request.Context(), hashInvitationToken(invitation), user)
+	return err
+}
+
+// checkPasswordPolicy answers the request with every violation if the new
+// password breaks the policy.
+func checkPasswordPolicy(ctx *gin.Context, policy *password.Policy, candidate password.Candidate) bool {
+	err := policy.Validate(candidate)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":      policyErr.Error(),
+			"violations": policyErr.Violations,
+		})
+		return false
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{
+		"error": err.Error(),
+	})
+	return false
+}
+
 func (register *RegisterHandler) validateEmail(email string) error {
 	if len(email) < minEmailLength || len(email) > maxEmailLength {
 		return fmt.Errorf("Email must be between %d-%d characters", minEmailLength, maxEmailLength)
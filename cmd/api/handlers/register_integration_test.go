@@ -3,6 +3,8 @@ package handlers
 import (
 	"encoding/json"
 	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
 	"multitech/pkg/storage"
 	"multitech/pkg/testutils"
 	"net/http"
@@ -20,7 +22,7 @@ func TestRegisterHandlerSuccess(t *testing.T) {
 	ctx, recorder := testutils.NewTestContext()
 	testutils.SetJSONBody(ctx, `{"username":"newuser","email":"newuser@example.com","password":"securepassword123"}`)
 
-	handler := NewRegisterHandler(userRepo)
+	handler := NewRegisterHandler(userRepo, storage.NewGormInvitationRepository(tx), storage.NewRedisEmailVerificationRepository(testutils.TestRedis), mailer.NewMemoryMailer(), password.DefaultPolicy(), RegistrationOpen)
 	handler.Handler(ctx)
 
 	assert.Equal(t, http.StatusCreated, recorder.Code)
@@ -53,13 +55,38 @@ func TestRegisterHandlerDuplicateUsername(t *testing.T) {
 	ctx, recorder := testutils.NewTestContext()
 	testutils.SetJSONBody(ctx, `{"username":"existinguser","email":"new@example.com","password":"password123"}`)
 
-	handler := NewRegisterHandler(userRepo)
+	handler := NewRegisterHandler(userRepo, storage.NewGormInvitationRepository(tx), storage.NewRedisEmailVerificationRepository(testutils.TestRedis), mailer.NewMemoryMailer(), password.DefaultPolicy(), RegistrationOpen)
 	handler.Handler(ctx)
 
 	assert.Equal(t, http.StatusConflict, recorder.Code)
 	assert.JSONEq(t, `{"error":"User already exists"}`, recorder.Body.String())
 }
 
+func TestRegisterHandlerDuplicateInAnotherCase(t *testing.T) {
+	for _, body := range []string{
+		`{"username":"ExistingUser","email":"new@example.com","password":"password123"}`,
+		`{"username":"newuser","email":"EXISTING@example.com","password":"password123"}`,
+	} {
+		// A rejected insert aborts the transaction, so every case gets its own.
+		tx := testutils.TestDB.Begin()
+		assert.NoError(t, tx.Create(&models.User{
+			Username: "existinguser",
+			Email:    "existing@example.com",
+			Password: "hashedpassword",
+		}).Error)
+
+		ctx, recorder := testutils.NewTestContext()
+		testutils.SetJSONBody(ctx, body)
+
+		handler := NewRegisterHandler(storage.NewGormUserRepository(tx), storage.NewGormInvitationRepository(tx), storage.NewRedisEmailVerificationRepository(testutils.TestRedis), mailer.NewMemoryMailer(), password.DefaultPolicy(), RegistrationOpen)
+		handler.Handler(ctx)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code, body)
+		assert.JSONEq(t, `{"error":"User already exists"}`, recorder.Body.String(), body)
+		tx.Rollback()
+	}
+}
+
 func TestRegisterHandlerInvalidData(t *testing.T) {
 	tx := testutils.TestDB.Begin()
 	defer tx.Rollback()
@@ -84,7 +111,7 @@ func TestRegisterHandlerInvalidData(t *testing.T) {
 		{
 			name:          "Short Password",
 			requestBody:   `{"username":"testuser","email":"test@example.com","password":"short"}`,
-			expectedError: `{"error":"Password must be at least 8 characters"}`,
+			expectedError: `{"error":"Password must be at least 8 characters","violations":[{"code":"min_length","message":"Password must be at least 8 characters"}]}`,
 		},
 	}
 
@@ -93,7 +120,7 @@ func TestRegisterHandlerInvalidData(t *testing.T) {
 			ctx, recorder := testutils.NewTestContext()
 			testutils.SetJSONBody(ctx, tc.requestBody)
 
-			handler := NewRegisterHandler(userRepo)
+			handler := NewRegisterHandler(userRepo, storage.NewGormInvitationRepository(tx), storage.NewRedisEmailVerificationRepository(testutils.TestRedis), mailer.NewMemoryMailer(), password.DefaultPolicy(), RegistrationOpen)
 			handler.Handler(ctx)
 
 			assert.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"multitech/internal/models"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
+	"multitech/pkg/storage"
 	"multitech/pkg/testutils"
 	"multitech/pkg/testutils/mocks"
 	"net/http"
@@ -24,7 +27,7 @@ func TestRegisterHandler(t *testing.T) {
 	}{
 		{
 			name:        "Success",
-			requestBody: `{"username": "user", "email": "test@mail.com", "password": "testpass", "user_id":"*"}`,
+			requestBody: `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "user_id":"*"}`,
 			mockUserSetup: func(mur *mocks.MockUserRepository) {
 				mur.CreateUserFunc = func(ctx context.Context, user *models.User) error {
 					return nil
@@ -36,6 +39,29 @@ func TestRegisterHandler(t *testing.T) {
 			expectedStatus: http.StatusCreated,
 			expectedBody:   `{"message":"User created successfully","user_id":"*"}`,
 		},
+		{
+			name:           "Password Policy Violations",
+			requestBody:    `{"username": "user", "email": "test@mail.com", "password": "test"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Password must be at least 8 characters; Password must not contain the email address","violations":[{"code":"min_length","message":"Password must be at least 8 characters"},{"code":"contains_user_input","message":"Password must not contain the email address"}]}`,
+		},
+		{
+			name:           "Username Looks Like Email",
+			requestBody:    `{"username": "other@mail.com", "email": "test@mail.com", "password": "correcthorse"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Username must not contain @"}`,
+		},
+		{
+			name:        "User Exists In Another Case",
+			requestBody: `{"username": "User", "email": "Test@mail.com", "password": "correcthorse"}`,
+			mockUserSetup: func(mur *mocks.MockUserRepository) {
+				mur.CreateUserFunc = func(ctx context.Context, user *models.User) error {
+					return storage.ErrUserExists
+				}
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"User already exists"}`,
+		},
 	}
 
 	originEnv := testutils.CaptureOriginEnv()
@@ -57,10 +83,16 @@ func TestRegisterHandler(t *testing.T) {
 			ctx, recorder := testutils.NewTestContext()
 			testutils.SetJSONBody(ctx, tt.requestBody)
 
-			registerHandler := NewRegisterHandler(mockUserRepo)
+			mail := mailer.NewMemoryMailer()
+			registerHandler := NewRegisterHandler(mockUserRepo, mocks.NewDefaultInvitationMock(), mocks.NewDefaultEmailVerificationMock(), mail, password.DefaultPolicy(), RegistrationOpen)
 			registerHandler.Handler(ctx)
 
 			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				sent, ok := mail.Last()
+				assert.True(t, ok, "a verification mail must be sent")
+				assert.Equal(t, "test@mail.com", sent.To)
+			}
 			if tt.expectedBody != "" {
 				if strings.Contains(tt.expectedBody, "*") {
 					var expected, actual map[string]interface{}
@@ -84,3 +116,155 @@ func TestRegisterHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterHandlerModes(t *testing.T) {
+	invitation := "invitation-token"
+
+	tests := []struct {
+		name            string
+		mode            RegistrationMode
+		requestBody     string
+		acceptSetup     func(*mocks.MockInvitationRepository)
+		expectedStatus  int
+		expectedError   string
+		expectedCreated bool
+		expectedAccept  bool
+	}{
+		{
+			name:           "Closed",
+			mode:           RegistrationClosed,
+			requestBody:    `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "invitation": "` + invitation + `"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "Registration is closed",
+		},
+		{
+			name:           "Invite Only Without Invitation",
+			mode:           RegistrationInviteOnly,
+			requestBody:    `{"username": "user", "email": "test@mail.com", "password": "correcthorse"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "Registration requires an invitation",
+		},
+		{
+			name:           "Invite Only With Invitation",
+			mode:           RegistrationInviteOnly,
+			requestBody:    `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "invitation": "` + invitation + `"}`,
+			expectedStatus: http.StatusCreated,
+			expectedAccept: true,
+		},
+		{
+			name:        "Invalid Invitation",
+			mode:        RegistrationInviteOnly,
+			requestBody: `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "invitation": "` + invitation + `"}`,
+			acceptSetup: func(mir *mocks.MockInvitationRepository) {
+				mir.AcceptInvitationFunc = func(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+					return nil, storage.ErrInvitationInvalid
+				}
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  storage.ErrInvitationInvalid.Error(),
+			expectedAccept: true,
+		},
+		{
+			name:        "Invitation For Another Email",
+			mode:        RegistrationInviteOnly,
+			requestBody: `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "invitation": "` + invitation + `"}`,
+			acceptSetup: func(mir *mocks.MockInvitationRepository) {
+				mir.AcceptInvitationFunc = func(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+					return nil, storage.ErrInvitationEmailMismatch
+				}
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  storage.ErrInvitationEmailMismatch.Error(),
+			expectedAccept: true,
+		},
+		{
+			name:        "Invited User Exists",
+			mode:        RegistrationInviteOnly,
+			requestBody: `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "invitation": "` + invitation + `"}`,
+			acceptSetup: func(mir *mocks.MockInvitationRepository) {
+				mir.AcceptInvitationFunc = func(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+					return nil, storage.ErrUserExists
+				}
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  "User already exists",
+			expectedAccept: true,
+		},
+		{
+			name:           "Open With Invitation",
+			mode:           RegistrationOpen,
+			requestBody:    `{"username": "user", "email": "test@mail.com", "password": "correcthorse", "invitation": "` + invitation + `"}`,
+			expectedStatus: http.StatusCreated,
+			expectedAccept: true,
+		},
+		{
+			name:            "Open Without Invitation",
+			mode:            RegistrationOpen,
+			requestBody:     `{"username": "user", "email": "test@mail.com", "password": "correcthorse"}`,
+			expectedStatus:  http.StatusCreated,
+			expectedCreated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.CreateUserFunc = func(ctx context.Context, user *models.User) error {
+				created = true
+				return nil
+			}
+
+			accepted := ""
+			mockInvitationRepo := mocks.NewDefaultInvitationMock()
+			if tt.acceptSetup != nil {
+				tt.acceptSetup(mockInvitationRepo)
+			}
+			accept := mockInvitationRepo.AcceptInvitationFunc
+			mockInvitationRepo.AcceptInvitationFunc = func(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+				accepted = tokenHash
+				return accept(ctx, tokenHash, user)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.requestBody)
+
+			registerHandler := NewRegisterHandler(mockUserRepo, mockInvitationRepo, mocks.NewDefaultEmailVerificationMock(), mailer.NewMemoryMailer(), password.DefaultPolicy(), tt.mode)
+			registerHandler.Handler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedError != "" {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedError, body["error"])
+			}
+			assert.Equal(t, tt.expectedCreated, created, "users without an invitation are created directly")
+			if tt.expectedAccept {
+				assert.Equal(t, hashInvitationToken(invitation), accepted, "only the token hash reaches storage")
+			} else {
+				assert.Empty(t, accepted)
+			}
+		})
+	}
+}
+
+func TestRegistrationModeFromEnv(t *testing.T) {
+	t.Setenv("REGISTRATION_MODE", "")
+	mode, err := RegistrationModeFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, RegistrationOpen, mode)
+
+	t.Setenv("REGISTRATION_MODE", "invite-only")
+	mode, err = RegistrationModeFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, RegistrationInviteOnly, mode)
+
+	t.Setenv("REGISTRATION_MODE", "closed")
+	mode, err = RegistrationModeFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, RegistrationClosed, mode)
+
+	t.Setenv("REGISTRATION_MODE", "invite")
+	_, err = RegistrationModeFromEnv()
+	assert.ErrorContains(t, err, "REGISTRATION_MODE")
+}
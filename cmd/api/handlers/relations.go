@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/rebac"
+	"multitech/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RelationsHandler struct {
+	checker   *rebac.Checker
+	tupleRepo storage.RelationTupleRepository
+}
+
+func NewRelationsHandler(checker *rebac.Checker, tupleRepo storage.RelationTupleRepository) *RelationsHandler {
+	return &RelationsHandler{
+		checker:   checker,
+		tupleRepo: tupleRepo,
+	}
+}
+
+// @Summary Check a relation
+// @Description Check whether a subject, the caller by default, has a relation to an object, directly, through a userset or as derived by the schema. Requires the relations:read permission
+// @Tags authz
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CheckRelationRequest true "Object, relation and subject"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authz/check [post]
+func (relations *RelationsHandler) CheckHandler(ctx *gin.Context) {
+	var request models.CheckRelationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	object, err := models.ParseObjectRef(request.Object)
+	if err == nil {
+		err = models.ValidateRelation(request.Relation)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	subject, ok := requestSubject(ctx, request.Subject)
+	if !ok {
+		return
+	}
+
+	allowed, err := relations.checker.Check(ctx.Request.Context(), object, request.Relation, subject)
+	if err != nil {
+		respondRelationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"allowed": allowed,
+	})
+}
+
+// @Summary Expand a relation
+// @Description Show every subject holding a relation to an object as a tree of usersets. Requires the relations:read permission
+// @Tags authz
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.ExpandRelationRequest true "Object and relation"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authz/expand [post]
+func (relations *RelationsHandler) ExpandHandler(ctx *gin.Context) {
+	var request models.ExpandRelationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	object, err := models.ParseObjectRef(request.Object)
+	if err == nil {
+		err = models.ValidateRelation(request.Relation)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	tree, err := relations.checker.Expand(ctx.Request.Context(), object, request.Relation)
+	if err != nil {
+		respondRelationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"tree": tree,
+	})
+}
+
+// @Summary List objects
+// @Description List the objects of a type that a subject, the caller by default, has a relation to. Requires the relations:read permission
+// @Tags authz
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.ListObjectsRequest true "Object type, relation and subject"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authz/list-objects [post]
+func (relations *RelationsHandler) ListObjectsHandler(ctx *gin.Context) {
+	var request models.ListObjectsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	err := models.ValidateObjectType(request.ObjectType)
+	if err == nil {
+		err = models.ValidateRelation(request.Relation)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	subject, ok := requestSubject(ctx, request.Subject)
+	if !ok {
+		return
+	}
+
+	objects, err := relations.checker.ListObjects(ctx.Request.Context(), request.ObjectType, request.Relation, subject)
+	if err != nil {
+		respondRelationError(ctx, err)
+		return
+	}
+
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.String())
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"objects": names,
+	})
+}
+
+// @Summary List relation tuples
+// @Description List stored relation tuples, optionally only those of an object, a relation or a subject. Requires the relations:read permission
+// @Tags authz
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param object query string false "Object, such as document:readme"
+// @Param relation query string false "Relation"
+// @Param subject query string false "Subject, such as user:7 or team:eng#member"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authz/tuples [get]
+func (relations *RelationsHandler) ListTuplesHandler(ctx *gin.Context) {
+	var filter storage.RelationTupleFilter
+	if value := ctx.Query("object"); value != "" {
+		object, err := models.ParseObjectRef(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		filter.ObjectType, filter.ObjectID = object.Type, object.ID
+	}
+	filter.Relation = ctx.Query("relation")
+	if value := ctx.Query("subject"); value != "" {
+		subject, err := models.ParseSubjectRef(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		filter.SubjectType, filter.SubjectID, filter.SubjectRelation = subject.Type, subject.ID, subject.Relation
+	}
+
+	tuples, err := relations.tupleRepo.ReadTuples(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing relation tuples: " + err.Error(),
+		})
+		return
+	}
+
+	keys := make([]models.TupleKey, 0, len(tuples))
+	for _, tuple := range tuples {
+		keys = append(keys, tuple.Key())
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"tuples": keys,
+	})
+}
+
+// @Summary Write relation tuples
+// @Description Store relation tuples, all or none of them. Storing a tuple again is not an error. Requires the relations:write permission
+// @Tags authz
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.RelationTuplesRequest true "Tuples"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authz/tuples [post]
+func (relations *RelationsHandler) WriteTuplesHandler(ctx *gin.Context) {
+	tuples, ok := relations.bindTuples(ctx)
+	if !ok {
+		return
+	}
+
+	if err := relations.tupleRepo.WriteTuples(ctx.Request.Context(), tuples); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error writing relation tuples: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Relation tuples written",
+	})
+}
+
+// @Summary Delete relation tuples
+// @Description Delete relation tuples. Tuples that are not stored are ignored. Requires the relations:write permission
+// @Tags authz
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.RelationTuplesRequest true "Tuples"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /authz/tuples [delete]
+func (relations *RelationsHandler) DeleteTuplesHandler(ctx *gin.Context) {
+	tuples, ok := relations.bindTuples(ctx)
+	if !ok {
+		return
+	}
+
+	if err := relations.tupleRepo.DeleteTuples(ctx.Request.Context(), tuples); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting relation tuples: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Relation tuples deleted",
+	})
+}
+
+// bindTuples parses the tuples of the request body and checks them against
+// the schema.
+func (relations *RelationsHandler) bindTuples(ctx *gin.Context) ([]models.RelationTuple, bool) {
+	var request models.RelationTuplesRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	tuples := make([]models.RelationTuple, 0, len(request.Tuples))
+	for _, key := range request.Tuples {
+		tuple, err := key.Tuple()
+		if err == nil {
+			err = relations.checker.Schema().Validate(tuple)
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, true
+}
+
+// requestSubject parses subject, defaulting to the caller.
+func requestSubject(ctx *gin.Context, subject string) (models.SubjectRef, bool) {
+	if subject == "" {
+		return middleware.CallerSubject(ctx), true
+	}
+	parsed, err := models.ParseSubjectRef(subject)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return models.SubjectRef{}, false
+	}
+	return parsed, true
+}
+
+func respondRelationError(ctx *gin.Context, err error) {
+	if errors.Is(err, rebac.ErrMaxDepth) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Error checking relations: " + err.Error(),
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/rebac"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationTupleRepository(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+	bg := context.Background()
+
+	tupleRepo := storage.NewGormRelationTupleRepository(tx)
+
+	tuples := testRelationTuples()
+	require.NoError(t, tupleRepo.WriteTuples(bg, tuples))
+	require.NoError(t, tupleRepo.WriteTuples(bg, tuples[:1]), "writing a tuple twice is not an error")
+
+	stored, err := tupleRepo.ReadTuples(bg, storage.RelationTupleFilter{ObjectType: "document", ObjectID: "readme"})
+	require.NoError(t, err)
+	keys := []string{}
+	for _, tuple := range stored {
+		keys = append(keys, tuple.String())
+	}
+	assert.Equal(t, []string{"document:readme#owner@user:3", "document:readme#viewer@team:eng#member"}, keys)
+
+	stored, err = tupleRepo.ReadTuples(bg, storage.RelationTupleFilter{SubjectType: "user", SubjectID: "7"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+
+	stored, err = tupleRepo.ReadTuples(bg, storage.RelationTupleFilter{SubjectType: "team", SubjectID: "eng"})
+	require.NoError(t, err)
+	assert.Empty(t, stored, "the userset team:eng#member is not the subject team:eng")
+
+	ids, err := tupleRepo.ListObjectIDs(bg, "document")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"readme", "roadmap"}, ids)
+
+	checker := rebac.NewChecker(tupleRepo, testRelationSchema())
+	readme := models.ObjectRef{Type: "document", ID: "readme"}
+	allowed, err := checker.Check(bg, readme, "viewer", models.SubjectRef{Type: "user", ID: "7"})
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, tupleRepo.DeleteTuples(bg, tuples[:1]))
+	require.NoError(t, tupleRepo.DeleteTuples(bg, tuples[:1]), "deleting a missing tuple is not an error")
+	allowed, err = checker.Check(bg, readme, "viewer", models.SubjectRef{Type: "user", ID: "7"})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/rebac"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRelationTuples() []models.RelationTuple {
+	return []models.RelationTuple{
+		{ObjectType: "team", ObjectID: "eng", Relation: "member", SubjectType: "user", SubjectID: "7"},
+		{ObjectType: "document", ObjectID: "readme", Relation: "owner", SubjectType: "user", SubjectID: "3"},
+		{ObjectType: "document", ObjectID: "readme", Relation: "viewer", SubjectType: "team", SubjectID: "eng", SubjectRelation: "member"},
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "owner", SubjectType: "user", SubjectID: "7"},
+	}
+}
+
+func testRelationSchema() *rebac.Schema {
+	return &rebac.Schema{Types: map[string]rebac.TypeDefinition{
+		"document": {Relations: map[string]rebac.RelationDefinition{
+			"owner":  {},
+			"viewer": {ImpliedBy: []string{"owner"}},
+		}},
+	}}
+}
+
+func newTestRelationsHandler(tupleRepo storage.RelationTupleRepository) *RelationsHandler {
+	return NewRelationsHandler(rebac.NewChecker(tupleRepo, testRelationSchema()), tupleRepo)
+}
+
+func TestRelationsCheckHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Caller Through Team",
+			body:           `{"object":"document:readme","relation":"viewer"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"allowed":true}`,
+		},
+		{
+			name:           "Other Subject Implied",
+			body:           `{"object":"document:readme","relation":"viewer","subject":"user:3"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"allowed":true}`,
+		},
+		{
+			name:           "Not Related",
+			body:           `{"object":"document:readme","relation":"owner","subject":"user:7"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"allowed":false}`,
+		},
+		{
+			name:           "Invalid Object",
+			body:           `{"object":"readme","relation":"viewer"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Subject",
+			body:           `{"object":"document:readme","relation":"viewer","subject":"user"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing Relation",
+			body:           `{"object":"document:readme"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("subject_type", middleware.SubjectTypeUser)
+			ctx.Set("user_id", uint(7))
+			testutils.SetJSONBody(ctx, tt.body)
+
+			newTestRelationsHandler(mocks.NewRelationTupleMock(testRelationTuples()...)).CheckHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRelationsCheckHandlerStorageError(t *testing.T) {
+	mockTupleRepo := mocks.NewDefaultRelationTupleMock()
+	mockTupleRepo.ReadTuplesFunc = func(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+		return nil, errors.New("database error")
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"object":"document:readme","relation":"viewer","subject":"user:1"}`)
+
+	newTestRelationsHandler(mockTupleRepo).CheckHandler(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestRelationsExpandHandler(t *testing.T) {
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"object":"document:readme","relation":"viewer"}`)
+
+	newTestRelationsHandler(mocks.NewRelationTupleMock(testRelationTuples()...)).ExpandHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"tree":{
+		"userset":"document:readme#viewer",
+		"subjects":["team:eng#member"],
+		"children":[
+			{"userset":"team:eng#member","subjects":["user:7"]},
+			{"userset":"document:readme#owner","subjects":["user:3"]}
+		]
+	}}`, recorder.Body.String())
+}
+
+func TestRelationsListObjectsHandler(t *testing.T) {
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("subject_type", middleware.SubjectTypeUser)
+	ctx.Set("user_id", uint(7))
+	testutils.SetJSONBody(ctx, `{"object_type":"document","relation":"viewer"}`)
+
+	newTestRelationsHandler(mocks.NewRelationTupleMock(testRelationTuples()...)).ListObjectsHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"objects":["document:readme","document:roadmap"]}`, recorder.Body.String())
+}
+
+func TestRelationsListTuplesHandler(t *testing.T) {
+	var filter storage.RelationTupleFilter
+	mockTupleRepo := mocks.NewDefaultRelationTupleMock()
+	mockTupleRepo.ReadTuplesFunc = func(ctx context.Context, f storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+		filter = f
+		return testRelationTuples()[2:3], nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/authz/tuples?object=document:readme&subject=team:eng%23member", nil)
+
+	newTestRelationsHandler(mockTupleRepo).ListTuplesHandler(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, storage.RelationTupleFilter{
+		ObjectType: "document", ObjectID: "readme",
+		SubjectType: "team", SubjectID: "eng", SubjectRelation: "member",
+	}, filter)
+	assert.JSONEq(t, `{"tuples":[{"object":"document:readme","relation":"viewer","subject":"team:eng#member"}]}`, recorder.Body.String())
+}
+
+func TestRelationsWriteTuplesHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		writeErr       error
+		expectedStatus int
+		expectWritten  []string
+	}{
+		{
+			name:           "Success",
+			body:           `{"tuples":[{"object":"document:readme","relation":"viewer","subject":"user:*"},{"object":"team:eng","relation":"member","subject":"user:9"}]}`,
+			expectedStatus: http.StatusOK,
+			expectWritten:  []string{"document:readme#viewer@user:*", "team:eng#member@user:9"},
+		},
+		{
+			name:           "Relation Not In Schema",
+			body:           `{"tuples":[{"object":"document:readme","relation":"approver","subject":"user:9"}]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Subject",
+			body:           `{"tuples":[{"object":"document:readme","relation":"viewer","subject":"user:*#member"}]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "No Tuples",
+			body:           `{"tuples":[]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Storage Error",
+			body:           `{"tuples":[{"object":"document:readme","relation":"viewer","subject":"user:9"}]}`,
+			writeErr:       errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written []string
+			mockTupleRepo := mocks.NewDefaultRelationTupleMock()
+			mockTupleRepo.WriteTuplesFunc = func(ctx context.Context, tuples []models.RelationTuple) error {
+				if tt.writeErr != nil {
+					return tt.writeErr
+				}
+				for _, tuple := range tuples {
+					written = append(written, tuple.String())
+				}
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			testutils.SetJSONBody(ctx, tt.body)
+
+			newTestRelationsHandler(mockTupleRepo).WriteTuplesHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectWritten, written)
+		})
+	}
+}
+
+func TestRelationsDeleteTuplesHandler(t *testing.T) {
+	var deleted []string
+	mockTupleRepo := mocks.NewDefaultRelationTupleMock()
+	mockTupleRepo.DeleteTuplesFunc = func(ctx context.Context, tuples []models.RelationTuple) error {
+		for _, tuple := range tuples {
+			deleted = append(deleted, tuple.String())
+		}
+		return nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	testutils.SetJSONBody(ctx, `{"tuples":[{"object":"document:readme","relation":"viewer","subject":"team:eng#member"}]}`)
+
+	newTestRelationsHandler(mockTupleRepo).DeleteTuplesHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"document:readme#viewer@team:eng#member"}, deleted)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"multitech/middleware"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,14 +18,25 @@ func NewProtectedHandler() *ProtectedHandler {
 // @Description Example protected endpoint
 // @Tags protected
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Router /protected [get]
 func (*ProtectedHandler) Handler(ctx *gin.Context) {
+	if ctx.GetString("subject_type") == middleware.SubjectTypeService {
+		ctx.JSON(http.StatusOK, gin.H{
+			"subject_type":       middleware.SubjectTypeService,
+			"service_account_id": ctx.GetUint("service_account_id"),
+			"message":            "Protected content",
+		})
+		return
+	}
+
 	userID := ctx.GetUint("user_id")
 	ctx.JSON(http.StatusOK, gin.H{
-		"user_id": userID,
-		"message": "Protected content",
+		"subject_type": middleware.SubjectTypeUser,
+		"user_id":      userID,
+		"message":      "Protected content",
 	})
 }
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RolesHandler struct {
+	roleRepo storage.RoleRepository
+}
+
+func NewRolesHandler(roleRepo storage.RoleRepository) *RolesHandler {
+	return &RolesHandler{
+		roleRepo: roleRepo,
+	}
+}
+
+// @Summary List roles
+// @Description List all roles with their permissions. Requires the roles:read permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/roles [get]
+func (roles *RolesHandler) ListHandler(ctx *gin.Context) {
+	list, err := roles.roleRepo.ListRoles(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing roles: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"roles": roleResponses(list),
+	})
+}
+
+// @Summary List roles of a user
+// @Description List the roles granted to a user. Requires the roles:read permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/users/{id}/roles [get]
+func (roles *RolesHandler) UserRolesHandler(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	list, err := roles.roleRepo.GetUserRoles(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error listing roles: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"roles": roleResponses(list),
+	})
+}
+
+// @Summary Grant a role
+// @Description Grant a role to a user. It is included in the user's access tokens from the next login or token refresh on. Requires the roles:write permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body models.AssignRoleRequest true "Role name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/users/{id}/roles [post]
+func (roles *RolesHandler) AssignHandler(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var request models.AssignRoleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := roles.roleRepo.AssignRole(ctx.Request.Context(), userID, request.Role); err != nil {
+		respondRoleError(ctx, err, "Error granting role: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Role granted",
+	})
+}
+
+// @Summary Revoke a role
+// @Description Revoke a role from a user. Access tokens minted before keep it until they expire. Requires the roles:write permission
+// @Tags admin
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/users/{id}/roles/{role} [delete]
+func (roles *RolesHandler) RevokeHandler(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := roles.roleRepo.RevokeRole(ctx.Request.Context(), userID, ctx.Param("role")); err != nil {
+		respondRoleError(ctx, err, "Error revoking role: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Role revoked",
+	})
+}
+
+func userIDParam(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": storage.ErrUserNotFound.Error(),
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondRoleError(ctx *gin.Context, err error, message string) {
+	if errors.Is(err, storage.ErrRoleNotFound) || errors.Is(err, storage.ErrUserNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error": message + err.Error(),
+	})
+}
+
+func roleResponses(roles []models.Role) []gin.H {
+	response := make([]gin.H, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, gin.H{
+			"name":        role.Name,
+			"description": role.Description,
+			"permissions": role.PermissionNames(),
+		})
+	}
+	return response
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleRepository(t *testing.T) {
+	tx := testutils.TestDB.Begin()
+	defer tx.Rollback()
+	bg := context.Background()
+
+	user := &models.User{Username: "roleuser", Email: "roleuser@example.com", Password: "x"}
+	require.NoError(t, tx.Create(user).Error)
+
+	roleRepo := storage.NewGormRoleRepository(tx)
+	userRepo := storage.NewGormUserRepository(tx)
+
+	editor := &models.Role{Name: "editor", Permissions: []models.Permission{{Name: "posts:write"}, {Name: "posts:read"}}}
+	require.NoError(t, roleRepo.CreateRole(bg, editor))
+	reader := &models.Role{Name: "reader", Permissions: []models.Permission{{Name: "posts:read"}}}
+	require.NoError(t, roleRepo.CreateRole(bg, reader), "permissions are shared between roles")
+	assert.ErrorIs(t, roleRepo.CreateRole(bg, &models.Role{Name: "editor"}), storage.ErrRoleExists)
+
+	require.NoError(t, roleRepo.AssignRole(bg, user.ID, "editor"))
+	require.NoError(t, roleRepo.AssignRole(bg, user.ID, "editor"), "granting twice is not an error")
+	assert.ErrorIs(t, roleRepo.AssignRole(bg, user.ID, "wizard"), storage.ErrRoleNotFound)
+
+	roles, err := roleRepo.GetUserRoles(bg, user.ID)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.ElementsMatch(t, []string{"posts:write", "posts:read"}, roles[0].PermissionNames())
+
+	stored, err := userRepo.GetUserByID(bg, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor"}, stored.RoleNames())
+
+	require.NoError(t, roleRepo.RevokeRole(bg, user.ID, "editor"))
+	stored, err = userRepo.GetUserByIdentifier(bg, "roleuser")
+	require.NoError(t, err)
+	assert.Empty(t, stored.RoleNames())
+
+	// Last, as the failed insert aborts the transaction.
+	assert.ErrorIs(t, roleRepo.AssignRole(bg, user.ID+1000, "editor"), storage.ErrUserNotFound)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolesListHandler(t *testing.T) {
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return []models.Role{
+			{Name: "admin", Description: "Everything", Permissions: []models.Permission{{Name: "*"}}},
+			{Name: "support", Permissions: []models.Permission{{Name: "users:read"}, {Name: "roles:read"}}},
+		}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+
+	NewRolesHandler(mockRoleRepo).ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"roles":[
+		{"name":"admin","description":"Everything","permissions":["*"]},
+		{"name":"support","description":"","permissions":["users:read","roles:read"]}
+	]}`, recorder.Body.String())
+}
+
+func TestRolesUserRolesHandler(t *testing.T) {
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.GetUserRolesFunc = func(ctx context.Context, userID uint) ([]models.Role, error) {
+		assert.Equal(t, uint(7), userID)
+		return []models.Role{{Name: "support"}}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	NewRolesHandler(mockRoleRepo).UserRolesHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"roles":[{"name":"support","description":"","permissions":[]}]}`, recorder.Body.String())
+}
+
+func TestRolesAssignHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		assignErr      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success",
+			userID:         "7",
+			body:           `{"role":"support"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Role granted"}`,
+		},
+		{
+			name:           "Missing Role",
+			userID:         "7",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid User ID",
+			userID:         "abc",
+			body:           `{"role":"support"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"User not found"}`,
+		},
+		{
+			name:           "Unknown Role",
+			userID:         "7",
+			body:           `{"role":"wizard"}`,
+			assignErr:      storage.ErrRoleNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"Role not found"}`,
+		},
+		{
+			name:           "Unknown User",
+			userID:         "999",
+			body:           `{"role":"support"}`,
+			assignErr:      storage.ErrUserNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"User not found"}`,
+		},
+		{
+			name:           "Storage Error",
+			userID:         "7",
+			body:           `{"role":"support"}`,
+			assignErr:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Error granting role: database error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var assigned string
+			mockRoleRepo := mocks.NewDefaultRoleMock()
+			mockRoleRepo.AssignRoleFunc = func(ctx context.Context, userID uint, roleName string) error {
+				assigned = roleName
+				return tt.assignErr
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Params = gin.Params{{Key: "id", Value: tt.userID}}
+			testutils.SetJSONBody(ctx, tt.body)
+
+			NewRolesHandler(mockRoleRepo).AssignHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "support", assigned)
+			}
+		})
+	}
+}
+
+func TestRolesRevokeHandler(t *testing.T) {
+	var revokedUser uint
+	var revokedRole string
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.RevokeRoleFunc = func(ctx context.Context, userID uint, roleName string) error {
+		revokedUser, revokedRole = userID, roleName
+		return nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Params = gin.Params{{Key: "id", Value: "7"}, {Key: "role", Value: "support"}}
+
+	NewRolesHandler(mockRoleRepo).RevokeHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, uint(7), revokedUser)
+	assert.Equal(t, "support", revokedRole)
+}
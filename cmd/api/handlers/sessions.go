@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"multitech/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionsHandler struct {
+	sessRepo    storage.SessionsRepository
+	refreshRepo storage.RefreshTokenRepository
+}
+
+func NewSessionsHandler(sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository) *SessionsHandler {
+	return &SessionsHandler{
+		sessRepo:    sessRepo,
+		refreshRepo: refreshRepo,
+	}
+}
+
+// @Summary List sessions
+// @Description List the active sessions of the current user with their device metadata
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /sessions [get]
+func (sessions *SessionsHandler) ListHandler(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+	currentID := ctx.GetString("session_id")
+
+	userSessions, err := sessions.sessRepo.ListUserSessions(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving sessions: " + err.Error(),
+		})
+		return
+	}
+
+	result := make([]gin.H, 0, len(userSessions))
+	for _, session := range userSessions {
+		result = append(result, gin.H{
+			"id":         session.ID,
+			"ip":         session.IP,
+			"user_agent": session.UserAgent,
+			"created_at": session.CreatedAt,
+			"last_seen":  session.LastSeen,
+			"expires_at": session.ExpiresAt,
+			"current":    session.ID == currentID,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"sessions": result,
+	})
+}
+
+// @Summary Revoke session
+// @Description Revoke one of the current user's sessions together with its refresh tokens
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /sessions/{id} [delete]
+func (sessions *SessionsHandler) DeleteHandler(ctx *gin.Context) {
+	userID := ctx.GetUint("user_id")
+	sessionID := ctx.Param("id")
+
+	session, err := sessions.sessRepo.GetSessionByID(ctx.Request.Context(), sessionID)
+	if err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error retrieving session: " + err.Error(),
+		})
+		return
+	}
+	// Sessions of other users are reported as missing so that their IDs cannot be probed.
+	if session == nil || session.UserID != userID {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	if err := sessions.sessRepo.DeleteSession(ctx.Request.Context(), sessionID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error deleting session: " + err.Error(),
+		})
+		return
+	}
+
+	if err := sessions.refreshRepo.RevokeFamily(ctx.Request.Context(), sessionID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error revoking refresh tokens: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
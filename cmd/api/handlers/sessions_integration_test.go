@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionsHandlerListAndRevoke(t *testing.T) {
+	bg := context.Background()
+	sessRepo := storage.NewRedisSessionRepository(testutils.TestRedis)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(testutils.TestRedis)
+	defer refreshRepo.RevokeUserRefreshTokens(bg, 61)
+	defer sessRepo.DeleteUserSessions(bg, 61)
+
+	createTestSession(t, sessRepo, "sessions-phone", 61, "sessions-phone-token")
+	createTestSession(t, sessRepo, "sessions-laptop", 61, "sessions-laptop-token")
+	assert.NoError(t, refreshRepo.StoreRefreshToken(bg, "sessions-laptop-refresh", &models.RefreshToken{
+		UserID:    61,
+		FamilyID:  "sessions-laptop",
+		CreatedAt: time.Now(),
+	}, time.Minute))
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(61))
+	ctx.Set("session_id", "sessions-phone")
+
+	handler := NewSessionsHandler(sessRepo, refreshRepo)
+	handler.ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Sessions []map[string]interface{} `json:"sessions"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Sessions, 2)
+
+	ctx, recorder = testutils.NewTestContext()
+	ctx.Set("user_id", uint(61))
+	ctx.Params = gin.Params{{Key: "id", Value: "sessions-laptop"}}
+	handler.DeleteHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	_, err := sessRepo.GetSession(bg, "sessions-laptop-token")
+	assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+	_, err = refreshRepo.GetRefreshToken(bg, "sessions-laptop-refresh")
+	assert.ErrorIs(t, err, storage.ErrRefreshTokenNotFound)
+
+	remaining, err := sessRepo.ListUserSessions(bg, 61)
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "sessions-phone", remaining[0].ID)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionsListHandler(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mockSessRepo := mocks.NewDefaultSessionsMock()
+	mockSessRepo.ListUserSessionsFunc = func(ctx context.Context, userID uint) ([]*models.Session, error) {
+		assert.Equal(t, uint(1), userID)
+		return []*models.Session{
+			{ID: "current", UserID: 1, IP: "10.0.0.1", UserAgent: "curl/8.0", CreatedAt: created, LastSeen: created, ExpiresAt: created},
+			{ID: "other", UserID: 1, IP: "10.0.0.2", UserAgent: "Firefox", CreatedAt: created, LastSeen: created, ExpiresAt: created},
+		}, nil
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(1))
+	ctx.Set("session_id", "current")
+
+	handler := NewSessionsHandler(mockSessRepo, mocks.NewDefaultRefreshTokenMock())
+	handler.ListHandler(ctx)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Sessions []map[string]interface{} `json:"sessions"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Sessions, 2)
+	assert.Equal(t, "current", response.Sessions[0]["id"])
+	assert.Equal(t, true, response.Sessions[0]["current"])
+	assert.Equal(t, "curl/8.0", response.Sessions[0]["user_agent"])
+	assert.Equal(t, "10.0.0.1", response.Sessions[0]["ip"])
+	assert.Equal(t, false, response.Sessions[1]["current"])
+}
+
+func TestSessionsDeleteHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		sessionID        string
+		mockSessSetup    func(*mocks.MockSessionsRepository)
+		mockRefreshSetup func(*mocks.MockRefreshTokenRepository)
+		expectedStatus   int
+		expectedBody     string
+	}{
+		{
+			name:      "Success",
+			sessionID: "laptop",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+					if sessionID != "laptop" {
+						return errors.New("unexpected session")
+					}
+					return nil
+				}
+			},
+			mockRefreshSetup: func(mrr *mocks.MockRefreshTokenRepository) {
+				mrr.RevokeFamilyFunc = func(ctx context.Context, familyID string) error {
+					if familyID != "laptop" {
+						return errors.New("unexpected family")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Session revoked"}`,
+		},
+		{
+			name:      "Unknown Session",
+			sessionID: "missing",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.GetSessionByIDFunc = func(ctx context.Context, sessionID string) (*models.Session, error) {
+					return nil, storage.ErrSessionNotFound
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"Session not found"}`,
+		},
+		{
+			name:      "Foreign Session",
+			sessionID: "foreign",
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.GetSessionByIDFunc = func(ctx context.Context, sessionID string) (*models.Session, error) {
+					return &models.Session{ID: sessionID, UserID: 2}, nil
+				}
+				msr.DeleteSessionFunc = func(ctx context.Context, sessionID string) error {
+					return errors.New("must not delete foreign session")
+				}
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"Session not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockRefreshRepo := mocks.NewDefaultRefreshTokenMock()
+			if tt.mockSessSetup != nil {
+				tt.mockSessSetup(mockSessRepo)
+			}
+			if tt.mockRefreshSetup != nil {
+				tt.mockRefreshSetup(mockRefreshRepo)
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("user_id", uint(1))
+			ctx.Params = gin.Params{{Key: "id", Value: tt.sessionID}}
+
+			handler := NewSessionsHandler(mockSessRepo, mockRefreshRepo)
+			handler.DeleteHandler(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.JSONEq(t, tt.expectedBody, recorder.Body.String())
+		})
+	}
+}
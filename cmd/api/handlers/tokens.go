@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// issueTokens opens a new session for the user on the requesting device and
+// returns an access token together with the first refresh token of the
+// session's token family. The session ID doubles as the family ID.
+func issueTokens(ctx *gin.Context, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, user *models.User) (*tokenPair, error) {
+	return issueMemberTokens(ctx, sessRepo, refreshRepo, user, nil)
+}
+
+// issueMemberTokens is issueTokens for a session within the organization of
+// member, whose tokens are organization tokens. Without a member it opens a
+// global session.
+func issueMemberTokens(ctx *gin.Context, sessRepo storage.SessionsRepository, refreshRepo storage.RefreshTokenRepository, user *models.User, member *models.OrganizationMember) (*tokenPair, error) {
+	var organizationID uint
+	if member != nil {
+		organizationID = member.OrganizationID
+	}
+	session, err := openSession(ctx, sessRepo, user.ID, organizationID, middleware.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.ID,
+		CreatedAt: session.CreatedAt,
+	}
+	if err := refreshRepo.StoreRefreshToken(ctx.Request.Context(), refreshToken, refresh, middleware.RefreshTokenTTL); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := issueMemberAccessToken(ctx.Request.Context(), sessRepo, user, member, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// openSession records a new session for the user on the requesting device,
+// within the organization unless organizationID is 0, lasting ttl.
+func openSession(ctx *gin.Context, sessRepo storage.SessionsRepository, userID uint, organizationID uint, ttl time.Duration) (*models.Session, error) {
+	sessionID, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:             sessionID,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		IP:             ctx.ClientIP(),
+		UserAgent:      ctx.Request.UserAgent(),
+		CreatedAt:      now,
+		LastSeen:       now,
+		ExpiresAt:      now.Add(ttl),
+	}
+	if err := sessRepo.CreateSession(ctx.Request.Context(), session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// issueAccessToken mints a first-party access token carrying the roles the
+// user was loaded with.
+func issueAccessToken(ctx context.Context, sessRepo storage.SessionsRepository, user *models.User, sessionID string) (string, error) {
+	token, err := middleware.GenerateToken(user.ID, sessionID, user.RoleNames())
+	if err != nil {
+		return "", err
+	}
+	return token, storeAccessToken(ctx, sessRepo, token, sessionID)
+}
+
+// issueMemberAccessToken mints an organization token carrying the role of
+// the member, or without a member a first-party access token.
+func issueMemberAccessToken(ctx context.Context, sessRepo storage.SessionsRepository, user *models.User, member *models.OrganizationMember, sessionID string) (string, error) {
+	if member == nil {
+		return issueAccessToken(ctx, sessRepo, user, sessionID)
+	}
+	token, err := middleware.GenerateOrganizationToken(user.ID, sessionID, member.OrganizationID, []string{member.Role})
+	if err != nil {
+		return "", err
+	}
+	return token, storeAccessToken(ctx, sessRepo, token, sessionID)
+}
+
+func issueScopedAccessToken(ctx context.Context, sessRepo storage.SessionsRepository, userID uint, sessionID string, scope string) (string, error) {
+	token, err := middleware.GenerateScopedToken(userID, sessionID, scope)
+	if err != nil {
+		return "", err
+	}
+	return token, storeAccessToken(ctx, sessRepo, token, sessionID)
+}
+
+func storeAccessToken(ctx context.Context, sessRepo storage.SessionsRepository, token string, sessionID string) error {
+	return sessRepo.StoreSession(ctx, token, sessionID, middleware.AccessTokenTTL)
+}
+
+// issueServiceToken opens a short-lived session for a service account and
+// returns its access token. The client credentials grant has no refresh
+// token, so the session ends together with the token.
+func issueServiceToken(ctx *gin.Context, sessRepo storage.SessionsRepository, account *models.ServiceAccount, scope string) (string, error) {
+	sessionID, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:               sessionID,
+		ServiceAccountID: account.ID,
+		IP:               ctx.ClientIP(),
+		UserAgent:        ctx.Request.UserAgent(),
+		CreatedAt:        now,
+		LastSeen:         now,
+		ExpiresAt:        now.Add(middleware.AccessTokenTTL),
+	}
+	if err := sessRepo.CreateSession(ctx.Request.Context(), session); err != nil {
+		return "", err
+	}
+
+	token, err := middleware.GenerateServiceToken(account.ID, sessionID, scope)
+	if err != nil {
+		return "", err
+	}
+
+	if err := sessRepo.StoreSession(ctx.Request.Context(), token, sessionID, middleware.AccessTokenTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
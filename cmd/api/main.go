@@ -8,6 +8,14 @@ package main
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description Personal API key created with POST /api-keys
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name X-Admin-Token
+// @description Value of ADMIN_API_TOKEN
 // @BasePath /
 
 import (
@@ -17,6 +25,11 @@ import (
 	_ "multitech/docs"
 	"multitech/internal/config"
 	"multitech/middleware"
+	"multitech/pkg/authz"
+	"multitech/pkg/hasher"
+	"multitech/pkg/mailer"
+	"multitech/pkg/password"
+	"multitech/pkg/rebac"
 	"multitech/pkg/storage"
 	"net/http"
 	"os"
@@ -33,6 +46,10 @@ func main() {
 
 	config.LoadEnv()
 
+	if _, err := middleware.LoadKeyring(); err != nil {
+		log.Fatalf("Error loading signing keys: %v", err)
+	}
+
 	redisClient, err := storage.InitRedis()
 	if err != nil {
 		log.Fatalf("Error init redis: %v", err)
@@ -44,24 +61,176 @@ func main() {
 		log.Fatalf("PostgreSQL init error: %v", err)
 	}
 
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("Mailer init error: %v", err)
+	}
+	if err := handlers.CheckEmailLoginURL(mail); err != nil {
+		log.Fatalf("Mailer init error: %v", err)
+	}
+	if err := handlers.CheckPasswordResetURL(mail); err != nil {
+		log.Fatalf("Mailer init error: %v", err)
+	}
+
+	passwordHasher, err := hasher.FromEnv()
+	if err != nil {
+		log.Fatalf("Password hasher error: %v", err)
+	}
+	hasher.SetDefault(passwordHasher)
+
+	passwordPolicy, err := password.PolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Password policy error: %v", err)
+	}
+
+	policyEngine, err := authz.FromEnv()
+	if err != nil {
+		log.Fatalf("Authorization policy error: %v", err)
+	}
+
+	relationSchema, err := rebac.SchemaFromEnv()
+	if err != nil {
+		log.Fatalf("Relation schema error: %v", err)
+	}
+
+	registrationMode, err := handlers.RegistrationModeFromEnv()
+	if err != nil {
+		log.Fatalf("Registration mode error: %v", err)
+	}
+
+	oidcEnabled, err := handlers.OIDCEnabledFromEnv()
+	if err != nil {
+		log.Fatalf("OIDC config error: %v", err)
+	}
+
 	userRepo := storage.NewGormUserRepository(postgresClient)
 	sessRepo := storage.NewRedisSessionRepository(redisClient)
+	refreshRepo := storage.NewRedisRefreshTokenRepository(redisClient)
+	clientRepo := storage.NewGormOAuthClientRepository(postgresClient)
+	codeRepo := storage.NewRedisAuthorizationCodeRepository(redisClient)
+	serviceRepo := storage.NewGormServiceAccountRepository(postgresClient)
+	apiKeyRepo := storage.NewGormAPIKeyRepository(postgresClient)
+	challengeRepo := storage.NewRedisMFAChallengeRepository(redisClient)
+	recoveryRepo := storage.NewGormRecoveryCodeRepository(postgresClient)
+	verificationRepo := storage.NewRedisEmailVerificationRepository(redisClient)
+	resetRepo := storage.NewRedisPasswordResetRepository(redisClient)
+	emailLoginRepo := storage.NewRedisEmailLoginRepository(redisClient)
+	attemptRepo := storage.NewRedisLoginAttemptRepository(redisClient)
+	rateLimitRepo := storage.NewRedisRateLimitRepository(redisClient)
+	roleRepo := storage.NewGormRoleRepository(postgresClient)
+	tupleRepo := storage.NewGormRelationTupleRepository(postgresClient)
+	orgRepo := storage.NewGormOrganizationRepository(postgresClient)
+	invitationRepo := storage.NewGormInvitationRepository(postgresClient)
 
 	healthCheck := handlers.NewHealthCheck(redisClient)
-	loginHandler := handlers.NewLoginHandler(userRepo, sessRepo)
-	registerHandler := handlers.NewRegisterHandler(userRepo)
+	loginHandler := handlers.NewLoginHandler(userRepo, sessRepo, refreshRepo, challengeRepo, attemptRepo)
+	registerHandler := handlers.NewRegisterHandler(userRepo, invitationRepo, verificationRepo, mail, passwordPolicy, registrationMode)
+	verificationHandler := handlers.NewEmailVerificationHandler(userRepo, verificationRepo, mail)
+	passwordResetHandler := handlers.NewPasswordResetHandler(userRepo, resetRepo, sessRepo, refreshRepo, mail, passwordPolicy)
+	logoutHandler := handlers.NewLogoutHandler(sessRepo, refreshRepo)
+	refreshHandler := handlers.NewRefreshHandler(userRepo, sessRepo, refreshRepo, orgRepo)
+	sessionsHandler := handlers.NewSessionsHandler(sessRepo, refreshRepo)
 	protectedHandler := handlers.NewProtectedHandler()
+	jwksHandler := handlers.NewJWKSHandler()
+	apiKeysHandler := handlers.NewAPIKeysHandler(apiKeyRepo)
+	recoveryHandler := handlers.NewRecoveryHandler(userRepo, recoveryRepo, sessRepo, refreshRepo, passwordPolicy)
+	emailLoginHandler := handlers.NewEmailLoginHandler(userRepo, emailLoginRepo, sessRepo, refreshRepo, challengeRepo, mail)
+	lockoutHandler := handlers.NewLockoutHandler(attemptRepo)
+	mfaHandler := handlers.NewMFAHandler(userRepo, sessRepo, refreshRepo, challengeRepo, attemptRepo)
+	rolesHandler := handlers.NewRolesHandler(roleRepo)
+	relationsHandler := handlers.NewRelationsHandler(rebac.NewChecker(tupleRepo, relationSchema), tupleRepo)
+	oidcHandler := handlers.NewOIDCHandler(userRepo, clientRepo, codeRepo, sessRepo, serviceRepo)
 
-	authMiddleware := middleware.NewAuthMiddleware(sessRepo)
+	authMiddleware := middleware.NewAuthMiddleware(sessRepo, apiKeyRepo)
+	requireUser := middleware.RequireSubjectType(middleware.SubjectTypeUser)
+	requireSession := middleware.RequireSession()
+	requireFirstParty := middleware.RequireFirstParty()
+	requireAdmin := middleware.RequireAdminToken()
+	requireOrganization := middleware.RequireOrganization("id")
+	rbac := middleware.NewRBAC(roleRepo)
+	authorizer := middleware.NewAuthorizer(policyEngine, roleRepo)
+	policiesHandler := handlers.NewPoliciesHandler(authorizer)
+	invitationsHandler := handlers.NewInvitationsHandler(invitationRepo, rbac)
+	organizationsHandler := handlers.NewOrganizationsHandler(orgRepo, userRepo, sessRepo, refreshRepo, rbac)
+
+	rateLimiter := middleware.NewRateLimiter(rateLimitRepo)
+	rateLimit := func(name string, fallback string, key middleware.RateLimitKeyFunc) gin.HandlerFunc {
+		policy, err := middleware.LoadRateLimitPolicy(name, fallback, key)
+		if err != nil {
+			log.Fatalf("Rate limit config error: %v", err)
+		}
+		return rateLimiter.Middleware(policy)
+	}
+	loginLimit := rateLimit("login", "20/1m", middleware.RateLimitByIP)
+	registerLimit := rateLimit("register", "10/1h", middleware.RateLimitByIP)
+	authLimit := rateLimit("authenticated", "300/1m", middleware.RateLimitByAPIKey)
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(middleware.TrustedProxiesFromEnv()); err != nil {
+		log.Fatalf("Trusted proxies error: %v", err)
+	}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	router.GET("/health", healthCheck.Handler)
-	router.GET("/protected", authMiddleware.Middleware(), protectedHandler.Handler)
+	router.GET("/.well-known/jwks.json", jwksHandler.Handler)
+	if oidcEnabled {
+		router.GET("/.well-known/openid-configuration", oidcHandler.DiscoveryHandler)
+		router.GET("/authorize", loginLimit, oidcHandler.AuthorizeHandler)
+		router.GET("/authorize/consent", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, oidcHandler.ConsentInfoHandler)
+		router.POST("/authorize/consent", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, oidcHandler.ConsentHandler)
+		router.GET("/userinfo", authMiddleware.Middleware(), authLimit, requireUser, oidcHandler.UserInfoHandler)
+	}
+	router.GET("/protected", authMiddleware.Middleware(), authLimit, requireFirstParty, protectedHandler.Handler)
+	router.GET("/verify-email", verificationHandler.VerifyHandler)
 
-	router.POST("/login", loginHandler.Handler)
-	router.POST("/register", registerHandler.Handler)
+	router.POST("/login", loginLimit, loginHandler.Handler)
+	router.POST("/login/mfa", loginLimit, mfaHandler.LoginHandler)
+	router.POST("/login/email", loginLimit, emailLoginHandler.RequestHandler)
+	router.POST("/login/email/verify", loginLimit, emailLoginHandler.VerifyHandler)
+	router.POST("/register", registerLimit, registerHandler.Handler)
+	router.POST("/verify-email/resend", loginLimit, verificationHandler.ResendHandler)
+	router.POST("/password/forgot", loginLimit, passwordResetHandler.ForgotHandler)
+	router.POST("/password/reset", loginLimit, passwordResetHandler.ResetHandler)
+	router.POST("/recover", loginLimit, recoveryHandler.RecoverHandler)
+	router.POST("/token", loginLimit, oidcHandler.TokenHandler)
+	router.POST("/token/refresh", loginLimit, refreshHandler.Handler)
+	router.POST("/logout", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, logoutHandler.Handler)
+	router.POST("/logout/all", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, logoutHandler.AllHandler)
+	router.GET("/sessions", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, sessionsHandler.ListHandler)
+	router.DELETE("/sessions/:id", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, sessionsHandler.DeleteHandler)
+	router.POST("/mfa/totp/enroll", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, mfaHandler.EnrollHandler)
+	router.POST("/mfa/totp/confirm", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, mfaHandler.ConfirmHandler)
+	router.GET("/recovery-codes", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, recoveryHandler.StatusHandler)
+	router.POST("/recovery-codes", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, recoveryHandler.GenerateHandler)
+	router.GET("/api-keys", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, apiKeysHandler.ListHandler)
+	router.POST("/api-keys", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, apiKeysHandler.CreateHandler)
+	router.DELETE("/api-keys/:id", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, apiKeysHandler.DeleteHandler)
+	router.POST("/admin/unlock", requireAdmin, lockoutHandler.UnlockHandler)
+	router.GET("/admin/roles", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("roles:read"), authorizer.RequirePolicy("roles:read", middleware.ResourceFromParams), rolesHandler.ListHandler)
+	router.GET("/admin/users/:id/roles", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("roles:read"), authorizer.RequirePolicy("roles:read", middleware.ResourceFromParams), rolesHandler.UserRolesHandler)
+	router.POST("/admin/users/:id/roles", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("roles:write"), authorizer.RequirePolicy("roles:write", middleware.ResourceFromParams), rolesHandler.AssignHandler)
+	router.DELETE("/admin/users/:id/roles/:role", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("roles:write"), authorizer.RequirePolicy("roles:write", middleware.ResourceFromParams), rolesHandler.RevokeHandler)
+	router.GET("/admin/invitations", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("invitations:read"), authorizer.RequirePolicy("invitations:read", middleware.ResourceFromParams), invitationsHandler.ListHandler)
+	router.POST("/admin/invitations", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("invitations:write"), authorizer.RequirePolicy("invitations:write", middleware.ResourceFromParams), invitationsHandler.CreateHandler)
+	router.DELETE("/admin/invitations/:id", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("invitations:write"), authorizer.RequirePolicy("invitations:write", middleware.ResourceFromParams), invitationsHandler.RevokeHandler)
+	router.GET("/admin/policies", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("policies:read"), authorizer.RequirePolicy("policies:read", middleware.ResourceFromParams), policiesHandler.ListHandler)
+	router.POST("/admin/policies/explain", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("policies:read"), authorizer.RequirePolicy("policies:read", middleware.ResourceFromParams), policiesHandler.ExplainHandler)
+	router.POST("/authz/check", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("relations:read"), authorizer.RequirePolicy("relations:read", middleware.ResourceFromParams), relationsHandler.CheckHandler)
+	router.POST("/authz/expand", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("relations:read"), authorizer.RequirePolicy("relations:read", middleware.ResourceFromParams), relationsHandler.ExpandHandler)
+	router.POST("/authz/list-objects", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("relations:read"), authorizer.RequirePolicy("relations:read", middleware.ResourceFromParams), relationsHandler.ListObjectsHandler)
+	router.GET("/authz/tuples", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("relations:read"), authorizer.RequirePolicy("relations:read", middleware.ResourceFromParams), relationsHandler.ListTuplesHandler)
+	router.POST("/authz/tuples", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("relations:write"), authorizer.RequirePolicy("relations:write", middleware.ResourceFromParams), relationsHandler.WriteTuplesHandler)
+	router.DELETE("/authz/tuples", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, rbac.RequirePermission("relations:write"), authorizer.RequirePolicy("relations:write", middleware.ResourceFromParams), relationsHandler.DeleteTuplesHandler)
+	router.GET("/organizations", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, organizationsHandler.ListHandler)
+	router.POST("/organizations", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, organizationsHandler.CreateHandler)
+	router.GET("/organizations/invitations", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, organizationsHandler.ListInvitationsHandler)
+	router.POST("/organizations/invitations/:invitation_id/accept", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, organizationsHandler.AcceptInvitationHandler)
+	router.DELETE("/organizations/invitations/:invitation_id", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, organizationsHandler.DeclineInvitationHandler)
+	router.POST("/organizations/:id/token", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireSession, organizationsHandler.TokenHandler)
+	router.GET("/organizations/:id/members", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireOrganization, rbac.RequireOrganizationPermission("members:read"), authorizer.RequirePolicy("members:read", middleware.ResourceFromParams), organizationsHandler.ListMembersHandler)
+	router.POST("/organizations/:id/invitations", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireOrganization, rbac.RequireOrganizationPermission("members:write"), authorizer.RequirePolicy("members:write", middleware.ResourceFromParams), organizationsHandler.InviteHandler)
+	router.PUT("/organizations/:id/members/:user_id", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireOrganization, rbac.RequireOrganizationPermission("members:write"), authorizer.RequirePolicy("members:write", middleware.ResourceFromParams), organizationsHandler.SetMemberHandler)
+	router.DELETE("/organizations/:id/members/:user_id", authMiddleware.Middleware(), authLimit, requireFirstParty, requireUser, requireOrganization, rbac.RequireOrganizationPermission("members:write"), authorizer.RequirePolicy("members:write", middleware.ResourceFromParams), organizationsHandler.RemoveMemberHandler)
 
 	srv := &http.Server{
 		Addr:    ":8080",
@@ -74,6 +243,18 @@ func main() {
 		}
 	}()
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if _, err := middleware.ReloadKeyring(); err != nil {
+				log.Println("Error reloading signing keys, keeping the previous keyring:", err)
+				continue
+			}
+			log.Println("Signing keys reloaded")
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
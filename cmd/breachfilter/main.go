@@ -0,0 +1,104 @@
+package main
+
+// Builds the Bloom filter file for PASSWORD_BREACH_FILTER from a list of
+// SHA-1 password hashes, one per line in hex. Anything after the first 40
+// characters is ignored, so the "HASH:COUNT" lines of the Pwned Passwords
+// downloads work as they are.
+//
+//	go run ./cmd/breachfilter -in pwned-passwords-sha1.txt -out breached.bloom
+//	go run ./cmd/breachfilter -in hashes.txt -out breached.bloom -fp 0.0001
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"multitech/pkg/password"
+	"os"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "", "File with one hex SHA-1 hash per line")
+	out := flag.String("out", "", "Bloom filter file to write")
+	falsePositiveRate := flag.Float64("fp", 0.001, "Share of safe passwords the filter may wrongly reject")
+	flag.Parse()
+
+	if *in == "" || *out == "" {
+		flag.Usage()
+		log.Fatal("-in and -out are required")
+	}
+	if *falsePositiveRate <= 0 || *falsePositiveRate >= 1 {
+		log.Fatal("-fp must be between 0 and 1")
+	}
+
+	// The first pass counts the hashes to size the filter.
+	count, skipped, err := scanHashes(*in, func([sha1.Size]byte) {})
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *in, err)
+	}
+	if count == 0 {
+		log.Fatalf("No SHA-1 hashes found in %s", *in)
+	}
+
+	filter := password.NewBloomFilter(count, *falsePositiveRate)
+	if _, _, err := scanHashes(*in, filter.Add); err != nil {
+		log.Fatalf("Error reading %s: %v", *in, err)
+	}
+
+	size, err := writeFilter(*out, filter)
+	if err != nil {
+		log.Fatalf("Error writing %s: %v", *out, err)
+	}
+
+	fmt.Printf("Hashes:  %d\n", count)
+	fmt.Printf("Skipped: %d invalid lines\n", skipped)
+	fmt.Printf("Written: %s (%d bytes)\n", *out, size)
+}
+
+func scanHashes(path string, add func([sha1.Size]byte)) (uint64, uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var count, skipped uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if len(line) < 2*sha1.Size {
+			skipped++
+			continue
+		}
+		var hash [sha1.Size]byte
+		if _, err := hex.Decode(hash[:], []byte(line[:2*sha1.Size])); err != nil {
+			skipped++
+			continue
+		}
+		add(hash)
+		count++
+	}
+	return count, skipped, scanner.Err()
+}
+
+func writeFilter(path string, filter *password.BloomFilter) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	writer := bufio.NewWriter(file)
+	size, err := filter.WriteTo(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
@@ -0,0 +1,122 @@
+package main
+
+// Registers an OAuth client for the built-in OpenID Connect provider, or a
+// service account for the client credentials grant, and prints its
+// credentials. The secret is shown once and stored only as a hash.
+//
+//	go run ./cmd/clients -name "My App" -redirect-uri https://app.example.com/callback
+//	go run ./cmd/clients -name "My SPA" -redirect-uri https://spa.example.com/cb -public
+//	go run ./cmd/clients -name "Nightly export" -service -scope "reports:read"
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"multitech/internal/models"
+	"multitech/middleware"
+	"multitech/pkg/storage"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type redirectURIs []string
+
+func (uris *redirectURIs) String() string {
+	return strings.Join(*uris, ",")
+}
+
+func (uris *redirectURIs) Set(value string) error {
+	*uris = append(*uris, value)
+	return nil
+}
+
+func main() {
+	var uris redirectURIs
+	name := flag.String("name", "", "Human readable client name")
+	public := flag.Bool("public", false, "Register a public client that authenticates with PKCE only")
+	service := flag.Bool("service", false, "Register a service account for the client credentials grant")
+	scope := flag.String("scope", "", "Space separated scopes a service account may request")
+	flag.Var(&uris, "redirect-uri", "Allowed redirect URI, may be repeated")
+	flag.Parse()
+
+	if *name == "" {
+		flag.Usage()
+		log.Fatal("-name is required")
+	}
+	if !*service && len(uris) == 0 {
+		flag.Usage()
+		log.Fatal("At least one -redirect-uri is required")
+	}
+
+	db, err := storage.InitPostgres()
+	if err != nil {
+		log.Fatalf("PostgreSQL init error: %v", err)
+	}
+
+	if *service {
+		createServiceAccount(db, *name, *scope)
+		return
+	}
+
+	clientID, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		log.Fatalf("Error generating client ID: %v", err)
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		Name:         *name,
+		RedirectURIs: uris,
+	}
+
+	var secret string
+	if !*public {
+		secret, err = middleware.GenerateOpaqueToken()
+		if err != nil {
+			log.Fatalf("Error generating client secret: %v", err)
+		}
+		client.ClientSecret = secret
+		if err := client.HashSecret(); err != nil {
+			log.Fatalf("Error hashing client secret: %v", err)
+		}
+	}
+
+	if err := storage.NewGormOAuthClientRepository(db).CreateClient(context.Background(), client); err != nil {
+		log.Fatalf("Error creating client: %v", err)
+	}
+
+	fmt.Printf("client_id:     %s\n", clientID)
+	if secret != "" {
+		fmt.Printf("client_secret: %s\n", secret)
+	}
+}
+
+func createServiceAccount(db *gorm.DB, name string, scope string) {
+	clientID, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		log.Fatalf("Error generating client ID: %v", err)
+	}
+	secret, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		log.Fatalf("Error generating client secret: %v", err)
+	}
+
+	account := &models.ServiceAccount{
+		ClientID:     clientID,
+		ClientSecret: secret,
+		Name:         name,
+		Scope:        strings.Join(strings.Fields(scope), " "),
+	}
+	if err := account.HashSecret(); err != nil {
+		log.Fatalf("Error hashing client secret: %v", err)
+	}
+
+	if err := storage.NewGormServiceAccountRepository(db).CreateServiceAccount(context.Background(), account); err != nil {
+		log.Fatalf("Error creating service account: %v", err)
+	}
+
+	fmt.Printf("client_id:     %s\n", clientID)
+	fmt.Printf("client_secret: %s\n", secret)
+}
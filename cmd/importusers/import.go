@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"multitech/internal/models"
+	"multitech/pkg/hasher"
+	"multitech/pkg/storage"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 254
+	maxEmailLength    = 254
+
+	statusImported = "imported"
+	statusExists   = "exists"
+	statusInvalid  = "invalid"
+	statusFailed   = "failed"
+)
+
+// userRecord is one user of the input, Line being its line number for the
+// report. ParseErr is set when the line could not be decoded.
+type userRecord struct {
+	Line            int    `json:"-"`
+	ParseErr        error  `json:"-"`
+	Username        string `json:"username"`
+	Email           string `json:"email"`
+	PasswordHash    string `json:"password_hash"`
+	EmailVerifiedAt string `json:"email_verified_at"`
+	CreatedAt       string `json:"created_at"`
+}
+
+type recordReader func(input io.Reader, handle func(userRecord) error) error
+
+var readers = map[string]recordReader{
+	"csv":   readCSV,
+	"jsonl": readJSONL,
+}
+
+// readCSV maps columns by the header row, so their order does not matter and
+// unknown columns are ignored.
+func readCSV(input io.Reader, handle func(userRecord) error) error {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"username", "email", "password_hash"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		}
+		record := userRecord{
+			Line:            line,
+			Username:        field("username"),
+			Email:           field("email"),
+			PasswordHash:    field("password_hash"),
+			EmailVerifiedAt: field("email_verified_at"),
+			CreatedAt:       field("created_at"),
+		}
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+}
+
+// readJSONL reports lines that are not valid JSON as invalid records instead
+// of stopping the import.
+func readJSONL(input io.Reader, handle func(userRecord) error) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record userRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			record = userRecord{ParseErr: err}
+		}
+		record.Line = line
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type importResult struct {
+	record userRecord
+	status string
+	reason string
+}
+
+// userImporter validates records and creates their users. Usernames and
+// emails are also checked for duplicates within the input, which the
+// database would otherwise report as existing users.
+type userImporter struct {
+	userRepo      storage.UserRepository
+	hasher        *hasher.Hasher
+	seenUsernames map[string]int
+	seenEmails    map[string]int
+}
+
+// newUserImporter only validates records when userRepo is nil.
+func newUserImporter(userRepo storage.UserRepository, userHasher *hasher.Hasher) *userImporter {
+	return &userImporter{
+		userRepo:      userRepo,
+		hasher:        userHasher,
+		seenUsernames: map[string]int{},
+		seenEmails:    map[string]int{},
+	}
+}
+
+func (importer *userImporter) importRecord(ctx context.Context, record userRecord) importResult {
+	user, err := importer.validate(record)
+	if err != nil {
+		return importResult{record: record, status: statusInvalid, reason: err.Error()}
+	}
+	if importer.userRepo == nil {
+		return importResult{record: record, status: statusImported}
+	}
+
+	if err := importer.userRepo.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			return importResult{record: record, status: statusExists, reason: err.Error()}
+		}
+		return importResult{record: record, status: statusFailed, reason: err.Error()}
+	}
+	return importResult{record: record, status: statusImported}
+}
+
+func (importer *userImporter) validate(record userRecord) (*models.User, error) {
+	if record.ParseErr != nil {
+		return nil, fmt.Errorf("Invalid JSON: %v", record.ParseErr)
+	}
+
+	username := strings.TrimSpace(record.Username)
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return nil, fmt.Errorf("Username must be between %d-%d characters", minUsernameLength, maxUsernameLength)
+	}
+	if strings.Contains(username, "@") {
+		return nil, errors.New("Username must not contain @")
+	}
+	email := strings.TrimSpace(record.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > maxEmailLength {
+		return nil, errors.New("Invalid email format")
+	}
+	if !importer.hasher.Supports(record.PasswordHash) {
+		return nil, errors.New("Unsupported or malformed password hash")
+	}
+
+	user := &models.User{
+		Username: username,
+		Email:    email,
+		Password: record.PasswordHash,
+	}
+	if record.EmailVerifiedAt != "" {
+		verifiedAt, err := time.Parse(time.RFC3339, record.EmailVerifiedAt)
+		if err != nil {
+			return nil, errors.New("Invalid email_verified_at, expected RFC 3339")
+		}
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	if record.CreatedAt != "" {
+		createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
+		if err != nil {
+			return nil, errors.New("Invalid created_at, expected RFC 3339")
+		}
+		user.CreatedAt = createdAt
+	}
+
+	if line, ok := importer.seenUsernames[strings.ToLower(username)]; ok {
+		return nil, fmt.Errorf("Duplicate username, first seen on line %d", line)
+	}
+	if line, ok := importer.seenEmails[strings.ToLower(email)]; ok {
+		return nil, fmt.Errorf("Duplicate email, first seen on line %d", line)
+	}
+	importer.seenUsernames[strings.ToLower(username)] = record.Line
+	importer.seenEmails[strings.ToLower(email)] = record.Line
+	return user, nil
+}
+
+// report lists every record that was not imported and counts all of them.
+type report struct {
+	writer *csv.Writer
+	counts map[string]int
+}
+
+func newReport(output io.Writer) (*report, error) {
+	writer := csv.NewWriter(output)
+	if err := writer.Write([]string{"line", "username", "status", "reason"}); err != nil {
+		return nil, err
+	}
+	return &report{writer: writer, counts: map[string]int{}}, nil
+}
+
+func (report *report) add(result importResult) error {
+	report.counts[result.status]++
+	if result.status == statusImported {
+		return nil
+	}
+	return report.writer.Write([]string{fmt.Sprint(result.record.Line), result.record.Username, result.status, result.reason})
+}
+
+func (report *report) flush() error {
+	report.writer.Flush()
+	return report.writer.Error()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/hasher"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils/mocks"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPBKDF2Hash = "$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA==$8nX7hwFEzIB8aPajJTYK8weHQc5Ngz0pFVAKvSu4jQA="
+	testBcryptHash = "$2a$10$V2ezVCk4gXWAQhkCHV4wfOq0b/LtD0PHnx.t1GcALDYAZ96NUChrm"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "email,username,password_hash,created_at\n" +
+		"ann@example.com,ann," + testPBKDF2Hash + ",2019-04-01T10:00:00Z\n" +
+		"bob@example.com,bob\n"
+
+	var records []userRecord
+	err := readCSV(strings.NewReader(input), func(record userRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testPBKDF2Hash, CreatedAt: "2019-04-01T10:00:00Z"}, records[0])
+	assert.Equal(t, userRecord{Line: 3, Username: "bob", Email: "bob@example.com"}, records[1])
+
+	err = readCSV(strings.NewReader("username,email\n"), func(userRecord) error { return nil })
+	assert.ErrorContains(t, err, "password_hash")
+}
+
+func TestReadJSONL(t *testing.T) {
+	input := `{"username":"ann","email":"ann@example.com","password_hash":"` + testBcryptHash + `"}` + "\n" +
+		"\n" +
+		"{not json\n"
+
+	var records []userRecord
+	err := readJSONL(strings.NewReader(input), func(record userRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "ann", records[0].Username)
+	assert.Equal(t, 1, records[0].Line)
+	assert.NoError(t, records[0].ParseErr)
+	assert.Equal(t, 3, records[1].Line)
+	assert.Error(t, records[1].ParseErr)
+}
+
+func TestImportRecord(t *testing.T) {
+	tests := []struct {
+		name           string
+		record         userRecord
+		createErr      error
+		expectedStatus string
+		expectedReason string
+	}{
+		{
+			name:           "PBKDF2",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testPBKDF2Hash, EmailVerifiedAt: "2020-01-01T00:00:00Z"},
+			expectedStatus: statusImported,
+		},
+		{
+			name:           "Bcrypt",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testBcryptHash},
+			expectedStatus: statusImported,
+		},
+		{
+			name:           "Plain Text Password",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: "hunter22"},
+			expectedStatus: statusInvalid,
+			expectedReason: "Unsupported or malformed password hash",
+		},
+		{
+			name:           "Malformed Hash",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: "$pbkdf2-sha256$i=0$c2FsdA$c2FsdA"},
+			expectedStatus: statusInvalid,
+			expectedReason: "Unsupported or malformed password hash",
+		},
+		{
+			name:           "Argon2id",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: "$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA"},
+			expectedStatus: statusImported,
+		},
+		{
+			name:           "Argon2id Memory Out Of Bounds",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: "$argon2id$v=19$m=4194304,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA"},
+			expectedStatus: statusInvalid,
+			expectedReason: "Unsupported or malformed password hash",
+		},
+		{
+			name:           "Argon2id Iterations Out Of Bounds",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: "$argon2id$v=19$m=19456,t=1000,p=1$c2FsdHNhbHRzYWx0c2FsdA$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA"},
+			expectedStatus: statusInvalid,
+			expectedReason: "Unsupported or malformed password hash",
+		},
+		{
+			name:           "Short Username",
+			record:         userRecord{Line: 2, Username: "an", Email: "ann@example.com", PasswordHash: testBcryptHash},
+			expectedStatus: statusInvalid,
+			expectedReason: "Username must be between 3-254 characters",
+		},
+		{
+			name:           "Username Looks Like Email",
+			record:         userRecord{Line: 2, Username: "ann@example.com", Email: "ann@example.com", PasswordHash: testBcryptHash},
+			expectedStatus: statusInvalid,
+			expectedReason: "Username must not contain @",
+		},
+		{
+			name:           "Invalid Email",
+			record:         userRecord{Line: 2, Username: "ann", Email: "Ann <ann@example.com>", PasswordHash: testBcryptHash},
+			expectedStatus: statusInvalid,
+			expectedReason: "Invalid email format",
+		},
+		{
+			name:           "Invalid Timestamp",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testBcryptHash, CreatedAt: "01/04/2019"},
+			expectedStatus: statusInvalid,
+			expectedReason: "Invalid created_at, expected RFC 3339",
+		},
+		{
+			name:           "Existing User",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testBcryptHash},
+			createErr:      storage.ErrUserExists,
+			expectedStatus: statusExists,
+			expectedReason: storage.ErrUserExists.Error(),
+		},
+		{
+			name:           "Database Error",
+			record:         userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testBcryptHash},
+			createErr:      errors.New("database error"),
+			expectedStatus: statusFailed,
+			expectedReason: "database error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *models.User
+			mockUserRepo := mocks.NewDefaultUserMock()
+			mockUserRepo.CreateUserFunc = func(ctx context.Context, user *models.User) error {
+				created = user
+				return tt.createErr
+			}
+
+			importer := newUserImporter(mockUserRepo, hasher.Default())
+			result := importer.importRecord(context.Background(), tt.record)
+
+			assert.Equal(t, tt.expectedStatus, result.status)
+			assert.Equal(t, tt.expectedReason, result.reason)
+			if tt.expectedStatus != statusImported {
+				return
+			}
+			require.NotNil(t, created)
+			assert.Equal(t, tt.record.PasswordHash, created.Password, "hashes are imported as they are")
+			assert.Equal(t, tt.record.EmailVerifiedAt != "", created.EmailVerifiedAt != nil)
+		})
+	}
+}
+
+func TestImportRecordDuplicates(t *testing.T) {
+	importer := newUserImporter(mocks.NewDefaultUserMock(), hasher.Default())
+	ctx := context.Background()
+
+	first := importer.importRecord(ctx, userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testBcryptHash})
+	assert.Equal(t, statusImported, first.status)
+
+	sameUsername := importer.importRecord(ctx, userRecord{Line: 3, Username: "ANN", Email: "other@example.com", PasswordHash: testBcryptHash})
+	assert.Equal(t, statusInvalid, sameUsername.status)
+	assert.Equal(t, "Duplicate username, first seen on line 2", sameUsername.reason)
+
+	sameEmail := importer.importRecord(ctx, userRecord{Line: 4, Username: "anne", Email: "Ann@Example.com", PasswordHash: testBcryptHash})
+	assert.Equal(t, statusInvalid, sameEmail.status)
+	assert.Equal(t, "Duplicate email, first seen on line 2", sameEmail.reason)
+}
+
+func TestImportRecordDryRun(t *testing.T) {
+	importer := newUserImporter(nil, hasher.Default())
+	result := importer.importRecord(context.Background(), userRecord{Line: 2, Username: "ann", Email: "ann@example.com", PasswordHash: testPBKDF2Hash})
+	assert.Equal(t, statusImported, result.status)
+}
+
+func TestReport(t *testing.T) {
+	var output strings.Builder
+	report, err := newReport(&output)
+	require.NoError(t, err)
+
+	require.NoError(t, report.add(importResult{record: userRecord{Line: 2, Username: "ann"}, status: statusImported}))
+	require.NoError(t, report.add(importResult{record: userRecord{Line: 3, Username: "bob"}, status: statusInvalid, reason: "Invalid email format"}))
+	require.NoError(t, report.flush())
+
+	assert.Equal(t, "line,username,status,reason\n3,bob,invalid,Invalid email format\n", output.String())
+	assert.Equal(t, map[string]int{statusImported: 1, statusInvalid: 1}, report.counts)
+}
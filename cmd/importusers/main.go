@@ -0,0 +1,98 @@
+package main
+
+// Imports users exported from another system, keeping their password hashes.
+// Hashes must be in a format pkg/hasher verifies (argon2id, bcrypt, PBKDF2,
+// scrypt or salted SHA-256); they are upgraded on each user's first login.
+//
+// Records are read from CSV with a header row, or from JSON Lines, with the
+// fields username, email, password_hash and the optional RFC 3339 timestamps
+// email_verified_at and created_at. Every rejected record is listed in the
+// report, a CSV file with the columns line, username, status and reason.
+//
+//	go run ./cmd/importusers -in users.csv -report report.csv
+//	go run ./cmd/importusers -in users.jsonl -dry-run
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"multitech/pkg/hasher"
+	"multitech/pkg/storage"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "", "CSV or JSON Lines file with the users to import")
+	format := flag.String("format", "", "Input format, csv or jsonl (default: from the file extension)")
+	reportPath := flag.String("report", "", "CSV file to write the rejected records to (default: stderr)")
+	dryRun := flag.Bool("dry-run", false, "Only validate the records, do not write to the database")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		log.Fatal("-in is required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*in)), ".")
+	}
+	read, ok := readers[*format]
+	if !ok {
+		log.Fatalf("Unknown format %q, expected csv or jsonl", *format)
+	}
+
+	userHasher, err := hasher.FromEnv()
+	if err != nil {
+		log.Fatalf("Password hasher config error: %v", err)
+	}
+
+	input, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", *in, err)
+	}
+	defer input.Close()
+
+	reportFile := os.Stderr
+	if *reportPath != "" {
+		reportFile, err = os.Create(*reportPath)
+		if err != nil {
+			log.Fatalf("Error creating %s: %v", *reportPath, err)
+		}
+		defer reportFile.Close()
+	}
+	report, err := newReport(reportFile)
+	if err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	var importer *userImporter
+	if *dryRun {
+		importer = newUserImporter(nil, userHasher)
+	} else {
+		db, err := storage.InitPostgres()
+		if err != nil {
+			log.Fatalf("PostgreSQL init error: %v", err)
+		}
+		importer = newUserImporter(storage.NewGormUserRepository(db), userHasher)
+	}
+
+	err = read(input, func(record userRecord) error {
+		return report.add(importer.importRecord(context.Background(), record))
+	})
+	if flushErr := report.flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Fatalf("Error importing %s: %v", *in, err)
+	}
+
+	fmt.Printf("Imported: %d\n", report.counts[statusImported])
+	fmt.Printf("Existing: %d\n", report.counts[statusExists])
+	fmt.Printf("Invalid:  %d\n", report.counts[statusInvalid])
+	fmt.Printf("Failed:   %d\n", report.counts[statusFailed])
+	if report.counts[statusFailed] > 0 {
+		os.Exit(1)
+	}
+}
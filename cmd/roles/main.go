@@ -0,0 +1,98 @@
+package main
+
+// Manages roles and grants them to users, for example to make the first
+// administrator who can then use the /admin/roles endpoints.
+//
+//	go run ./cmd/roles -list
+//	go run ./cmd/roles -create support -description "Support staff" -permission users:read -permission roles:read
+//	go run ./cmd/roles -grant admin -user alice
+//	go run ./cmd/roles -revoke admin -user alice@example.com
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"strings"
+)
+
+type permissionList []string
+
+func (permissions *permissionList) String() string {
+	return strings.Join(*permissions, ",")
+}
+
+func (permissions *permissionList) Set(value string) error {
+	*permissions = append(*permissions, value)
+	return nil
+}
+
+func main() {
+	var permissions permissionList
+	list := flag.Bool("list", false, "List all roles with their permissions")
+	create := flag.String("create", "", "Create a role with this name")
+	description := flag.String("description", "", "Description of the created role")
+	grant := flag.String("grant", "", "Grant this role to -user")
+	revoke := flag.String("revoke", "", "Revoke this role from -user")
+	identifier := flag.String("user", "", "Username or email address of the user")
+	flag.Var(&permissions, "permission", "Permission of the created role, may be repeated")
+	flag.Parse()
+
+	if !*list && *create == "" && *grant == "" && *revoke == "" {
+		flag.Usage()
+		log.Fatal("One of -list, -create, -grant or -revoke is required")
+	}
+	if (*grant != "" || *revoke != "") && *identifier == "" {
+		flag.Usage()
+		log.Fatal("-user is required to grant or revoke a role")
+	}
+
+	db, err := storage.InitPostgres()
+	if err != nil {
+		log.Fatalf("PostgreSQL init error: %v", err)
+	}
+	roleRepo := storage.NewGormRoleRepository(db)
+	ctx := context.Background()
+
+	if *create != "" {
+		role := &models.Role{Name: *create, Description: *description}
+		for _, name := range permissions {
+			role.Permissions = append(role.Permissions, models.Permission{Name: name})
+		}
+		if err := roleRepo.CreateRole(ctx, role); err != nil {
+			log.Fatalf("Error creating role: %v", err)
+		}
+		fmt.Printf("Created role %s\n", role.Name)
+	}
+
+	if *grant != "" || *revoke != "" {
+		user, err := storage.NewGormUserRepository(db).GetUserByIdentifier(ctx, *identifier)
+		if err != nil {
+			log.Fatalf("Error finding user %s: %v", *identifier, err)
+		}
+		if *grant != "" {
+			if err := roleRepo.AssignRole(ctx, user.ID, *grant); err != nil {
+				log.Fatalf("Error granting role: %v", err)
+			}
+			fmt.Printf("Granted %s to %s\n", *grant, user.Username)
+		}
+		if *revoke != "" {
+			if err := roleRepo.RevokeRole(ctx, user.ID, *revoke); err != nil {
+				log.Fatalf("Error revoking role: %v", err)
+			}
+			fmt.Printf("Revoked %s from %s\n", *revoke, user.Username)
+		}
+	}
+
+	if *list {
+		roles, err := roleRepo.ListRoles(ctx)
+		if err != nil {
+			log.Fatalf("Error listing roles: %v", err)
+		}
+		for _, role := range roles {
+			fmt.Printf("%-20s %s\n", role.Name, strings.Join(role.PermissionNames(), " "))
+		}
+	}
+}
@@ -1,8 +1,168 @@
-CREATE TABLE users {
+CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(255) NOT NULL UNIQUE,
     email VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
+    email_verified_at TIMESTAMP,
+    totp_secret VARCHAR(64) NOT NULL DEFAULT '',
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-}
\ No newline at end of file
+);
+
+-- Logins look users up by username or email address, ignoring case, so
+-- neither may repeat in another case.
+CREATE UNIQUE INDEX idx_users_username_lower ON users (lower(username));
+CREATE UNIQUE INDEX idx_users_email_lower ON users (lower(email));
+
+CREATE TABLE oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL UNIQUE,
+    client_secret VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE service_accounts (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL UNIQUE,
+    client_secret VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    scope TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX idx_user_roles_role_id ON user_roles (role_id);
+
+-- Relation tuples object#relation@subject. subject_relation is empty for
+-- subjects such as user:7 and set for usersets such as team:eng#member.
+CREATE TABLE relation_tuples (
+    id BIGSERIAL PRIMARY KEY,
+    object_type VARCHAR(64) NOT NULL,
+    object_id VARCHAR(128) NOT NULL,
+    relation VARCHAR(64) NOT NULL,
+    subject_type VARCHAR(64) NOT NULL,
+    subject_id VARCHAR(128) NOT NULL,
+    subject_relation VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_relation_tuples_unique
+    ON relation_tuples (object_type, object_id, relation, subject_type, subject_id, subject_relation);
+CREATE INDEX idx_relation_tuples_subject ON relation_tuples (subject_type, subject_id);
+
+-- Invitations to register while REGISTRATION_MODE is invite-only. Only the
+-- SHA-256 hash of the token is stored.
+CREATE TABLE invitations (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    role VARCHAR(255) NOT NULL DEFAULT '',
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE organizations (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- role names a role whose permissions apply within the organization only.
+CREATE TABLE organization_members (
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(255) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members (user_id);
+
+-- Users join an organization by accepting an invitation to it. A user has at
+-- most one invitation per organization.
+CREATE TABLE organization_invitations (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(255) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_invitations_user_id ON organization_invitations (user_id);
+
+-- The admin role may do anything. Grant it with go run ./cmd/roles.
+INSERT INTO roles (name, description) VALUES ('admin', 'Full access to all admin endpoints');
+INSERT INTO permissions (name) VALUES ('*');
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT roles.id, permissions.id FROM roles, permissions
+    WHERE roles.name = 'admin' AND permissions.name = '*';
+
+-- Organization roles: owners manage the members and the organization,
+-- members see who else belongs to it.
+INSERT INTO roles (name, description) VALUES
+    ('org_owner', 'Manages an organization and its members'),
+    ('org_member', 'Member of an organization');
+INSERT INTO permissions (name) VALUES ('members:*'), ('organization:*'), ('members:read');
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT roles.id, permissions.id FROM roles, permissions
+    WHERE (roles.name = 'org_owner' AND permissions.name IN ('members:*', 'organization:*'))
+       OR (roles.name = 'org_member' AND permissions.name = 'members:read');
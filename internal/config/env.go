@@ -7,7 +7,6 @@ import (
 
 func LoadEnv() {
 	required := []string{
-		"JWT_SECRET",
 		"REDIS_URL",
 	}
 
@@ -16,4 +15,8 @@ func LoadEnv() {
 			log.Fatalf("Missing required environment variable: %s", key)
 		}
 	}
+
+	if os.Getenv("JWT_KEYS_DIR") == "" && os.Getenv("JWT_PRIVATE_KEY_FILE") == "" && os.Getenv("JWT_SECRET") == "" {
+		log.Fatalf("Missing required environment variable: JWT_SECRET, JWT_PRIVATE_KEY_FILE or JWT_KEYS_DIR")
+	}
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential that authenticates as its user. Only the
+// SHA-256 hash of the key is stored; the prefix identifies the key in listings
+// and is used to look it up.
+type APIKey struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"-" gorm:"index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"unique"`
+	KeyHash    string     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (key *APIKey) Expired(now time.Time) bool {
+	return key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)
+}
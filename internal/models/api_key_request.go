@@ -0,0 +1,6 @@
+package models
+
+type APIKeyRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
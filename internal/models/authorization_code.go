@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type AuthorizationCode struct {
+	ClientID            string    `json:"client_id"`
+	UserID              uint      `json:"user_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scope               string    `json:"scope"`
+	Nonce               string    `json:"nonce"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	AuthTime            time.Time `json:"auth_time"`
+}
+
+// AuthorizationRequest is an authorization request of a client waiting for
+// the user to sign in and consent.
+type AuthorizationRequest struct {
+	ClientID            string `json:"client_id"`
+	ClientName          string `json:"client_name"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ConsentRequest struct {
+	ConsentChallenge string `json:"consent_challenge" binding:"required"`
+	Approve          bool   `json:"approve"`
+}
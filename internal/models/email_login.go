@@ -0,0 +1,25 @@
+package models
+
+const (
+	EmailLoginMethodLink = "link"
+	EmailLoginMethodCode = "code"
+)
+
+// EmailLoginChallenge is a pending passwordless login. Link challenges are
+// completed by their token alone, code challenges need the mailed code too.
+type EmailLoginChallenge struct {
+	UserID   uint   `json:"user_id"`
+	Method   string `json:"method"`
+	CodeHash string `json:"-"`
+	Attempts int    `json:"attempts"`
+}
+
+type EmailLoginRequest struct {
+	Email  string `json:"email" binding:"required"`
+	Method string `json:"method" binding:"omitempty,oneof=link code"`
+}
+
+type EmailLoginVerification struct {
+	LoginToken string `json:"login_token" binding:"required"`
+	Code       string `json:"code"`
+}
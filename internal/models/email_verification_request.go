@@ -0,0 +1,5 @@
+package models
+
+type EmailVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
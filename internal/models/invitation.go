@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Invitation lets one person register while registration is invite-only,
+// with the invited email address. Only the SHA-256 hash of its token is
+// stored. Role, if set, is granted to the registered user.
+type Invitation struct {
+	ID        uint       `json:"id"`
+	Email     string     `json:"email"`
+	TokenHash string     `json:"-" gorm:"unique"`
+	Role      string     `json:"role,omitempty"`
+	InvitedBy uint       `json:"invited_by"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	UserID    *uint      `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Pending reports whether the invitation is neither used nor expired.
+func (invitation *Invitation) Pending(now time.Time) bool {
+	return invitation.UsedAt == nil && now.Before(invitation.ExpiresAt)
+}
@@ -0,0 +1,7 @@
+package models
+
+type InvitationRequest struct {
+	Email         string `json:"email" binding:"required,email,max=254"`
+	Role          string `json:"role"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=90"`
+}
@@ -1,6 +1,17 @@
 package models
 
+// LoginCredentials identify the user by username or email address. Username
+// is the former name of Identifier and still accepted.
 type LoginCredentials struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Identifier string `json:"identifier"`
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password" binding:"required"`
+}
+
+// LoginIdentifier returns Identifier, or Username for older clients.
+func (creds *LoginCredentials) LoginIdentifier() string {
+	if creds.Identifier != "" {
+		return creds.Identifier
+	}
+	return creds.Username
 }
@@ -0,0 +1,8 @@
+package models
+
+// MFAChallenge is the pending state between a verified password and a
+// verified second factor.
+type MFAChallenge struct {
+	UserID   uint `json:"user_id"`
+	Attempts int  `json:"attempts"`
+}
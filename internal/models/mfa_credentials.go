@@ -0,0 +1,10 @@
+package models
+
+type MFALoginCredentials struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TOTPConfirmation struct {
+	Code string `json:"code" binding:"required"`
+}
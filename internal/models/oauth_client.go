@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type OAuthClient struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id" gorm:"unique"`
+	ClientSecret string    `json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris" gorm:"column:redirect_uris;serializer:json"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Public reports whether the client has no secret and authenticates with PKCE alone.
+func (c *OAuthClient) Public() bool {
+	return c.ClientSecret == ""
+}
+
+func (c *OAuthClient) HashSecret() error {
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(c.ClientSecret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	c.ClientSecret = string(hashedSecret)
+	return nil
+}
+
+func (c *OAuthClient) CheckSecret(secret string) error {
+	return bcrypt.CompareHashAndPassword([]byte(c.ClientSecret), []byte(secret))
+}
+
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+func (*OAuthClient) TableName() string {
+	return "oauth_clients"
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+// Roles seeded for organizations. The creator of an organization becomes
+// its owner; users added without a role become members.
+const (
+	OrganizationOwnerRole  = "org_owner"
+	OrganizationMemberRole = "org_member"
+)
+
+// OrganizationRolePrefix starts the names of the roles that may be held
+// within an organization. Other roles, such as admin, are only granted
+// globally.
+const OrganizationRolePrefix = "org_"
+
+// Organization is a tenant. Users belong to organizations through
+// OrganizationMember and act within one with an organization token.
+type Organization struct {
+	ID        uint      `json:"id"`
+	Slug      string    `json:"slug" gorm:"unique"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrganizationMember grants a user a role within an organization. Role
+// names a row of roles, whose permissions apply in that organization only.
+type OrganizationMember struct {
+	OrganizationID uint          `json:"organization_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID         uint          `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Role           string        `json:"role"`
+	Organization   *Organization `json:"organization,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// OrganizationInvitation offers a user membership in an organization with
+// Role. The user only becomes a member by accepting it before ExpiresAt.
+type OrganizationInvitation struct {
+	ID             uint          `json:"id"`
+	OrganizationID uint          `json:"organization_id" gorm:"uniqueIndex:idx_organization_invitations_member,priority:1"`
+	UserID         uint          `json:"user_id" gorm:"uniqueIndex:idx_organization_invitations_member,priority:2"`
+	Role           string        `json:"role"`
+	InvitedBy      uint          `json:"invited_by"`
+	ExpiresAt      time.Time     `json:"expires_at"`
+	Organization   *Organization `json:"organization,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+func ValidateSlug(slug string) error {
+	if !slugPattern.MatchString(slug) {
+		return fmt.Errorf("Invalid slug %q, expected 2 to 63 lowercase letters, digits or dashes", slug)
+	}
+	return nil
+}
+
+// ValidateOrganizationRole rejects roles that are not meant to be held
+// within an organization.
+func ValidateOrganizationRole(role string) error {
+	if !strings.HasPrefix(role, OrganizationRolePrefix) {
+		return fmt.Errorf("Invalid organization role %q, expected a role starting with %s", role, OrganizationRolePrefix)
+	}
+	return nil
+}
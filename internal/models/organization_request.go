@@ -0,0 +1,15 @@
+package models
+
+type CreateOrganizationRequest struct {
+	Slug string `json:"slug" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+type InviteMemberRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role"`
+}
+
+type SetMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type PasswordForgotRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
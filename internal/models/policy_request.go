@@ -0,0 +1,11 @@
+package models
+
+// ExplainPolicyRequest asks how the policies decide Action. Attributes given
+// for the subject, request or resource replace those of the caller's own
+// request; request.time is an RFC 3339 timestamp.
+type ExplainPolicyRequest struct {
+	Action   string         `json:"action" binding:"required"`
+	Subject  map[string]any `json:"subject,omitempty"`
+	Request  map[string]any `json:"request,omitempty"`
+	Resource map[string]any `json:"resource,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RateLimitResult is the outcome of counting one request against a limit.
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is the number of requests that would still be allowed right now.
+	Remaining int
+	// RetryAfter is how long a rejected request has to wait.
+	RetryAfter time.Duration
+	// ResetAfter is how long it takes until the full limit is available again.
+	ResetAfter time.Duration
+}
@@ -0,0 +1,7 @@
+package models
+
+type RecoverCredentials struct {
+	Username     string `json:"username" binding:"required"`
+	RecoveryCode string `json:"recovery_code" binding:"required"`
+	NewPassword  string `json:"new_password" binding:"required"`
+}
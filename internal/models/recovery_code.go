@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code that lets a user set a new password
+// without logging in. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
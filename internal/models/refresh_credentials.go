@@ -0,0 +1,5 @@
+package models
+
+type RefreshCredentials struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	UserID    uint      `json:"user_id"`
+	FamilyID  string    `json:"family_id"`
+	Used      bool      `json:"used"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -4,4 +4,7 @@ type RegisterCredentials struct {
 	Username string `json:"username" gorm:"unique"`
 	Password string `json:"password"`
 	Email    string `json:"email" gorm:"unique"`
+	// Invitation is the invitation token, required while registration is
+	// invite-only.
+	Invitation string `json:"invitation,omitempty"`
 }
@@ -0,0 +1,26 @@
+package models
+
+// CheckRelationRequest asks whether Subject, the caller when empty, has
+// Relation to Object.
+type CheckRelationRequest struct {
+	Object   string `json:"object" binding:"required"`
+	Relation string `json:"relation" binding:"required"`
+	Subject  string `json:"subject"`
+}
+
+type ExpandRelationRequest struct {
+	Object   string `json:"object" binding:"required"`
+	Relation string `json:"relation" binding:"required"`
+}
+
+// ListObjectsRequest asks for the objects of ObjectType that Subject, the
+// caller when empty, has Relation to.
+type ListObjectsRequest struct {
+	ObjectType string `json:"object_type" binding:"required"`
+	Relation   string `json:"relation" binding:"required"`
+	Subject    string `json:"subject"`
+}
+
+type RelationTuplesRequest struct {
+	Tuples []TupleKey `json:"tuples" binding:"required,min=1,dive"`
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	idPattern   = regexp.MustCompile(`^[A-Za-z0-9_.@|\-]{1,128}$`)
+)
+
+// WildcardID as the ID of a subject stands for every subject of its type,
+// as in "document:handbook#viewer@user:*".
+const WildcardID = "*"
+
+// ObjectRef names an object as "<type>:<id>", such as "document:readme".
+type ObjectRef struct {
+	Type string
+	ID   string
+}
+
+func ParseObjectRef(value string) (ObjectRef, error) {
+	objectType, id, found := strings.Cut(value, ":")
+	if !found || !namePattern.MatchString(objectType) || !idPattern.MatchString(id) {
+		return ObjectRef{}, fmt.Errorf("Invalid object %q, expected <type>:<id>", value)
+	}
+	return ObjectRef{Type: objectType, ID: id}, nil
+}
+
+func (object ObjectRef) String() string {
+	return object.Type + ":" + object.ID
+}
+
+// SubjectRef names who a relation is granted to: an object such as
+// "user:7", or with a relation, such as "team:eng#member", everyone having
+// that relation to the object.
+type SubjectRef struct {
+	Type     string
+	ID       string
+	Relation string
+}
+
+func ParseSubjectRef(value string) (SubjectRef, error) {
+	objectPart, relation, hasRelation := strings.Cut(value, "#")
+	objectType, id, found := strings.Cut(objectPart, ":")
+	switch {
+	case !found || !namePattern.MatchString(objectType):
+	case id != WildcardID && !idPattern.MatchString(id):
+	case hasRelation && (id == WildcardID || !namePattern.MatchString(relation)):
+	default:
+		return SubjectRef{Type: objectType, ID: id, Relation: relation}, nil
+	}
+	return SubjectRef{}, fmt.Errorf("Invalid subject %q, expected <type>:<id> or <type>:<id>#<relation>", value)
+}
+
+// Object returns the object part of the subject, without its relation.
+func (subject SubjectRef) Object() ObjectRef {
+	return ObjectRef{Type: subject.Type, ID: subject.ID}
+}
+
+func (subject SubjectRef) String() string {
+	if subject.Relation == "" {
+		return subject.Object().String()
+	}
+	return subject.Object().String() + "#" + subject.Relation
+}
+
+// RelationTuple states that a subject has a relation to an object, written
+// "<object>#<relation>@<subject>", such as "document:readme#viewer@user:7".
+type RelationTuple struct {
+	ID              uint      `json:"-"`
+	ObjectType      string    `json:"-" gorm:"uniqueIndex:idx_relation_tuples_unique,priority:1"`
+	ObjectID        string    `json:"-" gorm:"uniqueIndex:idx_relation_tuples_unique,priority:2"`
+	Relation        string    `json:"-" gorm:"uniqueIndex:idx_relation_tuples_unique,priority:3"`
+	SubjectType     string    `json:"-" gorm:"uniqueIndex:idx_relation_tuples_unique,priority:4;index:idx_relation_tuples_subject,priority:1"`
+	SubjectID       string    `json:"-" gorm:"uniqueIndex:idx_relation_tuples_unique,priority:5;index:idx_relation_tuples_subject,priority:2"`
+	SubjectRelation string    `json:"-" gorm:"uniqueIndex:idx_relation_tuples_unique,priority:6;not null;default:''"`
+	CreatedAt       time.Time `json:"-"`
+}
+
+func NewRelationTuple(object ObjectRef, relation string, subject SubjectRef) RelationTuple {
+	return RelationTuple{
+		ObjectType:      object.Type,
+		ObjectID:        object.ID,
+		Relation:        relation,
+		SubjectType:     subject.Type,
+		SubjectID:       subject.ID,
+		SubjectRelation: subject.Relation,
+	}
+}
+
+func (tuple *RelationTuple) Object() ObjectRef {
+	return ObjectRef{Type: tuple.ObjectType, ID: tuple.ObjectID}
+}
+
+func (tuple *RelationTuple) Subject() SubjectRef {
+	return SubjectRef{Type: tuple.SubjectType, ID: tuple.SubjectID, Relation: tuple.SubjectRelation}
+}
+
+func (tuple *RelationTuple) String() string {
+	return tuple.Object().String() + "#" + tuple.Relation + "@" + tuple.Subject().String()
+}
+
+func (tuple *RelationTuple) Key() TupleKey {
+	return TupleKey{
+		Object:   tuple.Object().String(),
+		Relation: tuple.Relation,
+		Subject:  tuple.Subject().String(),
+	}
+}
+
+// TupleKey is a relation tuple as exchanged over the API.
+type TupleKey struct {
+	Object   string `json:"object" binding:"required"`
+	Relation string `json:"relation" binding:"required"`
+	Subject  string `json:"subject" binding:"required"`
+}
+
+func (key TupleKey) Tuple() (RelationTuple, error) {
+	object, err := ParseObjectRef(key.Object)
+	if err != nil {
+		return RelationTuple{}, err
+	}
+	if err := ValidateRelation(key.Relation); err != nil {
+		return RelationTuple{}, err
+	}
+	subject, err := ParseSubjectRef(key.Subject)
+	if err != nil {
+		return RelationTuple{}, err
+	}
+	return NewRelationTuple(object, key.Relation, subject), nil
+}
+
+func ValidateObjectType(objectType string) error {
+	if !namePattern.MatchString(objectType) {
+		return fmt.Errorf("Invalid object type %q", objectType)
+	}
+	return nil
+}
+
+func ValidateRelation(relation string) error {
+	if !namePattern.MatchString(relation) {
+		return fmt.Errorf("Invalid relation %q", relation)
+	}
+	return nil
+}
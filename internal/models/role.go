@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Role bundles permissions such as "users:write" and is granted to users.
+type Role struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name" gorm:"unique"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"-" gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// Permission names an action as "<resource>:<action>". A role holding
+// "<resource>:*" may do everything on the resource, one holding "*" anything.
+type Permission struct {
+	ID   uint   `json:"-"`
+	Name string `json:"name" gorm:"unique"`
+}
+
+func (role *Role) PermissionNames() []string {
+	names := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
@@ -0,0 +1,5 @@
+package models
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
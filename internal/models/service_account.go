@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ServiceAccount is a machine identity that obtains tokens through the
+// OAuth 2.0 client credentials grant instead of logging in as a User.
+type ServiceAccount struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id" gorm:"unique"`
+	ClientSecret string    `json:"-"`
+	Name         string    `json:"name"`
+	Scope        string    `json:"scope"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (sa *ServiceAccount) HashSecret() error {
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(sa.ClientSecret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	sa.ClientSecret = string(hashedSecret)
+	return nil
+}
+
+func (sa *ServiceAccount) CheckSecret(secret string) error {
+	return bcrypt.CompareHashAndPassword([]byte(sa.ClientSecret), []byte(secret))
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Session belongs either to a user or, for client credentials tokens, to a
+// service account. Service account sessions are not listed with user sessions.
+// Sessions opened for an organization token carry its OrganizationID.
+type Session struct {
+	ID               string    `json:"id"`
+	UserID           uint      `json:"user_id"`
+	ServiceAccountID uint      `json:"service_account_id,omitempty"`
+	OrganizationID   uint      `json:"organization_id,omitempty"`
+	IP               string    `json:"ip"`
+	UserAgent        string    `json:"user_agent"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastSeen         time.Time `json:"last_seen"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type UnlockRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip" binding:"omitempty,ip"`
+}
@@ -1,29 +1,58 @@
 package models
 
 import (
+	"multitech/pkg/hasher"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username" gorm:"unique"`
-	Email     string    `json:"email" gorm:"unique"`
-	Password  string    `json:"-"`
+	ID uint `json:"id"`
+	// Username and Email are unique regardless of case, since logins look
+	// users up by either of them ignoring case.
+	Username string `json:"username" gorm:"unique;uniqueIndex:idx_users_username_lower,expression:lower(username)"`
+	Email    string `json:"email" gorm:"unique;uniqueIndex:idx_users_email_lower,expression:lower(email)"`
+	// Password is the hash of the password, in any format the hasher
+	// verifies, including those of imported users.
+	Password string `json:"-"`
+	// EmailVerifiedAt is set once the user followed a verification link
+	// sent to Email.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// TOTPSecret is set on enrollment and only enforced once TOTPEnabled is
+	// confirmed. TOTPLastStep is the last accepted time step, to stop replays.
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"totp_enabled"`
+	TOTPLastStep int64  `json:"-"`
+	// Roles are loaded with the user and embedded in its access tokens.
+	Roles     []Role    `json:"-" gorm:"many2many:user_roles"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+func (u *User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// HashPassword replaces the plain text Password with its hash, made by the
+// configured hasher.
 func (u *User) HashPassword() error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hasher.Default().Hash(u.Password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 	return nil
 }
 
 func (u *User) CheckPassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	return hasher.Default().Verify(u.Password, password)
+}
+
+// PasswordNeedsRehash reports whether the stored hash was made with another
+// algorithm or parameters than the configured ones.
+func (u *User) PasswordNeedsRehash() bool {
+	return hasher.Default().NeedsRehash(u.Password)
 }
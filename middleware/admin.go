@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdminToken admits requests that present ADMIN_API_TOKEN. Admin
+// endpoints are disabled while the variable is unset.
+func RequireAdminToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		expected := os.Getenv("ADMIN_API_TOKEN")
+		if expected == "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin API is disabled"})
+			return
+		}
+
+		token := ctx.GetHeader(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAdminToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		configured     string
+		token          string
+		expectedStatus int
+	}{
+		{name: "Valid token", configured: "s3cret", token: "s3cret", expectedStatus: http.StatusOK},
+		{name: "Wrong token", configured: "s3cret", token: "guess", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing token", configured: "s3cret", expectedStatus: http.StatusUnauthorized},
+		{name: "Admin API disabled", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.configured != "" {
+				os.Setenv("ADMIN_API_TOKEN", tt.configured)
+				defer os.Unsetenv("ADMIN_API_TOKEN")
+			}
+
+			recorder := httptest.NewRecorder()
+			ctx, router := gin.CreateTestContext(recorder)
+			router.POST("/admin", RequireAdminToken(), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/admin", nil)
+			if tt.token != "" {
+				ctx.Request.Header.Set(AdminTokenHeader, tt.token)
+			}
+			router.HandleContext(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+		})
+	}
+}
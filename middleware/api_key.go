@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyHeader carries personal API keys as an alternative to a Bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// API keys look like mt_<prefix>_<secret>. The prefix is stored in clear to
+// find and identify the key, the whole key is only stored as a hash.
+const (
+	apiKeyScheme    = "mt"
+	apiKeyPrefixLen = 6
+)
+
+// GenerateAPIKey returns a new API key together with its lookup prefix.
+func GenerateAPIKey() (string, string, error) {
+	prefixBytes := make([]byte, apiKeyPrefixLen)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	return apiKeyScheme + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 of the key. The key carries 256
+// bits of entropy, so a fast unsalted hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != 2*apiKeyPrefixLen || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func checkAPIKeyHash(key string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "mt_"+prefix+"_"))
+
+	parsed, ok := apiKeyPrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+	assert.True(t, checkAPIKeyHash(key, HashAPIKey(key)))
+	assert.False(t, checkAPIKeyHash(key+"x", HashAPIKey(key)))
+
+	other, otherPrefix, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, prefix, otherPrefix)
+}
+
+func TestAPIKeyPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		prefix string
+		ok     bool
+	}{
+		{name: "Valid key", key: "mt_0123456789ab_secret_with_underscores", prefix: "0123456789ab", ok: true},
+		{name: "Wrong scheme", key: "xx_0123456789ab_secret"},
+		{name: "Short prefix", key: "mt_0123_secret"},
+		{name: "Missing secret", key: "mt_0123456789ab_"},
+		{name: "JWT", key: "eyJhbGciOiJIUzI1NiJ9.e30.sig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := apiKeyPrefix(tt.key)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.prefix, prefix)
+		})
+	}
+}
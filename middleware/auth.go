@@ -1,9 +1,14 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"multitech/internal/models"
 	"multitech/pkg/storage"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -11,24 +16,74 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// lastSeenResolution limits how often a session's last_seen is written back.
+	lastSeenResolution = time.Minute
+)
+
+// Subject types distinguish human callers from machine callers. Tokens
+// without a subject_type claim belong to users.
+const (
+	SubjectTypeUser    = "user"
+	SubjectTypeService = "service"
+)
+
 type AuthMiddleware struct {
-	sessRepo storage.SessionsRepository
+	sessRepo   storage.SessionsRepository
+	apiKeyRepo storage.APIKeyRepository
 }
 
-func NewAuthMiddleware(sessRepo storage.SessionsRepository) *AuthMiddleware {
+func NewAuthMiddleware(sessRepo storage.SessionsRepository, apiKeyRepo storage.APIKeyRepository) *AuthMiddleware {
 	return &AuthMiddleware{
-		sessRepo: sessRepo,
+		sessRepo:   sessRepo,
+		apiKeyRepo: apiKeyRepo,
 	}
 }
 
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID           uint   `json:"user_id"`
+	ServiceAccountID uint   `json:"service_account_id,omitempty"`
+	SubjectType      string `json:"subject_type,omitempty"`
+	SessionID        string `json:"sid,omitempty"`
+	Scope            string `json:"scope,omitempty"`
+	// Roles are the user's roles when the token was minted. Tokens for OAuth
+	// clients carry none, so they never pass RequirePermission.
+	Roles []string `json:"roles,omitempty"`
+	// OrganizationID is set on organization tokens, which act within that
+	// organization only, with the member's OrganizationRoles and no Roles.
+	OrganizationID    uint     `json:"org_id,omitempty"`
+	OrganizationRoles []string `json:"org_roles,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Subject returns the subject type of the token, defaulting to a user.
+func (claims *Claims) Subject() string {
+	if claims.SubjectType == "" {
+		return SubjectTypeUser
+	}
+	return claims.SubjectType
+}
+
+func (claims *Claims) matchesSession(session *models.Session) bool {
+	switch claims.Subject() {
+	case SubjectTypeUser:
+		return session.ServiceAccountID == 0 && session.UserID == claims.UserID && session.OrganizationID == claims.OrganizationID
+	case SubjectTypeService:
+		return claims.ServiceAccountID != 0 && session.ServiceAccountID == claims.ServiceAccountID
+	default:
+		return false
+	}
+}
+
 func (auth *AuthMiddleware) Middleware() gin.HandlerFunc {
-	secret := os.Getenv("JWT_SECRET")
 	return func(ctx *gin.Context) {
+		if apiKey := ctx.GetHeader(APIKeyHeader); apiKey != "" {
+			auth.authenticateAPIKey(ctx, apiKey)
+			return
+		}
+
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -36,9 +91,7 @@ func (auth *AuthMiddleware) Middleware() gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+		token, err := ParseToken(tokenString)
 
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -51,25 +104,217 @@ func (auth *AuthMiddleware) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		if _, err = auth.sessRepo.GetSession(ctx, tokenString); err != nil {
+		session, err := auth.sessRepo.GetSession(ctx, tokenString)
+		if err != nil || !claims.matchesSession(session) {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
 			return
 		}
 
-		ctx.Set("user_id", claims.UserID)
+		if now := time.Now(); now.Sub(session.LastSeen) >= lastSeenResolution {
+			if err := auth.sessRepo.TouchSession(ctx, session.ID, now); err != nil {
+				log.Printf("Error updating session last_seen: %v", err)
+			}
+		}
+
+		ctx.Set("subject_type", claims.Subject())
+		if claims.Subject() == SubjectTypeService {
+			ctx.Set("service_account_id", claims.ServiceAccountID)
+		} else {
+			ctx.Set("user_id", claims.UserID)
+		}
+		ctx.Set("token", tokenString)
+		ctx.Set("session_id", session.ID)
+		ctx.Set("scope", claims.Scope)
+		ctx.Set("roles", claims.Roles)
+		if claims.OrganizationID != 0 {
+			ctx.Set("organization_id", claims.OrganizationID)
+			ctx.Set("organization_roles", claims.OrganizationRoles)
+			ctx.Request = ctx.Request.WithContext(storage.WithOrganization(ctx.Request.Context(), claims.OrganizationID))
+		}
 		ctx.Next()
 	}
 }
 
-func GenerateToken(userID uint) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		},
+// authenticateAPIKey admits the owner of a valid API key. API key callers
+// have no session, so session_id and token stay unset.
+func (auth *AuthMiddleware) authenticateAPIKey(ctx *gin.Context, apiKey string) {
+	prefix, ok := apiKeyPrefix(apiKey)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		return
+	}
+
+	key, err := auth.apiKeyRepo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving API key"})
+		return
+	}
+
+	now := time.Now()
+	if !checkAPIKeyHash(apiKey, key.KeyHash) || key.Expired(now) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		return
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastSeenResolution {
+		if err := auth.apiKeyRepo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			log.Printf("Error updating API key last_used_at: %v", err)
+		}
+	}
+
+	ctx.Set("subject_type", SubjectTypeUser)
+	ctx.Set("user_id", key.UserID)
+	ctx.Set("api_key_id", key.ID)
+	ctx.Next()
+}
+
+// RequireSession rejects callers authenticated without a session, such as
+// API keys. It must run after AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("session_id") == "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoint requires a session token"})
+			return
+		}
+		ctx.Next()
 	}
+}
 
-	secret := os.Getenv("JWT_SECRET")
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+// RequireFirstParty rejects user tokens issued to OAuth clients, which carry
+// the scope the user granted the client and are only good for /userinfo.
+// Service account tokens are the account's own credential and pass. It must
+// run after AuthMiddleware.
+func RequireFirstParty() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("subject_type") == SubjectTypeUser && ctx.GetString("scope") != "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoint not available to OAuth client tokens"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// GenerateToken mints a first-party access token carrying the user's roles.
+func GenerateToken(userID uint, sessionID string, roles []string) (string, error) {
+	return generateUserToken(userID, sessionID, "", roles)
+}
+
+// GenerateScopedToken mints an access token for an OAuth client carrying the
+// granted scope. RequireFirstParty keeps it off every route but /userinfo.
+func GenerateScopedToken(userID uint, sessionID string, scope string) (string, error) {
+	return generateUserToken(userID, sessionID, scope, nil)
+}
+
+// GenerateOrganizationToken mints an access token that acts within the
+// organization only, carrying the member's roles in it. The user's own roles
+// are left out, so the token never passes RequirePermission.
+func GenerateOrganizationToken(userID uint, sessionID string, organizationID uint, organizationRoles []string) (string, error) {
+	return signAccessToken(&Claims{
+		UserID:            userID,
+		SessionID:         sessionID,
+		OrganizationID:    organizationID,
+		OrganizationRoles: organizationRoles,
+	})
+}
+
+func generateUserToken(userID uint, sessionID string, scope string, roles []string) (string, error) {
+	return signAccessToken(&Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Scope:     scope,
+		Roles:     roles,
+	})
+}
+
+// GenerateServiceToken mints an access token for a service account.
+func GenerateServiceToken(serviceAccountID uint, sessionID string, scope string) (string, error) {
+	return signAccessToken(&Claims{
+		ServiceAccountID: serviceAccountID,
+		SubjectType:      SubjectTypeService,
+		SessionID:        sessionID,
+		Scope:            scope,
+	})
+}
+
+// signAccessToken gives the claims a fresh token ID and the access token
+// lifetime and signs them.
+func signAccessToken(claims *Claims) (string, error) {
+	tokenID, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
+	}
+	return SignToken(claims)
+}
+
+// RequireSubjectType rejects callers of any other subject type. It must run
+// after AuthMiddleware.
+func RequireSubjectType(subjectType string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("subject_type") != subjectType {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoint not available for this caller"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// SignToken signs arbitrary claims with the active key of the keyring.
+func SignToken(claims jwt.Claims) (string, error) {
+	ring, err := LoadKeyring()
+	if err != nil {
+		return "", err
+	}
+
+	key := ring.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Private)
+}
+
+// ParseToken verifies an access token against the keyring key named by its
+// kid header. Only that key's own algorithm is accepted, so a token cannot
+// downgrade an asymmetric key to HMAC with the public key as secret.
+func ParseToken(tokenString string) (*jwt.Token, error) {
+	return ParseTokenClaims(tokenString, &Claims{})
+}
+
+// ParseTokenClaims verifies a token like ParseToken and decodes its payload
+// into claims, for tokens signed with SignToken that are not access tokens.
+func ParseTokenClaims(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	ring, err := LoadKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ring.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownSigningKey, kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("Unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods(ring.Algorithms()))
+}
+
+// GenerateOpaqueToken returns a random URL-safe string carrying 256 bits of entropy.
+func GenerateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"multitech/internal/models"
 	"multitech/pkg/storage"
 	"multitech/pkg/testutils"
 	"multitech/pkg/testutils/mocks"
@@ -72,8 +73,8 @@ func TestAuthMiddleware(t *testing.T) {
 				em.Set("JWT_SECRET", "test-secret")
 			},
 			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
-				msr.GetSessionFunc = func(ctx context.Context, token string) (uint, error) {
-					return 0, storage.ErrSessionExists
+				msr.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+					return nil, storage.ErrSessionNotFound
 				}
 			},
 			expectedStatus: http.StatusUnauthorized,
@@ -86,12 +87,26 @@ func TestAuthMiddleware(t *testing.T) {
 				em.Set("JWT_SECRET", "test-secret")
 			},
 			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
-				msr.GetSessionFunc = func(ctx context.Context, token string) (uint, error) {
-					return 1, nil
+				msr.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+					return &models.Session{ID: "session", UserID: 1, LastSeen: time.Now()}, nil
 				}
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:  "Valid token bound to another user's session",
+			token: "Bearer " + validToken,
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_SECRET", "test-secret")
+			},
+			mockSessSetup: func(msr *mocks.MockSessionsRepository) {
+				msr.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+					return &models.Session{ID: "session", UserID: 2, LastSeen: time.Now()}, nil
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  `{"error":"Invalid or expired session"}`,
+		},
 	}
 
 	originEnv := testutils.CaptureOriginEnv()
@@ -115,7 +130,7 @@ func TestAuthMiddleware(t *testing.T) {
 				ctx.Request.Header.Set("Authorization", tt.token)
 			}
 
-			middleware := NewAuthMiddleware(mockSessRepo)
+			middleware := NewAuthMiddleware(mockSessRepo, mocks.NewDefaultAPIKeyMock())
 			handler := middleware.Middleware()
 			handler(ctx)
 
@@ -126,3 +141,300 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewareTouchesStaleSession(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "test-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	token, err := GenerateToken(1, "session", nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		lastSeen      time.Time
+		expectTouched bool
+	}{
+		{
+			name:          "Recently seen session is not written",
+			lastSeen:      time.Now(),
+			expectTouched: false,
+		},
+		{
+			name:          "Stale session is touched",
+			lastSeen:      time.Now().Add(-time.Hour),
+			expectTouched: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			touched := false
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+				return &models.Session{ID: "session", UserID: 1, LastSeen: tt.lastSeen}, nil
+			}
+			mockSessRepo.TouchSessionFunc = func(ctx context.Context, sessionID string, lastSeen time.Time) error {
+				touched = true
+				assert.Equal(t, "session", sessionID)
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request.Header.Set("Authorization", "Bearer "+token)
+
+			middleware := NewAuthMiddleware(mockSessRepo, mocks.NewDefaultAPIKeyMock())
+			middleware.Middleware()(ctx)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tt.expectTouched, touched)
+			assert.Equal(t, "session", ctx.GetString("session_id"))
+		})
+	}
+}
+
+func TestAuthMiddlewareServiceAccount(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "test-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	serviceToken, err := GenerateServiceToken(7, "service-session", "reports:read")
+	assert.NoError(t, err)
+	userToken, err := GenerateToken(7, "user-session", nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		token          string
+		session        *models.Session
+		expectedStatus int
+		expectedType   string
+	}{
+		{
+			name:           "Service token with service session",
+			token:          serviceToken,
+			session:        &models.Session{ID: "service-session", ServiceAccountID: 7, LastSeen: time.Now()},
+			expectedStatus: http.StatusOK,
+			expectedType:   SubjectTypeService,
+		},
+		{
+			name:           "Service token bound to a user session",
+			token:          serviceToken,
+			session:        &models.Session{ID: "service-session", UserID: 7, LastSeen: time.Now()},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "User token bound to a service session",
+			token:          userToken,
+			session:        &models.Session{ID: "user-session", UserID: 7, ServiceAccountID: 7, LastSeen: time.Now()},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "User token with user session",
+			token:          userToken,
+			session:        &models.Session{ID: "user-session", UserID: 7, LastSeen: time.Now()},
+			expectedStatus: http.StatusOK,
+			expectedType:   SubjectTypeUser,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+				return tt.session, nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request.Header.Set("Authorization", "Bearer "+tt.token)
+
+			middleware := NewAuthMiddleware(mockSessRepo, mocks.NewDefaultAPIKeyMock())
+			middleware.Middleware()(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedType, ctx.GetString("subject_type"))
+			if tt.expectedType == SubjectTypeService {
+				assert.Equal(t, uint(7), ctx.GetUint("service_account_id"))
+				_, hasUser := ctx.Get("user_id")
+				assert.False(t, hasUser)
+				assert.Equal(t, "reports:read", ctx.GetString("scope"))
+			}
+		})
+	}
+}
+
+func TestRequireSubjectType(t *testing.T) {
+	tests := []struct {
+		name           string
+		subjectType    string
+		expectedStatus int
+	}{
+		{
+			name:           "Matching subject type",
+			subjectType:    SubjectTypeUser,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Other subject type",
+			subjectType:    SubjectTypeService,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("subject_type", tt.subjectType)
+
+			RequireSubjectType(SubjectTypeUser)(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
+
+func TestRequireFirstParty(t *testing.T) {
+	tests := []struct {
+		name           string
+		subjectType    string
+		scope          string
+		expectedStatus int
+	}{
+		{
+			name:           "First-party user token",
+			subjectType:    SubjectTypeUser,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OAuth client token",
+			subjectType:    SubjectTypeUser,
+			scope:          "openid profile",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Service account token",
+			subjectType:    SubjectTypeService,
+			scope:          "reports:read",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Set("subject_type", tt.subjectType)
+			ctx.Set("scope", tt.scope)
+
+			RequireFirstParty()(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	rawKey, prefix, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name           string
+		key            string
+		storedKey      *models.APIKey
+		lookupErr      error
+		expectedStatus int
+		expectTouched  bool
+	}{
+		{
+			name:           "Valid key",
+			key:            rawKey,
+			storedKey:      &models.APIKey{ID: 3, UserID: 9, Prefix: prefix, KeyHash: HashAPIKey(rawKey)},
+			expectedStatus: http.StatusOK,
+			expectTouched:  true,
+		},
+		{
+			name:           "Recently used key is not written",
+			key:            rawKey,
+			storedKey:      &models.APIKey{ID: 3, UserID: 9, Prefix: prefix, KeyHash: HashAPIKey(rawKey), LastUsedAt: timePtr(time.Now())},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Wrong secret",
+			key:            "mt_" + prefix + "_wrong",
+			storedKey:      &models.APIKey{ID: 3, UserID: 9, Prefix: prefix, KeyHash: HashAPIKey(rawKey)},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Expired key",
+			key:            rawKey,
+			storedKey:      &models.APIKey{ID: 3, UserID: 9, Prefix: prefix, KeyHash: HashAPIKey(rawKey), ExpiresAt: &past},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Revoked key",
+			key:            rawKey,
+			lookupErr:      storage.ErrAPIKeyNotFound,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Malformed key",
+			key:            "not-a-key",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			touched := false
+			mockKeyRepo := mocks.NewDefaultAPIKeyMock()
+			mockKeyRepo.GetAPIKeyByPrefixFunc = func(ctx context.Context, lookup string) (*models.APIKey, error) {
+				assert.Equal(t, prefix, lookup)
+				return tt.storedKey, tt.lookupErr
+			}
+			mockKeyRepo.TouchAPIKeyFunc = func(ctx context.Context, id uint, lastUsed time.Time) error {
+				touched = true
+				return nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request.Header.Set(APIKeyHeader, tt.key)
+
+			middleware := NewAuthMiddleware(mocks.NewDefaultSessionsMock(), mockKeyRepo)
+			middleware.Middleware()(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectTouched, touched)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, uint(9), ctx.GetUint("user_id"))
+				assert.Equal(t, SubjectTypeUser, ctx.GetString("subject_type"))
+				assert.Empty(t, ctx.GetString("session_id"))
+			} else {
+				assert.Equal(t, `{"error":"Invalid API key"}`, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("user_id", uint(9))
+	RequireSession()(ctx)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.True(t, ctx.IsAborted())
+
+	ctx, recorder = testutils.NewTestContext()
+	ctx.Set("session_id", "session")
+	RequireSession()(ctx)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.False(t, ctx.IsAborted())
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
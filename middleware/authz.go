@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"log"
+	"multitech/pkg/authz"
+	"multitech/pkg/storage"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResourceFunc returns the attributes of the resource a request acts on,
+// such as its owner_id, for the resource variable of policy conditions.
+type ResourceFunc func(ctx *gin.Context) (map[string]any, error)
+
+// ResourceFromParams exposes the path parameters of the route as resource
+// attributes. Integer parameters become integers, so that a condition can
+// compare resource.id with subject.id.
+func ResourceFromParams(ctx *gin.Context) (map[string]any, error) {
+	resource := make(map[string]any, len(ctx.Params))
+	for _, param := range ctx.Params {
+		if number, err := strconv.ParseInt(param.Value, 10, 64); err == nil {
+			resource[param.Key] = number
+			continue
+		}
+		resource[param.Key] = param.Value
+	}
+	return resource, nil
+}
+
+// Authorizer enforces the CEL policies of an authz.Engine.
+type Authorizer struct {
+	engine   *authz.Engine
+	roleRepo storage.RoleRepository
+}
+
+func NewAuthorizer(engine *authz.Engine, roleRepo storage.RoleRepository) *Authorizer {
+	return &Authorizer{
+		engine:   engine,
+		roleRepo: roleRepo,
+	}
+}
+
+// RequirePolicy rejects requests the policies do not allow action on. The
+// resource attributes come from resource, which may be nil. It must run
+// after AuthMiddleware.
+func (authorizer *Authorizer) RequirePolicy(action string, resource ResourceFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		attributes := map[string]any{}
+		if resource != nil {
+			var err error
+			attributes, err = resource(ctx)
+			if err != nil {
+				log.Printf("Error loading resource for %s: %v", action, err)
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error checking permissions"})
+				return
+			}
+		}
+
+		input, err := authorizer.Input(ctx, action, attributes)
+		if err != nil {
+			log.Printf("Error loading roles: %v", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error checking permissions"})
+			return
+		}
+
+		decision := authorizer.engine.Evaluate(input)
+		for _, evaluation := range decision.Evaluations {
+			if evaluation.Error != "" {
+				log.Printf("Error evaluating policy %s: %s", evaluation.Policy, evaluation.Error)
+			}
+		}
+		if !decision.Allowed {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied by policy"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// Input describes the caller authenticated by AuthMiddleware and its request
+// for a decision on action.
+func (authorizer *Authorizer) Input(ctx *gin.Context, action string, resource map[string]any) (authz.Input, error) {
+	subject, err := authorizer.subject(ctx)
+	if err != nil {
+		return authz.Input{}, err
+	}
+	return authz.Input{
+		Action:   action,
+		Subject:  subject,
+		Request:  PolicyRequest(ctx),
+		Resource: resource,
+	}, nil
+}
+
+// Engine returns the policies the authorizer enforces.
+func (authorizer *Authorizer) Engine() *authz.Engine {
+	return authorizer.engine
+}
+
+func (authorizer *Authorizer) subject(ctx *gin.Context) (map[string]any, error) {
+	subjectType := ctx.GetString("subject_type")
+	if subjectType == SubjectTypeService {
+		return map[string]any{
+			"type":  subjectType,
+			"id":    int64(ctx.GetUint("service_account_id")),
+			"roles": []string{},
+			"scope": ctx.GetString("scope"),
+		}, nil
+	}
+
+	roles, err := callerRoles(ctx, authorizer.roleRepo)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	subject := map[string]any{
+		"type":       subjectType,
+		"id":         int64(ctx.GetUint("user_id")),
+		"roles":      roles,
+		"scope":      ctx.GetString("scope"),
+		"session_id": ctx.GetString("session_id"),
+	}
+	if _, ok := ctx.Get("api_key_id"); ok {
+		subject["api_key_id"] = int64(ctx.GetUint("api_key_id"))
+	}
+	if organizationID := ctx.GetUint("organization_id"); organizationID != 0 {
+		subject["org_id"] = int64(organizationID)
+		subject["org_roles"] = ctx.GetStringSlice("organization_roles")
+	}
+	return subject, nil
+}
+
+// PolicyRequest describes the request for the request variable of policy
+// conditions. route is the matched route pattern, such as "/items/:id".
+func PolicyRequest(ctx *gin.Context) map[string]any {
+	return map[string]any{
+		"method": ctx.Request.Method,
+		"path":   ctx.Request.URL.Path,
+		"route":  ctx.FullPath(),
+		"ip":     ctx.ClientIP(),
+		"time":   time.Now().UTC(),
+	}
+}
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/authz"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPolicyEngine(t *testing.T) *authz.Engine {
+	engine, err := authz.NewEngine([]authz.Policy{
+		{
+			Name:      "owners",
+			Effect:    authz.EffectAllow,
+			Actions:   []string{"api_keys:*"},
+			Condition: `subject.type == "user" && resource.owner_id == subject.id`,
+		},
+		{
+			Name:      "support",
+			Effect:    authz.EffectAllow,
+			Actions:   []string{"users:read"},
+			Condition: `"support" in subject.roles`,
+		},
+		{
+			Name:      "no-api-key-writes",
+			Effect:    authz.EffectDeny,
+			Actions:   []string{"*"},
+			Condition: `"api_key_id" in subject && request.method != "GET"`,
+		},
+	})
+	require.NoError(t, err)
+	return engine
+}
+
+func TestRequirePolicy(t *testing.T) {
+	ownerResource := func(ctx *gin.Context) (map[string]any, error) {
+		return map[string]any{"owner_id": 7}, nil
+	}
+
+	tests := []struct {
+		name           string
+		setup          func(*gin.Context)
+		action         string
+		resource       ResourceFunc
+		userRoles      []models.Role
+		expectedStatus int
+	}{
+		{
+			name: "Owner",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(7))
+			},
+			action:         "api_keys:delete",
+			resource:       ownerResource,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Not The Owner",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(8))
+			},
+			action:         "api_keys:delete",
+			resource:       ownerResource,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Service Account With Same ID",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeService)
+				ctx.Set("service_account_id", uint(7))
+			},
+			action:         "api_keys:delete",
+			resource:       ownerResource,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Role From Token",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(1))
+				ctx.Set("roles", []string{"support"})
+			},
+			action:         "users:read",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Role Of API Key Owner",
+			setup: func(ctx *gin.Context) {
+				ctx.Request.Method = http.MethodGet
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(1))
+				ctx.Set("api_key_id", uint(3))
+			},
+			action:         "users:read",
+			userRoles:      []models.Role{{Name: "support"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "API Key Write Denied",
+			setup: func(ctx *gin.Context) {
+				ctx.Request.Method = http.MethodDelete
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(7))
+				ctx.Set("api_key_id", uint(3))
+			},
+			action:         "api_keys:delete",
+			resource:       ownerResource,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Resource Error",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(7))
+			},
+			action: "api_keys:delete",
+			resource: func(ctx *gin.Context) (map[string]any, error) {
+				return nil, errors.New("database error")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRoleRepo := mocks.NewDefaultRoleMock()
+			mockRoleRepo.GetUserRolesFunc = func(ctx context.Context, userID uint) ([]models.Role, error) {
+				return tt.userRoles, nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api-keys/1", nil)
+			tt.setup(ctx)
+
+			NewAuthorizer(testPolicyEngine(t), mockRoleRepo).RequirePolicy(tt.action, tt.resource)(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
+
+func TestRoutePermissionAndPolicy(t *testing.T) {
+	engine, err := authz.NewEngine(append(authz.DefaultPolicies(), authz.Policy{
+		Name:      "no-self-grants",
+		Effect:    authz.EffectDeny,
+		Actions:   []string{"roles:write"},
+		Condition: `resource.id == subject.id`,
+	}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		userID         uint
+		roles          []string
+		path           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Allowed",
+			userID:         1,
+			roles:          []string{"admin"},
+			path:           "/admin/users/2/roles",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Denied By Policy",
+			userID:         1,
+			roles:          []string{"admin"},
+			path:           "/admin/users/1/roles",
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "Access denied by policy",
+		},
+		{
+			name:           "Missing Permission",
+			userID:         1,
+			roles:          []string{"support"},
+			path:           "/admin/users/2/roles",
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "Missing permission roles:write",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRoleRepo := mocks.NewDefaultRoleMock()
+			mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+				return testRoles(), nil
+			}
+			authenticate := func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", tt.userID)
+				ctx.Set("roles", tt.roles)
+			}
+			authorizer := NewAuthorizer(engine, mockRoleRepo)
+
+			// Guarded like the role assignment route of the API.
+			router := gin.New()
+			router.POST("/admin/users/:id/roles", authenticate, NewRBAC(mockRoleRepo).RequirePermission("roles:write"), authorizer.RequirePolicy("roles:write", ResourceFromParams), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedError != "" {
+				assert.JSONEq(t, `{"error":"`+tt.expectedError+`"}`, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestResourceFromParams(t *testing.T) {
+	ctx, _ := testutils.NewTestContext()
+	ctx.Params = gin.Params{{Key: "id", Value: "42"}, {Key: "role", Value: "support"}}
+
+	resource, err := ResourceFromParams(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": int64(42), "role": "support"}, resource)
+}
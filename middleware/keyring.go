@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNoSigningKey      = errors.New("No signing key configured: set JWT_KEYS_DIR, JWT_PRIVATE_KEY_FILE or JWT_SECRET")
+	ErrUnknownSigningKey = errors.New("Unknown signing key")
+)
+
+// Keyring holds the key that signs new tokens and every key that is still
+// accepted for verification. Rotation happens in three steps: add the new
+// key so that all replicas can verify it, switch JWT_ACTIVE_KEY_ID to it, and
+// delete the old key once the longest-lived token signed with it has expired.
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeyring(active *SigningKey, verifyOnly ...*SigningKey) *Keyring {
+	ring := &Keyring{
+		active: active,
+		keys:   map[string]*SigningKey{active.ID: active},
+	}
+	for _, key := range verifyOnly {
+		ring.keys[key.ID] = key
+	}
+	return ring
+}
+
+func (ring *Keyring) Active() *SigningKey {
+	return ring.active
+}
+
+// Lookup returns the verification key for kid. Tokens minted before key IDs
+// were introduced carry no kid and resolve to a key configured without one.
+func (ring *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := ring.keys[kid]
+	return key, ok
+}
+
+// Algorithms lists the signing algorithms of every verification key.
+func (ring *Keyring) Algorithms() []string {
+	seen := make(map[string]bool)
+	algs := make([]string, 0, len(ring.keys))
+	for _, key := range ring.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// JWKSet publishes the public half of every asymmetric verification key.
+func (ring *Keyring) JWKSet() (*JWKSet, error) {
+	ids := make([]string, 0, len(ring.keys))
+	for id := range ring.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := &JWKSet{Keys: []JWK{}}
+	for _, id := range ids {
+		key := ring.keys[id]
+		if key.Symmetric() {
+			continue
+		}
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+var keyringCache struct {
+	mtx         sync.Mutex
+	fingerprint string
+	ring        *Keyring
+}
+
+var keyringEnv = []string{
+	"JWT_KEYS_DIR",
+	"JWT_ACTIVE_KEY_ID",
+	"JWT_PRIVATE_KEY_FILE",
+	"JWT_KEY_ID",
+	"JWT_SIGNING_ALG",
+	"JWT_SECRET",
+}
+
+// LoadKeyring returns the keyring described by the environment:
+//
+//   - JWT_KEYS_DIR: every *.pem file (RSA, ECDSA or Ed25519) and every *.secret
+//     file (HMAC) in the directory is a key whose ID is the file name without
+//     extension.
+//   - JWT_PRIVATE_KEY_FILE / JWT_SECRET: a single key with ID JWT_KEY_ID, kept
+//     for setups that predate the key directory.
+//   - JWT_ACTIVE_KEY_ID: the key that signs new tokens. It may be omitted when
+//     only one key is configured, or to keep signing with the single key above.
+//
+// The keyring is cached until the environment changes or ReloadKeyring is called.
+func LoadKeyring() (*Keyring, error) {
+	var fingerprint strings.Builder
+	for _, name := range keyringEnv {
+		fingerprint.WriteString(os.Getenv(name))
+		fingerprint.WriteByte(0)
+	}
+
+	keyringCache.mtx.Lock()
+	defer keyringCache.mtx.Unlock()
+	if keyringCache.ring != nil && keyringCache.fingerprint == fingerprint.String() {
+		return keyringCache.ring, nil
+	}
+
+	ring, err := loadKeyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	keyringCache.fingerprint = fingerprint.String()
+	keyringCache.ring = ring
+	return ring, nil
+}
+
+// ReloadKeyring drops the cached keyring so that key files are read again.
+func ReloadKeyring() (*Keyring, error) {
+	keyringCache.mtx.Lock()
+	keyringCache.ring = nil
+	keyringCache.mtx.Unlock()
+	return LoadKeyring()
+}
+
+func loadKeyringFromEnv() (*Keyring, error) {
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	keys := make(map[string]*SigningKey)
+
+	var single *SigningKey
+	switch keyID := os.Getenv("JWT_KEY_ID"); {
+	case os.Getenv("JWT_PRIVATE_KEY_FILE") != "":
+		key, err := loadPEMSigningKey(os.Getenv("JWT_PRIVATE_KEY_FILE"), alg, keyID)
+		if err != nil {
+			return nil, err
+		}
+		single = key
+	case os.Getenv("JWT_SECRET") != "":
+		single = NewHMACSigningKey(keyID, []byte(os.Getenv("JWT_SECRET")))
+	}
+	if single != nil {
+		keys[single.ID] = single
+	}
+
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		dirKeys, err := loadKeysDir(dir, alg)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range dirKeys {
+			if _, exists := keys[key.ID]; exists {
+				return nil, fmt.Errorf("Duplicate signing key ID %q", key.ID)
+			}
+			keys[key.ID] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	var active *SigningKey
+	activeID, activeSet := os.LookupEnv("JWT_ACTIVE_KEY_ID")
+	switch {
+	case activeSet && activeID != "":
+		active = keys[activeID]
+		if active == nil {
+			return nil, fmt.Errorf("%w: JWT_ACTIVE_KEY_ID %q", ErrUnknownSigningKey, activeID)
+		}
+	case single != nil:
+		active = single
+	case len(keys) == 1:
+		for _, key := range keys {
+			active = key
+		}
+	default:
+		return nil, errors.New("JWT_ACTIVE_KEY_ID is required when several signing keys are configured")
+	}
+
+	verifyOnly := make([]*SigningKey, 0, len(keys)-1)
+	for id, key := range keys {
+		if id != active.ID {
+			verifyOnly = append(verifyOnly, key)
+		}
+	}
+	return NewKeyring(active, verifyOnly...), nil
+}
+
+func loadKeysDir(dir string, alg string) ([]*SigningKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading signing keys: %w", err)
+	}
+
+	var keys []*SigningKey
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		ext := filepath.Ext(entry.Name())
+		keyID := strings.TrimSuffix(entry.Name(), ext)
+
+		switch ext {
+		case ".pem":
+			key, err := loadPEMSigningKey(path, alg, keyID)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case ".secret":
+			secret, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading signing key: %w", err)
+			}
+			secret = []byte(strings.TrimSpace(string(secret)))
+			if len(secret) == 0 {
+				return nil, fmt.Errorf("Signing key %s is empty", path)
+			}
+			keys = append(keys, NewHMACSigningKey(keyID, secret))
+		}
+	}
+	return keys, nil
+}
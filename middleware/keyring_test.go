@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeECKey(t *testing.T, dir string, keyID string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyID+".pem"), data, 0600))
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeECKey(t, dir, "2024-01")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2024-06.secret"), []byte("rotated-secret\n"), 0600))
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_KEYS_DIR", dir)
+	mockEnv.Set("JWT_ACTIVE_KEY_ID", "2024-01")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	oldToken, err := GenerateToken(1, "session", nil)
+	require.NoError(t, err)
+
+	ring, err := LoadKeyring()
+	require.NoError(t, err)
+	set, err := ring.JWKSet()
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "2024-01", set.Keys[0].Kid)
+
+	// Switch the signer: tokens of the previous key stay valid.
+	os.Setenv("JWT_ACTIVE_KEY_ID", "2024-06")
+
+	newToken, err := GenerateToken(1, "session", nil)
+	require.NoError(t, err)
+
+	parsed, err := ParseToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06", parsed.Header["kid"])
+	assert.Equal(t, "HS256", parsed.Method.Alg())
+
+	parsed, err = ParseToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", parsed.Header["kid"])
+
+	// Retire the previous key.
+	require.NoError(t, os.Remove(filepath.Join(dir, "2024-01.pem")))
+	_, err = ReloadKeyring()
+	require.NoError(t, err)
+
+	_, err = ParseToken(oldToken)
+	assert.Error(t, err)
+
+	_, err = ParseToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestKeyringLegacySecretWithoutKeyID(t *testing.T) {
+	dir := t.TempDir()
+	writeECKey(t, dir, "current")
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "legacy-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	legacyToken, err := GenerateToken(1, "session", nil)
+	require.NoError(t, err)
+
+	os.Setenv("JWT_KEYS_DIR", dir)
+	os.Setenv("JWT_ACTIVE_KEY_ID", "current")
+
+	newToken, err := GenerateToken(1, "session", nil)
+	require.NoError(t, err)
+
+	parsed, err := ParseToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", parsed.Method.Alg())
+
+	parsed, err = ParseToken(legacyToken)
+	require.NoError(t, err)
+	assert.Nil(t, parsed.Header["kid"])
+}
+
+func TestKeyringConfigurationErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeECKey(t, dir, "first")
+	writeECKey(t, dir, "second")
+
+	tests := []struct {
+		name     string
+		envSetup func(*mocks.EnvMock)
+	}{
+		{
+			name:     "No keys",
+			envSetup: func(em *mocks.EnvMock) {},
+		},
+		{
+			name: "Several keys without active key",
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_KEYS_DIR", dir)
+			},
+		},
+		{
+			name: "Unknown active key",
+			envSetup: func(em *mocks.EnvMock) {
+				em.Set("JWT_KEYS_DIR", dir)
+				em.Set("JWT_ACTIVE_KEY_ID", "third")
+			},
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			tt.envSetup(mockEnv)
+			os.Clearenv()
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			_, err := LoadKeyring()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnsupportedKeyType = errors.New("Unsupported signing key type")
+
+// SigningKey is the key material used to mint and verify access tokens.
+// Asymmetric keys carry a key ID that is written into the "kid" header and
+// published through the JWKS endpoint; HMAC keys are never published.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewHMACSigningKey(keyID string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:      keyID,
+		Method:  jwt.SigningMethodHS256,
+		Private: secret,
+		Public:  secret,
+	}
+}
+
+func loadPEMSigningKey(path string, alg string, keyID string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Error reading signing key: no PEM block in %s", path)
+	}
+
+	private, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(keyID, alg, private)
+}
+
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("Unsupported PEM block type %q", block.Type)
+	}
+}
+
+// NewSigningKey wraps an RSA, ECDSA or Ed25519 private key. The algorithm is
+// derived from the key unless alg picks one of the RSA variants, and an empty
+// keyID is replaced by the key's RFC 7638 thumbprint.
+func NewSigningKey(keyID string, alg string, private interface{}) (*SigningKey, error) {
+	var method jwt.SigningMethod
+	var public crypto.PublicKey
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		switch alg {
+		case "", "RS256":
+			method = jwt.SigningMethodRS256
+		case "RS384":
+			method = jwt.SigningMethodRS384
+		case "RS512":
+			method = jwt.SigningMethodRS512
+		case "PS256":
+			method = jwt.SigningMethodPS256
+		case "PS384":
+			method = jwt.SigningMethodPS384
+		case "PS512":
+			method = jwt.SigningMethodPS512
+		default:
+			return nil, fmt.Errorf("Algorithm %s cannot be used with an RSA key", alg)
+		}
+		public = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			method = jwt.SigningMethodES256
+		case elliptic.P384():
+			method = jwt.SigningMethodES384
+		case elliptic.P521():
+			method = jwt.SigningMethodES512
+		default:
+			return nil, ErrUnsupportedKeyType
+		}
+		if alg != "" && alg != method.Alg() {
+			return nil, fmt.Errorf("Algorithm %s cannot be used with a %s key", alg, key.Curve.Params().Name)
+		}
+		public = &key.PublicKey
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+		if alg != "" && alg != method.Alg() {
+			return nil, fmt.Errorf("Algorithm %s cannot be used with an Ed25519 key", alg)
+		}
+		public = key.Public()
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+
+	key := &SigningKey{
+		ID:      keyID,
+		Method:  method,
+		Private: private,
+		Public:  public,
+	}
+
+	if key.ID == "" {
+		thumbprint, err := key.thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = thumbprint
+	}
+	return key, nil
+}
+
+// Symmetric reports whether the key is a shared secret that must not be published.
+func (key *SigningKey) Symmetric() bool {
+	_, ok := key.Public.([]byte)
+	return ok
+}
+
+// JWK returns the public half of the key. It fails for symmetric keys.
+func (key *SigningKey) JWK() (JWK, error) {
+	jwk := JWK{
+		Kid: key.ID,
+		Use: "sig",
+		Alg: key.Method.Alg(),
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, ErrUnsupportedKeyType
+	}
+	return jwk, nil
+}
+
+func (key *SigningKey) thumbprint() (string, error) {
+	jwk, err := key.JWK()
+	if err != nil {
+		return "", err
+	}
+
+	// RFC 7638 hashes only the required members, in lexicographic order.
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
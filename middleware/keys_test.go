@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEMKey(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestAsymmetricSigningKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		blockType   string
+		der         []byte
+		alg         string
+		expectedAlg string
+		expectedKty string
+	}{
+		{
+			name:        "RSA PKCS1",
+			blockType:   "RSA PRIVATE KEY",
+			der:         x509.MarshalPKCS1PrivateKey(rsaKey),
+			expectedAlg: "RS256",
+			expectedKty: "RSA",
+		},
+		{
+			name:        "RSA with explicit PS256",
+			blockType:   "RSA PRIVATE KEY",
+			der:         x509.MarshalPKCS1PrivateKey(rsaKey),
+			alg:         "PS256",
+			expectedAlg: "PS256",
+			expectedKty: "RSA",
+		},
+		{
+			name:        "ECDSA P-256",
+			blockType:   "EC PRIVATE KEY",
+			der:         ecDER,
+			expectedAlg: "ES256",
+			expectedKty: "EC",
+		},
+		{
+			name:        "Ed25519 PKCS8",
+			blockType:   "PRIVATE KEY",
+			der:         edDER,
+			expectedAlg: "EdDSA",
+			expectedKty: "OKP",
+		},
+	}
+
+	originEnv := testutils.CaptureOriginEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEnv := mocks.NewEnvMock()
+			mockEnv.Set("JWT_PRIVATE_KEY_FILE", writePEMKey(t, tt.blockType, tt.der))
+			mockEnv.Set("JWT_SIGNING_ALG", tt.alg)
+			mockEnv.Apply()
+			defer mockEnv.Restore(originEnv)
+
+			tokenString, err := GenerateToken(1, "session", nil)
+			require.NoError(t, err)
+
+			token, err := ParseToken(tokenString)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAlg, token.Method.Alg())
+			assert.NotEmpty(t, token.Header["kid"])
+			assert.Equal(t, uint(1), token.Claims.(*Claims).UserID)
+
+			ring, err := LoadKeyring()
+			require.NoError(t, err)
+			set, err := ring.JWKSet()
+			require.NoError(t, err)
+			require.Len(t, set.Keys, 1)
+			assert.Equal(t, tt.expectedKty, set.Keys[0].Kty)
+			assert.Equal(t, tt.expectedAlg, set.Keys[0].Alg)
+			assert.Equal(t, token.Header["kid"], set.Keys[0].Kid)
+		})
+	}
+}
+
+func TestParseTokenRejectsAlgorithmConfusion(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_PRIVATE_KEY_FILE", writePEMKey(t, "EC PRIVATE KEY", ecDER))
+	mockEnv.Set("JWT_KEY_ID", "ec-key")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: 1})
+	forged.Header["kid"] = "ec-key"
+	forgedString, err := forged.SignedString(publicDER)
+	require.NoError(t, err)
+
+	_, err = ParseToken(forgedString)
+	assert.Error(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	unknown := jwt.NewWithClaims(jwt.SigningMethodES256, &Claims{UserID: 1})
+	unknown.Header["kid"] = "other-key"
+	unknownString, err := unknown.SignedString(otherKey)
+	require.NoError(t, err)
+
+	_, err = ParseToken(unknownString)
+	assert.Error(t, err)
+}
+
+func TestHMACKeyIsNotPublished(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "test-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	ring, err := LoadKeyring()
+	require.NoError(t, err)
+	set, err := ring.JWKSet()
+	require.NoError(t, err)
+	assert.Empty(t, set.Keys)
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"multitech/pkg/storage"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc names the bucket a request is counted in.
+type RateLimitKeyFunc func(ctx *gin.Context) string
+
+// RateLimitPolicy allows Limit requests per Period for every key. Policies
+// with a Limit of zero are disabled.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    RateLimitKeyFunc
+}
+
+// LoadRateLimitPolicy reads the policy from RATE_LIMIT_<NAME>, falling back to
+// fallback when the variable is unset. See ParseRateLimit for the format.
+func LoadRateLimitPolicy(name string, fallback string, key RateLimitKeyFunc) (RateLimitPolicy, error) {
+	envKey := "RATE_LIMIT_" + strings.ToUpper(name)
+	value := os.Getenv(envKey)
+	if value == "" {
+		value = fallback
+	}
+
+	limit, period, err := ParseRateLimit(value)
+	if err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("%s: %w", envKey, err)
+	}
+	return RateLimitPolicy{Name: name, Limit: limit, Period: period, Key: key}, nil
+}
+
+// ParseRateLimit parses limits such as "10/1m", "100/h" or "off".
+func ParseRateLimit(value string) (int, time.Duration, error) {
+	if value == "off" {
+		return 0, 0, nil
+	}
+
+	count, window, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid rate limit %q, expected <limit>/<period>", value)
+	}
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: bad limit", value)
+	}
+	if window != "" && strings.Trim(window, "hms") == "" {
+		window = "1" + window
+	}
+	period, err := time.ParseDuration(window)
+	if err != nil || period < time.Millisecond {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: bad period", value)
+	}
+	return limit, period, nil
+}
+
+// TrustedProxiesFromEnv reads the comma-separated TRUSTED_PROXIES list of
+// proxy addresses or CIDRs whose X-Forwarded-For headers are honoured. It
+// returns nil, trusting no proxy, when the variable is unset.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// RateLimitByIP counts requests per client IP. The client IP only follows
+// X-Forwarded-For when the request comes from a trusted proxy.
+func RateLimitByIP(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// RateLimitByUser counts requests per user or service account authenticated
+// by AuthMiddleware, and anonymous requests per client IP.
+func RateLimitByUser(ctx *gin.Context) string {
+	switch ctx.GetString("subject_type") {
+	case SubjectTypeUser:
+		return fmt.Sprintf("user:%d", ctx.GetUint("user_id"))
+	case SubjectTypeService:
+		return fmt.Sprintf("service:%d", ctx.GetUint("service_account_id"))
+	}
+	return RateLimitByIP(ctx)
+}
+
+// RateLimitByAPIKey counts requests per API key, so that every script of a
+// user gets its own budget. Other requests are counted like RateLimitByUser.
+func RateLimitByAPIKey(ctx *gin.Context) string {
+	if _, ok := ctx.Get("api_key_id"); ok {
+		return fmt.Sprintf("api_key:%d", ctx.GetUint("api_key_id"))
+	}
+	return RateLimitByUser(ctx)
+}
+
+type RateLimiter struct {
+	rateLimitRepo storage.RateLimitRepository
+}
+
+func NewRateLimiter(rateLimitRepo storage.RateLimitRepository) *RateLimiter {
+	return &RateLimiter{
+		rateLimitRepo: rateLimitRepo,
+	}
+}
+
+// Middleware enforces policy and describes it in the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers. Requests
+// are let through when the store is unavailable, an outage of Redis should
+// not take down logins.
+func (limiter *RateLimiter) Middleware(policy RateLimitPolicy) gin.HandlerFunc {
+	if policy.Limit <= 0 {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	limit := strconv.Itoa(policy.Limit)
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int(math.Ceil(policy.Period.Seconds())))
+
+	return func(ctx *gin.Context) {
+		key := policy.Name + ":" + policy.Key(ctx)
+		result, err := limiter.rateLimitRepo.AllowRequest(ctx.Request.Context(), key, policy.Limit, policy.Period)
+		if err != nil {
+			log.Printf("Error checking rate limit %s: %v", policy.Name, err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Limit", limit)
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+		ctx.Header("RateLimit-Policy", policyHeader)
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func ceilSeconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
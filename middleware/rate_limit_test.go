@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value  string
+		limit  int
+		period time.Duration
+		valid  bool
+	}{
+		{value: "10/1m", limit: 10, period: time.Minute, valid: true},
+		{value: "100/h", limit: 100, period: time.Hour, valid: true},
+		{value: "5/30s", limit: 5, period: 30 * time.Second, valid: true},
+		{value: "off", valid: true},
+		{value: "0/m", period: time.Minute, valid: true},
+		{value: "10"},
+		{value: "ten/m"},
+		{value: "-1/m"},
+		{value: "10/"},
+		{value: "10/fortnight"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			limit, period, err := ParseRateLimit(tt.value)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.limit, limit)
+			assert.Equal(t, tt.period, period)
+		})
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		context map[string]any
+		user    string
+		apiKey  string
+	}{
+		{name: "Anonymous", user: "ip:203.0.113.7", apiKey: "ip:203.0.113.7"},
+		{
+			name:    "User",
+			context: map[string]any{"subject_type": SubjectTypeUser, "user_id": uint(4)},
+			user:    "user:4",
+			apiKey:  "user:4",
+		},
+		{
+			name:    "Service Account",
+			context: map[string]any{"subject_type": SubjectTypeService, "service_account_id": uint(2)},
+			user:    "service:2",
+			apiKey:  "service:2",
+		},
+		{
+			name:    "API Key",
+			context: map[string]any{"subject_type": SubjectTypeUser, "user_id": uint(4), "api_key_id": uint(9)},
+			user:    "user:4",
+			apiKey:  "api_key:9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx.Request.RemoteAddr = "203.0.113.7:40000"
+			for key, value := range tt.context {
+				ctx.Set(key, value)
+			}
+
+			assert.Equal(t, "ip:203.0.113.7", RateLimitByIP(ctx))
+			assert.Equal(t, tt.user, RateLimitByUser(ctx))
+			assert.Equal(t, tt.apiKey, RateLimitByAPIKey(ctx))
+		})
+	}
+}
+
+func TestRateLimitByIPTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies string
+		key     string
+	}{
+		{name: "No Trusted Proxies", key: "ip:203.0.113.7"},
+		{name: "Untrusted Proxy", proxies: "10.0.0.0/8", key: "ip:203.0.113.7"},
+		{name: "Trusted Proxy", proxies: "192.0.2.1, 203.0.113.0/24", key: "ip:198.51.100.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+			ctx, engine := gin.CreateTestContext(httptest.NewRecorder())
+			assert.NoError(t, engine.SetTrustedProxies(TrustedProxiesFromEnv()))
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx.Request.RemoteAddr = "203.0.113.7:40000"
+			ctx.Request.Header.Set("X-Forwarded-For", "198.51.100.4")
+
+			assert.Equal(t, tt.key, RateLimitByIP(ctx))
+		})
+	}
+}
+
+func TestLoadRateLimitPolicy(t *testing.T) {
+	policy, err := LoadRateLimitPolicy("login", "20/1m", RateLimitByIP)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, policy.Limit)
+	assert.Equal(t, time.Minute, policy.Period)
+
+	t.Setenv("RATE_LIMIT_LOGIN", "5/h")
+	policy, err = LoadRateLimitPolicy("login", "20/1m", RateLimitByIP)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, policy.Limit)
+	assert.Equal(t, time.Hour, policy.Period)
+
+	t.Setenv("RATE_LIMIT_LOGIN", "lots")
+	_, err = LoadRateLimitPolicy("login", "20/1m", RateLimitByIP)
+	assert.ErrorContains(t, err, "RATE_LIMIT_LOGIN")
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		limit           int
+		result          *models.RateLimitResult
+		err             error
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			name:           "Allowed",
+			limit:          10,
+			result:         &models.RateLimitResult{Allowed: true, Remaining: 7, ResetAfter: 18 * time.Second},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit":     "10",
+				"RateLimit-Remaining": "7",
+				"RateLimit-Reset":     "18",
+				"RateLimit-Policy":    "10;w=60",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name:           "Rejected",
+			limit:          10,
+			result:         &models.RateLimitResult{Remaining: 0, RetryAfter: 5500 * time.Millisecond, ResetAfter: time.Minute},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedHeaders: map[string]string{
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "60",
+				"Retry-After":         "6",
+			},
+		},
+		{
+			name:           "Store Unavailable",
+			limit:          10,
+			err:            errors.New("redis error"),
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit": "",
+			},
+		},
+		{
+			name:           "Disabled",
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var countedKey string
+			mockRateLimitRepo := mocks.NewDefaultRateLimitMock()
+			mockRateLimitRepo.AllowRequestFunc = func(ctx context.Context, key string, limit int, period time.Duration) (*models.RateLimitResult, error) {
+				countedKey = key
+				return tt.result, tt.err
+			}
+			policy := RateLimitPolicy{Name: "test", Limit: tt.limit, Period: time.Minute, Key: RateLimitByIP}
+
+			recorder := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(recorder)
+			router.GET("/limited", NewRateLimiter(mockRateLimitRepo).Middleware(policy), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			request := httptest.NewRequest(http.MethodGet, "/limited", nil)
+			request.RemoteAddr = "203.0.113.7:40000"
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			for header, value := range tt.expectedHeaders {
+				assert.Equal(t, value, recorder.Header().Get(header), header)
+			}
+			if tt.limit > 0 {
+				assert.Equal(t, "test:ip:203.0.113.7", countedKey)
+			} else {
+				assert.Empty(t, countedKey)
+			}
+		})
+	}
+}
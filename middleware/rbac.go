@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"multitech/pkg/storage"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rolePermissionsTTL bounds how long a change to the permissions of a role
+// takes to apply. Granting or revoking a role applies with the next access
+// token instead.
+const rolePermissionsTTL = time.Minute
+
+// RBAC authorizes users by the permissions of their roles. The roles come
+// from the access token; API key callers have no token, so theirs are looked
+// up.
+type RBAC struct {
+	roleRepo storage.RoleRepository
+
+	mu          sync.Mutex
+	permissions map[string][]string
+	loadedAt    time.Time
+}
+
+func NewRBAC(roleRepo storage.RoleRepository) *RBAC {
+	return &RBAC{
+		roleRepo: roleRepo,
+	}
+}
+
+// RequirePermission rejects callers none of whose roles grants permission,
+// such as "users:write". It must run after AuthMiddleware.
+func (rbac *RBAC) RequirePermission(permission string) gin.HandlerFunc {
+	return rbac.require(permission, func(ctx *gin.Context) ([]string, error) {
+		return callerRoles(ctx, rbac.roleRepo)
+	})
+}
+
+// RequireOrganizationPermission rejects callers none of whose roles in the
+// organization of their token grants permission. The user's own roles do not
+// count. It must run after RequireOrganization.
+func (rbac *RBAC) RequireOrganizationPermission(permission string) gin.HandlerFunc {
+	return rbac.require(permission, func(ctx *gin.Context) ([]string, error) {
+		return ctx.GetStringSlice("organization_roles"), nil
+	})
+}
+
+func (rbac *RBAC) require(permission string, rolesFunc func(*gin.Context) ([]string, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		roles, err := rolesFunc(ctx)
+		if err != nil {
+			log.Printf("Error loading roles: %v", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error checking permissions"})
+			return
+		}
+
+		allowed, err := rbac.rolesGrant(ctx.Request.Context(), roles, permission)
+		if err != nil {
+			log.Printf("Error loading role permissions: %v", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// callerRoles returns the roles of the user authenticated by AuthMiddleware.
+func callerRoles(ctx *gin.Context, roleRepo storage.RoleRepository) ([]string, error) {
+	if ctx.GetString("subject_type") != SubjectTypeUser {
+		return nil, nil
+	}
+	if value, ok := ctx.Get("roles"); ok {
+		roles, _ := value.([]string)
+		return roles, nil
+	}
+
+	userRoles, err := roleRepo.GetUserRoles(ctx.Request.Context(), ctx.GetUint("user_id"))
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0, len(userRoles))
+	for _, role := range userRoles {
+		roles = append(roles, role.Name)
+	}
+	return roles, nil
+}
+
+func (rbac *RBAC) rolesGrant(ctx context.Context, roles []string, permission string) (bool, error) {
+	if len(roles) == 0 {
+		return false, nil
+	}
+	rolePermissions, err := rbac.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		for _, granted := range rolePermissions[role] {
+			if PermissionGrants(granted, permission) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// RolesCoverRole reports whether roles together grant every permission of
+// role, so that whoever holds them gains nothing by handing role out. It
+// fails with storage.ErrRoleNotFound for unknown roles.
+func (rbac *RBAC) RolesCoverRole(ctx context.Context, roles []string, role string) (bool, error) {
+	rolePermissions, err := rbac.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+	required, ok := rolePermissions[role]
+	if !ok {
+		return false, storage.ErrRoleNotFound
+	}
+	for _, permission := range required {
+		covered := false
+		for _, held := range roles {
+			for _, granted := range rolePermissions[held] {
+				if PermissionGrants(granted, permission) {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				break
+			}
+		}
+		if !covered {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CallerCoversRole is RolesCoverRole for the roles of the user
+// authenticated by AuthMiddleware.
+func (rbac *RBAC) CallerCoversRole(ctx *gin.Context, role string) (bool, error) {
+	roles, err := callerRoles(ctx, rbac.roleRepo)
+	if err != nil {
+		return false, err
+	}
+	return rbac.RolesCoverRole(ctx.Request.Context(), roles, role)
+}
+
+// rolePermissions returns the permissions of every role, reloading them once
+// they are older than rolePermissionsTTL.
+func (rbac *RBAC) rolePermissions(ctx context.Context) (map[string][]string, error) {
+	rbac.mu.Lock()
+	defer rbac.mu.Unlock()
+
+	if rbac.permissions != nil && time.Since(rbac.loadedAt) < rolePermissionsTTL {
+		return rbac.permissions, nil
+	}
+
+	roles, err := rbac.roleRepo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.PermissionNames()
+	}
+	rbac.permissions = permissions
+	rbac.loadedAt = time.Now()
+	return permissions, nil
+}
+
+// PermissionGrants reports whether holding granted allows required. "*"
+// allows everything and "users:*" every action on users.
+func PermissionGrants(granted string, required string) bool {
+	if granted == "*" || granted == required {
+		return true
+	}
+	resource, found := strings.CutSuffix(granted, ":*")
+	return found && strings.HasPrefix(required, resource+":")
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionGrants(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		expected bool
+	}{
+		{granted: "users:write", required: "users:write", expected: true},
+		{granted: "users:read", required: "users:write", expected: false},
+		{granted: "users:*", required: "users:write", expected: true},
+		{granted: "users:*", required: "usersettings:write", expected: false},
+		{granted: "*", required: "roles:write", expected: true},
+		{granted: "", required: "users:write", expected: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, PermissionGrants(tt.granted, tt.required), "%s grants %s", tt.granted, tt.required)
+	}
+}
+
+func testRoles() []models.Role {
+	return []models.Role{
+		{Name: "admin", Permissions: []models.Permission{{Name: "*"}}},
+		{Name: "support", Permissions: []models.Permission{{Name: "users:read"}, {Name: "logins:*"}}},
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name           string
+		setup          func(*gin.Context)
+		permission     string
+		userRoles      []models.Role
+		expectedStatus int
+	}{
+		{
+			name: "Token Role Grants Permission",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(1))
+				ctx.Set("roles", []string{"support"})
+			},
+			permission:     "logins:unlock",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Token Role Lacks Permission",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(1))
+				ctx.Set("roles", []string{"support"})
+			},
+			permission:     "users:write",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Token Without Roles",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(1))
+				ctx.Set("roles", []string(nil))
+			},
+			permission:     "users:read",
+			userRoles:      testRoles(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Unknown Role",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("roles", []string{"deleted"})
+			},
+			permission:     "users:read",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "API Key Roles Looked Up",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(1))
+				ctx.Set("api_key_id", uint(3))
+			},
+			permission:     "roles:write",
+			userRoles:      []models.Role{{Name: "admin"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Service Account",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeService)
+				ctx.Set("service_account_id", uint(1))
+			},
+			permission:     "users:read",
+			userRoles:      testRoles(),
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRoleRepo := mocks.NewDefaultRoleMock()
+			mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+				return testRoles(), nil
+			}
+			mockRoleRepo.GetUserRolesFunc = func(ctx context.Context, userID uint) ([]models.Role, error) {
+				return tt.userRoles, nil
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			tt.setup(ctx)
+
+			NewRBAC(mockRoleRepo).RequirePermission(tt.permission)(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
+
+func TestRequirePermissionCachesRoles(t *testing.T) {
+	loads := 0
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		loads++
+		return testRoles(), nil
+	}
+
+	rbac := NewRBAC(mockRoleRepo)
+	requireRead := rbac.RequirePermission("users:read")
+	for i := 0; i < 3; i++ {
+		ctx, recorder := testutils.NewTestContext()
+		ctx.Set("subject_type", SubjectTypeUser)
+		ctx.Set("roles", []string{"support"})
+		requireRead(ctx)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+	assert.Equal(t, 1, loads)
+}
+
+func TestRolesCoverRole(t *testing.T) {
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return append(testRoles(),
+			models.Role{Name: "auditor", Permissions: []models.Permission{{Name: "users:read"}}},
+			models.Role{Name: "login-support", Permissions: []models.Permission{{Name: "logins:reset"}, {Name: "users:read"}}},
+		), nil
+	}
+	rbac := NewRBAC(mockRoleRepo)
+
+	tests := []struct {
+		name     string
+		roles    []string
+		role     string
+		expected bool
+	}{
+		{name: "Wildcard Covers Everything", roles: []string{"admin"}, role: "support", expected: true},
+		{name: "Same Permissions", roles: []string{"support"}, role: "auditor", expected: true},
+		{name: "Resource Wildcard", roles: []string{"support"}, role: "login-support", expected: true},
+		{name: "Narrower Roles", roles: []string{"auditor"}, role: "support", expected: false},
+		{name: "Beyond Every Role", roles: []string{"support", "auditor"}, role: "admin", expected: false},
+		{name: "No Roles", role: "auditor", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covered, err := rbac.RolesCoverRole(context.Background(), tt.roles, tt.role)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, covered)
+		})
+	}
+
+	_, err := rbac.RolesCoverRole(context.Background(), []string{"admin"}, "overlord")
+	assert.ErrorIs(t, err, storage.ErrRoleNotFound)
+}
+
+func TestRequirePermissionStorageError(t *testing.T) {
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return nil, errors.New("database error")
+	}
+
+	ctx, recorder := testutils.NewTestContext()
+	ctx.Set("subject_type", SubjectTypeUser)
+	ctx.Set("roles", []string{"admin"})
+
+	NewRBAC(mockRoleRepo).RequirePermission("users:read")(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestRolesClaim(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "test-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	token, err := GenerateToken(1, "session", []string{"admin", "support"})
+	require.NoError(t, err)
+	scopedToken, err := GenerateScopedToken(1, "session", "openid")
+	require.NoError(t, err)
+
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return testRoles(), nil
+	}
+	auth := NewAuthMiddleware(mocks.NewDefaultSessionsMock(), mocks.NewDefaultAPIKeyMock())
+	rbac := NewRBAC(mockRoleRepo)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedRoles  []string
+		expectedStatus int
+	}{
+		{name: "First-party token", token: token, expectedRoles: []string{"admin", "support"}, expectedStatus: http.StatusOK},
+		{name: "OAuth client token", token: scopedToken, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var roles []string
+			recorder := httptest.NewRecorder()
+			ctx, router := gin.CreateTestContext(recorder)
+			router.GET("/admin", auth.Middleware(), rbac.RequirePermission("users:write"), func(ctx *gin.Context) {
+				roles = ctx.GetStringSlice("roles")
+				ctx.Status(http.StatusOK)
+			})
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/admin", nil)
+			ctx.Request.Header.Set("Authorization", "Bearer "+tt.token)
+			router.HandleContext(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedRoles, roles)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"log"
+	"multitech/internal/models"
+	"multitech/pkg/rebac"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ObjectFunc names the object a request acts on.
+type ObjectFunc func(ctx *gin.Context) (models.ObjectRef, error)
+
+// ObjectFromParam names the object by a path parameter, such as
+// ObjectFromParam("document", "id") for /documents/:id.
+func ObjectFromParam(objectType string, param string) ObjectFunc {
+	return func(ctx *gin.Context) (models.ObjectRef, error) {
+		return models.ParseObjectRef(objectType + ":" + ctx.Param(param))
+	}
+}
+
+// CallerSubject names the caller authenticated by AuthMiddleware in relation
+// tuples: "user:<id>" for users and their API keys, "service:<id>" for
+// service accounts.
+func CallerSubject(ctx *gin.Context) models.SubjectRef {
+	if ctx.GetString("subject_type") == SubjectTypeService {
+		return models.SubjectRef{Type: SubjectTypeService, ID: strconv.FormatUint(uint64(ctx.GetUint("service_account_id")), 10)}
+	}
+	return models.SubjectRef{Type: SubjectTypeUser, ID: strconv.FormatUint(uint64(ctx.GetUint("user_id")), 10)}
+}
+
+// Relations authorizes callers by their relations to objects.
+type Relations struct {
+	checker *rebac.Checker
+}
+
+func NewRelations(checker *rebac.Checker) *Relations {
+	return &Relations{
+		checker: checker,
+	}
+}
+
+// Allowed reports whether the caller has relation to object. Handlers use it
+// for objects that are only known after reading the request.
+func (relations *Relations) Allowed(ctx *gin.Context, object models.ObjectRef, relation string) (bool, error) {
+	return relations.checker.Check(ctx.Request.Context(), object, relation, CallerSubject(ctx))
+}
+
+// RequireRelation rejects callers without relation to the object of the
+// request. Requests naming an invalid object get 404. It must run after
+// AuthMiddleware.
+func (relations *Relations) RequireRelation(relation string, object ObjectFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		target, err := object(ctx)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		allowed, err := relations.Allowed(ctx, target, relation)
+		if err != nil {
+			log.Printf("Error checking relation %s on %s: %v", relation, target, err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error checking permissions"})
+			return
+		}
+		if !allowed {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing relation " + relation + " on " + target.String()})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/rebac"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRelation(t *testing.T) {
+	tuples := []models.RelationTuple{
+		{ObjectType: "document", ObjectID: "readme", Relation: "viewer", SubjectType: "user", SubjectID: "7"},
+		{ObjectType: "document", ObjectID: "readme", Relation: "viewer", SubjectType: "service", SubjectID: "2"},
+	}
+
+	tests := []struct {
+		name           string
+		setup          func(*gin.Context)
+		param          string
+		readErr        error
+		expectedStatus int
+	}{
+		{
+			name: "Viewer",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(7))
+			},
+			param:          "readme",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Service Account",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeService)
+				ctx.Set("service_account_id", uint(2))
+			},
+			param:          "readme",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Other User",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(8))
+			},
+			param:          "readme",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Invalid Object",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(7))
+			},
+			param:          "read me",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "Storage Error",
+			setup: func(ctx *gin.Context) {
+				ctx.Set("subject_type", SubjectTypeUser)
+				ctx.Set("user_id", uint(7))
+			},
+			param:          "readme",
+			readErr:        errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTupleRepo := mocks.NewRelationTupleMock(tuples...)
+			if tt.readErr != nil {
+				mockTupleRepo.ReadTuplesFunc = func(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+					return nil, tt.readErr
+				}
+			}
+
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Params = gin.Params{{Key: "id", Value: tt.param}}
+			tt.setup(ctx)
+
+			relations := NewRelations(rebac.NewChecker(mockTupleRepo, nil))
+			relations.RequireRelation("viewer", ObjectFromParam("document", "id"))(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
+
+func TestCallerSubject(t *testing.T) {
+	ctx, _ := testutils.NewTestContext()
+	ctx.Set("subject_type", SubjectTypeUser)
+	ctx.Set("user_id", uint(7))
+	ctx.Set("api_key_id", uint(3))
+	assert.Equal(t, "user:7", CallerSubject(ctx).String())
+
+	ctx, _ = testutils.NewTestContext()
+	ctx.Set("subject_type", SubjectTypeService)
+	ctx.Set("service_account_id", uint(2))
+	assert.Equal(t, "service:2", CallerSubject(ctx).String())
+}
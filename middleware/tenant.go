@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"multitech/pkg/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireOrganization rejects callers whose token is not an organization
+// token for the organization named by the path parameter param, so that a
+// token of one tenant never reaches another. It must run after
+// AuthMiddleware.
+func RequireOrganization(param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		organizationID, err := strconv.ParseUint(ctx.Param(param), 10, 0)
+		if err != nil || organizationID == 0 {
+			ctx.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": storage.ErrOrganizationNotFound.Error()})
+			return
+		}
+
+		callerOrganization := ctx.GetUint("organization_id")
+		if callerOrganization == 0 {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoint requires an organization token"})
+			return
+		}
+		if callerOrganization != uint(organizationID) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is not valid for this organization"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils"
+	"multitech/pkg/testutils/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireOrganization(t *testing.T) {
+	tests := []struct {
+		name           string
+		organizationID uint
+		param          string
+		expectedStatus int
+	}{
+		{name: "Same Organization", organizationID: 4, param: "4", expectedStatus: http.StatusOK},
+		{name: "Other Organization", organizationID: 5, param: "4", expectedStatus: http.StatusForbidden},
+		{name: "Global Token", param: "4", expectedStatus: http.StatusForbidden},
+		{name: "Invalid Organization", organizationID: 4, param: "acme", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, recorder := testutils.NewTestContext()
+			ctx.Params = gin.Params{{Key: "id", Value: tt.param}}
+			if tt.organizationID != 0 {
+				ctx.Set("organization_id", tt.organizationID)
+			}
+
+			RequireOrganization("id")(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, ctx.IsAborted())
+		})
+	}
+}
+
+func TestOrganizationToken(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "test-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	ownerToken, err := GenerateOrganizationToken(1, "session", 4, []string{"org_owner"})
+	require.NoError(t, err)
+	memberToken, err := GenerateOrganizationToken(1, "session", 4, []string{"org_member"})
+	require.NoError(t, err)
+	globalToken, err := GenerateToken(1, "session", []string{"admin"})
+	require.NoError(t, err)
+
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return []models.Role{
+			{Name: "admin", Permissions: []models.Permission{{Name: "*"}}},
+			{Name: "org_owner", Permissions: []models.Permission{{Name: "members:*"}}},
+			{Name: "org_member", Permissions: []models.Permission{{Name: "members:read"}}},
+		}, nil
+	}
+	rbac := NewRBAC(mockRoleRepo)
+
+	tests := []struct {
+		name                string
+		token               string
+		sessionOrganization uint
+		path                string
+		expectedStatus      int
+		expectedScope       uint
+	}{
+		{name: "Owner", token: ownerToken, sessionOrganization: 4, path: "/organizations/4/members", expectedStatus: http.StatusOK, expectedScope: 4},
+		{name: "Member Lacks Permission", token: memberToken, sessionOrganization: 4, path: "/organizations/4/members", expectedStatus: http.StatusForbidden},
+		{name: "Other Organization", token: ownerToken, sessionOrganization: 4, path: "/organizations/5/members", expectedStatus: http.StatusForbidden},
+		{name: "Global Admin Token", token: globalToken, path: "/organizations/4/members", expectedStatus: http.StatusForbidden},
+		{name: "Session Of Other Organization", token: ownerToken, sessionOrganization: 5, path: "/organizations/4/members", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+				return &models.Session{ID: "session", UserID: 1, OrganizationID: tt.sessionOrganization, LastSeen: time.Now()}, nil
+			}
+			auth := NewAuthMiddleware(mockSessRepo, mocks.NewDefaultAPIKeyMock())
+
+			var scope uint
+			recorder := httptest.NewRecorder()
+			ctx, router := gin.CreateTestContext(recorder)
+			router.PUT("/organizations/:id/members", auth.Middleware(), RequireOrganization("id"), rbac.RequireOrganizationPermission("members:write"), func(ctx *gin.Context) {
+				scope, _ = storage.OrganizationFromContext(ctx.Request.Context())
+				ctx.Status(http.StatusOK)
+			})
+			ctx.Request = httptest.NewRequest(http.MethodPut, tt.path, nil)
+			ctx.Request.Header.Set("Authorization", "Bearer "+tt.token)
+			router.HandleContext(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, tt.expectedScope, scope)
+		})
+	}
+}
+
+func TestOrganizationTokenLacksGlobalRoles(t *testing.T) {
+	originEnv := testutils.CaptureOriginEnv()
+	mockEnv := mocks.NewEnvMock()
+	mockEnv.Set("JWT_SECRET", "test-secret")
+	mockEnv.Apply()
+	defer mockEnv.Restore(originEnv)
+
+	organizationToken, err := GenerateOrganizationToken(1, "session", 4, []string{"org_owner"})
+	require.NoError(t, err)
+	globalToken, err := GenerateToken(1, "session", []string{"admin"})
+	require.NoError(t, err)
+
+	mockRoleRepo := mocks.NewDefaultRoleMock()
+	mockRoleRepo.ListRolesFunc = func(ctx context.Context) ([]models.Role, error) {
+		return []models.Role{
+			{Name: "admin", Permissions: []models.Permission{{Name: "*"}}},
+			{Name: "org_owner", Permissions: []models.Permission{{Name: "*"}}},
+		}, nil
+	}
+	rbac := NewRBAC(mockRoleRepo)
+
+	tests := []struct {
+		name                string
+		token               string
+		sessionOrganization uint
+		expectedStatus      int
+	}{
+		{name: "Global Token", token: globalToken, expectedStatus: http.StatusOK},
+		{name: "Organization Token", token: organizationToken, sessionOrganization: 4, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessRepo := mocks.NewDefaultSessionsMock()
+			mockSessRepo.GetSessionFunc = func(ctx context.Context, token string) (*models.Session, error) {
+				return &models.Session{ID: "session", UserID: 1, OrganizationID: tt.sessionOrganization, LastSeen: time.Now()}, nil
+			}
+			auth := NewAuthMiddleware(mockSessRepo, mocks.NewDefaultAPIKeyMock())
+
+			recorder := httptest.NewRecorder()
+			ctx, router := gin.CreateTestContext(recorder)
+			router.GET("/admin/roles", auth.Middleware(), rbac.RequirePermission("roles:read"), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/admin/roles", nil)
+			ctx.Request.Header.Set("Authorization", "Bearer "+tt.token)
+			router.HandleContext(ctx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Package authz decides requests with policies whose conditions are CEL
+// expressions over the subject making a request, the request itself and the
+// resource it acts on.
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy effects. A matching deny policy overrides every allow policy.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// maxEvaluationCost bounds the work a single condition may do, so that a
+// careless policy cannot stall requests.
+const maxEvaluationCost = 100000
+
+var ErrInvalidPolicy = errors.New("Invalid policy")
+
+// Policy allows or denies Actions when Condition holds. Actions are named
+// like permissions, "<resource>:<action>", and match "*" and "<resource>:*"
+// patterns. An empty Condition always holds.
+type Policy struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Effect      string   `yaml:"effect" json:"effect"`
+	Actions     []string `yaml:"actions" json:"actions"`
+	Condition   string   `yaml:"condition" json:"condition,omitempty"`
+}
+
+func (policy *Policy) appliesTo(action string) bool {
+	for _, pattern := range policy.Actions {
+		if pattern == "*" || pattern == action {
+			return true
+		}
+		resource, found := strings.CutSuffix(pattern, ":*")
+		if found && strings.HasPrefix(action, resource+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// Input is what a decision is made on. Subject, Request and Resource are
+// exposed to conditions as the variables of the same name, next to action.
+type Input struct {
+	Action   string         `json:"action"`
+	Subject  map[string]any `json:"subject"`
+	Request  map[string]any `json:"request"`
+	Resource map[string]any `json:"resource"`
+}
+
+func (input *Input) activation() map[string]any {
+	return map[string]any{
+		"action":   input.Action,
+		"subject":  orEmpty(input.Subject),
+		"request":  orEmpty(input.Request),
+		"resource": orEmpty(input.Resource),
+	}
+}
+
+func orEmpty(attributes map[string]any) map[string]any {
+	if attributes == nil {
+		return map[string]any{}
+	}
+	return attributes
+}
+
+// Evaluation records how one policy took part in a decision.
+type Evaluation struct {
+	Policy  string `json:"policy"`
+	Effect  string `json:"effect"`
+	Applies bool   `json:"applies"`
+	Matched bool   `json:"matched"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Decision is the outcome of Evaluate with the reasoning behind it.
+type Decision struct {
+	Allowed     bool         `json:"allowed"`
+	Reason      string       `json:"reason"`
+	Policy      string       `json:"policy,omitempty"`
+	Evaluations []Evaluation `json:"evaluations"`
+}
+
+type compiledPolicy struct {
+	Policy
+	program cel.Program
+}
+
+// Engine evaluates a fixed set of policies. It is safe for concurrent use.
+type Engine struct {
+	policies []compiledPolicy
+}
+
+// NewEngine validates and compiles policies. Conditions must be boolean CEL
+// expressions; ones whose type is only known at runtime, like a bare
+// resource.locked, are checked when evaluated.
+func NewEngine(policies []Policy) (*Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("action", cel.StringType),
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{}
+	names := map[string]bool{}
+	for _, policy := range policies {
+		if policy.Name == "" {
+			return nil, fmt.Errorf("%w: missing name", ErrInvalidPolicy)
+		}
+		if names[policy.Name] {
+			return nil, fmt.Errorf("%w %s: duplicate name", ErrInvalidPolicy, policy.Name)
+		}
+		names[policy.Name] = true
+		if policy.Effect != EffectAllow && policy.Effect != EffectDeny {
+			return nil, fmt.Errorf("%w %s: effect must be %q or %q", ErrInvalidPolicy, policy.Name, EffectAllow, EffectDeny)
+		}
+		if len(policy.Actions) == 0 {
+			return nil, fmt.Errorf("%w %s: no actions", ErrInvalidPolicy, policy.Name)
+		}
+
+		condition := policy.Condition
+		if strings.TrimSpace(condition) == "" {
+			condition = "true"
+		}
+		ast, issues := env.Compile(condition)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("%w %s: %v", ErrInvalidPolicy, policy.Name, issues.Err())
+		}
+		if output := ast.OutputType(); output != cel.BoolType && output != cel.DynType {
+			return nil, fmt.Errorf("%w %s: condition must be a boolean, not %s", ErrInvalidPolicy, policy.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast, cel.CostLimit(maxEvaluationCost))
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %v", ErrInvalidPolicy, policy.Name, err)
+		}
+		engine.policies = append(engine.policies, compiledPolicy{Policy: policy, program: program})
+	}
+	return engine, nil
+}
+
+type policyFile struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// LoadFile reads policies from a YAML (or JSON) file with a top-level
+// "policies" list.
+func LoadFile(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	engine, err := NewEngine(file.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return engine, nil
+}
+
+// DefaultPolicies allow every action. Routes check role permissions before
+// policies, so without a policy file the roles alone decide.
+func DefaultPolicies() []Policy {
+	return []Policy{{
+		Name:        "default-allow",
+		Description: "Leaves every action to role permissions, as no AUTHZ_POLICY_FILE is configured",
+		Effect:      EffectAllow,
+		Actions:     []string{"*"},
+	}}
+}
+
+// FromEnv loads the policies in AUTHZ_POLICY_FILE, or DefaultPolicies without
+// the file. A file replaces the defaults, so it has to allow every action
+// that should stay possible.
+func FromEnv() (*Engine, error) {
+	path := os.Getenv("AUTHZ_POLICY_FILE")
+	if path == "" {
+		return NewEngine(DefaultPolicies())
+	}
+	return LoadFile(path)
+}
+
+// Policies returns the policies of the engine in evaluation order.
+func (engine *Engine) Policies() []Policy {
+	policies := make([]Policy, 0, len(engine.policies))
+	for _, policy := range engine.policies {
+		policies = append(policies, policy.Policy)
+	}
+	return policies
+}
+
+// Evaluate decides input. Actions are denied unless an allow policy matches
+// and no deny policy does. A deny policy whose condition fails to evaluate,
+// for instance on a missing attribute, denies as well; an allow policy that
+// fails does not allow.
+func (engine *Engine) Evaluate(input Input) Decision {
+	activation := input.activation()
+	decision := Decision{Evaluations: make([]Evaluation, 0, len(engine.policies))}
+
+	var allowedBy, deniedBy string
+	for _, policy := range engine.policies {
+		evaluation := Evaluation{Policy: policy.Name, Effect: policy.Effect}
+		if policy.appliesTo(input.Action) {
+			evaluation.Applies = true
+			matched, err := policy.eval(activation)
+			evaluation.Matched = matched
+			if err != nil {
+				evaluation.Error = err.Error()
+				evaluation.Matched = policy.Effect == EffectDeny
+			}
+			if evaluation.Matched {
+				if policy.Effect == EffectDeny && deniedBy == "" {
+					deniedBy = policy.Name
+				}
+				if policy.Effect == EffectAllow && allowedBy == "" {
+					allowedBy = policy.Name
+				}
+			}
+		}
+		decision.Evaluations = append(decision.Evaluations, evaluation)
+	}
+
+	switch {
+	case deniedBy != "":
+		decision.Policy = deniedBy
+		decision.Reason = "Denied by policy " + deniedBy
+	case allowedBy != "":
+		decision.Allowed = true
+		decision.Policy = allowedBy
+		decision.Reason = "Allowed by policy " + allowedBy
+	default:
+		decision.Reason = "No policy allows " + input.Action
+	}
+	return decision
+}
+
+func (policy *compiledPolicy) eval(activation map[string]any) (bool, error) {
+	value, _, err := policy.program.Eval(activation)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := value.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluated to %v, not a boolean", value)
+	}
+	return matched, nil
+}
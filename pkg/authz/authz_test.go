@@ -0,0 +1,219 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicies = []Policy{
+	{
+		Name:      "owners-edit-during-business-hours",
+		Effect:    EffectAllow,
+		Actions:   []string{"documents:edit"},
+		Condition: `resource.owner_id == subject.id && request.time.getDayOfWeek("Europe/Berlin") in [1, 2, 3, 4, 5] && request.time.getHours("Europe/Berlin") >= 9 && request.time.getHours("Europe/Berlin") < 17`,
+	},
+	{
+		Name:      "editors",
+		Effect:    EffectAllow,
+		Actions:   []string{"documents:*"},
+		Condition: `"editor" in subject.roles`,
+	},
+	{
+		Name:      "locked-documents",
+		Effect:    EffectDeny,
+		Actions:   []string{"documents:edit", "documents:delete"},
+		Condition: `resource.locked`,
+	},
+}
+
+// Monday, 10:00 in Berlin.
+var businessHours = time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+func TestEvaluate(t *testing.T) {
+	engine, err := NewEngine(testPolicies)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		input         Input
+		expectAllowed bool
+		expectPolicy  string
+		expectReason  string
+	}{
+		{
+			name: "Owner During Business Hours",
+			input: Input{
+				Action:   "documents:edit",
+				Subject:  map[string]any{"id": int64(7), "roles": []string{}},
+				Request:  map[string]any{"time": businessHours},
+				Resource: map[string]any{"owner_id": int64(7), "locked": false},
+			},
+			expectAllowed: true,
+			expectPolicy:  "owners-edit-during-business-hours",
+			expectReason:  "Allowed by policy owners-edit-during-business-hours",
+		},
+		{
+			name: "Owner At Night",
+			input: Input{
+				Action:   "documents:edit",
+				Subject:  map[string]any{"id": int64(7), "roles": []string{}},
+				Request:  map[string]any{"time": businessHours.Add(12 * time.Hour)},
+				Resource: map[string]any{"owner_id": int64(7), "locked": false},
+			},
+			expectReason: "No policy allows documents:edit",
+		},
+		{
+			name: "Someone Else",
+			input: Input{
+				Action:   "documents:edit",
+				Subject:  map[string]any{"id": int64(8), "roles": []string{}},
+				Request:  map[string]any{"time": businessHours},
+				Resource: map[string]any{"owner_id": int64(7), "locked": false},
+			},
+			expectReason: "No policy allows documents:edit",
+		},
+		{
+			name: "Editor Any Time",
+			input: Input{
+				Action:   "documents:delete",
+				Subject:  map[string]any{"id": int64(8), "roles": []string{"editor"}},
+				Request:  map[string]any{"time": businessHours.Add(12 * time.Hour)},
+				Resource: map[string]any{"owner_id": int64(7), "locked": false},
+			},
+			expectAllowed: true,
+			expectPolicy:  "editors",
+			expectReason:  "Allowed by policy editors",
+		},
+		{
+			name: "Deny Overrides Allow",
+			input: Input{
+				Action:   "documents:edit",
+				Subject:  map[string]any{"id": int64(7), "roles": []string{"editor"}},
+				Request:  map[string]any{"time": businessHours},
+				Resource: map[string]any{"owner_id": int64(7), "locked": true},
+			},
+			expectPolicy: "locked-documents",
+			expectReason: "Denied by policy locked-documents",
+		},
+		{
+			name: "Failing Deny Denies",
+			input: Input{
+				Action:   "documents:edit",
+				Subject:  map[string]any{"id": int64(7), "roles": []string{"editor"}},
+				Request:  map[string]any{"time": businessHours},
+				Resource: map[string]any{"owner_id": int64(7)},
+			},
+			expectPolicy: "locked-documents",
+			expectReason: "Denied by policy locked-documents",
+		},
+		{
+			name: "Failing Allow Does Not Allow",
+			input: Input{
+				Action:  "documents:read",
+				Subject: map[string]any{"id": int64(7)},
+			},
+			expectReason: "No policy allows documents:read",
+		},
+		{
+			name:         "Unknown Action",
+			input:        Input{Action: "users:write"},
+			expectReason: "No policy allows users:write",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.input)
+
+			assert.Equal(t, tt.expectAllowed, decision.Allowed)
+			assert.Equal(t, tt.expectPolicy, decision.Policy)
+			assert.Equal(t, tt.expectReason, decision.Reason)
+			assert.Len(t, decision.Evaluations, len(testPolicies))
+		})
+	}
+}
+
+func TestEvaluateExplains(t *testing.T) {
+	engine, err := NewEngine(testPolicies)
+	require.NoError(t, err)
+
+	decision := engine.Evaluate(Input{
+		Action:   "documents:edit",
+		Subject:  map[string]any{"id": int64(7), "roles": []string{}},
+		Request:  map[string]any{"time": businessHours},
+		Resource: map[string]any{"owner_id": int64(7)},
+	})
+
+	require.Len(t, decision.Evaluations, 3)
+	assert.Equal(t, Evaluation{Policy: "owners-edit-during-business-hours", Effect: EffectAllow, Applies: true, Matched: true}, decision.Evaluations[0])
+	assert.Equal(t, Evaluation{Policy: "editors", Effect: EffectAllow, Applies: true}, decision.Evaluations[1])
+	assert.True(t, decision.Evaluations[2].Matched)
+	assert.Contains(t, decision.Evaluations[2].Error, "locked")
+}
+
+func TestNewEngineRejectsInvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+	}{
+		{name: "Missing Name", policy: Policy{Effect: EffectAllow, Actions: []string{"*"}}},
+		{name: "Unknown Effect", policy: Policy{Name: "p", Effect: "maybe", Actions: []string{"*"}}},
+		{name: "No Actions", policy: Policy{Name: "p", Effect: EffectAllow}},
+		{name: "Syntax Error", policy: Policy{Name: "p", Effect: EffectAllow, Actions: []string{"*"}, Condition: "subject.id =="}},
+		{name: "Unknown Variable", policy: Policy{Name: "p", Effect: EffectAllow, Actions: []string{"*"}, Condition: "user.id == 1"}},
+		{name: "Not A Boolean", policy: Policy{Name: "p", Effect: EffectAllow, Actions: []string{"*"}, Condition: `"yes"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine([]Policy{tt.policy})
+			assert.ErrorIs(t, err, ErrInvalidPolicy)
+		})
+	}
+
+	_, err := NewEngine([]Policy{testPolicies[0], testPolicies[0]})
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+policies:
+  - name: admins
+    effect: allow
+    actions: ["*"]
+    condition: '"admin" in subject.roles'
+  - name: read-only-api-keys
+    description: API keys may only read
+    effect: deny
+    actions: ["*"]
+    condition: '"api_key_id" in subject && request.method != "GET"'
+`), 0o600))
+
+	engine, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, engine.Policies(), 2)
+	assert.Equal(t, "API keys may only read", engine.Policies()[1].Description)
+
+	admin := map[string]any{"id": int64(1), "roles": []string{"admin"}}
+	assert.True(t, engine.Evaluate(Input{Action: "users:write", Subject: admin, Request: map[string]any{"method": "POST"}}).Allowed)
+
+	admin["api_key_id"] = int64(3)
+	assert.False(t, engine.Evaluate(Input{Action: "users:write", Subject: admin, Request: map[string]any{"method": "POST"}}).Allowed)
+	assert.True(t, engine.Evaluate(Input{Action: "users:read", Subject: admin, Request: map[string]any{"method": "GET"}}).Allowed)
+}
+
+func TestFromEnvWithoutFileAllowsEverything(t *testing.T) {
+	t.Setenv("AUTHZ_POLICY_FILE", "")
+
+	engine, err := FromEnv()
+	require.NoError(t, err)
+	decision := engine.Evaluate(Input{Action: "users:read"})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "default-allow", decision.Policy)
+}
@@ -0,0 +1,130 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams are the cost parameters of argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendation of 19 MiB of memory,
+// two iterations and one lane.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+// Argon2id encodes hashes in the PHC string format:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) *Argon2id {
+	return &Argon2id{
+		params: params,
+	}
+}
+
+// validate rejects parameters whose hashes decodeArgon2id would refuse.
+func (algorithm *Argon2id) validate() error {
+	if algorithm.params.Memory > maxArgon2idMemory {
+		return fmt.Errorf("ARGON2_MEMORY must be at most %d", maxArgon2idMemory)
+	}
+	if algorithm.params.Iterations > maxArgon2idIterations {
+		return fmt.Errorf("ARGON2_ITERATIONS must be at most %d", maxArgon2idIterations)
+	}
+	return nil
+}
+
+func (algorithm *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, algorithm.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	params := algorithm.params
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (algorithm *Argon2id) Recognizes(encoded string) bool {
+	_, _, _, err := decodeArgon2id(encoded)
+	return err == nil
+}
+
+func (algorithm *Argon2id) Verify(encoded string, password string) error {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func (algorithm *Argon2id) Outdated(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != algorithm.params.Memory ||
+		params.Iterations != algorithm.params.Iterations ||
+		params.Parallelism != algorithm.params.Parallelism ||
+		len(salt) != algorithm.params.SaltLength ||
+		uint32(len(key)) != algorithm.params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if params.Memory == 0 || params.Memory > maxArgon2idMemory ||
+		params.Iterations == 0 || params.Iterations > maxArgon2idIterations ||
+		params.Parallelism == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := phcEncoding.DecodeString(fields[4])
+	if err != nil || len(salt) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := phcEncoding.DecodeString(fields[5])
+	if err != nil || len(key) == 0 || len(key) > maxArgon2idKeyLength {
+		return params, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = len(salt)
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,62 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultBcryptCost = 12
+
+// Bcrypt keeps the modular crypt format of bcrypt, $2a$<cost>$<salt+hash>,
+// which the PHC string format adopted as is.
+type Bcrypt struct {
+	cost int
+}
+
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{
+		cost: cost,
+	}
+}
+
+func (algorithm *Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), algorithm.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (algorithm *Bcrypt) Recognizes(encoded string) bool {
+	if !strings.HasPrefix(encoded, "$2a$") && !strings.HasPrefix(encoded, "$2b$") && !strings.HasPrefix(encoded, "$2y$") {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(encoded))
+	return err == nil
+}
+
+func (algorithm *Bcrypt) Verify(encoded string, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedPassword
+	}
+	if err != nil {
+		return ErrInvalidHash
+	}
+	return nil
+}
+
+func (algorithm *Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != algorithm.cost
+}
+
+func (algorithm *Bcrypt) validate() error {
+	if algorithm.cost < bcrypt.MinCost || algorithm.cost > bcrypt.MaxCost {
+		return fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
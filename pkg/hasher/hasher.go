@@ -0,0 +1,169 @@
+// Package hasher hashes passwords for storage and verifies stored hashes,
+// including hashes made with older algorithms or parameters, so that they can
+// be upgraded when their owner logs in.
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("Password does not match")
+	ErrUnknownHash        = errors.New("Unknown password hash format")
+	ErrInvalidHash        = errors.New("Invalid password hash")
+)
+
+// Algorithm hashes passwords in one format.
+type Algorithm interface {
+	// Hash returns the encoded hash of password with a fresh salt.
+	Hash(password string) (string, error)
+	// Recognizes reports whether encoded is a well-formed hash of the
+	// algorithm.
+	Recognizes(encoded string) bool
+	// Verify checks password against encoded. A wrong password is reported
+	// as ErrMismatchedPassword.
+	Verify(encoded string, password string) error
+	// Outdated reports whether encoded was made with other parameters than
+	// the ones the algorithm is configured with.
+	Outdated(encoded string) bool
+}
+
+// Hasher hashes new passwords with its current algorithm and verifies hashes
+// of every algorithm it knows.
+type Hasher struct {
+	current Algorithm
+	known   []Algorithm
+}
+
+// New returns a hasher that hashes with current and additionally verifies
+// hashes made with legacy.
+func New(current Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{
+		current: current,
+		known:   append([]Algorithm{current}, legacy...),
+	}
+}
+
+func (hasher *Hasher) Hash(password string) (string, error) {
+	return hasher.current.Hash(password)
+}
+
+// Supports reports whether encoded is a well-formed hash the hasher can
+// verify.
+func (hasher *Hasher) Supports(encoded string) bool {
+	_, err := hasher.algorithm(encoded)
+	return err == nil
+}
+
+func (hasher *Hasher) Verify(encoded string, password string) error {
+	algorithm, err := hasher.algorithm(encoded)
+	if err != nil {
+		return err
+	}
+	return algorithm.Verify(encoded, password)
+}
+
+// NeedsRehash reports whether encoded should be replaced by a hash of the
+// current algorithm with its current parameters.
+func (hasher *Hasher) NeedsRehash(encoded string) bool {
+	if !hasher.current.Recognizes(encoded) {
+		return true
+	}
+	return hasher.current.Outdated(encoded)
+}
+
+func (hasher *Hasher) algorithm(encoded string) (Algorithm, error) {
+	for _, algorithm := range hasher.known {
+		if algorithm.Recognizes(encoded) {
+			return algorithm, nil
+		}
+	}
+	return nil, ErrUnknownHash
+}
+
+var defaultHasher atomic.Pointer[Hasher]
+
+func init() {
+	defaultHasher.Store(New(NewArgon2id(DefaultArgon2idParams), append([]Algorithm{NewBcrypt(DefaultBcryptCost)}, LegacyAlgorithms()...)...))
+}
+
+// Default returns the hasher used for user passwords. Until SetDefault is
+// called it hashes with argon2id and the default parameters.
+func Default() *Hasher {
+	return defaultHasher.Load()
+}
+
+// SetDefault replaces the hasher used for user passwords.
+func SetDefault(hasher *Hasher) {
+	defaultHasher.Store(hasher)
+}
+
+// FromEnv builds the hasher selected by PASSWORD_HASHER: "argon2id" (the
+// default, tuned with ARGON2_MEMORY in KiB, ARGON2_ITERATIONS and
+// ARGON2_PARALLELISM) or "bcrypt" (tuned with BCRYPT_COST). Hashes of the
+// other algorithm and of LegacyAlgorithms are still verified and upgraded on
+// login.
+func FromEnv() (*Hasher, error) {
+	cost, err := envInt("BCRYPT_COST", DefaultBcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	bcryptAlgorithm := NewBcrypt(cost)
+	if err := bcryptAlgorithm.validate(); err != nil {
+		return nil, err
+	}
+
+	params := DefaultArgon2idParams
+	if params.Memory, err = envUint32("ARGON2_MEMORY", params.Memory); err != nil {
+		return nil, err
+	}
+	if params.Iterations, err = envUint32("ARGON2_ITERATIONS", params.Iterations); err != nil {
+		return nil, err
+	}
+	parallelism, err := envUint32("ARGON2_PARALLELISM", uint32(params.Parallelism))
+	if err != nil {
+		return nil, err
+	}
+	params.Parallelism = uint8(min(parallelism, 255))
+	argon2idAlgorithm := NewArgon2id(params)
+	if err := argon2idAlgorithm.validate(); err != nil {
+		return nil, err
+	}
+
+	switch algorithm := os.Getenv("PASSWORD_HASHER"); algorithm {
+	case "", "argon2id":
+		return New(argon2idAlgorithm, append([]Algorithm{bcryptAlgorithm}, LegacyAlgorithms()...)...), nil
+	case "bcrypt":
+		return New(bcryptAlgorithm, append([]Algorithm{argon2idAlgorithm}, LegacyAlgorithms()...)...), nil
+	default:
+		return nil, fmt.Errorf("Unknown PASSWORD_HASHER %q", algorithm)
+	}
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", key)
+	}
+	return number, nil
+}
+
+func envUint32(key string, fallback uint32) (uint32, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || number == 0 {
+		return 0, fmt.Errorf("%s must be a positive number", key)
+	}
+	return uint32(number), nil
+}
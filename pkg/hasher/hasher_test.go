@@ -0,0 +1,176 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2idParams keep the tests fast; they are far too weak for real use.
+var testArgon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2id(t *testing.T) {
+	algorithm := NewArgon2id(testArgon2idParams)
+
+	encoded, err := algorithm.Hash("correct horse")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, encoded)
+	assert.True(t, algorithm.Recognizes(encoded))
+
+	assert.NoError(t, algorithm.Verify(encoded, "correct horse"))
+	assert.ErrorIs(t, algorithm.Verify(encoded, "wrong horse"), ErrMismatchedPassword)
+
+	other, err := algorithm.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other, "every hash needs its own salt")
+}
+
+func TestArgon2idInvalidHashes(t *testing.T) {
+	algorithm := NewArgon2id(testArgon2idParams)
+	encoded, err := algorithm.Hash("correct horse")
+	require.NoError(t, err)
+
+	for _, invalid := range []string{
+		strings.Replace(encoded, "v=19", "v=16", 1),
+		strings.Replace(encoded, "m=64", "m=0", 1),
+		strings.Replace(encoded, "t=1", "t=x", 1),
+		encoded[:strings.LastIndex(encoded, "$")],
+		encoded + "$extra",
+		"$argon2id$",
+	} {
+		assert.ErrorIs(t, algorithm.Verify(invalid, "correct horse"), ErrInvalidHash, invalid)
+		assert.True(t, algorithm.Outdated(invalid), invalid)
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	argon2id := NewArgon2id(testArgon2idParams)
+	bcryptCost4 := NewBcrypt(4)
+	current := New(argon2id, bcryptCost4)
+
+	currentHash, err := argon2id.Hash("secret")
+	require.NoError(t, err)
+	weakerParams := testArgon2idParams
+	weakerParams.Memory = 32
+	weakerHash, err := NewArgon2id(weakerParams).Hash("secret")
+	require.NoError(t, err)
+	bcryptHash, err := bcryptCost4.Hash("secret")
+	require.NoError(t, err)
+
+	assert.False(t, current.NeedsRehash(currentHash))
+	assert.True(t, current.NeedsRehash(weakerHash), "outdated parameters")
+	assert.True(t, current.NeedsRehash(bcryptHash), "outdated algorithm")
+	assert.True(t, current.NeedsRehash("plaintext"))
+
+	// Legacy hashes are still verified, whatever their parameters.
+	assert.NoError(t, current.Verify(weakerHash, "secret"))
+	assert.NoError(t, current.Verify(bcryptHash, "secret"))
+	assert.ErrorIs(t, current.Verify(bcryptHash, "guess"), ErrMismatchedPassword)
+	assert.ErrorIs(t, current.Verify("plaintext", "plaintext"), ErrUnknownHash)
+
+	bcryptCost5 := New(NewBcrypt(5), argon2id)
+	assert.True(t, bcryptCost5.NeedsRehash(bcryptHash), "outdated cost")
+	assert.True(t, bcryptCost5.NeedsRehash(currentHash))
+	assert.NoError(t, bcryptCost5.Verify(currentHash, "secret"))
+}
+
+func TestFromEnv(t *testing.T) {
+	hasher, err := FromEnv()
+	require.NoError(t, err)
+	encoded, err := hasher.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+	t.Setenv("PASSWORD_HASHER", "bcrypt")
+	t.Setenv("BCRYPT_COST", "5")
+	hasher, err = FromEnv()
+	require.NoError(t, err)
+	bcryptHash, err := hasher.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(bcryptHash, "$2a$05$"))
+	assert.NoError(t, hasher.Verify(encoded, "secret"), "argon2id hashes stay valid")
+	assert.True(t, hasher.NeedsRehash(encoded))
+
+	t.Setenv("BCRYPT_COST", "99")
+	_, err = FromEnv()
+	assert.ErrorContains(t, err, "BCRYPT_COST")
+
+	t.Setenv("BCRYPT_COST", "")
+	t.Setenv("ARGON2_MEMORY", "0")
+	_, err = FromEnv()
+	assert.ErrorContains(t, err, "ARGON2_MEMORY")
+
+	t.Setenv("ARGON2_MEMORY", "2097152")
+	_, err = FromEnv()
+	assert.ErrorContains(t, err, "ARGON2_MEMORY")
+
+	t.Setenv("ARGON2_MEMORY", "")
+	t.Setenv("PASSWORD_HASHER", "md5")
+	_, err = FromEnv()
+	assert.ErrorContains(t, err, "PASSWORD_HASHER")
+}
+
+func TestLegacyAlgorithms(t *testing.T) {
+	for _, algorithm := range []Algorithm{
+		NewPBKDF2("sha1", 10),
+		NewPBKDF2("sha256", 10),
+		NewPBKDF2("sha512", 10),
+		NewScrypt(4, 8, 1),
+		NewSaltedSHA256(true),
+		NewSaltedSHA256(false),
+	} {
+		encoded, err := algorithm.Hash("correct horse")
+		require.NoError(t, err)
+		assert.True(t, algorithm.Recognizes(encoded), encoded)
+		assert.NoError(t, algorithm.Verify(encoded, "correct horse"), encoded)
+		assert.ErrorIs(t, algorithm.Verify(encoded, "wrong horse"), ErrMismatchedPassword, encoded)
+		assert.True(t, algorithm.Outdated(encoded), encoded)
+
+		fields := strings.Split(encoded, "$")
+		assert.False(t, algorithm.Recognizes(strings.Join(fields[:4], "$")), encoded)
+		assert.ErrorIs(t, algorithm.Verify(encoded+"$extra", "correct horse"), ErrInvalidHash, encoded)
+	}
+}
+
+func TestLegacyParameterBounds(t *testing.T) {
+	const hash = "$c2FsdHNhbHRzYWx0c2FsdA==$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA="
+	for _, encoded := range []string{
+		"$pbkdf2-sha256$i=10000001" + hash,
+		"$scrypt$ln=21,r=8,p=1" + hash,
+		"$scrypt$ln=4,r=33,p=1" + hash,
+		"$scrypt$ln=4,r=8,p=17" + hash,
+		"$scrypt$ln=4,r=8,p=0" + hash,
+		"$argon2id$v=19$m=1048577,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA",
+		"$argon2id$v=19$m=19456,t=33,p=1$c2FsdHNhbHRzYWx0c2FsdA$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$" + strings.Repeat("A", 172),
+	} {
+		legacy := New(NewArgon2id(testArgon2idParams), LegacyAlgorithms()...)
+		assert.False(t, legacy.Supports(encoded), encoded)
+		assert.Error(t, legacy.Verify(encoded, "password"), encoded)
+	}
+}
+
+func TestLegacyKnownHashes(t *testing.T) {
+	// Produced outside of Go, the first two with base64 padding as exports
+	// often keep it.
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "PBKDF2-SHA256", encoded: "$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA==$8nX7hwFEzIB8aPajJTYK8weHQc5Ngz0pFVAKvSu4jQA="},
+		{name: "Scrypt", encoded: "$scrypt$ln=4,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA==$5f/Vi+XRWGUNGScbsma6KJ4zLFIke/NJsrvr7lQLAyA="},
+		{name: "Salted SHA-256", encoded: "$salted-sha256$pos=prefix$c2FsdHNhbHRzYWx0c2FsdA$aakqttEpxKT1S5vgvZnoXxoPt16eKVgR4pNL8As0Um0"},
+	}
+
+	legacy := New(NewArgon2id(testArgon2idParams), LegacyAlgorithms()...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, legacy.Supports(tt.encoded))
+			assert.NoError(t, legacy.Verify(tt.encoded, "password"))
+			assert.ErrorIs(t, legacy.Verify(tt.encoded, "passw0rd"), ErrMismatchedPassword)
+			assert.True(t, legacy.NeedsRehash(tt.encoded))
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Bounds on the parameters of imported hashes, so that a single login cannot
+// tie up a server. Argon2id memory is in KiB.
+const (
+	maxPBKDF2Iterations   = 10_000_000
+	maxScryptLogN         = 20
+	maxScryptR            = 32
+	maxScryptP            = 16
+	maxArgon2idMemory     = 1 << 20
+	maxArgon2idIterations = 32
+	maxArgon2idKeyLength  = 128
+	legacySaltLength      = 16
+)
+
+// LegacyAlgorithms verify the hashes of systems users are imported from.
+// They are never used for new hashes outside of tests.
+func LegacyAlgorithms() []Algorithm {
+	return []Algorithm{
+		NewPBKDF2("sha256", 600_000),
+		NewScrypt(15, 8, 1),
+		NewSaltedSHA256(true),
+	}
+}
+
+var pbkdf2Digests = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// PBKDF2 encodes hashes as $pbkdf2-<digest>$i=<iterations>$<salt>$<hash>
+// with digest sha1, sha256 or sha512.
+type PBKDF2 struct {
+	digest     string
+	iterations int
+}
+
+func NewPBKDF2(digest string, iterations int) *PBKDF2 {
+	return &PBKDF2{
+		digest:     digest,
+		iterations: iterations,
+	}
+}
+
+func (algorithm *PBKDF2) Hash(password string) (string, error) {
+	newDigest, ok := pbkdf2Digests[algorithm.digest]
+	if !ok {
+		return "", fmt.Errorf("Unknown PBKDF2 digest %q", algorithm.digest)
+	}
+	salt, err := randomSalt()
+	if err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), salt, algorithm.iterations, newDigest().Size(), newDigest)
+	return fmt.Sprintf("$pbkdf2-%s$i=%d$%s$%s", algorithm.digest, algorithm.iterations,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (algorithm *PBKDF2) Recognizes(encoded string) bool {
+	_, _, _, _, err := decodePBKDF2(encoded)
+	return err == nil
+}
+
+func (algorithm *PBKDF2) Verify(encoded string, password string) error {
+	newDigest, iterations, salt, key, err := decodePBKDF2(encoded)
+	if err != nil {
+		return err
+	}
+	candidate := pbkdf2.Key([]byte(password), salt, iterations, len(key), newDigest)
+	return compareKeys(candidate, key)
+}
+
+func (algorithm *PBKDF2) Outdated(encoded string) bool {
+	return true
+}
+
+func decodePBKDF2(encoded string) (func() hash.Hash, int, []byte, []byte, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 || fields[0] != "" || !strings.HasPrefix(fields[1], "pbkdf2-") {
+		return nil, 0, nil, nil, ErrInvalidHash
+	}
+	newDigest, ok := pbkdf2Digests[strings.TrimPrefix(fields[1], "pbkdf2-")]
+	if !ok {
+		return nil, 0, nil, nil, ErrInvalidHash
+	}
+	var iterations int
+	if _, err := fmt.Sscanf(fields[2], "i=%d", &iterations); err != nil || iterations < 1 || iterations > maxPBKDF2Iterations {
+		return nil, 0, nil, nil, ErrInvalidHash
+	}
+	salt, key, err := decodeSaltAndKey(fields[3], fields[4])
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+	return newDigest, iterations, salt, key, nil
+}
+
+// Scrypt encodes hashes as $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>.
+type Scrypt struct {
+	logN int
+	r    int
+	p    int
+}
+
+func NewScrypt(logN int, r int, p int) *Scrypt {
+	return &Scrypt{
+		logN: logN,
+		r:    r,
+		p:    p,
+	}
+}
+
+func (algorithm *Scrypt) Hash(password string) (string, error) {
+	salt, err := randomSalt()
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<algorithm.logN, algorithm.r, algorithm.p, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", algorithm.logN, algorithm.r, algorithm.p,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (algorithm *Scrypt) Recognizes(encoded string) bool {
+	_, _, _, _, _, err := decodeScrypt(encoded)
+	return err == nil
+}
+
+func (algorithm *Scrypt) Verify(encoded string, password string) error {
+	logN, r, p, salt, key, err := decodeScrypt(encoded)
+	if err != nil {
+		return err
+	}
+	candidate, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(key))
+	if err != nil {
+		return ErrInvalidHash
+	}
+	return compareKeys(candidate, key)
+}
+
+func (algorithm *Scrypt) Outdated(encoded string) bool {
+	return true
+}
+
+func decodeScrypt(encoded string) (int, int, int, []byte, []byte, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 || fields[0] != "" || fields[1] != "scrypt" {
+		return 0, 0, 0, nil, nil, ErrInvalidHash
+	}
+	var logN, r, p int
+	if _, err := fmt.Sscanf(fields[2], "ln=%d,r=%d,p=%d", &logN, &r, &p); err != nil {
+		return 0, 0, 0, nil, nil, ErrInvalidHash
+	}
+	if logN < 1 || logN > maxScryptLogN || r < 1 || r > maxScryptR || p < 1 || p > maxScryptP {
+		return 0, 0, 0, nil, nil, ErrInvalidHash
+	}
+	salt, key, err := decodeSaltAndKey(fields[3], fields[4])
+	if err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	return logN, r, p, salt, key, nil
+}
+
+// SaltedSHA256 encodes a single round of SHA-256 over the salt and the
+// password as $salted-sha256$pos=<prefix|suffix>$<salt>$<hash>, where pos
+// says whether the salt goes before or after the password.
+type SaltedSHA256 struct {
+	prefix bool
+}
+
+func NewSaltedSHA256(prefix bool) *SaltedSHA256 {
+	return &SaltedSHA256{
+		prefix: prefix,
+	}
+}
+
+func (algorithm *SaltedSHA256) Hash(password string) (string, error) {
+	salt, err := randomSalt()
+	if err != nil {
+		return "", err
+	}
+	position := "suffix"
+	if algorithm.prefix {
+		position = "prefix"
+	}
+	key := saltedSHA256(salt, password, algorithm.prefix)
+	return fmt.Sprintf("$salted-sha256$pos=%s$%s$%s", position,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (algorithm *SaltedSHA256) Recognizes(encoded string) bool {
+	_, _, _, err := decodeSaltedSHA256(encoded)
+	return err == nil
+}
+
+func (algorithm *SaltedSHA256) Verify(encoded string, password string) error {
+	prefix, salt, key, err := decodeSaltedSHA256(encoded)
+	if err != nil {
+		return err
+	}
+	return compareKeys(saltedSHA256(salt, password, prefix), key)
+}
+
+func (algorithm *SaltedSHA256) Outdated(encoded string) bool {
+	return true
+}
+
+func decodeSaltedSHA256(encoded string) (bool, []byte, []byte, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 || fields[0] != "" || fields[1] != "salted-sha256" {
+		return false, nil, nil, ErrInvalidHash
+	}
+	var prefix bool
+	switch fields[2] {
+	case "pos=prefix":
+		prefix = true
+	case "pos=suffix":
+	default:
+		return false, nil, nil, ErrInvalidHash
+	}
+	salt, key, err := decodeSaltAndKey(fields[3], fields[4])
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if len(key) != sha256.Size {
+		return false, nil, nil, ErrInvalidHash
+	}
+	return prefix, salt, key, nil
+}
+
+func saltedSHA256(salt []byte, password string, prefix bool) []byte {
+	digest := sha256.New()
+	if prefix {
+		digest.Write(salt)
+		digest.Write([]byte(password))
+	} else {
+		digest.Write([]byte(password))
+		digest.Write(salt)
+	}
+	return digest.Sum(nil)
+}
+
+// decodeSaltAndKey accepts base64 with and without padding, as exports of
+// other systems often keep it.
+func decodeSaltAndKey(encodedSalt string, encodedKey string) ([]byte, []byte, error) {
+	salt, err := phcEncoding.DecodeString(strings.TrimRight(encodedSalt, "="))
+	if err != nil || len(salt) == 0 {
+		return nil, nil, ErrInvalidHash
+	}
+	key, err := phcEncoding.DecodeString(strings.TrimRight(encodedKey, "="))
+	if err != nil || len(key) == 0 {
+		return nil, nil, ErrInvalidHash
+	}
+	return salt, key, nil
+}
+
+func compareKeys(candidate []byte, key []byte) error {
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func randomSalt() ([]byte, error) {
+	salt := make([]byte, legacySaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message to its own .eml file instead of sending
+// it, for local development.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (mailer *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(mailer.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(mailer.dir, 0o700); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(mailer.dir, name), data, 0o600)
+}
@@ -0,0 +1,78 @@
+// Package mailer sends transactional email such as verification links.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultFrom = "no-reply@localhost"
+
+var ErrInvalidHeader = errors.New("Mail header contains a line break")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv builds the mailer selected by MAILER_DRIVER: "smtp", "file"
+// (the default, writing to MAILER_DIR) or "memory".
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultFrom
+	}
+
+	switch driver := os.Getenv("MAILER_DRIVER"); driver {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, errors.New("SMTP_HOST is required for the smtp mailer")
+		}
+		return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "", "file":
+		dir := os.Getenv("MAILER_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return NewFileMailer(dir, from), nil
+	case "memory":
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("Unknown MAILER_DRIVER %q", driver)
+	}
+}
+
+// format renders msg as an RFC 5322 plain text message.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
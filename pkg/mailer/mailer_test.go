@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data, err := format("no-reply@example.com", Message{
+		To:      "alice@example.com",
+		Subject: "Hello",
+		Body:    "line one\nline two",
+	}, date)
+	require.NoError(t, err)
+
+	expected := "From: no-reply@example.com\r\n" +
+		"To: alice@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Wed, 01 May 2024 12:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"line one\r\nline two"
+	assert.Equal(t, expected, string(data))
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	tests := []Message{
+		{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hello"},
+		{To: "alice@example.com", Subject: "Hello\nBcc: eve@example.com"},
+	}
+	for _, msg := range tests {
+		_, err := format("no-reply@example.com", msg, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidHeader)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := NewFileMailer(dir, "no-reply@example.com")
+
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "One", Body: "first"}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Two", Body: "second"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\nfirst"))
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	_, ok := mailer.Last()
+	assert.False(t, ok)
+
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "One"}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "bob@example.com", Subject: "Two"}))
+
+	last, ok := mailer.Last()
+	assert.True(t, ok)
+	assert.Equal(t, "bob@example.com", last.To)
+	assert.Len(t, mailer.Messages(), 2)
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		expected Mailer
+		wantErr  bool
+	}{
+		{env: map[string]string{}, expected: &FileMailer{}},
+		{env: map[string]string{"MAILER_DRIVER": "memory"}, expected: &MemoryMailer{}},
+		{env: map[string]string{"MAILER_DRIVER": "smtp", "SMTP_HOST": "smtp.example.com"}, expected: &SMTPMailer{}},
+		{env: map[string]string{"MAILER_DRIVER": "smtp"}, wantErr: true},
+		{env: map[string]string{"MAILER_DRIVER": "carrier-pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		for _, key := range []string{"MAILER_DRIVER", "SMTP_HOST"} {
+			t.Setenv(key, tt.env[key])
+		}
+		mailer, err := FromEnv()
+		if tt.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.IsType(t, tt.expected, mailer)
+	}
+}
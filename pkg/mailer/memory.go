@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps sent messages in memory, for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mailer *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	mailer.messages = append(mailer.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of all messages sent so far.
+func (mailer *MemoryMailer) Messages() []Message {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	return append([]Message(nil), mailer.messages...)
+}
+
+// Last returns the most recently sent message.
+func (mailer *MemoryMailer) Last() (Message, bool) {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	if len(mailer.messages) == 0 {
+		return Message{}, false
+	}
+	return mailer.messages[len(mailer.messages)-1], true
+}
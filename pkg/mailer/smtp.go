@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer delivers through an SMTP relay. net/smtp upgrades the
+// connection with STARTTLS when the server offers it, and refuses to send
+// credentials over plain text to anything but localhost.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(mailer.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{msg.To}, data)
+}
@@ -0,0 +1,135 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic starts every Bloom filter file, followed by a version byte, the
+// number of hash functions (uint32), the number of bits (uint64), both little
+// endian, and the bits.
+const (
+	bloomMagic   = "MTBF"
+	bloomVersion = 1
+	bloomHeader  = len(bloomMagic) + 1 + 4 + 8
+)
+
+var ErrInvalidBloomFilter = errors.New("Invalid Bloom filter file")
+
+// BloomFilter is a compact set of SHA-1 password hashes. It never misses a
+// hash that was added, but reports hashes that were not added with the false
+// positive rate it was sized for.
+type BloomFilter struct {
+	bits   []byte
+	size   uint64
+	hashes uint32
+}
+
+// NewBloomFilter sizes a filter for n hashes at the given false positive rate.
+func NewBloomFilter(n uint64, falsePositiveRate float64) *BloomFilter {
+	if n == 0 {
+		n = 1
+	}
+	size := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	size = max((size+7)/8*8, 64)
+	hashes := uint32(max(math.Round(float64(size)/float64(n)*math.Ln2), 1))
+	return &BloomFilter{
+		bits:   make([]byte, size/8),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// Add inserts a SHA-1 hash.
+func (filter *BloomFilter) Add(hash [sha1.Size]byte) {
+	h1, h2 := bloomHashes(hash)
+	for i := uint64(0); i < uint64(filter.hashes); i++ {
+		bit := (h1 + i*h2) % filter.size
+		filter.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Contains reports whether hash was probably added.
+func (filter *BloomFilter) Contains(hash [sha1.Size]byte) bool {
+	h1, h2 := bloomHashes(hash)
+	for i := uint64(0); i < uint64(filter.hashes); i++ {
+		bit := (h1 + i*h2) % filter.size
+		if filter.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Breached reports whether password probably appears in the breach corpus
+// the filter was built from.
+func (filter *BloomFilter) Breached(password string) bool {
+	return filter.Contains(sha1.Sum([]byte(password)))
+}
+
+// WriteTo writes the filter in the format read by ReadBloomFilter.
+func (filter *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, bloomHeader)
+	header = append(header, bloomMagic...)
+	header = append(header, bloomVersion)
+	header = binary.LittleEndian.AppendUint32(header, filter.hashes)
+	header = binary.LittleEndian.AppendUint64(header, filter.size)
+
+	written, err := w.Write(header)
+	if err != nil {
+		return int64(written), err
+	}
+	n, err := w.Write(filter.bits)
+	return int64(written + n), err
+}
+
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, bloomHeader)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrInvalidBloomFilter
+	}
+	if string(header[:len(bloomMagic)]) != bloomMagic || header[len(bloomMagic)] != bloomVersion {
+		return nil, ErrInvalidBloomFilter
+	}
+	hashes := binary.LittleEndian.Uint32(header[len(bloomMagic)+1:])
+	size := binary.LittleEndian.Uint64(header[len(bloomMagic)+5:])
+	if hashes == 0 || size == 0 || size%8 != 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	bits := make([]byte, size/8)
+	if _, err := io.ReadFull(r, bits); err != nil {
+		return nil, ErrInvalidBloomFilter
+	}
+	if n, _ := r.Read(make([]byte, 1)); n != 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+	return &BloomFilter{bits: bits, size: size, hashes: hashes}, nil
+}
+
+// LoadBloomFilter reads a filter file built by cmd/breachfilter.
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	filter, err := ReadBloomFilter(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return filter, nil
+}
+
+// bloomHashes derives the two hashes of double hashing from the SHA-1, which
+// is uniformly distributed already.
+func bloomHashes(hash [sha1.Size]byte) (uint64, uint64) {
+	return binary.LittleEndian.Uint64(hash[:8]), binary.LittleEndian.Uint64(hash[8:16]) | 1
+}
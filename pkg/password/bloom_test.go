@@ -0,0 +1,85 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		filter.Add(sha1.Sum([]byte(fmt.Sprintf("breached-%d", i))))
+	}
+
+	for i := 0; i < 1000; i++ {
+		require.True(t, filter.Breached(fmt.Sprintf("breached-%d", i)), "added hashes must never be missed")
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.Breached(fmt.Sprintf("safe-%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 200, "false positive rate far above 1%%")
+}
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	filter := NewBloomFilter(10, 0.001)
+	filter.Add(sha1.Sum([]byte("password1")))
+
+	var buf bytes.Buffer
+	size, err := filter.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), size)
+
+	read, err := ReadBloomFilter(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.True(t, read.Breached("password1"))
+	assert.Equal(t, filter, read)
+
+	data := buf.Bytes()
+	corrupt := map[string][]byte{
+		"Truncated":      data[:len(data)-1],
+		"Trailing data":  append(bytes.Clone(data), 0),
+		"Wrong magic":    append([]byte("XXXX"), data[4:]...),
+		"Header only":    data[:bloomHeader],
+		"Empty file":     nil,
+		"Future version": append(append([]byte(bloomMagic), 2), data[5:]...),
+	}
+	for name, data := range corrupt {
+		_, err := ReadBloomFilter(bytes.NewReader(data))
+		assert.ErrorIs(t, err, ErrInvalidBloomFilter, name)
+	}
+}
+
+func TestPolicyFromEnvBreachFilter(t *testing.T) {
+	filter := NewBloomFilter(10, 0.001)
+	filter.Add(sha1.Sum([]byte("Summer2024!")))
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = filter.WriteTo(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	t.Setenv("PASSWORD_BREACH_FILTER", path)
+	policy, err := PolicyFromEnv()
+	require.NoError(t, err)
+
+	var policyErr *PolicyError
+	require.ErrorAs(t, policy.Validate(Candidate{Password: "Summer2024!"}), &policyErr)
+	assert.Equal(t, []string{"breached"}, violationCodes(policyErr))
+	assert.NoError(t, policy.Validate(Candidate{Password: "Winter2024!"}))
+
+	t.Setenv("PASSWORD_BREACH_FILTER", filepath.Join(t.TempDir(), "missing.bloom"))
+	_, err = PolicyFromEnv()
+	assert.ErrorContains(t, err, "PASSWORD_BREACH_FILTER")
+}
@@ -0,0 +1,158 @@
+// Package password decides whether a new password is acceptable.
+package password
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMinLength = 8
+	// defaultMaxBytes is the input limit of bcrypt, which ignores everything
+	// past it.
+	defaultMaxBytes = 72
+)
+
+// Candidate is a new password together with what is known about its owner,
+// so that rules can reject passwords derived from the account itself.
+type Candidate struct {
+	Password string
+	Username string
+	Email    string
+}
+
+// Violation is a rule a password breaks. Code is stable for clients, Message
+// is meant for people.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Rule checks one property of a password and returns nil if it holds.
+type Rule interface {
+	Check(candidate Candidate) *Violation
+}
+
+// RuleFunc adapts a function to a Rule.
+type RuleFunc func(candidate Candidate) *Violation
+
+func (fn RuleFunc) Check(candidate Candidate) *Violation {
+	return fn(candidate)
+}
+
+// PolicyError lists every rule a password breaks.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (err *PolicyError) Error() string {
+	messages := make([]string, len(err.Violations))
+	for i, violation := range err.Violations {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Policy is a set of rules every new password has to pass.
+type Policy struct {
+	rules []Rule
+}
+
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{
+		rules: rules,
+	}
+}
+
+// Add appends rules to the policy. It is meant for setup and must not be
+// called while passwords are being validated.
+func (policy *Policy) Add(rules ...Rule) {
+	policy.rules = append(policy.rules, rules...)
+}
+
+// Validate runs all rules and returns a *PolicyError with every violation,
+// so that users can fix their password in one go.
+func (policy *Policy) Validate(candidate Candidate) error {
+	var violations []Violation
+	for _, rule := range policy.rules {
+		if violation := rule.Check(candidate); violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// DefaultPolicy accepts 8 to 72 byte passwords that do not contain the
+// username or email address.
+func DefaultPolicy() *Policy {
+	return NewPolicy(MinLength(defaultMinLength), MaxBytes(defaultMaxBytes), NoUserInputs())
+}
+
+// PolicyFromEnv builds the policy from PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH
+// (characters), PASSWORD_MAX_BYTES, PASSWORD_MIN_CLASSES, PASSWORD_MIN_ENTROPY
+// (bits), PASSWORD_BANNED_WORDS (comma separated) and PASSWORD_BREACH_FILTER
+// (a Bloom filter file). The username and email address are always banned.
+func PolicyFromEnv() (*Policy, error) {
+	minLength, err := envInt("PASSWORD_MIN_LENGTH", defaultMinLength)
+	if err != nil {
+		return nil, err
+	}
+	maxLength, err := envInt("PASSWORD_MAX_LENGTH", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxBytes, err := envInt("PASSWORD_MAX_BYTES", defaultMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	minClasses, err := envInt("PASSWORD_MIN_CLASSES", 0)
+	if err != nil {
+		return nil, err
+	}
+	minEntropy, err := envInt("PASSWORD_MIN_ENTROPY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := NewPolicy(MinLength(minLength), NoUserInputs())
+	if maxLength > 0 {
+		policy.Add(MaxLength(maxLength))
+	}
+	if maxBytes > 0 {
+		policy.Add(MaxBytes(maxBytes))
+	}
+	if minClasses > 0 {
+		policy.Add(MinCharacterClasses(minClasses))
+	}
+	if minEntropy > 0 {
+		policy.Add(MinEntropy(float64(minEntropy)))
+	}
+	if words := os.Getenv("PASSWORD_BANNED_WORDS"); words != "" {
+		policy.Add(BannedWords(strings.Split(words, ",")...))
+	}
+	if path := os.Getenv("PASSWORD_BREACH_FILTER"); path != "" {
+		filter, err := LoadBloomFilter(path)
+		if err != nil {
+			return nil, fmt.Errorf("PASSWORD_BREACH_FILTER: %w", err)
+		}
+		policy.Add(NotBreached(filter))
+	}
+	return policy, nil
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number", key)
+	}
+	return number, nil
+}
@@ -0,0 +1,124 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		password string
+		code     string
+	}{
+		{name: "Min length ok", rule: MinLength(8), password: "eightchr"},
+		{name: "Min length counts characters", rule: MinLength(4), password: "äöü", code: "min_length"},
+		{name: "Max length ok", rule: MaxLength(4), password: "äöüß"},
+		{name: "Max length too long", rule: MaxLength(4), password: "abcde", code: "max_length"},
+		{name: "Max bytes counts bytes", rule: MaxBytes(6), password: "äöüß", code: "max_bytes"},
+		{name: "Max bytes ok", rule: MaxBytes(8), password: "äöüß"},
+		{name: "Classes ok", rule: MinCharacterClasses(3), password: "Abcdef12"},
+		{name: "Classes missing", rule: MinCharacterClasses(3), password: "abcdef12", code: "character_classes"},
+		{name: "Symbols count as a class", rule: MinCharacterClasses(2), password: "abc def!"},
+		{name: "Entropy ok", rule: MinEntropy(40), password: "k9#Lm2qZ"},
+		{name: "Entropy too low", rule: MinEntropy(40), password: "aaaaaaaaaaaa", code: "too_weak"},
+		{name: "Banned word", rule: BannedWords("password", " acme "), password: "MyAcme2024", code: "banned_word"},
+		{name: "No banned word", rule: BannedWords("password", ""), password: "correcthorse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := tt.rule.Check(Candidate{Password: tt.password})
+			if tt.code == "" {
+				assert.Nil(t, violation)
+				return
+			}
+			require.NotNil(t, violation)
+			assert.Equal(t, tt.code, violation.Code)
+			assert.NotEmpty(t, violation.Message)
+		})
+	}
+}
+
+func TestNoUserInputs(t *testing.T) {
+	tests := []struct {
+		name      string
+		candidate Candidate
+		message   string
+	}{
+		{name: "Unrelated", candidate: Candidate{Password: "correcthorse", Username: "alice", Email: "alice@example.com"}},
+		{name: "Username", candidate: Candidate{Password: "xxALICExx", Username: "alice"}, message: "Password must not contain the username"},
+		{name: "Email local part", candidate: Candidate{Password: "wonderland-jones!", Username: "alice", Email: "Jones@example.com"}, message: "Password must not contain the email address"},
+		{name: "Short inputs are ignored", candidate: Candidate{Password: "bobsled-team", Username: "bo", Email: "x@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := NoUserInputs().Check(tt.candidate)
+			if tt.message == "" {
+				assert.Nil(t, violation)
+				return
+			}
+			require.NotNil(t, violation)
+			assert.Equal(t, tt.message, violation.Message)
+		})
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	assert.Zero(t, Entropy(""))
+	assert.Less(t, Entropy("abcdefgh"), Entropy("kxqmwzvr"), "runs are cheap")
+	assert.Less(t, Entropy("aaaaaaaa"), Entropy("acacacac"), "repeats are cheap")
+	assert.Less(t, Entropy("kxqmwzvr"), Entropy("kXqm3z!r"), "more alphabets are stronger")
+	assert.InDelta(t, 8*4.7, Entropy("kxqmwzvr"), 0.1)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := NewPolicy(MinLength(8), MinCharacterClasses(2))
+	policy.Add(NoUserInputs())
+
+	assert.NoError(t, policy.Validate(Candidate{Password: "correct horse", Username: "alice"}))
+
+	err := policy.Validate(Candidate{Password: "alice", Username: "alice"})
+	var policyErr *PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []string{"min_length", "character_classes", "contains_user_input"}, violationCodes(policyErr))
+	assert.Equal(t, "Password must be at least 8 characters; "+
+		"Password must contain 2 of lowercase letters, uppercase letters, digits and symbols; "+
+		"Password must not contain the username", err.Error())
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	policy, err := PolicyFromEnv()
+	require.NoError(t, err)
+	assert.NoError(t, policy.Validate(Candidate{Password: "password"}))
+	assert.Error(t, policy.Validate(Candidate{Password: string(make([]byte, 73))}))
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_MIN_CLASSES", "3")
+	t.Setenv("PASSWORD_MIN_ENTROPY", "50")
+	t.Setenv("PASSWORD_BANNED_WORDS", "password,acme")
+	policy, err = PolicyFromEnv()
+	require.NoError(t, err)
+
+	err = policy.Validate(Candidate{Password: "password"})
+	var policyErr *PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []string{"min_length", "character_classes", "too_weak", "banned_word"}, violationCodes(policyErr))
+	assert.NoError(t, policy.Validate(Candidate{Password: "Tr0ub4dor&3-Staple"}))
+
+	t.Setenv("PASSWORD_MAX_BYTES", "lots")
+	_, err = PolicyFromEnv()
+	assert.ErrorContains(t, err, "PASSWORD_MAX_BYTES")
+}
+
+func violationCodes(err *PolicyError) []string {
+	codes := make([]string, len(err.Violations))
+	for i, violation := range err.Violations {
+		codes[i] = violation.Code
+	}
+	return codes
+}
@@ -0,0 +1,169 @@
+package password
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minUserInputLength keeps very short usernames from banning common
+// substrings.
+const minUserInputLength = 3
+
+// MinLength requires at least n characters.
+func MinLength(n int) Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		if utf8.RuneCountInString(candidate.Password) < n {
+			return &Violation{Code: "min_length", Message: fmt.Sprintf("Password must be at least %d characters", n)}
+		}
+		return nil
+	})
+}
+
+// MaxLength allows at most n characters.
+func MaxLength(n int) Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		if utf8.RuneCountInString(candidate.Password) > n {
+			return &Violation{Code: "max_length", Message: fmt.Sprintf("Password must be at most %d characters", n)}
+		}
+		return nil
+	})
+}
+
+// MaxBytes allows at most n bytes of UTF-8, which is what hash functions
+// with an input limit care about.
+func MaxBytes(n int) Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		if len(candidate.Password) > n {
+			return &Violation{Code: "max_bytes", Message: fmt.Sprintf("Password must be at most %d bytes", n)}
+		}
+		return nil
+	})
+}
+
+// MinCharacterClasses requires n of lowercase letters, uppercase letters,
+// digits and other characters.
+func MinCharacterClasses(n int) Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		if len(characterClasses(candidate.Password)) < n {
+			return &Violation{
+				Code:    "character_classes",
+				Message: fmt.Sprintf("Password must contain %d of lowercase letters, uppercase letters, digits and symbols", n),
+			}
+		}
+		return nil
+	})
+}
+
+// MinEntropy requires an estimated strength of at least bits, see Entropy.
+func MinEntropy(bits float64) Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		if Entropy(candidate.Password) < bits {
+			return &Violation{Code: "too_weak", Message: "Password is too easy to guess"}
+		}
+		return nil
+	})
+}
+
+// NoUserInputs rejects passwords containing the username, the email address
+// or the local part of the email address, ignoring case.
+func NoUserInputs() Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		password := strings.ToLower(candidate.Password)
+		if containsInput(password, candidate.Username) {
+			return &Violation{Code: "contains_user_input", Message: "Password must not contain the username"}
+		}
+		localPart, _, _ := strings.Cut(candidate.Email, "@")
+		if containsInput(password, candidate.Email) || containsInput(password, localPart) {
+			return &Violation{Code: "contains_user_input", Message: "Password must not contain the email address"}
+		}
+		return nil
+	})
+}
+
+// BannedWords rejects passwords containing any of words, ignoring case.
+func BannedWords(words ...string) Rule {
+	banned := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			banned = append(banned, word)
+		}
+	}
+
+	return RuleFunc(func(candidate Candidate) *Violation {
+		password := strings.ToLower(candidate.Password)
+		for _, word := range banned {
+			if strings.Contains(password, word) {
+				return &Violation{Code: "banned_word", Message: fmt.Sprintf("Password must not contain %q", word)}
+			}
+		}
+		return nil
+	})
+}
+
+// Entropy estimates the strength of password in bits. Each character adds
+// log2 of the size of the alphabets the password draws from, except that
+// repeated characters and runs such as "abc" or "321" add a single bit.
+func Entropy(password string) float64 {
+	pool := 0
+	for _, size := range characterClasses(password) {
+		pool += size
+	}
+	if pool == 0 {
+		return 0
+	}
+	perCharacter := math.Log2(float64(pool))
+
+	bits := 0.0
+	var previous rune
+	for i, r := range []rune(password) {
+		if i > 0 && (r == previous || r == previous+1 || r == previous-1) {
+			bits++
+		} else {
+			bits += perCharacter
+		}
+		previous = r
+	}
+	return bits
+}
+
+// characterClasses returns the classes password uses with the size of their
+// alphabet.
+func characterClasses(password string) map[string]int {
+	classes := map[string]int{}
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			classes["lower"] = 26
+		case unicode.IsUpper(r):
+			classes["upper"] = 26
+		case unicode.IsDigit(r):
+			classes["digit"] = 10
+		default:
+			classes["symbol"] = 33
+		}
+	}
+	return classes
+}
+
+func containsInput(password string, input string) bool {
+	input = strings.ToLower(strings.TrimSpace(input))
+	return utf8.RuneCountInString(input) >= minUserInputLength && strings.Contains(password, input)
+}
+
+// BreachList is a corpus of passwords known from data breaches.
+type BreachList interface {
+	Breached(password string) bool
+}
+
+// NotBreached rejects passwords found in list.
+func NotBreached(list BreachList) Rule {
+	return RuleFunc(func(candidate Candidate) *Violation {
+		if list.Breached(candidate.Password) {
+			return &Violation{Code: "breached", Message: "Password appears in a known data breach"}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,214 @@
+// Package rebac answers relationship-based permission checks in the style of
+// Zanzibar: relations between objects and subjects are stored as tuples such
+// as "document:readme#viewer@team:eng#member", and a Schema derives further
+// relations from them.
+package rebac
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+)
+
+// maxDepth bounds how long a chain of usersets and rewrites a check follows.
+// Cycles, such as two teams being members of each other, end earlier as
+// every userset is visited once.
+const maxDepth = 25
+
+var ErrMaxDepth = errors.New("Relation check exceeded the maximum depth")
+
+// Checker evaluates relations over the tuples in a repository.
+type Checker struct {
+	tupleRepo storage.RelationTupleRepository
+	schema    *Schema
+}
+
+func NewChecker(tupleRepo storage.RelationTupleRepository, schema *Schema) *Checker {
+	if schema == nil {
+		schema = &Schema{}
+	}
+	return &Checker{
+		tupleRepo: tupleRepo,
+		schema:    schema,
+	}
+}
+
+// Schema returns the schema relations are derived with.
+func (checker *Checker) Schema() *Schema {
+	return checker.schema
+}
+
+// Check reports whether subject has relation to object, directly, through a
+// userset such as a team membership, or as derived by the schema.
+func (checker *Checker) Check(ctx context.Context, object models.ObjectRef, relation string, subject models.SubjectRef) (bool, error) {
+	return checker.check(ctx, object, relation, subject, map[string]bool{}, 0)
+}
+
+func (checker *Checker) check(ctx context.Context, object models.ObjectRef, relation string, subject models.SubjectRef, visited map[string]bool, depth int) (bool, error) {
+	userset := object.String() + "#" + relation
+	if visited[userset] {
+		return false, nil
+	}
+	visited[userset] = true
+	if depth > maxDepth {
+		return false, ErrMaxDepth
+	}
+
+	tuples, err := checker.tuples(ctx, object, relation)
+	if err != nil {
+		return false, err
+	}
+	for _, tuple := range tuples {
+		if subjectMatches(tuple.Subject(), subject) {
+			return true, nil
+		}
+	}
+	for _, tuple := range tuples {
+		if tuple.SubjectRelation == "" {
+			continue
+		}
+		found, err := checker.check(ctx, tuple.Subject().Object(), tuple.SubjectRelation, subject, visited, depth+1)
+		if found || err != nil {
+			return found, err
+		}
+	}
+
+	definition := checker.schema.relation(object.Type, relation)
+	for _, implied := range definition.ImpliedBy {
+		found, err := checker.check(ctx, object, implied, subject, visited, depth+1)
+		if found || err != nil {
+			return found, err
+		}
+	}
+	for _, inherit := range definition.Inherit {
+		related, err := checker.tuples(ctx, object, inherit.From)
+		if err != nil {
+			return false, err
+		}
+		for _, tuple := range related {
+			found, err := checker.check(ctx, tuple.Subject().Object(), inherit.Relation, subject, visited, depth+1)
+			if found || err != nil {
+				return found, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// subjectMatches reports whether a tuple granted to granted grants subject.
+// "user:*" grants every user, but not usersets.
+func subjectMatches(granted models.SubjectRef, subject models.SubjectRef) bool {
+	if granted == subject {
+		return true
+	}
+	return granted.ID == models.WildcardID && granted.Type == subject.Type && subject.Relation == ""
+}
+
+// ExpandNode is the tree of subjects holding a relation to an object.
+type ExpandNode struct {
+	// Userset is the object and relation, such as "document:readme#viewer".
+	Userset string `json:"userset"`
+	// Subjects lists the subjects of the relation's own tuples.
+	Subjects []string `json:"subjects"`
+	// Children expand the usersets among Subjects, the relations implying
+	// this one and the relations it is inherited from.
+	Children []*ExpandNode `json:"children,omitempty"`
+}
+
+// Expand returns who has relation to object and why.
+func (checker *Checker) Expand(ctx context.Context, object models.ObjectRef, relation string) (*ExpandNode, error) {
+	return checker.expand(ctx, object, relation, map[string]bool{})
+}
+
+// expand builds the node of a userset. Usersets already being expanded
+// further up, in a cycle, get a node without subjects.
+func (checker *Checker) expand(ctx context.Context, object models.ObjectRef, relation string, path map[string]bool) (*ExpandNode, error) {
+	node := &ExpandNode{
+		Userset:  object.String() + "#" + relation,
+		Subjects: []string{},
+	}
+	if path[node.Userset] {
+		return node, nil
+	}
+	if len(path) > maxDepth {
+		return nil, ErrMaxDepth
+	}
+	path[node.Userset] = true
+	defer delete(path, node.Userset)
+
+	tuples, err := checker.tuples(ctx, object, relation)
+	if err != nil {
+		return nil, err
+	}
+	for _, tuple := range tuples {
+		node.Subjects = append(node.Subjects, tuple.Subject().String())
+	}
+	for _, tuple := range tuples {
+		if tuple.SubjectRelation == "" {
+			continue
+		}
+		if err := checker.expandChild(ctx, node, tuple.Subject().Object(), tuple.SubjectRelation, path); err != nil {
+			return nil, err
+		}
+	}
+
+	definition := checker.schema.relation(object.Type, relation)
+	for _, implied := range definition.ImpliedBy {
+		if err := checker.expandChild(ctx, node, object, implied, path); err != nil {
+			return nil, err
+		}
+	}
+	for _, inherit := range definition.Inherit {
+		related, err := checker.tuples(ctx, object, inherit.From)
+		if err != nil {
+			return nil, err
+		}
+		for _, tuple := range related {
+			if err := checker.expandChild(ctx, node, tuple.Subject().Object(), inherit.Relation, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return node, nil
+}
+
+func (checker *Checker) expandChild(ctx context.Context, node *ExpandNode, object models.ObjectRef, relation string, path map[string]bool) error {
+	child, err := checker.expand(ctx, object, relation, path)
+	if err != nil {
+		return err
+	}
+	node.Children = append(node.Children, child)
+	return nil
+}
+
+// ListObjects returns the objects of objectType that subject has relation
+// to. Every object of the type is checked, so it suits types with a
+// moderate number of objects.
+func (checker *Checker) ListObjects(ctx context.Context, objectType string, relation string, subject models.SubjectRef) ([]models.ObjectRef, error) {
+	ids, err := checker.tupleRepo.ListObjectIDs(ctx, objectType)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []models.ObjectRef{}
+	for _, id := range ids {
+		object := models.ObjectRef{Type: objectType, ID: id}
+		found, err := checker.Check(ctx, object, relation, subject)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+func (checker *Checker) tuples(ctx context.Context, object models.ObjectRef, relation string) ([]models.RelationTuple, error) {
+	return checker.tupleRepo.ReadTuples(ctx, storage.RelationTupleFilter{
+		ObjectType: object.Type,
+		ObjectID:   object.ID,
+		Relation:   relation,
+	})
+}
@@ -0,0 +1,212 @@
+package rebac
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+	"multitech/pkg/testutils/mocks"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tuple(t *testing.T, object string, relation string, subject string) models.RelationTuple {
+	parsed, err := models.TupleKey{Object: object, Relation: relation, Subject: subject}.Tuple()
+	require.NoError(t, err)
+	return parsed
+}
+
+func object(t *testing.T, value string) models.ObjectRef {
+	parsed, err := models.ParseObjectRef(value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func subject(t *testing.T, value string) models.SubjectRef {
+	parsed, err := models.ParseSubjectRef(value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func testSchema() *Schema {
+	return &Schema{Types: map[string]TypeDefinition{
+		"folder": {Relations: map[string]RelationDefinition{
+			"owner":  {},
+			"viewer": {ImpliedBy: []string{"owner"}},
+		}},
+		"document": {Relations: map[string]RelationDefinition{
+			"parent": {},
+			"owner":  {},
+			"editor": {ImpliedBy: []string{"owner"}},
+			"viewer": {
+				ImpliedBy: []string{"editor"},
+				Inherit:   []Inheritance{{From: "parent", Relation: "viewer"}},
+			},
+		}},
+	}}
+}
+
+func testTuples(t *testing.T) []models.RelationTuple {
+	return []models.RelationTuple{
+		tuple(t, "team:eng", "member", "user:1"),
+		tuple(t, "team:eng", "member", "team:platform#member"),
+		tuple(t, "team:platform", "member", "user:2"),
+		tuple(t, "team:platform", "member", "team:eng#member"),
+		tuple(t, "document:readme", "owner", "user:3"),
+		tuple(t, "document:readme", "viewer", "team:eng#member"),
+		tuple(t, "document:readme", "parent", "folder:docs"),
+		tuple(t, "folder:docs", "owner", "user:4"),
+		tuple(t, "document:handbook", "viewer", "user:*"),
+		tuple(t, "document:secret", "owner", "user:5"),
+	}
+}
+
+func TestCheck(t *testing.T) {
+	checker := NewChecker(mocks.NewRelationTupleMock(testTuples(t)...), testSchema())
+
+	tests := []struct {
+		name     string
+		object   string
+		relation string
+		subject  string
+		expected bool
+	}{
+		{name: "Direct", object: "document:readme", relation: "owner", subject: "user:3", expected: true},
+		{name: "Implied", object: "document:readme", relation: "viewer", subject: "user:3", expected: true},
+		{name: "Implied Does Not Widen", object: "document:readme", relation: "owner", subject: "user:1", expected: false},
+		{name: "Through Team", object: "document:readme", relation: "viewer", subject: "user:1", expected: true},
+		{name: "Through Nested Team", object: "document:readme", relation: "viewer", subject: "user:2", expected: true},
+		{name: "Userset Itself", object: "document:readme", relation: "viewer", subject: "team:eng#member", expected: true},
+		{name: "Inherited From Folder", object: "document:readme", relation: "viewer", subject: "user:4", expected: true},
+		{name: "Inherited Relation Only", object: "document:readme", relation: "editor", subject: "user:4", expected: false},
+		{name: "Wildcard", object: "document:handbook", relation: "viewer", subject: "user:99", expected: true},
+		{name: "Wildcard Other Type", object: "document:handbook", relation: "viewer", subject: "service:99", expected: false},
+		{name: "Cyclic Teams", object: "document:readme", relation: "viewer", subject: "user:99", expected: false},
+		{name: "Other Document", object: "document:secret", relation: "viewer", subject: "user:1", expected: false},
+		{name: "Unknown Object", object: "document:missing", relation: "viewer", subject: "user:1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := checker.Check(context.Background(), object(t, tt.object), tt.relation, subject(t, tt.subject))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, allowed)
+		})
+	}
+}
+
+func TestCheckStorageError(t *testing.T) {
+	mockTupleRepo := mocks.NewDefaultRelationTupleMock()
+	mockTupleRepo.ReadTuplesFunc = func(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+		return nil, errors.New("database error")
+	}
+
+	_, err := NewChecker(mockTupleRepo, nil).Check(context.Background(), object(t, "document:readme"), "viewer", subject(t, "user:1"))
+
+	assert.Error(t, err)
+}
+
+func TestCheckMaxDepth(t *testing.T) {
+	var tuples []models.RelationTuple
+	for i := 0; i <= maxDepth+1; i++ {
+		tuples = append(tuples, models.RelationTuple{
+			ObjectType: "team", ObjectID: string(rune('a' + i)), Relation: "member",
+			SubjectType: "team", SubjectID: string(rune('a' + i + 1)), SubjectRelation: "member",
+		})
+	}
+
+	_, err := NewChecker(mocks.NewRelationTupleMock(tuples...), nil).Check(context.Background(), object(t, "team:a"), "member", subject(t, "user:1"))
+
+	assert.ErrorIs(t, err, ErrMaxDepth)
+}
+
+func TestExpand(t *testing.T) {
+	checker := NewChecker(mocks.NewRelationTupleMock(testTuples(t)...), testSchema())
+
+	tree, err := checker.Expand(context.Background(), object(t, "document:readme"), "viewer")
+	require.NoError(t, err)
+
+	assert.Equal(t, "document:readme#viewer", tree.Userset)
+	assert.Equal(t, []string{"team:eng#member"}, tree.Subjects)
+	require.Len(t, tree.Children, 3)
+
+	team := tree.Children[0]
+	assert.Equal(t, "team:eng#member", team.Userset)
+	assert.Equal(t, []string{"user:1", "team:platform#member"}, team.Subjects)
+	require.Len(t, team.Children, 1)
+	assert.Equal(t, []string{"user:2", "team:eng#member"}, team.Children[0].Subjects)
+	require.Len(t, team.Children[0].Children, 1)
+	assert.Empty(t, team.Children[0].Children[0].Subjects, "the cycle back to team:eng ends")
+
+	editor := tree.Children[1]
+	assert.Equal(t, "document:readme#editor", editor.Userset)
+	require.Len(t, editor.Children, 1)
+	assert.Equal(t, []string{"user:3"}, editor.Children[0].Subjects)
+
+	folder := tree.Children[2]
+	assert.Equal(t, "folder:docs#viewer", folder.Userset)
+	require.Len(t, folder.Children, 1)
+	assert.Equal(t, []string{"user:4"}, folder.Children[0].Subjects)
+}
+
+func TestListObjects(t *testing.T) {
+	checker := NewChecker(mocks.NewRelationTupleMock(testTuples(t)...), testSchema())
+
+	objects, err := checker.ListObjects(context.Background(), "document", "viewer", subject(t, "user:1"))
+	require.NoError(t, err)
+	assert.Equal(t, []models.ObjectRef{object(t, "document:readme"), object(t, "document:handbook")}, objects)
+
+	objects, err = checker.ListObjects(context.Background(), "document", "owner", subject(t, "user:1"))
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestLoadSchema(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "schema.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	schema, err := LoadSchema(write(`
+types:
+  document:
+    relations:
+      parent:
+      owner:
+      viewer:
+        implied_by: [owner]
+        inherit:
+          - from: parent
+            relation: viewer
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"owner"}, schema.relation("document", "viewer").ImpliedBy)
+	assert.Equal(t, []Inheritance{{From: "parent", Relation: "viewer"}}, schema.relation("document", "viewer").Inherit)
+	assert.NoError(t, schema.Validate(tuple(t, "document:readme", "owner", "user:1")))
+	assert.Error(t, schema.Validate(tuple(t, "document:readme", "approver", "user:1")))
+	assert.NoError(t, schema.Validate(tuple(t, "team:eng", "member", "user:1")), "types without a definition accept any relation")
+
+	_, err = LoadSchema(write(`
+types:
+  document:
+    relations:
+      viewer:
+        implied_by: [owner]
+`))
+	assert.ErrorContains(t, err, `implied by undeclared relation "owner"`)
+
+	_, err = LoadSchema(write(`
+types:
+  Document:
+    relations:
+      viewer:
+`))
+	assert.ErrorContains(t, err, "Invalid object type")
+}
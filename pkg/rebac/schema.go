@@ -0,0 +1,106 @@
+package rebac
+
+import (
+	"fmt"
+	"multitech/internal/models"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema declares how relations of an object type derive from each other.
+// Relations of types it does not declare hold only through their tuples.
+type Schema struct {
+	Types map[string]TypeDefinition `yaml:"types"`
+}
+
+type TypeDefinition struct {
+	Relations map[string]RelationDefinition `yaml:"relations"`
+}
+
+// RelationDefinition widens a relation beyond its own tuples.
+type RelationDefinition struct {
+	// ImpliedBy names relations of the same object that imply this one,
+	// e.g. every editor of a document is also a viewer.
+	ImpliedBy []string `yaml:"implied_by"`
+	// Inherit grants the relation to subjects having a relation to a
+	// related object, e.g. viewers of a document's parent folder.
+	Inherit []Inheritance `yaml:"inherit"`
+}
+
+// Inheritance follows the From relation of an object to other objects and
+// takes the subjects having Relation to those.
+type Inheritance struct {
+	From     string `yaml:"from"`
+	Relation string `yaml:"relation"`
+}
+
+// LoadSchema reads a YAML schema file with a top-level "types" map.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schema Schema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := schema.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &schema, nil
+}
+
+// SchemaFromEnv loads the schema in REBAC_SCHEMA_FILE. Without the file
+// every relation holds only through its tuples.
+func SchemaFromEnv() (*Schema, error) {
+	path := os.Getenv("REBAC_SCHEMA_FILE")
+	if path == "" {
+		return &Schema{}, nil
+	}
+	return LoadSchema(path)
+}
+
+func (schema *Schema) validate() error {
+	for typeName, definition := range schema.Types {
+		if err := models.ValidateObjectType(typeName); err != nil {
+			return err
+		}
+		for relation, rewrite := range definition.Relations {
+			if err := models.ValidateRelation(relation); err != nil {
+				return fmt.Errorf("%s: %w", typeName, err)
+			}
+			for _, implied := range rewrite.ImpliedBy {
+				if _, ok := definition.Relations[implied]; !ok {
+					return fmt.Errorf("%s#%s: implied by undeclared relation %q", typeName, relation, implied)
+				}
+			}
+			for _, inherit := range rewrite.Inherit {
+				if _, ok := definition.Relations[inherit.From]; !ok {
+					return fmt.Errorf("%s#%s: inherits from undeclared relation %q", typeName, relation, inherit.From)
+				}
+				if err := models.ValidateRelation(inherit.Relation); err != nil {
+					return fmt.Errorf("%s#%s: %w", typeName, relation, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate rejects tuples naming a relation that the schema does not declare
+// for the object type.
+func (schema *Schema) Validate(tuple models.RelationTuple) error {
+	definition, ok := schema.Types[tuple.ObjectType]
+	if !ok {
+		return nil
+	}
+	if _, ok := definition.Relations[tuple.Relation]; !ok {
+		return fmt.Errorf("Type %s has no relation %s", tuple.ObjectType, tuple.Relation)
+	}
+	return nil
+}
+
+func (schema *Schema) relation(objectType string, relation string) RelationDefinition {
+	return schema.Types[objectType].Relations[relation]
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormAPIKeyRepository struct {
+	*gorm.DB
+}
+
+func NewGormAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &gormAPIKeyRepository{db}
+}
+
+func (keyRepo *gormAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return keyRepo.WithContext(ctx).Create(key).Error
+}
+
+func (keyRepo *gormAPIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := keyRepo.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &key, err
+}
+
+func (keyRepo *gormAPIKeyRepository) ListUserAPIKeys(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	err := keyRepo.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (keyRepo *gormAPIKeyRepository) DeleteAPIKey(ctx context.Context, userID uint, id uint) error {
+	result := keyRepo.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (keyRepo *gormAPIKeyRepository) TouchAPIKey(ctx context.Context, id uint, lastUsed time.Time) error {
+	return keyRepo.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", lastUsed).Error
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"time"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	ListUserAPIKeys(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	// DeleteAPIKey revokes a key of the given user. Keys of other users are reported as not found.
+	DeleteAPIKey(ctx context.Context, userID uint, id uint) error
+	TouchAPIKey(ctx context.Context, id uint, lastUsed time.Time) error
+}
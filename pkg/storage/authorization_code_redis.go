@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"multitech/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type authorizationCodeRepository struct {
+	client *redis.Client
+}
+
+func NewRedisAuthorizationCodeRepository(client *redis.Client) AuthorizationCodeRepository {
+	return &authorizationCodeRepository{
+		client: client,
+	}
+}
+
+func (codeRepo *authorizationCodeRepository) StoreAuthorizationCode(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error {
+	data, err := json.Marshal(authCode)
+	if err != nil {
+		return err
+	}
+
+	created, err := codeRepo.client.SetNX(ctx, authorizationCodeKey(code), data, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if !created {
+		return ErrInvalidData
+	}
+	return nil
+}
+
+func (codeRepo *authorizationCodeRepository) ConsumeAuthorizationCode(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+	data, err := codeRepo.client.GetDel(ctx, authorizationCodeKey(code)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrAuthorizationCodeNotFound
+		}
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+
+	var authCode models.AuthorizationCode
+	if err := json.Unmarshal(data, &authCode); err != nil {
+		return nil, err
+	}
+	return &authCode, nil
+}
+
+func (codeRepo *authorizationCodeRepository) StoreAuthorizationRequest(ctx context.Context, challenge string, request *models.AuthorizationRequest, ttl time.Duration) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	created, err := codeRepo.client.SetNX(ctx, authorizationRequestKey(challenge), data, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if !created {
+		return ErrInvalidData
+	}
+	return nil
+}
+
+func (codeRepo *authorizationCodeRepository) GetAuthorizationRequest(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+	return codeRepo.loadAuthorizationRequest(codeRepo.client.Get(ctx, authorizationRequestKey(challenge)))
+}
+
+func (codeRepo *authorizationCodeRepository) ConsumeAuthorizationRequest(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+	return codeRepo.loadAuthorizationRequest(codeRepo.client.GetDel(ctx, authorizationRequestKey(challenge)))
+}
+
+func (*authorizationCodeRepository) loadAuthorizationRequest(cmd *redis.StringCmd) (*models.AuthorizationRequest, error) {
+	data, err := cmd.Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrAuthorizationRequestNotFound
+		}
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+
+	var request models.AuthorizationRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func authorizationCodeKey(code string) string {
+	return "auth_code:" + code
+}
+
+func authorizationRequestKey(challenge string) string {
+	return "auth_request:" + challenge
+}
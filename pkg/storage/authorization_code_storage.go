@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"time"
+)
+
+var (
+	ErrAuthorizationCodeNotFound    = errors.New("Invalid or expired authorization code")
+	ErrAuthorizationRequestNotFound = errors.New("Invalid or expired consent challenge")
+)
+
+type AuthorizationCodeRepository interface {
+	StoreAuthorizationCode(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error
+	// ConsumeAuthorizationCode returns the code and deletes it, so that every code is redeemed at most once.
+	ConsumeAuthorizationCode(ctx context.Context, code string) (*models.AuthorizationCode, error)
+	StoreAuthorizationRequest(ctx context.Context, challenge string, request *models.AuthorizationRequest, ttl time.Duration) error
+	GetAuthorizationRequest(ctx context.Context, challenge string) (*models.AuthorizationRequest, error)
+	// ConsumeAuthorizationRequest returns the request and deletes it, so that consent is given or refused once.
+	ConsumeAuthorizationRequest(ctx context.Context, challenge string) (*models.AuthorizationRequest, error)
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"multitech/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type emailLoginRepository struct {
+	client *redis.Client
+}
+
+func NewRedisEmailLoginRepository(client *redis.Client) EmailLoginRepository {
+	return &emailLoginRepository{
+		client: client,
+	}
+}
+
+func (loginRepo *emailLoginRepository) CreateEmailLoginChallenge(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error {
+	key := emailLoginKey(token)
+	_, err := loginRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"user_id", challenge.UserID,
+			"method", challenge.Method,
+			"code_hash", challenge.CodeHash,
+			"attempts", challenge.Attempts,
+		)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (loginRepo *emailLoginRepository) GetEmailLoginChallenge(ctx context.Context, token string) (*models.EmailLoginChallenge, error) {
+	values, err := loginRepo.client.HGetAll(ctx, emailLoginKey(token)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, ErrEmailLoginNotFound
+	}
+
+	userID, err := strconv.ParseUint(values["user_id"], 10, 0)
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+	attempts, err := strconv.Atoi(values["attempts"])
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+	return &models.EmailLoginChallenge{
+		UserID:   uint(userID),
+		Method:   values["method"],
+		CodeHash: values["code_hash"],
+		Attempts: attempts,
+	}, nil
+}
+
+func (loginRepo *emailLoginRepository) RecordEmailLoginAttempt(ctx context.Context, token string) (int, error) {
+	attempts, err := recordAttemptScript.Run(ctx, loginRepo.client, []string{emailLoginKey(token)}).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	if attempts < 0 {
+		return 0, ErrEmailLoginNotFound
+	}
+	return attempts, nil
+}
+
+func (loginRepo *emailLoginRepository) DeleteEmailLoginChallenge(ctx context.Context, token string) error {
+	deleted, err := loginRepo.client.Del(ctx, emailLoginKey(token)).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if deleted == 0 {
+		return ErrEmailLoginNotFound
+	}
+	return nil
+}
+
+func (loginRepo *emailLoginRepository) ThrottleEmailLogin(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+	key := emailLoginThrottleKey(email)
+	acquired, err := loginRepo.client.SetNX(ctx, key, 1, interval).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	if acquired {
+		return 0, nil
+	}
+
+	wait, err := loginRepo.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return wait, nil
+}
+
+func emailLoginKey(token string) string {
+	return "email_login:" + token
+}
+
+func emailLoginThrottleKey(email string) string {
+	return "email_login_throttle:" + strings.ToLower(email)
+}
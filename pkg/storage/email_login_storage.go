@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"time"
+)
+
+var ErrEmailLoginNotFound = errors.New("Invalid or expired login token")
+
+type EmailLoginRepository interface {
+	CreateEmailLoginChallenge(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error
+	GetEmailLoginChallenge(ctx context.Context, token string) (*models.EmailLoginChallenge, error)
+	// RecordEmailLoginAttempt increments and returns the number of codes
+	// tried against the challenge. Callers record the attempt before checking
+	// the code, so that concurrent requests cannot try more codes than the limit.
+	RecordEmailLoginAttempt(ctx context.Context, token string) (int, error)
+	// DeleteEmailLoginChallenge removes the challenge. It fails with
+	// ErrEmailLoginNotFound if another request already used it.
+	DeleteEmailLoginChallenge(ctx context.Context, token string) error
+	// ThrottleEmailLogin claims the right to send a login mail to email for
+	// interval. If the right is already taken it returns how long the caller
+	// has to wait.
+	ThrottleEmailLogin(ctx context.Context, email string, interval time.Duration) (time.Duration, error)
+}
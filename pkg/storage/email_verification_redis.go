@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type emailVerificationRepository struct {
+	client *redis.Client
+}
+
+func NewRedisEmailVerificationRepository(client *redis.Client) EmailVerificationRepository {
+	return &emailVerificationRepository{
+		client: client,
+	}
+}
+
+func (verificationRepo *emailVerificationRepository) StoreEmailVerification(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error {
+	if err := verificationRepo.client.Set(ctx, emailVerificationKey(tokenID), userID, ttl).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (verificationRepo *emailVerificationRepository) ConsumeEmailVerification(ctx context.Context, tokenID string) (uint, error) {
+	userID, err := verificationRepo.client.GetDel(ctx, emailVerificationKey(tokenID)).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrEmailVerificationNotFound
+		}
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	return uint(userID), nil
+}
+
+func (verificationRepo *emailVerificationRepository) ThrottleEmailVerification(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+	key := emailVerificationThrottleKey(email)
+	acquired, err := verificationRepo.client.SetNX(ctx, key, 1, interval).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	if acquired {
+		return 0, nil
+	}
+
+	wait, err := verificationRepo.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	// The key expired between SETNX and PTTL; a retry will succeed.
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return wait, nil
+}
+
+func emailVerificationKey(tokenID string) string {
+	return "email_verification:" + tokenID
+}
+
+func emailVerificationThrottleKey(email string) string {
+	return "email_verification_throttle:" + strings.ToLower(email)
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrEmailVerificationNotFound = errors.New("Invalid or expired verification token")
+
+type EmailVerificationRepository interface {
+	StoreEmailVerification(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error
+	// ConsumeEmailVerification returns the user the token was issued to and
+	// deletes it, so that every verification link works once.
+	ConsumeEmailVerification(ctx context.Context, tokenID string) (uint, error)
+	// ThrottleEmailVerification claims the right to send a verification mail
+	// to email for interval. If the right is already taken it returns how long
+	// the caller has to wait.
+	ThrottleEmailVerification(ctx context.Context, email string, interval time.Duration) (time.Duration, error)
+}
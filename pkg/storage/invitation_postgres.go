@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormInvitationRepository struct {
+	*gorm.DB
+}
+
+func NewGormInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &gormInvitationRepository{db}
+}
+
+func (inviteRepo *gormInvitationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	db := inviteRepo.WithContext(ctx)
+	if invitation.Role != "" {
+		var roles int64
+		if err := db.Model(&models.Role{}).Where("name = ?", invitation.Role).Count(&roles).Error; err != nil {
+			return err
+		}
+		if roles == 0 {
+			return ErrRoleNotFound
+		}
+	}
+	return db.Create(invitation).Error
+}
+
+func (inviteRepo *gormInvitationRepository) ListInvitations(ctx context.Context) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := inviteRepo.WithContext(ctx).Order("created_at DESC, id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+func (inviteRepo *gormInvitationRepository) DeleteInvitation(ctx context.Context, id uint) error {
+	result := inviteRepo.WithContext(ctx).Where("id = ?", id).Delete(&models.Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (inviteRepo *gormInvitationRepository) AcceptInvitation(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := inviteRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock makes a concurrent registration with the same token wait
+		// and then find the invitation used.
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(&invitation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if !invitation.Pending(now) {
+			return ErrInvitationInvalid
+		}
+		if !strings.EqualFold(invitation.Email, user.Email) {
+			return ErrInvitationEmailMismatch
+		}
+
+		if err := tx.Create(user).Error; err != nil {
+			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+				return ErrUserExists
+			}
+			return err
+		}
+
+		if invitation.Role != "" {
+			var role models.Role
+			err := tx.Where("name = ?", invitation.Role).First(&role).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoleNotFound
+			}
+			if err != nil {
+				return err
+			}
+			err = tx.Table("user_roles").
+				Create(map[string]interface{}{"user_id": user.ID, "role_id": role.ID}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		invitation.UsedAt = &now
+		invitation.UserID = &user.ID
+		return tx.Model(&invitation).Updates(map[string]interface{}{"used_at": now, "user_id": user.ID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
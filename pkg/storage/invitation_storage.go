@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+)
+
+var (
+	ErrInvitationNotFound      = errors.New("Invitation not found")
+	ErrInvitationInvalid       = errors.New("Invalid or expired invitation")
+	ErrInvitationEmailMismatch = errors.New("Invitation is for another email address")
+)
+
+type InvitationRepository interface {
+	// CreateInvitation stores the invitation. Its role, if set, must exist.
+	CreateInvitation(ctx context.Context, invitation *models.Invitation) error
+	// ListInvitations returns every invitation, newest first.
+	ListInvitations(ctx context.Context) ([]models.Invitation, error)
+	// DeleteInvitation revokes the invitation.
+	DeleteInvitation(ctx context.Context, id uint) error
+	// AcceptInvitation creates the user with the pending invitation of
+	// tokenHash, grants the invitation's role and marks the invitation used,
+	// all or nothing. The user's email address must be the invited one.
+	AcceptInvitation(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recordLoginFailureScript increments the failure counter and starts its
+// window on the first failure, so that later failures do not extend it.
+var recordLoginFailureScript = redis.NewScript(`
+local failures = redis.call("INCR", KEYS[1])
+if failures == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return failures
+`)
+
+type loginAttemptRepository struct {
+	client *redis.Client
+}
+
+func NewRedisLoginAttemptRepository(client *redis.Client) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		client: client,
+	}
+}
+
+func (attemptRepo *loginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	failures, err := recordLoginFailureScript.Run(ctx, attemptRepo.client, []string{loginFailuresKey(key)}, window.Milliseconds()).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	return failures, nil
+}
+
+func (attemptRepo *loginAttemptRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	if err := attemptRepo.client.Del(ctx, loginFailuresKey(key)).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (attemptRepo *loginAttemptRepository) BlockLogin(ctx context.Context, key string, reason string, duration time.Duration) error {
+	if err := attemptRepo.client.Set(ctx, loginBlockKey(key), reason, duration).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (attemptRepo *loginAttemptRepository) GetLoginBlock(ctx context.Context, key string) (string, time.Duration, error) {
+	blockKey := loginBlockKey(key)
+	var reason *redis.StringCmd
+	var remaining *redis.DurationCmd
+	_, err := attemptRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		reason = pipe.Get(ctx, blockKey)
+		remaining = pipe.PTTL(ctx, blockKey)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("redis error: %w", err)
+	}
+	if remaining.Val() <= 0 {
+		return "", 0, nil
+	}
+	return reason.Val(), remaining.Val(), nil
+}
+
+func (attemptRepo *loginAttemptRepository) UnblockLogin(ctx context.Context, key string) error {
+	if err := attemptRepo.client.Del(ctx, loginBlockKey(key)).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func loginFailuresKey(key string) string {
+	return "login_failures:" + key
+}
+
+func loginBlockKey(key string) string {
+	return "login_block:" + key
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Reasons a login key can be blocked for.
+const (
+	LoginBlockLocked    = "locked"
+	LoginBlockThrottled = "throttled"
+)
+
+// LoginAttemptRepository tracks failed logins per key, such as a username or
+// a client IP, and blocks keys for a while.
+type LoginAttemptRepository interface {
+	// RecordLoginFailure counts a failed login for key and returns the number
+	// of failures within window, which starts with the first failure.
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	ResetLoginFailures(ctx context.Context, key string) error
+	BlockLogin(ctx context.Context, key string, reason string, duration time.Duration) error
+	// GetLoginBlock returns why and for how much longer key is blocked. The
+	// duration is zero if the key is not blocked.
+	GetLoginBlock(ctx context.Context, key string) (string, time.Duration, error)
+	UnblockLogin(ctx context.Context, key string) error
+}
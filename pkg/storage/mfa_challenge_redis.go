@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"multitech/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recordAttemptScript increments the attempt counter without recreating a
+// challenge that expired or was used in the meantime.
+var recordAttemptScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "attempts", 1)
+`)
+
+type mfaChallengeRepository struct {
+	client *redis.Client
+}
+
+func NewRedisMFAChallengeRepository(client *redis.Client) MFAChallengeRepository {
+	return &mfaChallengeRepository{
+		client: client,
+	}
+}
+
+func (challengeRepo *mfaChallengeRepository) CreateMFAChallenge(ctx context.Context, token string, challenge *models.MFAChallenge, ttl time.Duration) error {
+	key := mfaChallengeKey(token)
+	_, err := challengeRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "user_id", challenge.UserID, "attempts", challenge.Attempts)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (challengeRepo *mfaChallengeRepository) GetMFAChallenge(ctx context.Context, token string) (*models.MFAChallenge, error) {
+	values, err := challengeRepo.client.HGetAll(ctx, mfaChallengeKey(token)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, ErrMFAChallengeNotFound
+	}
+
+	userID, err := strconv.ParseUint(values["user_id"], 10, 0)
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+	attempts, err := strconv.Atoi(values["attempts"])
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+	return &models.MFAChallenge{
+		UserID:   uint(userID),
+		Attempts: attempts,
+	}, nil
+}
+
+func (challengeRepo *mfaChallengeRepository) RecordAttempt(ctx context.Context, token string) (int, error) {
+	attempts, err := recordAttemptScript.Run(ctx, challengeRepo.client, []string{mfaChallengeKey(token)}).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	if attempts < 0 {
+		return 0, ErrMFAChallengeNotFound
+	}
+	return attempts, nil
+}
+
+func (challengeRepo *mfaChallengeRepository) DeleteMFAChallenge(ctx context.Context, token string) error {
+	deleted, err := challengeRepo.client.Del(ctx, mfaChallengeKey(token)).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if deleted == 0 {
+		return ErrMFAChallengeNotFound
+	}
+	return nil
+}
+
+func mfaChallengeKey(token string) string {
+	return "mfa_challenge:" + token
+}
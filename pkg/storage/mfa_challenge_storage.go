@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"time"
+)
+
+var ErrMFAChallengeNotFound = errors.New("Invalid or expired MFA challenge")
+
+type MFAChallengeRepository interface {
+	CreateMFAChallenge(ctx context.Context, token string, challenge *models.MFAChallenge, ttl time.Duration) error
+	GetMFAChallenge(ctx context.Context, token string) (*models.MFAChallenge, error)
+	// RecordAttempt increments and returns the number of codes tried against
+	// the challenge. Callers record the attempt before checking the code, so
+	// that concurrent requests cannot try more codes than the limit.
+	RecordAttempt(ctx context.Context, token string) (int, error)
+	// DeleteMFAChallenge removes the challenge. It fails with
+	// ErrMFAChallengeNotFound if another request already used it.
+	DeleteMFAChallenge(ctx context.Context, token string) error
+}
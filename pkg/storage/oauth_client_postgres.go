@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type gormOAuthClientRepository struct {
+	*gorm.DB
+}
+
+func NewGormOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &gormOAuthClientRepository{db}
+}
+
+func (clientRepo *gormOAuthClientRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := clientRepo.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrClientNotFound
+	}
+	return &client, err
+}
+
+func (clientRepo *gormOAuthClientRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	err := clientRepo.WithContext(ctx).Create(client).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return ErrClientExists
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+)
+
+var (
+	ErrClientExists   = errors.New("OAuth client already exists")
+	ErrClientNotFound = errors.New("OAuth client not found")
+)
+
+type OAuthClientRepository interface {
+	GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormOrganizationRepository struct {
+	*gorm.DB
+}
+
+func NewGormOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &gormOrganizationRepository{db}
+}
+
+func (orgRepo *gormOrganizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization, ownerID uint) error {
+	return orgRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+				return ErrOrganizationExists
+			}
+			return err
+		}
+		if err := requireRole(tx, models.OrganizationOwnerRole); err != nil {
+			return err
+		}
+		return setMember(tx, organization.ID, ownerID, models.OrganizationOwnerRole)
+	})
+}
+
+func (orgRepo *gormOrganizationRepository) GetOrganization(ctx context.Context, id uint) (*models.Organization, error) {
+	var organization models.Organization
+	err := orgRepo.WithContext(ctx).Where("id = ?", id).First(&organization).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrganizationNotFound
+	}
+	return &organization, err
+}
+
+func (orgRepo *gormOrganizationRepository) ListUserOrganizations(ctx context.Context, userID uint) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	err := orgRepo.WithContext(ctx).Preload("Organization").
+		Where("user_id = ?", userID).
+		Order("organization_id").
+		Find(&members).Error
+	return members, err
+}
+
+func (orgRepo *gormOrganizationRepository) GetMembership(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	err := orgRepo.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMembershipNotFound
+	}
+	return &member, err
+}
+
+func (orgRepo *gormOrganizationRepository) ListMembers(ctx context.Context, organizationID uint) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	err := orgRepo.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Order("user_id").
+		Find(&members).Error
+	return members, err
+}
+
+func (orgRepo *gormOrganizationRepository) SetMemberRole(ctx context.Context, organizationID uint, userID uint, role string) error {
+	return orgRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireRole(tx, role); err != nil {
+			return err
+		}
+		if role != models.OrganizationOwnerRole {
+			if err := keepOwner(tx, organizationID, userID); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&models.OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ?", organizationID, userID).
+			Update("role", role)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMembershipNotFound
+		}
+		return nil
+	})
+}
+
+func (orgRepo *gormOrganizationRepository) RemoveMember(ctx context.Context, organizationID uint, userID uint) error {
+	return orgRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := keepOwner(tx, organizationID, userID); err != nil {
+			return err
+		}
+		result := tx.Where("organization_id = ? AND user_id = ?", organizationID, userID).
+			Delete(&models.OrganizationMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMembershipNotFound
+		}
+		return nil
+	})
+}
+
+func (orgRepo *gormOrganizationRepository) InviteMember(ctx context.Context, invitation *models.OrganizationInvitation) error {
+	return orgRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var organizations int64
+		if err := tx.Model(&models.Organization{}).Where("id = ?", invitation.OrganizationID).Count(&organizations).Error; err != nil {
+			return err
+		}
+		if organizations == 0 {
+			return ErrOrganizationNotFound
+		}
+		if err := requireRole(tx, invitation.Role); err != nil {
+			return err
+		}
+		var members int64
+		err := tx.Model(&models.OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ?", invitation.OrganizationID, invitation.UserID).
+			Count(&members).Error
+		if err != nil {
+			return err
+		}
+		if members > 0 {
+			return ErrMemberExists
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "invited_by", "expires_at", "created_at"}),
+		}).Create(invitation).Error
+		if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+			return ErrUserNotFound
+		}
+		return err
+	})
+}
+
+func (orgRepo *gormOrganizationRepository) ListUserInvitations(ctx context.Context, userID uint) ([]models.OrganizationInvitation, error) {
+	var invitations []models.OrganizationInvitation
+	err := orgRepo.WithContext(ctx).Preload("Organization").
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("id").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (orgRepo *gormOrganizationRepository) AcceptMemberInvitation(ctx context.Context, id uint, userID uint) (*models.OrganizationMember, error) {
+	var member *models.OrganizationMember
+	err := orgRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock makes a concurrent accept or decline wait and then find
+		// the invitation gone.
+		var invitation models.OrganizationInvitation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&invitation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if !time.Now().Before(invitation.ExpiresAt) {
+			return ErrInvitationInvalid
+		}
+
+		if err := requireRole(tx, invitation.Role); err != nil {
+			return err
+		}
+		if err := setMember(tx, invitation.OrganizationID, userID, invitation.Role); err != nil {
+			return err
+		}
+		if err := tx.Delete(&invitation).Error; err != nil {
+			return err
+		}
+		member = &models.OrganizationMember{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         userID,
+			Role:           invitation.Role,
+		}
+		return nil
+	})
+	return member, err
+}
+
+func (orgRepo *gormOrganizationRepository) DeclineMemberInvitation(ctx context.Context, id uint, userID uint) error {
+	result := orgRepo.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.OrganizationInvitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func requireRole(tx *gorm.DB, role string) error {
+	var roles int64
+	if err := tx.Model(&models.Role{}).Where("name = ?", role).Count(&roles).Error; err != nil {
+		return err
+	}
+	if roles == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// setMember adds the user to the organization with role, or changes the role
+// of a member. The role must exist.
+func setMember(tx *gorm.DB, organizationID uint, userID uint, role string) error {
+	member := &models.OrganizationMember{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(member).Error
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// keepOwner fails when the user is the only owner of the organization, so
+// that demoting or removing them would leave it without one. The owners are
+// locked until the transaction ends.
+func keepOwner(tx *gorm.DB, organizationID uint, userID uint) error {
+	var owners []uint
+	err := tx.Model(&models.OrganizationMember{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("organization_id = ? AND role = ?", organizationID, models.OrganizationOwnerRole).
+		Pluck("user_id", &owners).Error
+	if err != nil {
+		return err
+	}
+	if len(owners) == 1 && owners[0] == userID {
+		return ErrLastOwner
+	}
+	return nil
+}
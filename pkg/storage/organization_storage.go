@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+)
+
+var (
+	ErrOrganizationExists   = errors.New("Organization already exists")
+	ErrOrganizationNotFound = errors.New("Organization not found")
+	ErrMembershipNotFound   = errors.New("Membership not found")
+	ErrLastOwner            = errors.New("Organization must keep an owner")
+	ErrMemberExists         = errors.New("User is already a member")
+)
+
+type OrganizationRepository interface {
+	// CreateOrganization stores the organization and makes ownerID its
+	// owner.
+	CreateOrganization(ctx context.Context, organization *models.Organization, ownerID uint) error
+	GetOrganization(ctx context.Context, id uint) (*models.Organization, error)
+	// ListUserOrganizations returns the memberships of the user with their
+	// organizations.
+	ListUserOrganizations(ctx context.Context, userID uint) ([]models.OrganizationMember, error)
+	GetMembership(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error)
+	ListMembers(ctx context.Context, organizationID uint) ([]models.OrganizationMember, error)
+	// SetMemberRole changes the role of a member. Users outside of the
+	// organization join it through InviteMember instead.
+	SetMemberRole(ctx context.Context, organizationID uint, userID uint, role string) error
+	RemoveMember(ctx context.Context, organizationID uint, userID uint) error
+	// InviteMember stores the invitation, replacing a pending one of the
+	// same user. It fails with ErrMemberExists for members.
+	InviteMember(ctx context.Context, invitation *models.OrganizationInvitation) error
+	// ListUserInvitations returns the unexpired invitations of the user with
+	// their organizations.
+	ListUserInvitations(ctx context.Context, userID uint) ([]models.OrganizationInvitation, error)
+	// AcceptMemberInvitation makes the user a member with the role of their
+	// invitation and deletes it, all or nothing. It fails with
+	// ErrInvitationInvalid unless the invitation is the user's and unexpired.
+	AcceptMemberInvitation(ctx context.Context, id uint, userID uint) (*models.OrganizationMember, error)
+	// DeclineMemberInvitation deletes an invitation of the user.
+	DeclineMemberInvitation(ctx context.Context, id uint, userID uint) error
+}
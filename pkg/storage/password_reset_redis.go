@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type passwordResetRepository struct {
+	client *redis.Client
+}
+
+func NewRedisPasswordResetRepository(client *redis.Client) PasswordResetRepository {
+	return &passwordResetRepository{
+		client: client,
+	}
+}
+
+func (resetRepo *passwordResetRepository) StorePasswordReset(ctx context.Context, token string, userID uint, ttl time.Duration) error {
+	if err := resetRepo.client.Set(ctx, passwordResetKey(token), userID, ttl).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (resetRepo *passwordResetRepository) GetPasswordReset(ctx context.Context, token string) (uint, error) {
+	userID, err := resetRepo.client.Get(ctx, passwordResetKey(token)).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrPasswordResetNotFound
+		}
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	return uint(userID), nil
+}
+
+func (resetRepo *passwordResetRepository) ConsumePasswordReset(ctx context.Context, token string) (uint, error) {
+	userID, err := resetRepo.client.GetDel(ctx, passwordResetKey(token)).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrPasswordResetNotFound
+		}
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	return uint(userID), nil
+}
+
+func (resetRepo *passwordResetRepository) ThrottlePasswordReset(ctx context.Context, email string, interval time.Duration) (bool, error) {
+	acquired, err := resetRepo.client.SetNX(ctx, passwordResetThrottleKey(email), 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis error: %w", err)
+	}
+	return acquired, nil
+}
+
+func passwordResetKey(token string) string {
+	return "password_reset:" + token
+}
+
+func passwordResetThrottleKey(email string) string {
+	return "password_reset_throttle:" + strings.ToLower(email)
+}
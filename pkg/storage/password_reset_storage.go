@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrPasswordResetNotFound = errors.New("Invalid or expired reset token")
+
+type PasswordResetRepository interface {
+	StorePasswordReset(ctx context.Context, token string, userID uint, ttl time.Duration) error
+	// GetPasswordReset returns the user the token was issued to without
+	// using it up.
+	GetPasswordReset(ctx context.Context, token string) (uint, error)
+	// ConsumePasswordReset returns the user the token was issued to and
+	// deletes it, so that every token resets a password at most once.
+	ConsumePasswordReset(ctx context.Context, token string) (uint, error)
+	// ThrottlePasswordReset claims the right to send a reset mail to email
+	// for interval. It reports false if the right is already taken.
+	ThrottlePasswordReset(ctx context.Context, email string, interval time.Duration) (bool, error)
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"multitech/internal/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// allowRequestScript implements the generic cell rate algorithm. The key holds
+// the theoretical arrival time (TAT) of the next request in milliseconds;
+// requests are spaced period/limit apart and up to limit of them may arrive
+// at once. The clock of Redis is used so that replicas agree on the time.
+var allowRequestScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local interval = period / limit
+
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local next_tat = tat + interval
+local allow_at = next_tat - period
+if now < allow_at then
+	return {0, 0, math.ceil(allow_at - now), math.ceil(tat - now)}
+end
+
+redis.call("SET", KEYS[1], tostring(next_tat), "PX", math.ceil(next_tat - now))
+return {1, math.floor((period - (next_tat - now)) / interval), 0, math.ceil(next_tat - now)}
+`)
+
+type rateLimitRepository struct {
+	client *redis.Client
+}
+
+func NewRedisRateLimitRepository(client *redis.Client) RateLimitRepository {
+	return &rateLimitRepository{
+		client: client,
+	}
+}
+
+func (rateLimitRepo *rateLimitRepository) AllowRequest(ctx context.Context, key string, limit int, period time.Duration) (*models.RateLimitResult, error) {
+	values, err := allowRequestScript.Run(ctx, rateLimitRepo.client, []string{rateLimitKey(key)}, limit, period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", values)
+	}
+
+	return &models.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+func rateLimitKey(key string) string {
+	return "rate_limit:" + key
+}
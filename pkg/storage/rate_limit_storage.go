@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+// RateLimitRepository counts requests per key in a store shared by all
+// replicas.
+type RateLimitRepository interface {
+	// AllowRequest counts a request against limit requests per period for key.
+	// Rejected requests are not counted.
+	AllowRequest(ctx context.Context, key string, limit int, period time.Duration) (*models.RateLimitResult, error)
+}
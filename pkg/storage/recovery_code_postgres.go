@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormRecoveryCodeRepository struct {
+	*gorm.DB
+}
+
+func NewGormRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &gormRecoveryCodeRepository{db}
+}
+
+func (codeRepo *gormRecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []*models.RecoveryCode) error {
+	return codeRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		for _, code := range codes {
+			code.UserID = userID
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (codeRepo *gormRecoveryCodeRepository) ConsumeRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	// The used_at condition makes concurrent use of the same code fail for all but one request.
+	result := codeRepo.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+func (codeRepo *gormRecoveryCodeRepository) CheckRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	var count int64
+	err := codeRepo.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+func (codeRepo *gormRecoveryCodeRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := codeRepo.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+)
+
+var ErrRecoveryCodeInvalid = errors.New("Invalid username or recovery code")
+
+type RecoveryCodeRepository interface {
+	// ReplaceRecoveryCodes discards all codes of the user and stores the new set.
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []*models.RecoveryCode) error
+	// ConsumeRecoveryCode marks an unused code as used. It fails with
+	// ErrRecoveryCodeInvalid if no unused code with that hash exists.
+	ConsumeRecoveryCode(ctx context.Context, userID uint, codeHash string) error
+	// CheckRecoveryCode reports ErrRecoveryCodeInvalid like ConsumeRecoveryCode,
+	// but leaves the code unused.
+	CheckRecoveryCode(ctx context.Context, userID uint, codeHash string) error
+	CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"multitech/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const maxRotateAttempts = 3
+
+type refreshTokenRepository struct {
+	client *redis.Client
+}
+
+func NewRedisRefreshTokenRepository(client *redis.Client) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		client: client,
+	}
+}
+
+func (refreshRepo *refreshTokenRepository) StoreRefreshToken(ctx context.Context, token string, refresh *models.RefreshToken, ttl time.Duration) error {
+	if refresh.FamilyID == "" {
+		return ErrInvalidData
+	}
+
+	data, err := json.Marshal(refresh)
+	if err != nil {
+		return err
+	}
+
+	created, err := refreshRepo.client.SetNX(ctx, refreshTokenKey(token), data, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if !created {
+		return ErrSessionExists
+	}
+
+	_, err = refreshRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		refreshRepo.indexToken(ctx, pipe, token, refresh, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (refreshRepo *refreshTokenRepository) GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	return refreshRepo.getRefreshToken(ctx, refreshRepo.client, token)
+}
+
+func (refreshRepo *refreshTokenRepository) RotateRefreshToken(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+	var rotated, reused *models.RefreshToken
+
+	oldKey := refreshTokenKey(oldToken)
+	rotate := func(tx *redis.Tx) error {
+		current, err := refreshRepo.getRefreshToken(ctx, tx, oldToken)
+		if err != nil {
+			return err
+		}
+		if current.Used {
+			reused = current
+			return ErrRefreshTokenReused
+		}
+
+		used := *current
+		used.Used = true
+		usedData, err := json.Marshal(&used)
+		if err != nil {
+			return err
+		}
+
+		rotated = &models.RefreshToken{
+			UserID:    current.UserID,
+			FamilyID:  current.FamilyID,
+			CreatedAt: time.Now(),
+		}
+		rotatedData, err := json.Marshal(rotated)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			// The used token is kept until it expires so that a replay can be detected.
+			pipe.SetArgs(ctx, oldKey, usedData, redis.SetArgs{KeepTTL: true})
+			pipe.SetEx(ctx, refreshTokenKey(newToken), rotatedData, ttl)
+			refreshRepo.indexToken(ctx, pipe, newToken, rotated, ttl)
+			return nil
+		})
+		return err
+	}
+
+	// A concurrent rotation of the same token makes the transaction fail; the
+	// retry then sees the token as used and treats it as a replay.
+	var err error
+	for attempt := 0; attempt < maxRotateAttempts; attempt++ {
+		err = refreshRepo.client.Watch(ctx, rotate, oldKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := refreshRepo.RevokeFamily(ctx, reused.FamilyID); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return reused, ErrRefreshTokenReused
+	}
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	return rotated, nil
+}
+
+func (refreshRepo *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	familyKey := refreshFamilyKey(familyID)
+	tokens, err := refreshRepo.client.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	keys := make([]string, 0, len(tokens)+1)
+	for _, token := range tokens {
+		keys = append(keys, refreshTokenKey(token))
+	}
+	keys = append(keys, familyKey)
+
+	if err := refreshRepo.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (refreshRepo *refreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint) error {
+	indexKey := userRefreshFamiliesKey(userID)
+	families, err := refreshRepo.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	for _, familyID := range families {
+		if err := refreshRepo.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+
+	if err := refreshRepo.client.Del(ctx, indexKey).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (refreshRepo *refreshTokenRepository) getRefreshToken(ctx context.Context, client redis.Cmdable, token string) (*models.RefreshToken, error) {
+	data, err := client.Get(ctx, refreshTokenKey(token)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+
+	var refresh models.RefreshToken
+	if err := json.Unmarshal(data, &refresh); err != nil {
+		return nil, err
+	}
+	return &refresh, nil
+}
+
+func (refreshRepo *refreshTokenRepository) indexToken(ctx context.Context, pipe redis.Pipeliner, token string, refresh *models.RefreshToken, ttl time.Duration) {
+	familyKey := refreshFamilyKey(refresh.FamilyID)
+	pipe.SAdd(ctx, familyKey, token)
+	pipe.Expire(ctx, familyKey, ttl)
+
+	userKey := userRefreshFamiliesKey(refresh.UserID)
+	pipe.SAdd(ctx, userKey, refresh.FamilyID)
+	pipe.Expire(ctx, userKey, ttl)
+}
+
+func refreshTokenKey(token string) string {
+	return "refresh:" + token
+}
+
+func refreshFamilyKey(familyID string) string {
+	return "refresh_family:" + familyID
+}
+
+func userRefreshFamiliesKey(userID uint) string {
+	return "user_refresh_families:" + strconv.FormatUint(uint64(userID), 10)
+}
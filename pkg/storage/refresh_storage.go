@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("Invalid or expired refresh token")
+	ErrRefreshTokenReused   = errors.New("Refresh token reuse detected")
+)
+
+type RefreshTokenRepository interface {
+	StoreRefreshToken(ctx context.Context, token string, refresh *models.RefreshToken, ttl time.Duration) error
+	GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error)
+	// RotateRefreshToken marks oldToken as used and stores newToken in the same
+	// family. Presenting an already used token revokes the whole family and
+	// returns the replayed token together with ErrRefreshTokenReused.
+	RotateRefreshToken(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"multitech/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormRelationTupleRepository struct {
+	*gorm.DB
+}
+
+func NewGormRelationTupleRepository(db *gorm.DB) RelationTupleRepository {
+	return &gormRelationTupleRepository{db}
+}
+
+func (tupleRepo *gormRelationTupleRepository) WriteTuples(ctx context.Context, tuples []models.RelationTuple) error {
+	if len(tuples) == 0 {
+		return nil
+	}
+	return tupleRepo.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&tuples).Error
+}
+
+func (tupleRepo *gormRelationTupleRepository) DeleteTuples(ctx context.Context, tuples []models.RelationTuple) error {
+	return tupleRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, tuple := range tuples {
+			err := tx.Where(
+				"object_type = ? AND object_id = ? AND relation = ? AND subject_type = ? AND subject_id = ? AND subject_relation = ?",
+				tuple.ObjectType, tuple.ObjectID, tuple.Relation, tuple.SubjectType, tuple.SubjectID, tuple.SubjectRelation,
+			).Delete(&models.RelationTuple{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (tupleRepo *gormRelationTupleRepository) ReadTuples(ctx context.Context, filter RelationTupleFilter) ([]models.RelationTuple, error) {
+	query := tupleRepo.WithContext(ctx)
+	conditions := []struct {
+		column string
+		value  string
+	}{
+		{"object_type", filter.ObjectType},
+		{"object_id", filter.ObjectID},
+		{"relation", filter.Relation},
+		{"subject_type", filter.SubjectType},
+		{"subject_id", filter.SubjectID},
+	}
+	for _, condition := range conditions {
+		if condition.value != "" {
+			query = query.Where(condition.column+" = ?", condition.value)
+		}
+	}
+	if filter.SubjectType != "" {
+		query = query.Where("subject_relation = ?", filter.SubjectRelation)
+	}
+
+	var tuples []models.RelationTuple
+	err := query.Order("id").Find(&tuples).Error
+	return tuples, err
+}
+
+func (tupleRepo *gormRelationTupleRepository) ListObjectIDs(ctx context.Context, objectType string) ([]string, error) {
+	var ids []string
+	err := tupleRepo.WithContext(ctx).Model(&models.RelationTuple{}).
+		Where("object_type = ?", objectType).
+		Distinct().Order("object_id").
+		Pluck("object_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"multitech/internal/models"
+)
+
+// RelationTupleFilter selects relation tuples. Empty fields match any
+// value, except SubjectRelation, which is only compared when SubjectType is
+// set.
+type RelationTupleFilter struct {
+	ObjectType      string
+	ObjectID        string
+	Relation        string
+	SubjectType     string
+	SubjectID       string
+	SubjectRelation string
+}
+
+type RelationTupleRepository interface {
+	// WriteTuples stores all tuples or none of them. Writing a stored tuple
+	// again is not an error.
+	WriteTuples(ctx context.Context, tuples []models.RelationTuple) error
+	// DeleteTuples removes the tuples. Tuples that are not stored are
+	// ignored.
+	DeleteTuples(ctx context.Context, tuples []models.RelationTuple) error
+	ReadTuples(ctx context.Context, filter RelationTupleFilter) ([]models.RelationTuple, error)
+	// ListObjectIDs returns the IDs of every object of the type that has any
+	// tuple.
+	ListObjectIDs(ctx context.Context, objectType string) ([]string, error)
+}
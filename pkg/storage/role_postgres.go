@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormRoleRepository struct {
+	*gorm.DB
+}
+
+func NewGormRoleRepository(db *gorm.DB) RoleRepository {
+	return &gormRoleRepository{db}
+}
+
+func (roleRepo *gormRoleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	return roleRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range role.Permissions {
+			permission := &role.Permissions[i]
+			if err := tx.Where("name = ?", permission.Name).FirstOrCreate(permission).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Permissions.*").Create(role).Error; err != nil {
+			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+				return ErrRoleExists
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (roleRepo *gormRoleRepository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := roleRepo.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (roleRepo *gormRoleRepository) GetUserRoles(ctx context.Context, userID uint) ([]models.Role, error) {
+	var roles []models.Role
+	err := roleRepo.WithContext(ctx).Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+	return roles, err
+}
+
+func (roleRepo *gormRoleRepository) AssignRole(ctx context.Context, userID uint, roleName string) error {
+	role, err := roleRepo.roleByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	err = roleRepo.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Table("user_roles").
+		Create(map[string]interface{}{"user_id": userID, "role_id": role.ID}).Error
+	if err != nil && strings.Contains(err.Error(), "violates foreign key constraint") {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func (roleRepo *gormRoleRepository) RevokeRole(ctx context.Context, userID uint, roleName string) error {
+	role, err := roleRepo.roleByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	return roleRepo.WithContext(ctx).
+		Exec("DELETE FROM user_roles WHERE user_id = ? AND role_id = ?", userID, role.ID).Error
+}
+
+func (roleRepo *gormRoleRepository) roleByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := roleRepo.WithContext(ctx).Where("name = ?", name).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	return &role, err
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+)
+
+var (
+	ErrRoleExists   = errors.New("Role already exists")
+	ErrRoleNotFound = errors.New("Role not found")
+)
+
+type RoleRepository interface {
+	// CreateRole stores the role together with its permissions, creating
+	// permissions that do not exist yet.
+	CreateRole(ctx context.Context, role *models.Role) error
+	// ListRoles returns all roles with their permissions.
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	// GetUserRoles returns the roles granted to the user, with their
+	// permissions.
+	GetUserRoles(ctx context.Context, userID uint) ([]models.Role, error)
+	// AssignRole grants the named role to the user. Granting a role twice
+	// is not an error.
+	AssignRole(ctx context.Context, userID uint, roleName string) error
+	RevokeRole(ctx context.Context, userID uint, roleName string) error
+}
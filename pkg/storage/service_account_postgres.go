@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type gormServiceAccountRepository struct {
+	*gorm.DB
+}
+
+func NewGormServiceAccountRepository(db *gorm.DB) ServiceAccountRepository {
+	return &gormServiceAccountRepository{db}
+}
+
+func (accountRepo *gormServiceAccountRepository) GetServiceAccountByClientID(ctx context.Context, clientID string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := accountRepo.WithContext(ctx).Where("client_id = ?", clientID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrServiceAccountNotFound
+	}
+	return &account, err
+}
+
+func (accountRepo *gormServiceAccountRepository) CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	err := accountRepo.WithContext(ctx).Create(account).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return ErrServiceAccountExists
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"multitech/internal/models"
+)
+
+var (
+	ErrServiceAccountExists   = errors.New("Service account already exists")
+	ErrServiceAccountNotFound = errors.New("Service account not found")
+)
+
+type ServiceAccountRepository interface {
+	GetServiceAccountByClientID(ctx context.Context, clientID string) (*models.ServiceAccount, error)
+	CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error
+}
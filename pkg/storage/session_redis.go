@@ -2,8 +2,12 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"multitech/internal/models"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -19,34 +23,248 @@ func NewRedisSessionRepository(client *redis.Client) SessionsRepository {
 	}
 }
 
-func (sessRepo *sessionRepository) StoreSession(ctx context.Context, token string, userID uint, ttl time.Duration) error {
+func (sessRepo *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if session.ID == "" || ttl <= 0 {
+		return ErrInvalidData
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	created, err := sessRepo.client.SetNX(ctx, sessionRecordKey(session.ID), data, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if !created {
+		return ErrSessionExists
+	}
+	if session.ServiceAccountID != 0 {
+		return nil
+	}
+
+	indexKey := userSessionsKey(session.UserID)
+	_, err = sessRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, indexKey, session.ID)
+		pipe.Expire(ctx, indexKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (sessRepo *sessionRepository) StoreSession(ctx context.Context, token string, sessionID string, ttl time.Duration) error {
 	key := sessionKey(token)
-	exists, err := sessRepo.client.Exists(ctx, key).Result()
+	created, err := sessRepo.client.SetNX(ctx, key, sessionID, ttl).Result()
 	if err != nil {
 		return fmt.Errorf("redis error: %w", err)
 	}
-	if exists == 1 {
+	if !created {
 		return ErrSessionExists
 	}
 
-	return sessRepo.client.SetEx(ctx, key, userID, ttl).Err()
+	tokensKey := sessionTokensKey(sessionID)
+	_, err = sessRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, tokensKey, token)
+		pipe.Expire(ctx, tokensKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
 }
 
-func (sessRepo *sessionRepository) GetSession(ctx context.Context, token string) (uint, error) {
-	userID, err := sessRepo.client.Get(ctx, sessionKey(token)).Uint64()
+func (sessRepo *sessionRepository) GetSession(ctx context.Context, token string) (*models.Session, error) {
+	sessionID, err := sessRepo.client.Get(ctx, sessionKey(token)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return 0, ErrSessionNotFound
+			return nil, ErrSessionNotFound
 		}
-		return 0, fmt.Errorf("redis error: %w", err)
+		return nil, fmt.Errorf("redis error: %w", err)
 	}
-	return uint(userID), err
+	return sessRepo.GetSessionByID(ctx, sessionID)
 }
 
-func (sessRepo *sessionRepository) DeleteSession(ctx context.Context, token string) error {
-	return sessRepo.client.Del(ctx, sessionKey(token)).Err()
+func (sessRepo *sessionRepository) GetSessionByID(ctx context.Context, sessionID string) (*models.Session, error) {
+	session, err := sessRepo.loadSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !inScope(ctx, session) {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// loadSession reads the session record regardless of the scope of ctx.
+func (sessRepo *sessionRepository) loadSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	data, err := sessRepo.client.Get(ctx, sessionRecordKey(sessionID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+
+	var session models.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (sessRepo *sessionRepository) ListUserSessions(ctx context.Context, userID uint) ([]*models.Session, error) {
+	indexKey := userSessionsKey(userID)
+	sessionIDs, err := sessRepo.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+
+	sessions := make([]*models.Session, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session, err := sessRepo.loadSession(ctx, sessionID)
+		if errors.Is(err, ErrSessionNotFound) {
+			sessRepo.client.SRem(ctx, indexKey, sessionID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if inScope(ctx, session) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (sessRepo *sessionRepository) TouchSession(ctx context.Context, sessionID string, lastSeen time.Time) error {
+	key := sessionRecordKey(sessionID)
+	err := sessRepo.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+
+		var session models.Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		if !lastSeen.After(session.LastSeen) {
+			return nil
+		}
+		session.LastSeen = lastSeen
+
+		data, err = json.Marshal(&session)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		return err
+	}, key)
+
+	// A concurrent touch already moved last_seen forward.
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return err
+}
+
+func (sessRepo *sessionRepository) DeleteSession(ctx context.Context, sessionID string) error {
+	session, err := sessRepo.loadSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	// Sessions of other organizations are not found, so there is nothing to delete.
+	if session != nil && !inScope(ctx, session) {
+		return nil
+	}
+
+	tokensKey := sessionTokensKey(sessionID)
+	tokens, err := sessRepo.client.SMembers(ctx, tokensKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	keys := make([]string, 0, len(tokens)+2)
+	for _, token := range tokens {
+		keys = append(keys, sessionKey(token))
+	}
+	keys = append(keys, tokensKey, sessionRecordKey(sessionID))
+
+	_, err = sessRepo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		if session != nil && session.ServiceAccountID == 0 {
+			pipe.SRem(ctx, userSessionsKey(session.UserID), sessionID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+func (sessRepo *sessionRepository) DeleteUserSessions(ctx context.Context, userID uint) error {
+	indexKey := userSessionsKey(userID)
+	sessionIDs, err := sessRepo.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := sessRepo.DeleteSession(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+
+	// The index still lists the sessions of other organizations.
+	if _, ok := OrganizationFromContext(ctx); ok {
+		return nil
+	}
+	if err := sessRepo.client.Del(ctx, indexKey).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+// inScope reports whether the session belongs to the organization ctx is
+// scoped to. Without a scope every session is in scope.
+func inScope(ctx context.Context, session *models.Session) bool {
+	organizationID, ok := OrganizationFromContext(ctx)
+	return !ok || session.OrganizationID == organizationID
 }
 
 func sessionKey(token string) string {
 	return "token:" + token
 }
+
+func sessionRecordKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func sessionTokensKey(sessionID string) string {
+	return "session_tokens:" + sessionID
+}
+
+func userSessionsKey(userID uint) string {
+	return "user_sessions:" + strconv.FormatUint(uint64(userID), 10)
+}
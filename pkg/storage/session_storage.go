@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"multitech/internal/models"
 	"time"
 )
 
@@ -12,8 +13,20 @@ var (
 	ErrSessionNotFound = errors.New("Invalid or expired session")
 )
 
+// SessionsRepository is scoped to an organization by a context made with
+// WithOrganization: sessions of other organizations and global sessions are
+// not found, listed or deleted. Creating, binding tokens to and touching
+// sessions is not scoped.
 type SessionsRepository interface {
-	StoreSession(ctx context.Context, token string, userID uint, ttl time.Duration) error
-	GetSession(ctx context.Context, token string) (uint, error)
-	DeleteSession(ctx context.Context, token string) error
+	// CreateSession stores the session record until session.ExpiresAt.
+	CreateSession(ctx context.Context, session *models.Session) error
+	// StoreSession binds an access token to an existing session for ttl.
+	StoreSession(ctx context.Context, token string, sessionID string, ttl time.Duration) error
+	GetSession(ctx context.Context, token string) (*models.Session, error)
+	GetSessionByID(ctx context.Context, sessionID string) (*models.Session, error)
+	ListUserSessions(ctx context.Context, userID uint) ([]*models.Session, error)
+	TouchSession(ctx context.Context, sessionID string, lastSeen time.Time) error
+	// DeleteSession removes the session record and every access token bound to it.
+	DeleteSession(ctx context.Context, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID uint) error
 }
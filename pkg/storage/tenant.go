@@ -0,0 +1,18 @@
+package storage
+
+import "context"
+
+type organizationKey struct{}
+
+// WithOrganization scopes the repositories that support tenants to the
+// organization: users outside of it are not found and its sessions are the
+// only ones listed or deleted.
+func WithOrganization(ctx context.Context, organizationID uint) context.Context {
+	return context.WithValue(ctx, organizationKey{}, organizationID)
+}
+
+// OrganizationFromContext returns the organization ctx is scoped to, if any.
+func OrganizationFromContext(ctx context.Context) (uint, bool) {
+	organizationID, ok := ctx.Value(organizationKey{}).(uint)
+	return organizationID, ok && organizationID != 0
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"multitech/internal/models"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormUserRepository struct {
@@ -17,17 +19,62 @@ func NewGormUserRepository(db *gorm.DB) UserRepository {
 	return &gormUserRepository{db}
 }
 
+func (userRepo *gormUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := userRepo.scoped(ctx).Preload("Roles").Where("id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return &user, err
+}
+
 func (userRepo *gormUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := userRepo.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	err := userRepo.scoped(ctx).Preload("Roles").Where("username = ?", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return &user, err
+}
+
+func (userRepo *gormUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := userRepo.scoped(ctx).Preload("Roles").Where("lower(email) = lower(?)", email).First(&user).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrUserNotFound
 	}
 	return &user, err
 }
 
+func (userRepo *gormUserRepository) GetUserByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
+	var user models.User
+	err := userRepo.scoped(ctx).Preload("Roles").
+		Where("lower(username) = lower(?) OR lower(email) = lower(?)", identifier, identifier).
+		Order(clause.Expr{SQL: "lower(username) = lower(?) DESC", Vars: []interface{}{identifier}}).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	return &user, err
+}
+
+// CreateUser stores the user. Within an organization scope the user joins
+// the organization as a member in the same transaction.
 func (userRepo *gormUserRepository) CreateUser(ctx context.Context, user *models.User) error {
-	err := userRepo.WithContext(ctx).Create(user).Error
+	err := userRepo.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		organizationID, ok := OrganizationFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: organizationID,
+			UserID:         user.ID,
+			Role:           models.OrganizationMemberRole,
+		}).Error
+	})
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
 			return ErrUserExists
@@ -36,3 +83,59 @@ func (userRepo *gormUserRepository) CreateUser(ctx context.Context, user *models
 	}
 	return nil
 }
+
+func (userRepo *gormUserRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	result := userRepo.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"password": passwordHash, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (userRepo *gormUserRepository) SetTOTP(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error {
+	result := userRepo.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"totp_secret":    secret,
+			"totp_enabled":   enabled,
+			"totp_last_step": lastStep,
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (userRepo *gormUserRepository) RecordTOTPStep(ctx context.Context, id uint, step int64) error {
+	result := userRepo.scoped(ctx).Model(&models.User{}).Where("id = ? AND totp_last_step < ?", id, step).
+		Updates(map[string]interface{}{"totp_last_step": step, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTOTPStepUsed
+	}
+	return nil
+}
+
+func (userRepo *gormUserRepository) MarkEmailVerified(ctx context.Context, id uint, at time.Time) error {
+	return userRepo.scoped(ctx).Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", id).
+		Updates(map[string]interface{}{"email_verified_at": at, "updated_at": at}).Error
+}
+
+// scoped restricts queries to the members of the organization ctx is scoped
+// to, if any.
+func (userRepo *gormUserRepository) scoped(ctx context.Context) *gorm.DB {
+	db := userRepo.WithContext(ctx)
+	if organizationID, ok := OrganizationFromContext(ctx); ok {
+		db = db.Where("users.id IN (SELECT user_id FROM organization_members WHERE organization_id = ?)", organizationID)
+	}
+	return db
+}
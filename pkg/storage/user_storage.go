@@ -4,14 +4,35 @@ import (
 	"context"
 	"errors"
 	"multitech/internal/models"
+	"time"
 )
 
 var (
 	ErrUserExists   = errors.New("User already exists")
 	ErrUserNotFound = errors.New("User not found")
+	ErrTOTPStepUsed = errors.New("TOTP code already used")
 )
 
+// UserRepository is scoped to an organization by a context made with
+// WithOrganization: only its members are found and updated.
 type UserRepository interface {
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// GetUserByIdentifier finds the user whose username or email address
+	// matches identifier, ignoring case, in a single lookup. A username match
+	// wins over an email match.
+	GetUserByIdentifier(ctx context.Context, identifier string) (*models.User, error)
 	CreateUser(ctx context.Context, user *models.User) error
+	// UpdatePassword stores a new password hash without touching other columns.
+	UpdatePassword(ctx context.Context, id uint, passwordHash string) error
+	// SetTOTP stores the TOTP secret, whether it is enforced and the last
+	// accepted time step without touching other columns.
+	SetTOTP(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error
+	// RecordTOTPStep stores step as the last accepted time step. It fails with
+	// ErrTOTPStepUsed unless step is later than the stored one, so that of two
+	// concurrent logins with the same code only one succeeds.
+	RecordTOTPStep(ctx context.Context, id uint, step int64) error
+	// MarkEmailVerified sets EmailVerifiedAt unless it is set already.
+	MarkEmailVerified(ctx context.Context, id uint, at time.Time) error
 }
@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockAPIKeyRepository struct {
+	CreateAPIKeyFunc      func(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByPrefixFunc func(ctx context.Context, prefix string) (*models.APIKey, error)
+	ListUserAPIKeysFunc   func(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	DeleteAPIKeyFunc      func(ctx context.Context, userID uint, id uint) error
+	TouchAPIKeyFunc       func(ctx context.Context, id uint, lastUsed time.Time) error
+}
+
+func NewDefaultAPIKeyMock() *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{
+		CreateAPIKeyFunc: func(ctx context.Context, key *models.APIKey) error {
+			key.ID = 1
+			return nil
+		},
+		GetAPIKeyByPrefixFunc: func(ctx context.Context, prefix string) (*models.APIKey, error) {
+			return &models.APIKey{
+				ID:     1,
+				UserID: 1,
+				Name:   "test key",
+				Prefix: prefix,
+			}, nil
+		},
+		ListUserAPIKeysFunc: func(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+			return []*models.APIKey{}, nil
+		},
+		DeleteAPIKeyFunc: func(ctx context.Context, userID uint, id uint) error {
+			return nil
+		},
+		TouchAPIKeyFunc: func(ctx context.Context, id uint, lastUsed time.Time) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return mock.CreateAPIKeyFunc(ctx, key)
+}
+
+func (mock *MockAPIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	return mock.GetAPIKeyByPrefixFunc(ctx, prefix)
+}
+
+func (mock *MockAPIKeyRepository) ListUserAPIKeys(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	return mock.ListUserAPIKeysFunc(ctx, userID)
+}
+
+func (mock *MockAPIKeyRepository) DeleteAPIKey(ctx context.Context, userID uint, id uint) error {
+	return mock.DeleteAPIKeyFunc(ctx, userID, id)
+}
+
+func (mock *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id uint, lastUsed time.Time) error {
+	return mock.TouchAPIKeyFunc(ctx, id, lastUsed)
+}
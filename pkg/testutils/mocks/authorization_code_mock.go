@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockAuthorizationCodeRepository struct {
+	StoreAuthorizationCodeFunc      func(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error
+	ConsumeAuthorizationCodeFunc    func(ctx context.Context, code string) (*models.AuthorizationCode, error)
+	StoreAuthorizationRequestFunc   func(ctx context.Context, challenge string, request *models.AuthorizationRequest, ttl time.Duration) error
+	GetAuthorizationRequestFunc     func(ctx context.Context, challenge string) (*models.AuthorizationRequest, error)
+	ConsumeAuthorizationRequestFunc func(ctx context.Context, challenge string) (*models.AuthorizationRequest, error)
+}
+
+func defaultAuthorizationRequest() *models.AuthorizationRequest {
+	return &models.AuthorizationRequest{
+		ClientID:            "client",
+		ClientName:          "Client",
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "openid",
+		State:               "xyz",
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func NewDefaultAuthorizationCodeMock() *MockAuthorizationCodeRepository {
+	return &MockAuthorizationCodeRepository{
+		StoreAuthorizationCodeFunc: func(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error {
+			return nil
+		},
+		ConsumeAuthorizationCodeFunc: func(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+			return &models.AuthorizationCode{
+				ClientID:    "client",
+				UserID:      1,
+				RedirectURI: "https://app.example.com/callback",
+				Scope:       "openid",
+				AuthTime:    time.Now(),
+			}, nil
+		},
+		StoreAuthorizationRequestFunc: func(ctx context.Context, challenge string, request *models.AuthorizationRequest, ttl time.Duration) error {
+			return nil
+		},
+		GetAuthorizationRequestFunc: func(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+			return defaultAuthorizationRequest(), nil
+		},
+		ConsumeAuthorizationRequestFunc: func(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+			return defaultAuthorizationRequest(), nil
+		},
+	}
+}
+
+func (mock *MockAuthorizationCodeRepository) StoreAuthorizationCode(ctx context.Context, code string, authCode *models.AuthorizationCode, ttl time.Duration) error {
+	return mock.StoreAuthorizationCodeFunc(ctx, code, authCode, ttl)
+}
+
+func (mock *MockAuthorizationCodeRepository) ConsumeAuthorizationCode(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+	return mock.ConsumeAuthorizationCodeFunc(ctx, code)
+}
+
+func (mock *MockAuthorizationCodeRepository) StoreAuthorizationRequest(ctx context.Context, challenge string, request *models.AuthorizationRequest, ttl time.Duration) error {
+	return mock.StoreAuthorizationRequestFunc(ctx, challenge, request, ttl)
+}
+
+func (mock *MockAuthorizationCodeRepository) GetAuthorizationRequest(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+	return mock.GetAuthorizationRequestFunc(ctx, challenge)
+}
+
+func (mock *MockAuthorizationCodeRepository) ConsumeAuthorizationRequest(ctx context.Context, challenge string) (*models.AuthorizationRequest, error) {
+	return mock.ConsumeAuthorizationRequestFunc(ctx, challenge)
+}
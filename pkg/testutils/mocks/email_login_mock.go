@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockEmailLoginRepository struct {
+	CreateEmailLoginChallengeFunc func(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error
+	GetEmailLoginChallengeFunc    func(ctx context.Context, token string) (*models.EmailLoginChallenge, error)
+	RecordEmailLoginAttemptFunc   func(ctx context.Context, token string) (int, error)
+	DeleteEmailLoginChallengeFunc func(ctx context.Context, token string) error
+	ThrottleEmailLoginFunc        func(ctx context.Context, email string, interval time.Duration) (time.Duration, error)
+}
+
+func NewDefaultEmailLoginMock() *MockEmailLoginRepository {
+	return &MockEmailLoginRepository{
+		CreateEmailLoginChallengeFunc: func(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error {
+			return nil
+		},
+		GetEmailLoginChallengeFunc: func(ctx context.Context, token string) (*models.EmailLoginChallenge, error) {
+			return &models.EmailLoginChallenge{UserID: 1, Method: models.EmailLoginMethodLink}, nil
+		},
+		RecordEmailLoginAttemptFunc: func(ctx context.Context, token string) (int, error) {
+			return 1, nil
+		},
+		DeleteEmailLoginChallengeFunc: func(ctx context.Context, token string) error {
+			return nil
+		},
+		ThrottleEmailLoginFunc: func(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+			return 0, nil
+		},
+	}
+}
+
+func (mock *MockEmailLoginRepository) CreateEmailLoginChallenge(ctx context.Context, token string, challenge *models.EmailLoginChallenge, ttl time.Duration) error {
+	return mock.CreateEmailLoginChallengeFunc(ctx, token, challenge, ttl)
+}
+
+func (mock *MockEmailLoginRepository) GetEmailLoginChallenge(ctx context.Context, token string) (*models.EmailLoginChallenge, error) {
+	return mock.GetEmailLoginChallengeFunc(ctx, token)
+}
+
+func (mock *MockEmailLoginRepository) RecordEmailLoginAttempt(ctx context.Context, token string) (int, error) {
+	return mock.RecordEmailLoginAttemptFunc(ctx, token)
+}
+
+func (mock *MockEmailLoginRepository) DeleteEmailLoginChallenge(ctx context.Context, token string) error {
+	return mock.DeleteEmailLoginChallengeFunc(ctx, token)
+}
+
+func (mock *MockEmailLoginRepository) ThrottleEmailLogin(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+	return mock.ThrottleEmailLoginFunc(ctx, email, interval)
+}
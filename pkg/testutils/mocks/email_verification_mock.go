@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+	"time"
+)
+
+type MockEmailVerificationRepository struct {
+	StoreEmailVerificationFunc    func(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error
+	ConsumeEmailVerificationFunc  func(ctx context.Context, tokenID string) (uint, error)
+	ThrottleEmailVerificationFunc func(ctx context.Context, email string, interval time.Duration) (time.Duration, error)
+}
+
+func NewDefaultEmailVerificationMock() *MockEmailVerificationRepository {
+	return &MockEmailVerificationRepository{
+		StoreEmailVerificationFunc: func(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error {
+			return nil
+		},
+		ConsumeEmailVerificationFunc: func(ctx context.Context, tokenID string) (uint, error) {
+			return 1, nil
+		},
+		ThrottleEmailVerificationFunc: func(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+			return 0, nil
+		},
+	}
+}
+
+func (mock *MockEmailVerificationRepository) StoreEmailVerification(ctx context.Context, tokenID string, userID uint, ttl time.Duration) error {
+	return mock.StoreEmailVerificationFunc(ctx, tokenID, userID, ttl)
+}
+
+func (mock *MockEmailVerificationRepository) ConsumeEmailVerification(ctx context.Context, tokenID string) (uint, error) {
+	return mock.ConsumeEmailVerificationFunc(ctx, tokenID)
+}
+
+func (mock *MockEmailVerificationRepository) ThrottleEmailVerification(ctx context.Context, email string, interval time.Duration) (time.Duration, error) {
+	return mock.ThrottleEmailVerificationFunc(ctx, email, interval)
+}
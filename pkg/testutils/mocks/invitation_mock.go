@@ -0,0 +1,56 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockInvitationRepository struct {
+	CreateInvitationFunc func(ctx context.Context, invitation *models.Invitation) error
+	ListInvitationsFunc  func(ctx context.Context) ([]models.Invitation, error)
+	DeleteInvitationFunc func(ctx context.Context, id uint) error
+	AcceptInvitationFunc func(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error)
+}
+
+func NewDefaultInvitationMock() *MockInvitationRepository {
+	return &MockInvitationRepository{
+		CreateInvitationFunc: func(ctx context.Context, invitation *models.Invitation) error {
+			invitation.ID = 1
+			return nil
+		},
+		ListInvitationsFunc: func(ctx context.Context) ([]models.Invitation, error) {
+			return nil, nil
+		},
+		DeleteInvitationFunc: func(ctx context.Context, id uint) error {
+			return nil
+		},
+		AcceptInvitationFunc: func(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+			user.ID = 1
+			now := time.Now()
+			return &models.Invitation{
+				ID:        1,
+				Email:     user.Email,
+				ExpiresAt: now.Add(time.Hour),
+				UsedAt:    &now,
+				UserID:    &user.ID,
+			}, nil
+		},
+	}
+}
+
+func (mock *MockInvitationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	return mock.CreateInvitationFunc(ctx, invitation)
+}
+
+func (mock *MockInvitationRepository) ListInvitations(ctx context.Context) ([]models.Invitation, error) {
+	return mock.ListInvitationsFunc(ctx)
+}
+
+func (mock *MockInvitationRepository) DeleteInvitation(ctx context.Context, id uint) error {
+	return mock.DeleteInvitationFunc(ctx, id)
+}
+
+func (mock *MockInvitationRepository) AcceptInvitation(ctx context.Context, tokenHash string, user *models.User) (*models.Invitation, error) {
+	return mock.AcceptInvitationFunc(ctx, tokenHash, user)
+}
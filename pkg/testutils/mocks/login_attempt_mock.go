@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"context"
+	"time"
+)
+
+type MockLoginAttemptRepository struct {
+	RecordLoginFailureFunc func(ctx context.Context, key string, window time.Duration) (int, error)
+	ResetLoginFailuresFunc func(ctx context.Context, key string) error
+	BlockLoginFunc         func(ctx context.Context, key string, reason string, duration time.Duration) error
+	GetLoginBlockFunc      func(ctx context.Context, key string) (string, time.Duration, error)
+	UnblockLoginFunc       func(ctx context.Context, key string) error
+}
+
+func NewDefaultLoginAttemptMock() *MockLoginAttemptRepository {
+	return &MockLoginAttemptRepository{
+		RecordLoginFailureFunc: func(ctx context.Context, key string, window time.Duration) (int, error) {
+			return 1, nil
+		},
+		ResetLoginFailuresFunc: func(ctx context.Context, key string) error {
+			return nil
+		},
+		BlockLoginFunc: func(ctx context.Context, key string, reason string, duration time.Duration) error {
+			return nil
+		},
+		GetLoginBlockFunc: func(ctx context.Context, key string) (string, time.Duration, error) {
+			return "", 0, nil
+		},
+		UnblockLoginFunc: func(ctx context.Context, key string) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockLoginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	return mock.RecordLoginFailureFunc(ctx, key, window)
+}
+
+func (mock *MockLoginAttemptRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	return mock.ResetLoginFailuresFunc(ctx, key)
+}
+
+func (mock *MockLoginAttemptRepository) BlockLogin(ctx context.Context, key string, reason string, duration time.Duration) error {
+	return mock.BlockLoginFunc(ctx, key, reason, duration)
+}
+
+func (mock *MockLoginAttemptRepository) GetLoginBlock(ctx context.Context, key string) (string, time.Duration, error) {
+	return mock.GetLoginBlockFunc(ctx, key)
+}
+
+func (mock *MockLoginAttemptRepository) UnblockLogin(ctx context.Context, key string) error {
+	return mock.UnblockLoginFunc(ctx, key)
+}
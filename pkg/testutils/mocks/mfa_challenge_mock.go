@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockMFAChallengeRepository struct {
+	CreateMFAChallengeFunc func(ctx context.Context, token string, challenge *models.MFAChallenge, ttl time.Duration) error
+	GetMFAChallengeFunc    func(ctx context.Context, token string) (*models.MFAChallenge, error)
+	RecordAttemptFunc      func(ctx context.Context, token string) (int, error)
+	DeleteMFAChallengeFunc func(ctx context.Context, token string) error
+}
+
+func NewDefaultMFAChallengeMock() *MockMFAChallengeRepository {
+	return &MockMFAChallengeRepository{
+		CreateMFAChallengeFunc: func(ctx context.Context, token string, challenge *models.MFAChallenge, ttl time.Duration) error {
+			return nil
+		},
+		GetMFAChallengeFunc: func(ctx context.Context, token string) (*models.MFAChallenge, error) {
+			return &models.MFAChallenge{UserID: 1}, nil
+		},
+		RecordAttemptFunc: func(ctx context.Context, token string) (int, error) {
+			return 1, nil
+		},
+		DeleteMFAChallengeFunc: func(ctx context.Context, token string) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockMFAChallengeRepository) CreateMFAChallenge(ctx context.Context, token string, challenge *models.MFAChallenge, ttl time.Duration) error {
+	return mock.CreateMFAChallengeFunc(ctx, token, challenge, ttl)
+}
+
+func (mock *MockMFAChallengeRepository) GetMFAChallenge(ctx context.Context, token string) (*models.MFAChallenge, error) {
+	return mock.GetMFAChallengeFunc(ctx, token)
+}
+
+func (mock *MockMFAChallengeRepository) RecordAttempt(ctx context.Context, token string) (int, error) {
+	return mock.RecordAttemptFunc(ctx, token)
+}
+
+func (mock *MockMFAChallengeRepository) DeleteMFAChallenge(ctx context.Context, token string) error {
+	return mock.DeleteMFAChallengeFunc(ctx, token)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+)
+
+type MockOAuthClientRepository struct {
+	GetClientByClientIDFunc func(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	CreateClientFunc        func(ctx context.Context, client *models.OAuthClient) error
+}
+
+func NewDefaultOAuthClientMock() *MockOAuthClientRepository {
+	return &MockOAuthClientRepository{
+		GetClientByClientIDFunc: func(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+			return &models.OAuthClient{
+				ID:           1,
+				ClientID:     clientID,
+				Name:         "Test client",
+				RedirectURIs: []string{"https://app.example.com/callback"},
+			}, nil
+		},
+		CreateClientFunc: func(ctx context.Context, client *models.OAuthClient) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockOAuthClientRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	return mock.GetClientByClientIDFunc(ctx, clientID)
+}
+
+func (mock *MockOAuthClientRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	return mock.CreateClientFunc(ctx, client)
+}
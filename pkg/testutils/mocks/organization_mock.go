@@ -0,0 +1,116 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+)
+
+type MockOrganizationRepository struct {
+	CreateOrganizationFunc      func(ctx context.Context, organization *models.Organization, ownerID uint) error
+	GetOrganizationFunc         func(ctx context.Context, id uint) (*models.Organization, error)
+	ListUserOrganizationsFunc   func(ctx context.Context, userID uint) ([]models.OrganizationMember, error)
+	GetMembershipFunc           func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error)
+	ListMembersFunc             func(ctx context.Context, organizationID uint) ([]models.OrganizationMember, error)
+	SetMemberRoleFunc           func(ctx context.Context, organizationID uint, userID uint, role string) error
+	RemoveMemberFunc            func(ctx context.Context, organizationID uint, userID uint) error
+	InviteMemberFunc            func(ctx context.Context, invitation *models.OrganizationInvitation) error
+	ListUserInvitationsFunc     func(ctx context.Context, userID uint) ([]models.OrganizationInvitation, error)
+	AcceptMemberInvitationFunc  func(ctx context.Context, id uint, userID uint) (*models.OrganizationMember, error)
+	DeclineMemberInvitationFunc func(ctx context.Context, id uint, userID uint) error
+}
+
+func NewDefaultOrganizationMock() *MockOrganizationRepository {
+	return &MockOrganizationRepository{
+		CreateOrganizationFunc: func(ctx context.Context, organization *models.Organization, ownerID uint) error {
+			organization.ID = 1
+			return nil
+		},
+		GetOrganizationFunc: func(ctx context.Context, id uint) (*models.Organization, error) {
+			return &models.Organization{
+				ID:   id,
+				Slug: "acme",
+				Name: "Acme",
+			}, nil
+		},
+		ListUserOrganizationsFunc: func(ctx context.Context, userID uint) ([]models.OrganizationMember, error) {
+			return nil, nil
+		},
+		GetMembershipFunc: func(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+			return &models.OrganizationMember{
+				OrganizationID: organizationID,
+				UserID:         userID,
+				Role:           models.OrganizationMemberRole,
+			}, nil
+		},
+		ListMembersFunc: func(ctx context.Context, organizationID uint) ([]models.OrganizationMember, error) {
+			return nil, nil
+		},
+		SetMemberRoleFunc: func(ctx context.Context, organizationID uint, userID uint, role string) error {
+			return nil
+		},
+		RemoveMemberFunc: func(ctx context.Context, organizationID uint, userID uint) error {
+			return nil
+		},
+		InviteMemberFunc: func(ctx context.Context, invitation *models.OrganizationInvitation) error {
+			invitation.ID = 1
+			return nil
+		},
+		ListUserInvitationsFunc: func(ctx context.Context, userID uint) ([]models.OrganizationInvitation, error) {
+			return nil, nil
+		},
+		AcceptMemberInvitationFunc: func(ctx context.Context, id uint, userID uint) (*models.OrganizationMember, error) {
+			return &models.OrganizationMember{
+				OrganizationID: 1,
+				UserID:         userID,
+				Role:           models.OrganizationMemberRole,
+			}, nil
+		},
+		DeclineMemberInvitationFunc: func(ctx context.Context, id uint, userID uint) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockOrganizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization, ownerID uint) error {
+	return mock.CreateOrganizationFunc(ctx, organization, ownerID)
+}
+
+func (mock *MockOrganizationRepository) GetOrganization(ctx context.Context, id uint) (*models.Organization, error) {
+	return mock.GetOrganizationFunc(ctx, id)
+}
+
+func (mock *MockOrganizationRepository) ListUserOrganizations(ctx context.Context, userID uint) ([]models.OrganizationMember, error) {
+	return mock.ListUserOrganizationsFunc(ctx, userID)
+}
+
+func (mock *MockOrganizationRepository) GetMembership(ctx context.Context, organizationID uint, userID uint) (*models.OrganizationMember, error) {
+	return mock.GetMembershipFunc(ctx, organizationID, userID)
+}
+
+func (mock *MockOrganizationRepository) ListMembers(ctx context.Context, organizationID uint) ([]models.OrganizationMember, error) {
+	return mock.ListMembersFunc(ctx, organizationID)
+}
+
+func (mock *MockOrganizationRepository) SetMemberRole(ctx context.Context, organizationID uint, userID uint, role string) error {
+	return mock.SetMemberRoleFunc(ctx, organizationID, userID, role)
+}
+
+func (mock *MockOrganizationRepository) RemoveMember(ctx context.Context, organizationID uint, userID uint) error {
+	return mock.RemoveMemberFunc(ctx, organizationID, userID)
+}
+
+func (mock *MockOrganizationRepository) InviteMember(ctx context.Context, invitation *models.OrganizationInvitation) error {
+	return mock.InviteMemberFunc(ctx, invitation)
+}
+
+func (mock *MockOrganizationRepository) ListUserInvitations(ctx context.Context, userID uint) ([]models.OrganizationInvitation, error) {
+	return mock.ListUserInvitationsFunc(ctx, userID)
+}
+
+func (mock *MockOrganizationRepository) AcceptMemberInvitation(ctx context.Context, id uint, userID uint) (*models.OrganizationMember, error) {
+	return mock.AcceptMemberInvitationFunc(ctx, id, userID)
+}
+
+func (mock *MockOrganizationRepository) DeclineMemberInvitation(ctx context.Context, id uint, userID uint) error {
+	return mock.DeclineMemberInvitationFunc(ctx, id, userID)
+}
@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"time"
+)
+
+type MockPasswordResetRepository struct {
+	StorePasswordResetFunc    func(ctx context.Context, token string, userID uint, ttl time.Duration) error
+	GetPasswordResetFunc      func(ctx context.Context, token string) (uint, error)
+	ConsumePasswordResetFunc  func(ctx context.Context, token string) (uint, error)
+	ThrottlePasswordResetFunc func(ctx context.Context, email string, interval time.Duration) (bool, error)
+}
+
+func NewDefaultPasswordResetMock() *MockPasswordResetRepository {
+	return &MockPasswordResetRepository{
+		StorePasswordResetFunc: func(ctx context.Context, token string, userID uint, ttl time.Duration) error {
+			return nil
+		},
+		GetPasswordResetFunc: func(ctx context.Context, token string) (uint, error) {
+			return 1, nil
+		},
+		ConsumePasswordResetFunc: func(ctx context.Context, token string) (uint, error) {
+			return 1, nil
+		},
+		ThrottlePasswordResetFunc: func(ctx context.Context, email string, interval time.Duration) (bool, error) {
+			return true, nil
+		},
+	}
+}
+
+func (mock *MockPasswordResetRepository) StorePasswordReset(ctx context.Context, token string, userID uint, ttl time.Duration) error {
+	return mock.StorePasswordResetFunc(ctx, token, userID, ttl)
+}
+
+func (mock *MockPasswordResetRepository) GetPasswordReset(ctx context.Context, token string) (uint, error) {
+	return mock.GetPasswordResetFunc(ctx, token)
+}
+
+func (mock *MockPasswordResetRepository) ConsumePasswordReset(ctx context.Context, token string) (uint, error) {
+	return mock.ConsumePasswordResetFunc(ctx, token)
+}
+
+func (mock *MockPasswordResetRepository) ThrottlePasswordReset(ctx context.Context, email string, interval time.Duration) (bool, error) {
+	return mock.ThrottlePasswordResetFunc(ctx, email, interval)
+}
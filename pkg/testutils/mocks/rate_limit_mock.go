@@ -0,0 +1,23 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockRateLimitRepository struct {
+	AllowRequestFunc func(ctx context.Context, key string, limit int, period time.Duration) (*models.RateLimitResult, error)
+}
+
+func NewDefaultRateLimitMock() *MockRateLimitRepository {
+	return &MockRateLimitRepository{
+		AllowRequestFunc: func(ctx context.Context, key string, limit int, period time.Duration) (*models.RateLimitResult, error) {
+			return &models.RateLimitResult{Allowed: true, Remaining: limit - 1, ResetAfter: period / time.Duration(limit)}, nil
+		},
+	}
+}
+
+func (mock *MockRateLimitRepository) AllowRequest(ctx context.Context, key string, limit int, period time.Duration) (*models.RateLimitResult, error) {
+	return mock.AllowRequestFunc(ctx, key, limit, period)
+}
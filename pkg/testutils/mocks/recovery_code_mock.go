@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+)
+
+type MockRecoveryCodeRepository struct {
+	ReplaceRecoveryCodesFunc     func(ctx context.Context, userID uint, codes []*models.RecoveryCode) error
+	ConsumeRecoveryCodeFunc      func(ctx context.Context, userID uint, codeHash string) error
+	CheckRecoveryCodeFunc        func(ctx context.Context, userID uint, codeHash string) error
+	CountUnusedRecoveryCodesFunc func(ctx context.Context, userID uint) (int64, error)
+}
+
+func NewDefaultRecoveryCodeMock() *MockRecoveryCodeRepository {
+	return &MockRecoveryCodeRepository{
+		ReplaceRecoveryCodesFunc: func(ctx context.Context, userID uint, codes []*models.RecoveryCode) error {
+			return nil
+		},
+		ConsumeRecoveryCodeFunc: func(ctx context.Context, userID uint, codeHash string) error {
+			return nil
+		},
+		CheckRecoveryCodeFunc: func(ctx context.Context, userID uint, codeHash string) error {
+			return nil
+		},
+		CountUnusedRecoveryCodesFunc: func(ctx context.Context, userID uint) (int64, error) {
+			return 0, nil
+		},
+	}
+}
+
+func (mock *MockRecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []*models.RecoveryCode) error {
+	return mock.ReplaceRecoveryCodesFunc(ctx, userID, codes)
+}
+
+func (mock *MockRecoveryCodeRepository) ConsumeRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	return mock.ConsumeRecoveryCodeFunc(ctx, userID, codeHash)
+}
+
+func (mock *MockRecoveryCodeRepository) CheckRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	return mock.CheckRecoveryCodeFunc(ctx, userID, codeHash)
+}
+
+func (mock *MockRecoveryCodeRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	return mock.CountUnusedRecoveryCodesFunc(ctx, userID)
+}
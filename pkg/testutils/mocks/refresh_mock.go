@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"time"
+)
+
+type MockRefreshTokenRepository struct {
+	StoreRefreshTokenFunc       func(ctx context.Context, token string, refresh *models.RefreshToken, ttl time.Duration) error
+	GetRefreshTokenFunc         func(ctx context.Context, token string) (*models.RefreshToken, error)
+	RotateRefreshTokenFunc      func(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error)
+	RevokeFamilyFunc            func(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokensFunc func(ctx context.Context, userID uint) error
+}
+
+func NewDefaultRefreshTokenMock() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{
+		StoreRefreshTokenFunc: func(ctx context.Context, token string, refresh *models.RefreshToken, ttl time.Duration) error {
+			return nil
+		},
+		GetRefreshTokenFunc: func(ctx context.Context, token string) (*models.RefreshToken, error) {
+			return &models.RefreshToken{
+				UserID:   1,
+				FamilyID: "family",
+			}, nil
+		},
+		RotateRefreshTokenFunc: func(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+			return &models.RefreshToken{
+				UserID:   1,
+				FamilyID: "family",
+			}, nil
+		},
+		RevokeFamilyFunc: func(ctx context.Context, familyID string) error {
+			return nil
+		},
+		RevokeUserRefreshTokensFunc: func(ctx context.Context, userID uint) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockRefreshTokenRepository) StoreRefreshToken(ctx context.Context, token string, refresh *models.RefreshToken, ttl time.Duration) error {
+	return mock.StoreRefreshTokenFunc(ctx, token, refresh, ttl)
+}
+
+func (mock *MockRefreshTokenRepository) GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	return mock.GetRefreshTokenFunc(ctx, token)
+}
+
+func (mock *MockRefreshTokenRepository) RotateRefreshToken(ctx context.Context, oldToken string, newToken string, ttl time.Duration) (*models.RefreshToken, error) {
+	return mock.RotateRefreshTokenFunc(ctx, oldToken, newToken, ttl)
+}
+
+func (mock *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return mock.RevokeFamilyFunc(ctx, familyID)
+}
+
+func (mock *MockRefreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint) error {
+	return mock.RevokeUserRefreshTokensFunc(ctx, userID)
+}
@@ -0,0 +1,87 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+	"multitech/pkg/storage"
+)
+
+type MockRelationTupleRepository struct {
+	WriteTuplesFunc   func(ctx context.Context, tuples []models.RelationTuple) error
+	DeleteTuplesFunc  func(ctx context.Context, tuples []models.RelationTuple) error
+	ReadTuplesFunc    func(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error)
+	ListObjectIDsFunc func(ctx context.Context, objectType string) ([]string, error)
+}
+
+func NewDefaultRelationTupleMock() *MockRelationTupleRepository {
+	return &MockRelationTupleRepository{
+		WriteTuplesFunc: func(ctx context.Context, tuples []models.RelationTuple) error {
+			return nil
+		},
+		DeleteTuplesFunc: func(ctx context.Context, tuples []models.RelationTuple) error {
+			return nil
+		},
+		ReadTuplesFunc: func(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+			return nil, nil
+		},
+		ListObjectIDsFunc: func(ctx context.Context, objectType string) ([]string, error) {
+			return nil, nil
+		},
+	}
+}
+
+// NewRelationTupleMock serves ReadTuples and ListObjectIDs from tuples.
+func NewRelationTupleMock(tuples ...models.RelationTuple) *MockRelationTupleRepository {
+	mock := NewDefaultRelationTupleMock()
+	mock.ReadTuplesFunc = func(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+		var matching []models.RelationTuple
+		for _, tuple := range tuples {
+			if tupleMatches(tuple, filter) {
+				matching = append(matching, tuple)
+			}
+		}
+		return matching, nil
+	}
+	mock.ListObjectIDsFunc = func(ctx context.Context, objectType string) ([]string, error) {
+		var ids []string
+		seen := map[string]bool{}
+		for _, tuple := range tuples {
+			if tuple.ObjectType == objectType && !seen[tuple.ObjectID] {
+				seen[tuple.ObjectID] = true
+				ids = append(ids, tuple.ObjectID)
+			}
+		}
+		return ids, nil
+	}
+	return mock
+}
+
+func tupleMatches(tuple models.RelationTuple, filter storage.RelationTupleFilter) bool {
+	matches := func(value string, want string) bool {
+		return want == "" || value == want
+	}
+	if filter.SubjectType != "" && tuple.SubjectRelation != filter.SubjectRelation {
+		return false
+	}
+	return matches(tuple.ObjectType, filter.ObjectType) &&
+		matches(tuple.ObjectID, filter.ObjectID) &&
+		matches(tuple.Relation, filter.Relation) &&
+		matches(tuple.SubjectType, filter.SubjectType) &&
+		matches(tuple.SubjectID, filter.SubjectID)
+}
+
+func (mock *MockRelationTupleRepository) WriteTuples(ctx context.Context, tuples []models.RelationTuple) error {
+	return mock.WriteTuplesFunc(ctx, tuples)
+}
+
+func (mock *MockRelationTupleRepository) DeleteTuples(ctx context.Context, tuples []models.RelationTuple) error {
+	return mock.DeleteTuplesFunc(ctx, tuples)
+}
+
+func (mock *MockRelationTupleRepository) ReadTuples(ctx context.Context, filter storage.RelationTupleFilter) ([]models.RelationTuple, error) {
+	return mock.ReadTuplesFunc(ctx, filter)
+}
+
+func (mock *MockRelationTupleRepository) ListObjectIDs(ctx context.Context, objectType string) ([]string, error) {
+	return mock.ListObjectIDsFunc(ctx, objectType)
+}
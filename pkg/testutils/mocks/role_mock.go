@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+)
+
+type MockRoleRepository struct {
+	CreateRoleFunc   func(ctx context.Context, role *models.Role) error
+	ListRolesFunc    func(ctx context.Context) ([]models.Role, error)
+	GetUserRolesFunc func(ctx context.Context, userID uint) ([]models.Role, error)
+	AssignRoleFunc   func(ctx context.Context, userID uint, roleName string) error
+	RevokeRoleFunc   func(ctx context.Context, userID uint, roleName string) error
+}
+
+func NewDefaultRoleMock() *MockRoleRepository {
+	return &MockRoleRepository{
+		CreateRoleFunc: func(ctx context.Context, role *models.Role) error {
+			return nil
+		},
+		ListRolesFunc: func(ctx context.Context) ([]models.Role, error) {
+			return nil, nil
+		},
+		GetUserRolesFunc: func(ctx context.Context, userID uint) ([]models.Role, error) {
+			return nil, nil
+		},
+		AssignRoleFunc: func(ctx context.Context, userID uint, roleName string) error {
+			return nil
+		},
+		RevokeRoleFunc: func(ctx context.Context, userID uint, roleName string) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockRoleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	return mock.CreateRoleFunc(ctx, role)
+}
+
+func (mock *MockRoleRepository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return mock.ListRolesFunc(ctx)
+}
+
+func (mock *MockRoleRepository) GetUserRoles(ctx context.Context, userID uint) ([]models.Role, error) {
+	return mock.GetUserRolesFunc(ctx, userID)
+}
+
+func (mock *MockRoleRepository) AssignRole(ctx context.Context, userID uint, roleName string) error {
+	return mock.AssignRoleFunc(ctx, userID, roleName)
+}
+
+func (mock *MockRoleRepository) RevokeRole(ctx context.Context, userID uint, roleName string) error {
+	return mock.RevokeRoleFunc(ctx, userID, roleName)
+}
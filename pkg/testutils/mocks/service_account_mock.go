@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"context"
+	"multitech/internal/models"
+)
+
+type MockServiceAccountRepository struct {
+	GetServiceAccountByClientIDFunc func(ctx context.Context, clientID string) (*models.ServiceAccount, error)
+	CreateServiceAccountFunc        func(ctx context.Context, account *models.ServiceAccount) error
+}
+
+func NewDefaultServiceAccountMock() *MockServiceAccountRepository {
+	return &MockServiceAccountRepository{
+		GetServiceAccountByClientIDFunc: func(ctx context.Context, clientID string) (*models.ServiceAccount, error) {
+			account := &models.ServiceAccount{
+				ID:           7,
+				ClientID:     clientID,
+				ClientSecret: "service-secret",
+				Name:         "Test service",
+			}
+			if err := account.HashSecret(); err != nil {
+				return nil, err
+			}
+			return account, nil
+		},
+		CreateServiceAccountFunc: func(ctx context.Context, account *models.ServiceAccount) error {
+			return nil
+		},
+	}
+}
+
+func (mock *MockServiceAccountRepository) GetServiceAccountByClientID(ctx context.Context, clientID string) (*models.ServiceAccount, error) {
+	return mock.GetServiceAccountByClientIDFunc(ctx, clientID)
+}
+
+func (mock *MockServiceAccountRepository) CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	return mock.CreateServiceAccountFunc(ctx, account)
+}
@@ -2,35 +2,79 @@ package mocks
 
 import (
 	"context"
+	"multitech/internal/models"
 	"time"
 )
 
 type MockSessionsRepository struct {
-	StoreSessionFunc  func(ctx context.Context, token string, userID uint, duration time.Duration) error
-	GetSessionFunc    func(ctx context.Context, token string) (uint, error)
-	DeleteSessionFunc func(ctx context.Context, token string) error
+	CreateSessionFunc      func(ctx context.Context, session *models.Session) error
+	StoreSessionFunc       func(ctx context.Context, token string, sessionID string, duration time.Duration) error
+	GetSessionFunc         func(ctx context.Context, token string) (*models.Session, error)
+	GetSessionByIDFunc     func(ctx context.Context, sessionID string) (*models.Session, error)
+	ListUserSessionsFunc   func(ctx context.Context, userID uint) ([]*models.Session, error)
+	TouchSessionFunc       func(ctx context.Context, sessionID string, lastSeen time.Time) error
+	DeleteSessionFunc      func(ctx context.Context, sessionID string) error
+	DeleteUserSessionsFunc func(ctx context.Context, userID uint) error
 }
 
 func NewDefaultSessionsMock() *MockSessionsRepository {
 	return &MockSessionsRepository{
-		StoreSessionFunc: func(ctx context.Context, token string, userID uint, duration time.Duration) error {
+		CreateSessionFunc: func(ctx context.Context, session *models.Session) error {
 			return nil
 		},
-		GetSessionFunc: func(ctx context.Context, token string) (uint, error) {
-			return 1, nil
+		StoreSessionFunc: func(ctx context.Context, token string, sessionID string, duration time.Duration) error {
+			return nil
+		},
+		GetSessionFunc: func(ctx context.Context, token string) (*models.Session, error) {
+			return &models.Session{
+				ID:       "session",
+				UserID:   1,
+				LastSeen: time.Now(),
+			}, nil
+		},
+		GetSessionByIDFunc: func(ctx context.Context, sessionID string) (*models.Session, error) {
+			return &models.Session{
+				ID:       sessionID,
+				UserID:   1,
+				LastSeen: time.Now(),
+			}, nil
+		},
+		ListUserSessionsFunc: func(ctx context.Context, userID uint) ([]*models.Session, error) {
+			return []*models.Session{}, nil
 		},
-		DeleteSessionFunc: func(ctx context.Context, token string) error {
+		TouchSessionFunc: func(ctx context.Context, sessionID string, lastSeen time.Time) error {
+			return nil
+		},
+		DeleteSessionFunc: func(ctx context.Context, sessionID string) error {
+			return nil
+		},
+		DeleteUserSessionsFunc: func(ctx context.Context, userID uint) error {
 			return nil
 		},
 	}
 }
 
-func (mock *MockSessionsRepository) StoreSession(ctx context.Context, token string, userID uint, duration time.Duration) error {
-	return mock.StoreSessionFunc(ctx, token, userID, duration)
+func (mock *MockSessionsRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	return mock.CreateSessionFunc(ctx, session)
+}
+func (mock *MockSessionsRepository) StoreSession(ctx context.Context, token string, sessionID string, duration time.Duration) error {
+	return mock.StoreSessionFunc(ctx, token, sessionID, duration)
 }
-func (mock *MockSessionsRepository) GetSession(ctx context.Context, token string) (uint, error) {
+func (mock *MockSessionsRepository) GetSession(ctx context.Context, token string) (*models.Session, error) {
 	return mock.GetSessionFunc(ctx, token)
 }
-func (mock *MockSessionsRepository) DeleteSession(ctx context.Context, token string) error {
-	return mock.DeleteSessionFunc(ctx, token)
+func (mock *MockSessionsRepository) GetSessionByID(ctx context.Context, sessionID string) (*models.Session, error) {
+	return mock.GetSessionByIDFunc(ctx, sessionID)
+}
+func (mock *MockSessionsRepository) ListUserSessions(ctx context.Context, userID uint) ([]*models.Session, error) {
+	return mock.ListUserSessionsFunc(ctx, userID)
+}
+func (mock *MockSessionsRepository) TouchSession(ctx context.Context, sessionID string, lastSeen time.Time) error {
+	return mock.TouchSessionFunc(ctx, sessionID, lastSeen)
+}
+func (mock *MockSessionsRepository) DeleteSession(ctx context.Context, sessionID string) error {
+	return mock.DeleteSessionFunc(ctx, sessionID)
+}
+func (mock *MockSessionsRepository) DeleteUserSessions(ctx context.Context, userID uint) error {
+	return mock.DeleteUserSessionsFunc(ctx, userID)
 }
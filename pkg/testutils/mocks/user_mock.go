@@ -3,15 +3,31 @@ package mocks
 import (
 	"context"
 	"multitech/internal/models"
+	"time"
 )
 
 type MockUserRepository struct {
-	GetUserByUsernameFunc func(ctx context.Context, username string) (*models.User, error)
-	CreateUserFunc        func(ctx context.Context, user *models.User) error
+	GetUserByIDFunc         func(ctx context.Context, id uint) (*models.User, error)
+	GetUserByUsernameFunc   func(ctx context.Context, username string) (*models.User, error)
+	GetUserByEmailFunc      func(ctx context.Context, email string) (*models.User, error)
+	GetUserByIdentifierFunc func(ctx context.Context, identifier string) (*models.User, error)
+	CreateUserFunc          func(ctx context.Context, user *models.User) error
+	UpdatePasswordFunc      func(ctx context.Context, id uint, passwordHash string) error
+	SetTOTPFunc             func(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error
+	RecordTOTPStepFunc      func(ctx context.Context, id uint, step int64) error
+	MarkEmailVerifiedFunc   func(ctx context.Context, id uint, at time.Time) error
 }
 
 func NewDefaultUserMock() *MockUserRepository {
 	return &MockUserRepository{
+		GetUserByIDFunc: func(ctx context.Context, id uint) (*models.User, error) {
+			return &models.User{
+				ID:       id,
+				Username: "testuser",
+				Email:    "test@example.com",
+				Password: "testpass",
+			}, nil
+		},
 		GetUserByUsernameFunc: func(ctx context.Context, username string) (*models.User, error) {
 			return &models.User{
 				ID:       1,
@@ -19,16 +35,71 @@ func NewDefaultUserMock() *MockUserRepository {
 				Password: "testpass",
 			}, nil
 		},
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{
+				ID:       1,
+				Username: "testuser",
+				Email:    email,
+				Password: "testpass",
+			}, nil
+		},
+		GetUserByIdentifierFunc: func(ctx context.Context, identifier string) (*models.User, error) {
+			return &models.User{
+				ID:       1,
+				Username: identifier,
+				Password: "testpass",
+			}, nil
+		},
 		CreateUserFunc: func(ctx context.Context, user *models.User) error {
 			return nil
 		},
+		UpdatePasswordFunc: func(ctx context.Context, id uint, passwordHash string) error {
+			return nil
+		},
+		SetTOTPFunc: func(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error {
+			return nil
+		},
+		RecordTOTPStepFunc: func(ctx context.Context, id uint, step int64) error {
+			return nil
+		},
+		MarkEmailVerifiedFunc: func(ctx context.Context, id uint, at time.Time) error {
+			return nil
+		},
 	}
 }
 
+func (mock *MockUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return mock.GetUserByIDFunc(ctx, id)
+}
+
 func (mock *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	return mock.GetUserByUsernameFunc(ctx, username)
 }
 
+func (mock *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return mock.GetUserByEmailFunc(ctx, email)
+}
+
+func (mock *MockUserRepository) GetUserByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
+	return mock.GetUserByIdentifierFunc(ctx, identifier)
+}
+
 func (mock *MockUserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	return mock.CreateUserFunc(ctx, user)
 }
+
+func (mock *MockUserRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	return mock.UpdatePasswordFunc(ctx, id, passwordHash)
+}
+
+func (mock *MockUserRepository) SetTOTP(ctx context.Context, id uint, secret string, enabled bool, lastStep int64) error {
+	return mock.SetTOTPFunc(ctx, id, secret, enabled, lastStep)
+}
+
+func (mock *MockUserRepository) RecordTOTPStep(ctx context.Context, id uint, step int64) error {
+	return mock.RecordTOTPStepFunc(ctx, id, step)
+}
+
+func (mock *MockUserRepository) MarkEmailVerified(ctx context.Context, id uint, at time.Time) error {
+	return mock.MarkEmailVerifiedFunc(ctx, id, at)
+}
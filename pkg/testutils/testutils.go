@@ -8,6 +8,7 @@ import (
 	"multitech/internal/models"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
@@ -79,6 +80,17 @@ func InitTestRedis(url string) *redis.Client {
 func RunMigrations(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
+		&models.OAuthClient{},
+		&models.ServiceAccount{},
+		&models.APIKey{},
+		&models.RecoveryCode{},
+		&models.Role{},
+		&models.Permission{},
+		&models.RelationTuple{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.OrganizationInvitation{},
+		&models.Invitation{},
 	)
 }
 
@@ -96,6 +108,16 @@ func SetJSONBody(ctx *gin.Context, body string) {
 	ctx.Request.Body = io.NopCloser(strings.NewReader(body))
 }
 
+func SetFormBody(ctx *gin.Context, values url.Values) {
+	ctx.Request.Method = http.MethodPost
+	ctx.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request.Body = io.NopCloser(strings.NewReader(values.Encode()))
+}
+
+func SetQuery(ctx *gin.Context, values url.Values) {
+	ctx.Request.URL = &url.URL{RawQuery: values.Encode()}
+}
+
 func CaptureOriginEnv() *map[string]string {
 	envs := make(map[string]string)
 	for _, env := range os.Environ() {
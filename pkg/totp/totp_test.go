@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code, err := Code(rfcSecret, current)
+	require.NoError(t, err)
+	previous, err := Code(rfcSecret, current-1)
+	require.NoError(t, err)
+	stale, err := Code(rfcSecret, current-2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		ok       bool
+		step     int64
+	}{
+		{name: "Current code", code: code, ok: true, step: current},
+		{name: "Previous period within skew", code: previous, ok: true, step: current - 1},
+		{name: "Outside skew", code: stale},
+		{name: "Replayed code", code: code, lastStep: current},
+		{name: "Wrong length", code: "12345"},
+		{name: "Wrong code", code: "000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.lastStep)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.step, step)
+		})
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri, err := url.Parse(URI("Multitech", "alice@example.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.True(t, strings.HasPrefix(uri.Path, "/Multitech:alice@example.com"))
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Multitech", uri.Query().Get("issuer"))
+}